@@ -0,0 +1,278 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// httpFlags are the serve flags of the --http transport.
+type httpFlags struct {
+	addr     *string
+	auth     *string
+	tlsCert  *string
+	tlsKey   *string
+	clientCA *string
+}
+
+// addHTTPFlags registers the --http transport flags on fs.
+func addHTTPFlags(fs *flag.FlagSet) *httpFlags {
+	return &httpFlags{
+		addr:     fs.String("http", "", "HTTP address to listen on (e.g. :8080). Uses streamable HTTP transport instead of stdio."),
+		auth:     fs.String("auth", "", "JSON file of the clients allowed to connect over HTTP, with their tokens or certificates and scopes"),
+		tlsCert:  fs.String("tls-cert", "", "TLS certificate file; serves HTTPS (requires --tls-key)"),
+		tlsKey:   fs.String("tls-key", "", "TLS private key file"),
+		clientCA: fs.String("tls-client-ca", "", "CA certificates file that client certificates must be signed by (mutual TLS)"),
+	}
+}
+
+// accessConfig is the --auth file.
+type accessConfig struct {
+	Clients []accessClient `json:"clients"`
+}
+
+// accessClient is a client allowed to connect over HTTP. It authenticates
+// with a bearer token or with a client certificate whose subject common
+// name is Certificate.
+type accessClient struct {
+	Name        string `json:"name"`
+	Token       string `json:"token,omitempty"`
+	Certificate string `json:"certificate,omitempty"`
+	Scope       string `json:"scope"`               // "read" or "write"
+	Namespace   string `json:"namespace,omitempty"` // restricts the client to these pages
+}
+
+// minTokenLength keeps guessable tokens out of the --auth file.
+const minTokenLength = 16
+
+// loadAccess reads and checks the --auth file.
+func loadAccess(path string) (*accessConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read auth file: %w", err)
+	}
+	var cfg accessConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse auth file %s: %w", path, err)
+	}
+	if len(cfg.Clients) == 0 {
+		return nil, fmt.Errorf("auth file %s lists no clients", path)
+	}
+
+	names := make(map[string]bool)
+	tokens := make(map[string]bool)
+	for i, c := range cfg.Clients {
+		if c.Name == "" {
+			return nil, fmt.Errorf("auth file %s: client %d has no name", path, i+1)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("auth file %s: client %q is listed twice", path, c.Name)
+		}
+		names[c.Name] = true
+		switch {
+		case (c.Token == "") == (c.Certificate == ""):
+			return nil, fmt.Errorf("auth file %s: client %q needs either a token or a certificate", path, c.Name)
+		case c.Token != "" && len(c.Token) < minTokenLength:
+			return nil, fmt.Errorf("auth file %s: token of client %q is shorter than %d characters", path, c.Name, minTokenLength)
+		case c.Token != "" && tokens[c.Token]:
+			return nil, fmt.Errorf("auth file %s: client %q reuses another client's token", path, c.Name)
+		}
+		tokens[c.Token] = true
+		if c.Scope != "read" && c.Scope != "write" {
+			return nil, fmt.Errorf("auth file %s: client %q has scope %q, want read or write", path, c.Name, c.Scope)
+		}
+	}
+	return &cfg, nil
+}
+
+// scope returns the scope the client was granted.
+func (c accessClient) scope() scope {
+	return scope{
+		readOnly:  c.Scope == "read",
+		namespace: strings.Trim(c.Namespace, "/"),
+	}
+}
+
+// check rejects flag combinations that cannot work, and the transport
+// flags without --http.
+func (f *httpFlags) check(access *accessConfig) error {
+	if *f.addr == "" {
+		if *f.auth != "" || *f.tlsCert != "" || *f.tlsKey != "" || *f.clientCA != "" {
+			return errors.New("--auth and the --tls flags require --http")
+		}
+		return nil
+	}
+	if (*f.tlsCert == "") != (*f.tlsKey == "") {
+		return errors.New("--tls-cert and --tls-key must be given together")
+	}
+	if *f.clientCA != "" && *f.tlsCert == "" {
+		return errors.New("--tls-client-ca requires --tls-cert and --tls-key")
+	}
+	if access != nil && *f.clientCA == "" {
+		for _, c := range access.Clients {
+			if c.Certificate != "" {
+				return fmt.Errorf("client %q authenticates with a certificate, which requires --tls-client-ca", c.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS configuration of the listener, or nil to serve
+// plain HTTP. Client certificates are verified against --tls-client-ca.
+// Without an --auth file they are the only credential, so every client
+// must present one.
+func (f *httpFlags) tlsConfig(access *accessConfig) (*tls.Config, error) {
+	if *f.tlsCert == "" {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if *f.clientCA == "" {
+		return cfg, nil
+	}
+	pem, err := os.ReadFile(*f.clientCA)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", *f.clientCA)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	if access != nil {
+		for _, c := range access.Clients {
+			if c.Token != "" {
+				cfg.ClientAuth = tls.VerifyClientCertIfGiven
+				break
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// serveHTTP serves MCP over streamable HTTP until the listener fails.
+// server returns the MCP server of a scope; readOnly caps every client's
+// scope.
+func serveHTTP(f *httpFlags, readOnly bool, server func(scope) *mcp.Server) error {
+	var access *accessConfig
+	if *f.auth != "" {
+		var err error
+		if access, err = loadAccess(*f.auth); err != nil {
+			return err
+		}
+	}
+	if err := f.check(access); err != nil {
+		return err
+	}
+	tlsConfig, err := f.tlsConfig(access)
+	if err != nil {
+		return err
+	}
+
+	var handler http.Handler
+	if access != nil {
+		handler = newAuthHandler(access, readOnly, server)
+	} else {
+		handler = mcpHandler(server(scope{readOnly: readOnly}))
+		if *f.clientCA == "" {
+			fmt.Fprintf(os.Stderr, "graphthulhu: warning: anyone who can reach %s can use the graph; see --auth and --tls-client-ca\n", *f.addr)
+		}
+	}
+
+	hs := &http.Server{Addr: *f.addr, Handler: handler, TLSConfig: tlsConfig}
+	if tlsConfig != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu: listening on %s (TLS)\n", *f.addr)
+		return hs.ListenAndServeTLS(*f.tlsCert, *f.tlsKey)
+	}
+	fmt.Fprintf(os.Stderr, "graphthulhu: listening on %s\n", *f.addr)
+	return hs.ListenAndServe()
+}
+
+// mcpHandler serves srv over streamable HTTP.
+func mcpHandler(srv *mcp.Server) http.Handler {
+	return mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
+		return srv
+	}, nil)
+}
+
+// authHandler authenticates every request and serves it with the handler
+// of the client it comes from. Each client has a handler of its own, so
+// the sessions one client opens cannot be used with another's credentials.
+type authHandler struct {
+	clients []*httpClient
+}
+
+// httpClient is an accessClient with its MCP handler.
+type httpClient struct {
+	accessClient
+	tokenSum [sha256.Size]byte
+	handler  http.Handler
+}
+
+// newAuthHandler returns the handler for the clients of access. readOnly
+// caps every client's scope.
+func newAuthHandler(access *accessConfig, readOnly bool, server func(scope) *mcp.Server) *authHandler {
+	h := &authHandler{}
+	for _, c := range access.Clients {
+		sc := c.scope()
+		sc.readOnly = sc.readOnly || readOnly
+		hc := &httpClient{accessClient: c, handler: mcpHandler(server(sc))}
+		if c.Token != "" {
+			hc.tokenSum = sha256.Sum256([]byte(c.Token))
+		}
+		h.clients = append(h.clients, hc)
+	}
+	return h
+}
+
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c := h.client(r)
+	if c == nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="graphthulhu"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	c.handler.ServeHTTP(w, r)
+}
+
+// client returns the client a request authenticates as, or nil. A bearer
+// token takes precedence over the client certificate.
+func (h *authHandler) client(r *http.Request) *httpClient {
+	if header := r.Header.Get("Authorization"); header != "" {
+		kind, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(kind, "Bearer") {
+			return nil
+		}
+		// Compare digests in constant time, and against every client, so the
+		// response time tells nothing about the tokens.
+		sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+		var match *httpClient
+		for _, c := range h.clients {
+			if c.Token != "" && subtle.ConstantTimeCompare(sum[:], c.tokenSum[:]) == 1 {
+				match = c
+			}
+		}
+		return match
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil
+	}
+	name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	for _, c := range h.clients {
+		if c.Certificate != "" && c.Certificate == name {
+			return c
+		}
+	}
+	return nil
+}
@@ -2,7 +2,12 @@ package backend
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/skridlevsky/graphthulhu/types"
 )
@@ -39,19 +44,29 @@ type Backend interface {
 }
 
 // HasDataScript is a marker interface for backends supporting DataScript queries.
-// Logseq implements this; Obsidian does not.
+// Logseq answers them natively; the vault backend evaluates them in-process
+// with the datalog package.
 type HasDataScript interface {
 	HasDataScript()
 }
 
+// GraphKind is implemented by backends that report which kind of graph they
+// serve: "logseq" for the Logseq API or a Logseq file graph, "obsidian" for
+// an Obsidian vault.
+type GraphKind interface {
+	GraphKind() string
+}
+
 // TagSearcher is implemented by backends that support tag search without DataScript.
 type TagSearcher interface {
 	FindBlocksByTag(ctx context.Context, tag string, includeChildren bool) ([]TagResult, error)
 }
 
 // PropertySearcher is implemented by backends that support property search without DataScript.
+// FindByProperty returns the pages and blocks whose property key satisfies
+// operator applied to values, as parser.NewPropertyMatcher defines them.
 type PropertySearcher interface {
-	FindByProperty(ctx context.Context, key, value, operator string) ([]PropertyResult, error)
+	FindByProperty(ctx context.Context, key, operator string, values []string) ([]PropertyResult, error)
 }
 
 // JournalSearcher is implemented by backends that support journal search without DataScript.
@@ -65,30 +80,179 @@ type FullTextSearcher interface {
 	FullTextSearch(ctx context.Context, query string, limit int) ([]SearchHit, error)
 }
 
-// SearchHit is a block found by full-text search.
+// PageResolver is implemented by backends where a link target is not always a
+// canonical page name — Obsidian resolves [[Spec]] to "projects/alpha/Spec"
+// when that basename is unique. ResolvePageName returns the canonical name,
+// "" when nothing matches, or an error when the name is ambiguous.
+type PageResolver interface {
+	ResolvePageName(ctx context.Context, name string) (string, error)
+}
+
+// PageFileStore is implemented by backends that keep every page in a file
+// of its own. The operation log undoes writes to such pages by restoring
+// their files byte for byte.
+type PageFileStore interface {
+	// PageFile returns the path of a page's file, relative to the graph
+	// root, and its content.
+	PageFile(ctx context.Context, name string) (path, content string, err error)
+	// WritePageFile replaces or creates a page file and re-indexes it.
+	WritePageFile(ctx context.Context, path, content string) error
+}
+
+// RenamePlanner is implemented by backends that can work out exactly what a
+// page rename would change without making it. rename_page's dry run uses it.
+type RenamePlanner interface {
+	PlanRenamePage(ctx context.Context, oldName, newName string) (*RenamePlan, error)
+}
+
+// RenamePlan is what renaming page From to To would change: the page file it
+// moves and every line rewritten so links follow the new name.
+type RenamePlan struct {
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	FromFile string       `json:"fromFile,omitempty"`
+	ToFile   string       `json:"toFile,omitempty"`
+	Links    []LineChange `json:"links"`
+}
+
+// LineChange is a line of a page that a write would rewrite. Line is 1-based
+// within File; when it is 0, Before and After are the whole file.
+type LineChange struct {
+	Page   string `json:"page"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// ErrConflict is returned when a block write's ifMatch precondition fails:
+// the block changed since the caller read it.
+var ErrConflict = errors.New("conflict")
+
+// BlockVersion returns the version of a block with the given content. It
+// changes whenever the content does.
+func BlockVersion(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:8])
+}
+
+// BlockVersioner is implemented by backends whose index can lag behind the
+// stored blocks, as a vault edited in another program does until the file
+// watcher catches up. CurrentBlockVersion returns the version of a block as
+// it is stored right now.
+type BlockVersioner interface {
+	CurrentBlockVersion(ctx context.Context, uuid string) (string, error)
+}
+
+// ifMatchKey is the context key of the precondition WithIfMatch attaches.
+type ifMatchKey struct{}
+
+// ifMatch is an ifMatch precondition: block uuid must have version.
+type ifMatch struct {
+	uuid, version string
+}
+
+// WithIfMatch returns a context carrying an ifMatch precondition on writes
+// to block uuid. Backends with a write lock, as a vault has, check it again
+// under that lock with CheckIfMatch, so that nothing can change the block
+// between the caller's check and the write. An empty version sets none.
+func WithIfMatch(ctx context.Context, uuid, version string) context.Context {
+	if version == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ifMatchKey{}, ifMatch{uuid: uuid, version: version})
+}
+
+// CheckIfMatch returns an ErrConflict error when ctx carries an ifMatch
+// precondition on block uuid that current, the block's version now, fails.
+func CheckIfMatch(ctx context.Context, uuid, current string) error {
+	m, ok := ctx.Value(ifMatchKey{}).(ifMatch)
+	if !ok || m.uuid != uuid || m.version == current {
+		return nil
+	}
+	return fmt.Errorf("%w: block %s has changed since version %s (now %s); read it again before writing", ErrConflict, uuid, m.version, current)
+}
+
+// HasIfMatch reports whether ctx carries an ifMatch precondition on block
+// uuid.
+func HasIfMatch(ctx context.Context, uuid string) bool {
+	m, ok := ctx.Value(ifMatchKey{}).(ifMatch)
+	return ok && m.uuid == uuid
+}
+
+// ChangeCommitter is implemented by backends that record writes in version
+// control, as a vault with git commits enabled does. CommitChanges commits
+// the writes made since the last call as one commit with the given message;
+// callers make it once per operation.
+type ChangeCommitter interface {
+	CommitChanges(ctx context.Context, message string) error
+}
+
+// PageHistorian is implemented by backends that keep earlier versions of
+// pages. PageHistory lists a page's versions, newest first; limit <= 0 means
+// all. PageAtVersion returns the page's file path and its content at version.
+type PageHistorian interface {
+	PageHistory(ctx context.Context, name string, limit int) ([]PageVersion, error)
+	PageAtVersion(ctx context.Context, name, version string) (path, content string, err error)
+}
+
+// PageVersion is one saved version of a page: a commit that changed its file.
+type PageVersion struct {
+	Version string    `json:"version"`
+	Time    time.Time `json:"time"`
+	Author  string    `json:"author"`
+	Message string    `json:"message"`
+}
+
+// PageWatcher is implemented by backends that can report changes to pages
+// as they happen: a vault through its file watcher, the Logseq API by
+// polling. WatchPages calls fn for every page created, changed or deleted
+// until ctx is done. fn must not block.
+type PageWatcher interface {
+	WatchPages(ctx context.Context, fn func(PageChange))
+}
+
+// PageChange reports that a page was created or changed, or deleted.
+type PageChange struct {
+	Page    string
+	Deleted bool
+}
+
+// SearchHit is a block found by full-text search. Score is the hit's
+// relevance (higher is better); hits are returned best first.
 type SearchHit struct {
-	PageName string `json:"page"`
-	UUID     string `json:"uuid"`
-	Content  string `json:"content"`
+	PageName string  `json:"page"`
+	UUID     string  `json:"uuid"`
+	Content  string  `json:"content"`
+	Score    float64 `json:"score,omitempty"`
 }
 
 // TagResult holds a block found by tag search, grouped by page.
+// PageTagged is set when the page itself carries the tag (e.g. in
+// frontmatter), in which case Blocks may be empty.
 type TagResult struct {
-	Page   string             `json:"page"`
-	Blocks []types.BlockEntity `json:"blocks"`
+	Page       string              `json:"page"`
+	PageTagged bool                `json:"pageTagged,omitempty"`
+	Blocks     []types.BlockEntity `json:"blocks"`
 }
 
-// PropertyResult holds a page or block found by property search.
+// PropertyResult holds a page or block found by property search. Name is
+// set for pages; UUID, Content and Page, the page the block is on, for
+// blocks.
 type PropertyResult struct {
 	Type       string         `json:"type"` // "page" or "block"
 	Name       string         `json:"name,omitempty"`
 	UUID       string         `json:"uuid,omitempty"`
+	Content    string         `json:"content,omitempty"`
+	Page       string         `json:"page,omitempty"`
 	Properties map[string]any `json:"properties"`
 }
 
-// JournalResult holds a journal entry found by search.
+// JournalResult holds a journal entry found by search. Scores, when set,
+// holds the relevance of each of Blocks (higher is better).
 type JournalResult struct {
 	Date    string              `json:"date"`
 	Page    string              `json:"page"`
 	Blocks  []types.BlockEntity `json:"blocks"`
+	Scores  []float64           `json:"scores,omitempty"`
 }
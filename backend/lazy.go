@@ -23,6 +23,15 @@ type IndexableBackend interface {
 	TagSearcher
 	PropertySearcher
 	JournalSearcher
+	PageResolver
+	PageFileStore
+	RenamePlanner
+	BlockVersioner
+	ChangeCommitter
+	PageHistorian
+	PageWatcher
+	HasDataScript
+	GraphKind
 }
 
 // LazyBackend wraps an IndexableBackend that needs time to initialize.
@@ -120,6 +129,15 @@ func (lb *LazyBackend) DatascriptQuery(ctx context.Context, query string, inputs
 	return lb.inner.DatascriptQuery(ctx, query, inputs...)
 }
 
+// HasDataScript implements backend.HasDataScript.
+func (lb *LazyBackend) HasDataScript() {}
+
+// GraphKind implements backend.GraphKind. The kind is fixed when the inner
+// backend is built, so it is answered without waiting for readiness.
+func (lb *LazyBackend) GraphKind() string {
+	return lb.inner.GraphKind()
+}
+
 // --- Write operations ---
 
 func (lb *LazyBackend) CreatePage(ctx context.Context, name string, properties map[string]any, opts map[string]any) (*types.PageEntity, error) {
@@ -203,11 +221,11 @@ func (lb *LazyBackend) FindBlocksByTag(ctx context.Context, tag string, includeC
 	return lb.inner.FindBlocksByTag(ctx, tag, includeChildren)
 }
 
-func (lb *LazyBackend) FindByProperty(ctx context.Context, key, value, operator string) ([]PropertyResult, error) {
+func (lb *LazyBackend) FindByProperty(ctx context.Context, key, operator string, values []string) ([]PropertyResult, error) {
 	if err := lb.wait(ctx); err != nil {
 		return nil, err
 	}
-	return lb.inner.FindByProperty(ctx, key, value, operator)
+	return lb.inner.FindByProperty(ctx, key, operator, values)
 }
 
 func (lb *LazyBackend) SearchJournals(ctx context.Context, query string, from, to string) ([]JournalResult, error) {
@@ -216,3 +234,65 @@ func (lb *LazyBackend) SearchJournals(ctx context.Context, query string, from, t
 	}
 	return lb.inner.SearchJournals(ctx, query, from, to)
 }
+
+func (lb *LazyBackend) ResolvePageName(ctx context.Context, name string) (string, error) {
+	if err := lb.wait(ctx); err != nil {
+		return "", err
+	}
+	return lb.inner.ResolvePageName(ctx, name)
+}
+
+func (lb *LazyBackend) PageFile(ctx context.Context, name string) (string, string, error) {
+	if err := lb.wait(ctx); err != nil {
+		return "", "", err
+	}
+	return lb.inner.PageFile(ctx, name)
+}
+
+func (lb *LazyBackend) WritePageFile(ctx context.Context, path, content string) error {
+	if err := lb.wait(ctx); err != nil {
+		return err
+	}
+	return lb.inner.WritePageFile(ctx, path, content)
+}
+
+func (lb *LazyBackend) PlanRenamePage(ctx context.Context, oldName, newName string) (*RenamePlan, error) {
+	if err := lb.wait(ctx); err != nil {
+		return nil, err
+	}
+	return lb.inner.PlanRenamePage(ctx, oldName, newName)
+}
+
+func (lb *LazyBackend) CurrentBlockVersion(ctx context.Context, uuid string) (string, error) {
+	if err := lb.wait(ctx); err != nil {
+		return "", err
+	}
+	return lb.inner.CurrentBlockVersion(ctx, uuid)
+}
+
+func (lb *LazyBackend) CommitChanges(ctx context.Context, message string) error {
+	if err := lb.wait(ctx); err != nil {
+		return err
+	}
+	return lb.inner.CommitChanges(ctx, message)
+}
+
+func (lb *LazyBackend) PageHistory(ctx context.Context, name string, limit int) ([]PageVersion, error) {
+	if err := lb.wait(ctx); err != nil {
+		return nil, err
+	}
+	return lb.inner.PageHistory(ctx, name, limit)
+}
+
+func (lb *LazyBackend) PageAtVersion(ctx context.Context, name, version string) (string, string, error) {
+	if err := lb.wait(ctx); err != nil {
+		return "", "", err
+	}
+	return lb.inner.PageAtVersion(ctx, name, version)
+}
+
+// WatchPages registers fn right away, so no change made while the backend
+// is still loading is missed.
+func (lb *LazyBackend) WatchPages(ctx context.Context, fn func(PageChange)) {
+	lb.inner.WatchPages(ctx, fn)
+}
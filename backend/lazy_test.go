@@ -60,12 +60,31 @@ func (stubBackend) FullTextSearch(context.Context, string, int) ([]backend.Searc
 func (stubBackend) FindBlocksByTag(context.Context, string, bool) ([]backend.TagResult, error) {
 	return []backend.TagResult{{Page: "p"}}, nil
 }
-func (stubBackend) FindByProperty(context.Context, string, string, string) ([]backend.PropertyResult, error) {
+func (stubBackend) FindByProperty(context.Context, string, string, []string) ([]backend.PropertyResult, error) {
 	return []backend.PropertyResult{{Type: "page", Name: "p"}}, nil
 }
 func (stubBackend) SearchJournals(context.Context, string, string, string) ([]backend.JournalResult, error) {
 	return []backend.JournalResult{{Page: "j"}}, nil
 }
+func (stubBackend) ResolvePageName(context.Context, string) (string, error) { return "p", nil }
+func (stubBackend) HasDataScript()                                          {}
+func (stubBackend) PageFile(context.Context, string) (string, string, error) {
+	return "p.md", "", nil
+}
+func (stubBackend) WritePageFile(context.Context, string, string) error { return nil }
+func (stubBackend) PlanRenamePage(context.Context, string, string) (*backend.RenamePlan, error) {
+	return &backend.RenamePlan{}, nil
+}
+func (stubBackend) CurrentBlockVersion(context.Context, string) (string, error) { return "v", nil }
+func (stubBackend) CommitChanges(context.Context, string) error                 { return nil }
+func (stubBackend) PageHistory(context.Context, string, int) ([]backend.PageVersion, error) {
+	return nil, nil
+}
+func (stubBackend) PageAtVersion(context.Context, string, string) (string, string, error) {
+	return "p.md", "", nil
+}
+func (stubBackend) WatchPages(context.Context, func(backend.PageChange)) {}
+func (stubBackend) GraphKind() string                                    { return "obsidian" }
 
 func TestLazyBackend_PingRespondsBeforeReady(t *testing.T) {
 	lb := backend.NewLazyBackend(stubBackend{})
@@ -173,7 +192,7 @@ func TestLazyBackend_CapabilityCallsForwarded(t *testing.T) {
 		t.Errorf("FindBlocksByTag forwarding broken: tags=%v err=%v", tags, err)
 	}
 
-	props, err := lb.FindByProperty(context.Background(), "k", "v", "eq")
+	props, err := lb.FindByProperty(context.Background(), "k", "eq", []string{"v"})
 	if err != nil || len(props) != 1 {
 		t.Errorf("FindByProperty forwarding broken: props=%v err=%v", props, err)
 	}
@@ -182,4 +201,9 @@ func TestLazyBackend_CapabilityCallsForwarded(t *testing.T) {
 	if err != nil || len(jrs) != 1 {
 		t.Errorf("SearchJournals forwarding broken: jrs=%v err=%v", jrs, err)
 	}
+
+	name, err := lb.ResolvePageName(context.Background(), "q")
+	if err != nil || name != "p" {
+		t.Errorf("ResolvePageName forwarding broken: name=%q err=%v", name, err)
+	}
 }
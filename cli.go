@@ -9,19 +9,21 @@ import (
 	"strings"
 	"time"
 
+	"github.com/skridlevsky/graphthulhu/backend"
 	"github.com/skridlevsky/graphthulhu/client"
 	"github.com/skridlevsky/graphthulhu/types"
 )
 
 // runJournal appends a block to today's (or a specified date's) journal page.
-func runJournal(args []string, c *client.Client) {
+func runJournal(args []string) {
 	fs := flag.NewFlagSet("journal", flag.ExitOnError)
+	bf := addBackendFlags(fs)
 	date := fs.String("date", "", "Journal date (YYYY-MM-DD). Default: today")
 	fs.StringVar(date, "d", "", "Journal date (YYYY-MM-DD). Default: today")
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: graphthulhu journal [--date YYYY-MM-DD] CONTENT\n")
 		fmt.Fprintf(os.Stderr, "       echo CONTENT | graphthulhu journal\n\n")
-		fmt.Fprintf(os.Stderr, "Appends a block to a Logseq journal page.\n")
+		fmt.Fprintf(os.Stderr, "Appends a block to a journal page.\n")
 		fmt.Fprintf(os.Stderr, "Prints the created block UUID on success.\n\n")
 		fs.PrintDefaults()
 	}
@@ -45,13 +47,25 @@ func runJournal(args []string, c *client.Client) {
 		t = time.Now()
 	}
 
+	c, closeBackend, err := openBackend(bf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu journal: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeBackend()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	pageName := findJournalPage(ctx, c, t)
 	if pageName == "" {
-		// No existing page found — use ordinal format (most common Logseq default).
-		pageName = ordinalDate(t)
+		// No existing page found — use the backend's own journal naming,
+		// or the ordinal format (most common Logseq default).
+		if jn, ok := c.(journalNamer); ok {
+			pageName = jn.JournalPageName(t)
+		} else {
+			pageName = ordinalDate(t)
+		}
 	}
 
 	block, err := c.AppendBlockInPage(ctx, pageName, content)
@@ -59,21 +73,36 @@ func runJournal(args []string, c *client.Client) {
 		fmt.Fprintf(os.Stderr, "graphthulhu journal: %v\n", err)
 		os.Exit(1)
 	}
+	commitWrite(ctx, c, "journal", pageName)
 
 	if block != nil {
 		fmt.Println(block.UUID)
 	}
 }
 
+// commitWrite commits a command's write when the backend records writes in
+// version control (--git-commit). The write has happened either way, so a
+// failed commit is only reported.
+func commitWrite(ctx context.Context, c backend.Backend, command, page string) {
+	vc, ok := c.(backend.ChangeCommitter)
+	if !ok {
+		return
+	}
+	if err := vc.CommitChanges(ctx, "graphthulhu: "+command+" "+page); err != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu %s: failed to commit: %v\n", command, err)
+	}
+}
+
 // runAdd appends a block to a named page.
-func runAdd(args []string, c *client.Client) {
+func runAdd(args []string) {
 	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	bf := addBackendFlags(fs)
 	page := fs.String("page", "", "Page name (required)")
 	fs.StringVar(page, "p", "", "Page name (required)")
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: graphthulhu add --page PAGE CONTENT\n")
 		fmt.Fprintf(os.Stderr, "       echo CONTENT | graphthulhu add -p PAGE\n\n")
-		fmt.Fprintf(os.Stderr, "Appends a block to a Logseq page (creates page if needed).\n")
+		fmt.Fprintf(os.Stderr, "Appends a block to a page (creates page if needed).\n")
 		fmt.Fprintf(os.Stderr, "Prints the created block UUID on success.\n\n")
 		fs.PrintDefaults()
 	}
@@ -92,6 +121,13 @@ func runAdd(args []string, c *client.Client) {
 		os.Exit(1)
 	}
 
+	c, closeBackend, err := openBackend(bf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu add: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeBackend()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -100,6 +136,7 @@ func runAdd(args []string, c *client.Client) {
 		fmt.Fprintf(os.Stderr, "graphthulhu add: %v\n", err)
 		os.Exit(1)
 	}
+	commitWrite(ctx, c, "add", *page)
 
 	if block != nil {
 		fmt.Println(block.UUID)
@@ -107,8 +144,9 @@ func runAdd(args []string, c *client.Client) {
 }
 
 // runSearch performs full-text search and prints results to stdout.
-func runSearch(args []string, c *client.Client) {
+func runSearch(args []string) {
 	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	bf := addBackendFlags(fs)
 	limit := fs.Int("limit", 10, "Max results")
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: graphthulhu search [-limit N] QUERY\n\n")
@@ -123,9 +161,33 @@ func runSearch(args []string, c *client.Client) {
 		os.Exit(1)
 	}
 
+	c, closeBackend, err := openBackend(bf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu search: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeBackend()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Use the backend's index when it has one.
+	if fts, ok := c.(backend.FullTextSearcher); ok {
+		hits, err := fts.FullTextSearch(ctx, query, *limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "graphthulhu search: %v\n", err)
+			os.Exit(1)
+		}
+		if len(hits) == 0 {
+			fmt.Fprintf(os.Stderr, "no results for %q\n", query)
+			os.Exit(1)
+		}
+		for _, hit := range hits {
+			fmt.Printf("%s | %s\n", hit.PageName, hit.Content)
+		}
+		return
+	}
+
 	queryLower := strings.ToLower(query)
 	pages, err := c.GetAllPages(ctx)
 	if err != nil {
@@ -158,6 +220,31 @@ func runSearch(args []string, c *client.Client) {
 
 // --- Helpers ---
 
+// journalNamer is implemented by backends that know what their journal page
+// for a day is called, so a new journal lands where the graph expects it.
+type journalNamer interface {
+	JournalPageName(t time.Time) string
+}
+
+// openBackend builds the backend bf selects. Vault and graph directories are
+// indexed before it returns, since a CLI command runs only once. The returned
+// function releases the backend.
+func openBackend(bf *backendFlags) (backend.Backend, func(), error) {
+	vc, err := bf.vaultClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	if vc == nil {
+		return client.New("", ""), func() {}, nil
+	}
+	if err := vc.Load(); err != nil {
+		vc.Close()
+		return nil, nil, fmt.Errorf("failed to load vault: %w", err)
+	}
+	vc.BuildBacklinks()
+	return vc, func() { vc.Close() }, nil
+}
+
 // readContent gets content from positional args or stdin (if piped).
 func readContent(fs *flag.FlagSet) string {
 	if args := fs.Args(); len(args) > 0 {
@@ -180,13 +267,17 @@ func readContent(fs *flag.FlagSet) string {
 	return strings.TrimSpace(string(data))
 }
 
-// findJournalPage tries common Logseq journal date formats to find an existing page.
-func findJournalPage(ctx context.Context, c *client.Client, t time.Time) string {
+// findJournalPage tries the backend's own journal name and common Logseq
+// journal date formats to find an existing page.
+func findJournalPage(ctx context.Context, c backend.Backend, t time.Time) string {
 	names := []string{
 		ordinalDate(t),
 		t.Format("2006-01-02"),
 		t.Format("January 2, 2006"),
 	}
+	if jn, ok := c.(journalNamer); ok {
+		names = append([]string{jn.JournalPageName(t)}, names...)
+	}
 
 	for _, name := range names {
 		page, err := c.GetPage(ctx, name)
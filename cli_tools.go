@@ -0,0 +1,625 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/client"
+	"github.com/skridlevsky/graphthulhu/oplog"
+	"github.com/skridlevsky/graphthulhu/vault"
+)
+
+// runTool runs the MCP tool called name as a CLI command. Flags come from
+// the tool's input schema, which the SDK derives from its types.*Input
+// struct, and the call goes through the same server and handlers as MCP
+// clients use. Prints the result as text, or as the raw tool output with
+// --json.
+func runTool(name string, args []string) {
+	name = strings.ReplaceAll(name, "-", "_")
+	prefix := "graphthulhu " + name
+	ctx := context.Background()
+	if known, err := knownTool(ctx, name); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", prefix, err)
+		os.Exit(1)
+	} else if !known {
+		fmt.Fprintf(os.Stderr, "graphthulhu: unknown command %q\n\n", name)
+		printUsage()
+		os.Exit(1)
+	}
+
+	// The backend decides which tools exist, so its flags are parsed
+	// before the tool's own.
+	bfs := flag.NewFlagSet(name, flag.ExitOnError)
+	bf := addBackendFlags(bfs)
+	readOnly := addReadOnlyFlag(bfs)
+	backendArgs, rest := extractFlags(args, bfs)
+	bfs.Parse(backendArgs)
+
+	b, closeBackend, err := openBackend(bf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", prefix, err)
+		os.Exit(1)
+	}
+	defer closeBackend()
+	ops, err := bf.operationLog()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", prefix, err)
+		os.Exit(1)
+	}
+
+	session, err := connectInProcess(ctx, newServer(b, *readOnly, ops, nil))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", prefix, err)
+		os.Exit(1)
+	}
+	defer session.Close()
+
+	tool, err := findTool(ctx, session, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", prefix, err)
+		os.Exit(1)
+	}
+	if tool == nil {
+		if *readOnly {
+			fmt.Fprintf(os.Stderr, "%s: not available with --read-only or this backend\n", prefix)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: not available with this backend\n", prefix)
+		}
+		os.Exit(1)
+	}
+
+	schema, err := parseInputSchema(tool.InputSchema)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", prefix, err)
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	flags := schema.addFlags(fs)
+	asJSON := fs.Bool("json", false, "Print the raw tool output instead of text")
+	input := fs.String("input", "", "Tool input as a JSON object; flags override its fields")
+	addBackendFlags(fs) // listed in -h; already parsed above
+	addReadOnlyFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: graphthulhu %s [flags]%s\n\n", name, schema.positionalUsage())
+		fmt.Fprintf(os.Stderr, "%s\n\n", tool.Description)
+		fs.PrintDefaults()
+	}
+	fs.Parse(rest)
+
+	arguments, err := schema.arguments(*input, flags, fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n\n", prefix, err)
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: arguments})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", prefix, err)
+		os.Exit(1)
+	}
+	text := resultText(res)
+	if res.IsError {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", prefix, text)
+		os.Exit(1)
+	}
+	if *asJSON {
+		fmt.Println(text)
+		return
+	}
+	printToolText(os.Stdout, text)
+}
+
+// runTools lists the tools the selected backend offers.
+func runTools(args []string) {
+	fs := flag.NewFlagSet("tools", flag.ExitOnError)
+	bf := addBackendFlags(fs)
+	readOnly := addReadOnlyFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: graphthulhu tools [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Lists the MCP tools available as commands. Run graphthulhu TOOL -h for a tool's flags.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	b, closeBackend, err := openBackend(bf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu tools: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeBackend()
+	ops, err := bf.operationLog()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu tools: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	session, err := connectInProcess(ctx, newServer(b, *readOnly, ops, nil))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu tools: %v\n", err)
+		os.Exit(1)
+	}
+	defer session.Close()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "graphthulhu tools: %v\n", err)
+			os.Exit(1)
+		}
+		summary, _, _ := strings.Cut(tool.Description, ". ")
+		fmt.Fprintf(tw, "%s\t%s\n", tool.Name, strings.TrimSuffix(summary, "."))
+	}
+	tw.Flush()
+}
+
+// knownTool reports whether some backend offers a tool called name. It
+// needs no graph: servers over a Logseq API client and a vault register
+// their tools without reaching either.
+func knownTool(ctx context.Context, name string) (bool, error) {
+	ops, err := oplog.Open("")
+	if err != nil {
+		return false, err
+	}
+	for _, b := range []backend.Backend{client.New("", ""), vault.New("")} {
+		session, err := connectInProcess(ctx, newServer(b, false, ops, nil))
+		if err != nil {
+			return false, err
+		}
+		tool, err := findTool(ctx, session, name)
+		session.Close()
+		if err != nil || tool != nil {
+			return tool != nil, err
+		}
+	}
+	return false, nil
+}
+
+// connectInProcess connects a client session to srv over an in-memory
+// transport.
+func connectInProcess(ctx context.Context, srv *mcp.Server) (*mcp.ClientSession, error) {
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := srv.Connect(ctx, serverTransport, nil); err != nil {
+		return nil, err
+	}
+	c := mcp.NewClient(&mcp.Implementation{Name: "graphthulhu-cli", Version: version}, nil)
+	return c.Connect(ctx, clientTransport, nil)
+}
+
+// findTool returns the tool called name, or nil if the server has none.
+func findTool(ctx context.Context, session *mcp.ClientSession, name string) (*mcp.Tool, error) {
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			return nil, err
+		}
+		if tool.Name == name {
+			return tool, nil
+		}
+	}
+	return nil, nil
+}
+
+// extractFlags splits args into the flags defined on fs, with their values,
+// and everything else, so fs can be parsed ahead of flags it doesn't know.
+func extractFlags(args []string, fs *flag.FlagSet) (known, rest []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return known, append(rest, args[i:]...)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f := fs.Lookup(name)
+		if !strings.HasPrefix(arg, "-") || f == nil {
+			rest = append(rest, arg)
+			continue
+		}
+		known = append(known, arg)
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); hasValue || (ok && bf.IsBoolFlag()) {
+			continue
+		}
+		if i+1 < len(args) {
+			i++
+			known = append(known, args[i])
+		}
+	}
+	return known, rest
+}
+
+// inputSchema is the part of a tool's JSON input schema the CLI uses.
+type inputSchema struct {
+	Properties map[string]*propSchema `json:"properties"`
+	Required   []string               `json:"required"`
+}
+
+// propSchema describes one tool input.
+type propSchema struct {
+	Type        json.RawMessage `json:"type"`
+	Description string          `json:"description"`
+	Items       *propSchema     `json:"items"`
+}
+
+func parseInputSchema(v any) (*inputSchema, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var s inputSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("read input schema: %w", err)
+	}
+	return &s, nil
+}
+
+// kind returns the JSON type of the input, ignoring "null" in type unions,
+// or "" when the schema doesn't say.
+func (p *propSchema) kind() string {
+	if p == nil {
+		return ""
+	}
+	var single string
+	if json.Unmarshal(p.Type, &single) == nil {
+		return single
+	}
+	var union []string
+	json.Unmarshal(p.Type, &union)
+	for _, t := range union {
+		if t != "null" {
+			return t
+		}
+	}
+	return ""
+}
+
+// addFlags defines a flag per input on fs, named in kebab case, and returns
+// them keyed by input name.
+func (s *inputSchema) addFlags(fs *flag.FlagSet) map[string]*toolFlag {
+	flags := make(map[string]*toolFlag, len(s.Properties))
+	for name, prop := range s.Properties {
+		f := &toolFlag{schema: prop}
+		usage := prop.Description
+		switch prop.kind() {
+		case "array":
+			usage += " (repeatable, or a JSON array)"
+		case "object":
+			usage += " (key=value, repeatable, or a JSON object)"
+		}
+		if slices.Contains(s.Required, name) {
+			usage += " (required)"
+		}
+		fs.Var(f, flagName(name), usage)
+		flags[name] = f
+	}
+	return flags
+}
+
+// positionalUsage describes the argument that fills the sole required text
+// input, if the tool has one.
+func (s *inputSchema) positionalUsage() string {
+	if name := s.positionalProp(); name != "" {
+		return " [" + strings.ToUpper(flagName(name)) + "]"
+	}
+	return ""
+}
+
+// positionalProp returns the input that positional arguments fill: the
+// tool's only required string input, or "" if there isn't exactly one.
+func (s *inputSchema) positionalProp() string {
+	var found string
+	for _, name := range s.Required {
+		if s.Properties[name].kind() != "string" {
+			continue
+		}
+		if found != "" {
+			return ""
+		}
+		found = name
+	}
+	return found
+}
+
+// arguments builds the tool call arguments from the --input JSON, the flags
+// that were set and any positional arguments.
+func (s *inputSchema) arguments(input string, flags map[string]*toolFlag, positional []string) (map[string]any, error) {
+	args := make(map[string]any)
+	if input != "" {
+		if err := json.Unmarshal([]byte(input), &args); err != nil {
+			return nil, fmt.Errorf("--input: %w", err)
+		}
+	}
+	for name, f := range flags {
+		if f.set {
+			args[name] = f.value
+		}
+	}
+	if len(positional) > 0 {
+		name := s.positionalProp()
+		if name == "" || flags[name].set {
+			return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(positional, " "))
+		}
+		args[name] = strings.Join(positional, " ")
+	}
+	for _, name := range s.Required {
+		if _, ok := args[name]; !ok {
+			return nil, fmt.Errorf("--%s is required", flagName(name))
+		}
+	}
+	return args, nil
+}
+
+// toolFlag is the flag for one tool input. Set converts each value to the
+// input's JSON type; arrays and objects accumulate over repeated flags.
+type toolFlag struct {
+	schema *propSchema
+	value  any
+	set    bool
+}
+
+func (f *toolFlag) String() string {
+	if f == nil || !f.set {
+		return ""
+	}
+	return fmt.Sprint(f.value)
+}
+
+func (f *toolFlag) IsBoolFlag() bool { return f.schema.kind() == "boolean" }
+
+func (f *toolFlag) Set(s string) error {
+	switch f.schema.kind() {
+	case "array":
+		items, _ := f.value.([]any)
+		if strings.HasPrefix(strings.TrimSpace(s), "[") {
+			var more []any
+			if err := json.Unmarshal([]byte(s), &more); err != nil {
+				return err
+			}
+			items = append(items, more...)
+		} else {
+			item, err := parseFlagValue(s, f.schema.Items)
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		f.value = items
+	case "object":
+		fields, _ := f.value.(map[string]any)
+		if fields == nil {
+			fields = make(map[string]any)
+		}
+		if strings.HasPrefix(strings.TrimSpace(s), "{") {
+			if err := json.Unmarshal([]byte(s), &fields); err != nil {
+				return err
+			}
+		} else {
+			key, value, ok := strings.Cut(s, "=")
+			if !ok {
+				return fmt.Errorf("want key=value or a JSON object")
+			}
+			fields[key] = value
+		}
+		f.value = fields
+	default:
+		v, err := parseFlagValue(s, f.schema)
+		if err != nil {
+			return err
+		}
+		f.value = v
+	}
+	f.set = true
+	return nil
+}
+
+// parseFlagValue converts one flag value to the JSON type in schema.
+func parseFlagValue(s string, schema *propSchema) (any, error) {
+	switch schema.kind() {
+	case "string":
+		return s, nil
+	case "integer":
+		return strconv.Atoi(s)
+	case "number":
+		return strconv.ParseFloat(s, 64)
+	case "boolean":
+		return strconv.ParseBool(s)
+	case "object":
+		var v map[string]any
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, fmt.Errorf("want a JSON object: %w", err)
+		}
+		return v, nil
+	}
+	// Untyped: JSON if it parses, text otherwise.
+	var v any
+	if json.Unmarshal([]byte(s), &v) == nil {
+		return v, nil
+	}
+	return s, nil
+}
+
+// flagName turns an input name such as maxBlocks into max-blocks.
+func flagName(prop string) string {
+	var b strings.Builder
+	for i, r := range prop {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// resultText joins the text content of a tool result.
+func resultText(res *mcp.CallToolResult) string {
+	var parts []string
+	for _, c := range res.Content {
+		if t, ok := c.(*mcp.TextContent); ok {
+			parts = append(parts, t.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// printToolText prints a tool's output for people: JSON as an indented
+// tree with lists of flat records as tables, anything else unchanged.
+func printToolText(w io.Writer, text string) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		fmt.Fprintln(w, text)
+		return
+	}
+	printTree(w, v, "")
+}
+
+// maxCellWidth truncates long values in table cells.
+const maxCellWidth = 60
+
+// printTree writes v at the given indent.
+func printTree(w io.Writer, v any, indent string) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := v[k]
+			switch {
+			case isScalar(child):
+				fmt.Fprintf(w, "%s%s: %s\n", indent, k, indentLines(scalarText(child), indent+"  "))
+			case isEmpty(child):
+				fmt.Fprintf(w, "%s%s: (none)\n", indent, k)
+			default:
+				fmt.Fprintf(w, "%s%s:\n", indent, k)
+				printTree(w, child, indent+"  ")
+			}
+		}
+	case []any:
+		if isTable(v) {
+			printTable(w, v, indent)
+			return
+		}
+		for _, item := range v {
+			if isScalar(item) {
+				fmt.Fprintf(w, "%s- %s\n", indent, indentLines(scalarText(item), indent+"  "))
+				continue
+			}
+			// Render the item one level deeper, then mark its first line.
+			var buf bytes.Buffer
+			printTree(&buf, item, indent+"  ")
+			out := buf.String()
+			if strings.HasPrefix(out, indent+"  ") {
+				out = indent + "- " + out[len(indent)+2:]
+			}
+			io.WriteString(w, out)
+		}
+	default:
+		fmt.Fprintf(w, "%s%s\n", indent, indentLines(scalarText(v), indent))
+	}
+}
+
+// isTable reports whether items are all objects with only scalar fields.
+func isTable(items []any) bool {
+	if len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return false
+		}
+		for _, field := range obj {
+			if !isScalar(field) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// printTable writes flat records as aligned columns, one per field.
+func printTable(w io.Writer, rows []any, indent string) {
+	seen := make(map[string]bool)
+	var cols []string
+	for _, row := range rows {
+		for k := range row.(map[string]any) {
+			if !seen[k] {
+				seen[k] = true
+				cols = append(cols, k)
+			}
+		}
+	}
+	sort.Strings(cols)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s%s\n", indent, strings.Join(cols, "\t"))
+	for _, row := range rows {
+		obj := row.(map[string]any)
+		cells := make([]string, len(cols))
+		for i, c := range cols {
+			if v, ok := obj[c]; ok {
+				cells[i] = cellText(scalarText(v))
+			}
+		}
+		fmt.Fprintf(tw, "%s%s\n", indent, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+}
+
+func isScalar(v any) bool {
+	switch v.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return true
+}
+
+func isEmpty(v any) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
+
+func scalarText(v any) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprint(v)
+}
+
+// indentLines indents the continuation lines of a multi-line value.
+func indentLines(s, indent string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
+}
+
+// cellText fits a value on one line of a table.
+func cellText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxCellWidth {
+		s = string(r[:maxCellWidth-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skridlevsky/graphthulhu/oplog"
+	"github.com/skridlevsky/graphthulhu/vault"
+	"github.com/skridlevsky/graphthulhu/vault/vaulttest"
+)
+
+// testVault indexes a small two-page vault.
+func testVault(t *testing.T) *vault.Client {
+	t.Helper()
+	vc, _ := vaulttest.New(t, map[string]string{
+		"Go.md":   "Go links to [[Rust]].\n",
+		"Rust.md": "Rust is fast.\n",
+	})
+	return vc
+}
+
+func TestToolInputsBecomeFlags(t *testing.T) {
+	ctx := context.Background()
+	ops, err := oplog.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := connectInProcess(ctx, newServer(testVault(t), false, ops, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	reserved := flag.NewFlagSet("reserved", flag.ContinueOnError)
+	addBackendFlags(reserved)
+	addReadOnlyFlag(reserved)
+	reserved.Bool("json", false, "")
+	reserved.String("input", "", "")
+
+	n := 0
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		schema, err := parseInputSchema(tool.InputSchema)
+		if err != nil {
+			t.Fatalf("%s: %v", tool.Name, err)
+		}
+		for name, prop := range schema.Properties {
+			if reserved.Lookup(flagName(name)) != nil {
+				t.Errorf("%s: input %q shadows a CLI flag", tool.Name, name)
+			}
+			if prop.kind() == "" {
+				t.Errorf("%s: input %q has no type", tool.Name, name)
+			}
+		}
+	}
+	if n < 30 {
+		t.Errorf("only %d tools listed", n)
+	}
+}
+
+func TestToolArguments(t *testing.T) {
+	schema, err := parseInputSchema(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name":       map[string]any{"type": "string"},
+			"maxBlocks":  map[string]any{"type": "integer"},
+			"compact":    map[string]any{"type": "boolean"},
+			"blocks":     map[string]any{"type": []string{"null", "array"}, "items": map[string]any{"type": "string"}},
+			"properties": map[string]any{"type": "object"},
+		},
+		"required": []string{"name"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parse := func(args ...string) (map[string]any, error) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags := schema.addFlags(fs)
+		input := fs.String("input", "", "")
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		return schema.arguments(*input, flags, fs.Args())
+	}
+
+	got, err := parse("--max-blocks", "5", "--compact", "--blocks", "a, b", "--blocks", `["c"]`,
+		"--properties", "type=demo", "--properties", `{"status":"open"}`, "My", "Page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"name":       "My Page",
+		"maxBlocks":  5,
+		"compact":    true,
+		"blocks":     []any{"a, b", "c"},
+		"properties": map[string]any{"type": "demo", "status": "open"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("arguments = %#v, want %#v", got, want)
+	}
+
+	got, err = parse("--input", `{"name":"From JSON","compact":true}`, "--compact=false")
+	if err != nil || got["name"] != "From JSON" || got["compact"] != false {
+		t.Errorf("--input with override = %v, %v", got, err)
+	}
+
+	if _, err := parse("--max-blocks", "five", "x"); err == nil {
+		t.Error("non-integer accepted")
+	}
+	if _, err := parse("--compact"); err == nil || !strings.Contains(err.Error(), "--name is required") {
+		t.Errorf("missing required input: %v", err)
+	}
+	if _, err := parse("--name", "a", "extra"); err == nil {
+		t.Error("positional argument accepted with --name set")
+	}
+}
+
+func TestKnownTool(t *testing.T) {
+	ctx := context.Background()
+	for name, want := range map[string]bool{
+		"get_page":         true,
+		"delete_page":      true,
+		"list_whiteboards": true,
+		"get_pages":        false,
+	} {
+		if got, err := knownTool(ctx, name); err != nil || got != want {
+			t.Errorf("knownTool(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+}
+
+func TestExtractFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	addBackendFlags(fs)
+	known, rest := extractFlags([]string{
+		"--name", "Go", "--vault", "/v", "--include-hidden", "-backend=obsidian", "--json", "--", "--graph",
+	}, fs)
+	if want := []string{"--vault", "/v", "--include-hidden", "-backend=obsidian"}; !reflect.DeepEqual(known, want) {
+		t.Errorf("known = %q, want %q", known, want)
+	}
+	if want := []string{"--name", "Go", "--json", "--", "--graph"}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("rest = %q, want %q", rest, want)
+	}
+}
+
+func TestPrintToolText(t *testing.T) {
+	var b strings.Builder
+	printToolText(&b, `{
+		"page": {"name": "Go", "tags": []},
+		"rows": [{"name": "a", "n": 1}, {"name": "b", "extra": null}],
+		"paths": [["Go", "Rust"]],
+		"note": "two\nlines"
+	}`)
+	want := `note: two
+  lines
+page:
+  name: Go
+  tags: (none)
+paths:
+  - - Go
+    - Rust
+rows:
+  extra  n  name
+         1  a
+  -         b
+`
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	printToolText(&b, "No path found.")
+	if b.String() != "No path found.\n" {
+		t.Errorf("plain text = %q", b.String())
+	}
+}
+
+func TestFlagName(t *testing.T) {
+	for in, want := range map[string]string{"name": "name", "maxBlocks": "max-blocks", "includeAncestors": "include-ancestors"} {
+		if got := flagName(in); got != want {
+			t.Errorf("flagName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
+	"github.com/skridlevsky/graphthulhu/backend"
 	"github.com/skridlevsky/graphthulhu/types"
 )
 
@@ -25,6 +27,13 @@ type Client struct {
 	apiURL     string
 	token      string
 	httpClient *http.Client
+
+	// WatchPages callers and whether the poller serving them runs (see
+	// watch.go).
+	watchMu     sync.Mutex
+	watchers    map[int]func(backend.PageChange)
+	nextWatcher int
+	polling     bool
 }
 
 // New creates a new Logseq API client.
@@ -299,3 +308,6 @@ func (c *Client) Ping(ctx context.Context) error {
 // HasDataScript marks the Logseq client as supporting DataScript queries.
 // Implements backend.HasDataScript.
 func (c *Client) HasDataScript() {}
+
+// GraphKind implements backend.GraphKind.
+func (c *Client) GraphKind() string { return "logseq" }
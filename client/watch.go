@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+)
+
+// pollInterval is how often WatchPages asks Logseq for changed pages.
+const pollInterval = 5 * time.Second
+
+// WatchPages implements backend.PageWatcher. The Logseq API has no change
+// feed, so the pages are polled every pollInterval and their UpdatedAt
+// compared with the previous poll. One poller serves every caller, and it
+// only runs while some caller's ctx is not done. Polls made while Logseq
+// is unreachable are skipped.
+func (c *Client) WatchPages(ctx context.Context, fn func(backend.PageChange)) {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	if c.watchers == nil {
+		c.watchers = make(map[int]func(backend.PageChange))
+	}
+	id := c.nextWatcher
+	c.nextWatcher++
+	c.watchers[id] = fn
+	if !c.polling {
+		c.polling = true
+		go c.poll()
+	}
+
+	go func() {
+		<-ctx.Done()
+		c.watchMu.Lock()
+		delete(c.watchers, id)
+		c.watchMu.Unlock()
+	}()
+}
+
+// poll runs the poller until no caller of WatchPages is left.
+func (c *Client) poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	ctx := context.Background()
+	var seen map[string]pageStamp
+	for {
+		seen = c.pollPages(ctx, seen, c.notifyWatchers)
+		<-ticker.C
+
+		c.watchMu.Lock()
+		if len(c.watchers) == 0 {
+			c.polling = false
+			c.watchMu.Unlock()
+			return
+		}
+		c.watchMu.Unlock()
+	}
+}
+
+// notifyWatchers passes a change to the WatchPages callers.
+func (c *Client) notifyWatchers(change backend.PageChange) {
+	c.watchMu.Lock()
+	defer c.watchMu.Unlock()
+	for _, fn := range c.watchers {
+		fn(change)
+	}
+}
+
+// pageStamp is a page as one poll saw it.
+type pageStamp struct {
+	name      string
+	updatedAt int64
+}
+
+// pollPages reports the pages that differ from seen and returns the pages
+// as they are now. With a nil seen it only takes stock.
+func (c *Client) pollPages(ctx context.Context, seen map[string]pageStamp, fn func(backend.PageChange)) map[string]pageStamp {
+	pages, err := c.GetAllPages(ctx)
+	if err != nil {
+		return seen
+	}
+
+	now := make(map[string]pageStamp, len(pages))
+	for _, p := range pages {
+		name := p.OriginalName
+		if name == "" {
+			name = p.Name
+		}
+		now[strings.ToLower(name)] = pageStamp{name: name, updatedAt: p.UpdatedAt}
+	}
+	if seen == nil {
+		return now
+	}
+
+	for key, page := range now {
+		if before, ok := seen[key]; !ok || before.updatedAt != page.updatedAt {
+			fn(backend.PageChange{Page: page.name})
+		}
+	}
+	for key, page := range seen {
+		if _, ok := now[key]; !ok {
+			fn(backend.PageChange{Page: page.name, Deleted: true})
+		}
+	}
+	return now
+}
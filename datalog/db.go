@@ -0,0 +1,145 @@
+// Package datalog evaluates a subset of DataScript queries against an
+// in-memory entity database, so backends without Logseq's DataScript engine
+// can still answer query_datalog and the tools built on it.
+//
+// Supported: :find with variables, pull expressions and the count, min, max
+// and sum aggregates; :in with scalar and collection bindings; :where data
+// patterns, predicates, function bindings, not, or and and clauses.
+// Rules and transactions are not supported.
+package datalog
+
+import "sort"
+
+// Ref is a value that points at another entity, such as :block/page.
+type Ref int
+
+// DB is an in-memory entity-attribute-value store. Build it with NewEntity
+// and Add, then run Query against it. A DB is not safe for concurrent
+// writes but may be queried concurrently once built.
+type DB struct {
+	entities []map[string][]any       // entity id - 1 → attribute → values
+	byAttr   map[string][]datom       // attribute → datoms, in insertion order
+	byValue  map[string]map[any][]int // attribute → value key → entity ids
+	many     map[string]bool          // cardinality-many attributes, see Many
+}
+
+// datom is one entity-value pair of an attribute.
+type datom struct {
+	e int
+	v any
+}
+
+// NewDB returns an empty database.
+func NewDB() *DB {
+	return &DB{
+		byAttr:  make(map[string][]datom),
+		byValue: make(map[string]map[any][]int),
+	}
+}
+
+// NewEntity allocates an entity and returns its id. Ids start at 1.
+func (db *DB) NewEntity() int {
+	db.entities = append(db.entities, make(map[string][]any))
+	return len(db.entities)
+}
+
+// Add records value v for attribute attr (e.g. "block/content") of entity e.
+// Ints are stored as int64 so they compare equal to query literals.
+func (db *DB) Add(e int, attr string, v any) {
+	v = normalizeValue(v)
+	db.entities[e-1][attr] = append(db.entities[e-1][attr], v)
+	db.byAttr[attr] = append(db.byAttr[attr], datom{e: e, v: v})
+	if key, ok := valueKey(v); ok {
+		idx := db.byValue[attr]
+		if idx == nil {
+			idx = make(map[any][]int)
+			db.byValue[attr] = idx
+		}
+		idx[key] = append(idx[key], e)
+	}
+}
+
+// Many declares attributes that hold several values, such as :block/refs.
+// Many marks attributes as cardinality-many (e.g. "block/refs"), so Pull
+// returns their values as a list even when there is only one.
+func (db *DB) Many(attrs ...string) {
+	if db.many == nil {
+		db.many = make(map[string]bool)
+	}
+	for _, a := range attrs {
+		db.many[a] = true
+	}
+}
+
+// Lookup returns the entity whose attr equals the string value, such as the
+// page with a given :block/name. The first entity added wins.
+func (db *DB) Lookup(attr, value string) (int, bool) {
+	if ids := db.byValue[attr][value]; len(ids) > 0 {
+		return ids[0], true
+	}
+	return 0, false
+}
+
+// Attrs returns the attributes of entity e in sorted order.
+func (db *DB) Attrs(e int) []string {
+	if e < 1 || e > len(db.entities) {
+		return nil
+	}
+	attrs := make([]string, 0, len(db.entities[e-1]))
+	for a := range db.entities[e-1] {
+		attrs = append(attrs, a)
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// values returns the values of attr on entity e.
+func (db *DB) values(e int, attr string) []any {
+	if e < 1 || e > len(db.entities) {
+		return nil
+	}
+	return db.entities[e-1][attr]
+}
+
+// entitiesWith returns the entities whose attr has value v.
+func (db *DB) entitiesWith(attr string, v any) []int {
+	if key, ok := valueKey(v); ok {
+		return db.byValue[attr][key]
+	}
+	var out []int
+	for _, d := range db.byAttr[attr] {
+		if equal(d.v, v) {
+			out = append(out, d.e)
+		}
+	}
+	return out
+}
+
+// normalizeValue widens Go ints to int64 and float32 to float64.
+func normalizeValue(v any) any {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case float32:
+		return float64(n)
+	}
+	return v
+}
+
+// valueKey returns a comparable index key for scalar values.
+func valueKey(v any) (any, bool) {
+	switch v := v.(type) {
+	case string, int64, bool, Keyword:
+		return v, true
+	case Ref:
+		return int64(v), true
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v), true
+		}
+		return v, true
+	}
+	return nil, false
+}
@@ -0,0 +1,311 @@
+package datalog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EDN values produced by the reader. Strings, int64, float64, bool and nil
+// map to their Go equivalents; #uuid and #inst literals read as their string.
+type (
+	// Keyword is an EDN keyword without its leading colon, e.g. "block/content".
+	Keyword string
+	// Symbol is an EDN symbol such as ?b, pull or clojure.string/includes?.
+	Symbol string
+	// Vector is an EDN vector: [a b c].
+	Vector []any
+	// List is an EDN list: (a b c).
+	List []any
+	// Set is an EDN set: #{a b c}.
+	Set []any
+	// Map is an EDN map, kept as ordered key/value pairs.
+	Map []MapEntry
+)
+
+// MapEntry is one key/value pair of an EDN map.
+type MapEntry struct {
+	Key, Val any
+}
+
+// Name returns the keyword without its namespace: "block/content" → "content".
+func (k Keyword) Name() string {
+	if i := strings.LastIndexByte(string(k), '/'); i >= 0 {
+		return string(k[i+1:])
+	}
+	return string(k)
+}
+
+// Get returns the value stored under key.
+func (m Map) Get(key any) (any, bool) {
+	for _, e := range m {
+		if equal(e.Key, key) {
+			return e.Val, true
+		}
+	}
+	return nil, false
+}
+
+// ReadEDN parses a single EDN value from s.
+func ReadEDN(s string) (any, error) {
+	r := &ednReader{src: s}
+	v, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	r.skipSpace()
+	if r.pos < len(r.src) {
+		return nil, r.errorf("unexpected %q after value", r.src[r.pos:min(len(r.src), r.pos+10)])
+	}
+	return v, nil
+}
+
+// ednReader is a recursive-descent reader over an EDN source string.
+type ednReader struct {
+	src string
+	pos int
+}
+
+// errEnd marks a closing delimiter where a value was expected; collections
+// use it to find their end.
+type errEnd struct{ delim byte }
+
+func (e errEnd) Error() string { return fmt.Sprintf("unexpected %q", e.delim) }
+
+func (r *ednReader) errorf(format string, args ...any) error {
+	return fmt.Errorf("edn: offset %d: %s", r.pos, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace, commas and ; comments.
+func (r *ednReader) skipSpace() {
+	for r.pos < len(r.src) {
+		switch c := r.src[r.pos]; {
+		case c == ';':
+			for r.pos < len(r.src) && r.src[r.pos] != '\n' {
+				r.pos++
+			}
+		case c == ',' || unicode.IsSpace(rune(c)):
+			r.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (r *ednReader) read() (any, error) {
+	r.skipSpace()
+	if r.pos >= len(r.src) {
+		return nil, r.errorf("unexpected end of input")
+	}
+	switch c := r.src[r.pos]; c {
+	case '[':
+		r.pos++
+		items, err := r.readSeq(']')
+		return Vector(items), err
+	case '(':
+		r.pos++
+		items, err := r.readSeq(')')
+		return List(items), err
+	case '{':
+		r.pos++
+		items, err := r.readSeq('}')
+		if err != nil {
+			return nil, err
+		}
+		if len(items)%2 != 0 {
+			return nil, r.errorf("map literal needs an even number of forms")
+		}
+		m := make(Map, 0, len(items)/2)
+		for i := 0; i < len(items); i += 2 {
+			m = append(m, MapEntry{Key: items[i], Val: items[i+1]})
+		}
+		return m, nil
+	case ']', ')', '}':
+		r.pos++
+		return nil, errEnd{c}
+	case '"':
+		return r.readString()
+	case ':':
+		r.pos++
+		tok := r.readToken()
+		if tok == "" {
+			return nil, r.errorf("empty keyword")
+		}
+		return Keyword(tok), nil
+	case '#':
+		return r.readDispatch()
+	case '\\':
+		r.pos++
+		tok := r.readToken()
+		if tok == "" && r.pos < len(r.src) {
+			tok = r.src[r.pos : r.pos+1]
+			r.pos++
+		}
+		return characterValue(tok), nil
+	}
+
+	tok := r.readToken()
+	if tok == "" {
+		return nil, r.errorf("unexpected %q", r.src[r.pos])
+	}
+	return atomValue(tok), nil
+}
+
+// readSeq reads values up to the closing delimiter end.
+func (r *ednReader) readSeq(end byte) ([]any, error) {
+	var items []any
+	for {
+		v, err := r.read()
+		if e, ok := err.(errEnd); ok {
+			if e.delim != end {
+				return nil, r.errorf("expected %q, got %q", end, e.delim)
+			}
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+}
+
+func (r *ednReader) readString() (string, error) {
+	var b strings.Builder
+	r.pos++ // opening quote
+	for r.pos < len(r.src) {
+		c := r.src[r.pos]
+		r.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if r.pos >= len(r.src) {
+				return "", r.errorf("unterminated string")
+			}
+			esc := r.src[r.pos]
+			r.pos++
+			switch esc {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(esc)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", r.errorf("unterminated string")
+}
+
+// readDispatch reads the # forms: sets, regexes, discards and tagged literals.
+func (r *ednReader) readDispatch() (any, error) {
+	r.pos++
+	if r.pos >= len(r.src) {
+		return nil, r.errorf("unexpected end of input after #")
+	}
+	switch r.src[r.pos] {
+	case '{':
+		r.pos++
+		items, err := r.readSeq('}')
+		return Set(items), err
+	case '"':
+		pattern, err := r.readRegexSource()
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, r.errorf("bad regex: %v", err)
+		}
+		return re, nil
+	case '_':
+		r.pos++
+		if _, err := r.read(); err != nil {
+			return nil, err
+		}
+		return r.read()
+	}
+	// Tagged literal such as #uuid "..." or #inst "...": keep the value.
+	if tag := r.readToken(); tag == "" {
+		return nil, r.errorf("bad dispatch character")
+	}
+	return r.read()
+}
+
+// readRegexSource reads a #"..." body, where backslashes are kept verbatim.
+func (r *ednReader) readRegexSource() (string, error) {
+	start := r.pos + 1
+	for i := start; i < len(r.src); i++ {
+		switch r.src[i] {
+		case '\\':
+			i++
+		case '"':
+			r.pos = i + 1
+			return r.src[start:i], nil
+		}
+	}
+	return "", r.errorf("unterminated regex")
+}
+
+// readToken reads a symbol, number or keyword body.
+func (r *ednReader) readToken() string {
+	start := r.pos
+	for r.pos < len(r.src) {
+		c := r.src[r.pos]
+		if unicode.IsSpace(rune(c)) || strings.IndexByte(`,;()[]{}"`, c) >= 0 {
+			break
+		}
+		r.pos++
+	}
+	return r.src[start:r.pos]
+}
+
+// atomValue converts a bare token to nil, a boolean, a number or a symbol.
+func atomValue(tok string) any {
+	switch tok {
+	case "nil":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if !isNumberStart(tok) {
+		return Symbol(tok)
+	}
+	if n, err := strconv.ParseInt(strings.TrimSuffix(tok, "N"), 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(strings.TrimSuffix(tok, "M"), 64); err == nil {
+		return f
+	}
+	return Symbol(tok)
+}
+
+// isNumberStart reports whether tok begins like a number: a digit, optionally
+// after a sign. This keeps symbols such as + and inf out of ParseFloat.
+func isNumberStart(tok string) bool {
+	if tok != "" && (tok[0] == '+' || tok[0] == '-') {
+		tok = tok[1:]
+	}
+	return tok != "" && tok[0] >= '0' && tok[0] <= '9'
+}
+
+// characterValue converts an EDN character literal body to a string.
+func characterValue(tok string) string {
+	switch tok {
+	case "newline":
+		return "\n"
+	case "space":
+		return " "
+	case "tab":
+		return "\t"
+	}
+	return tok
+}
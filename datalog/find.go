@@ -0,0 +1,190 @@
+package datalog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// project turns the rows left after :where into the :find result.
+func (q *query) project(db *DB, rows []bindings) (any, error) {
+	vars := make([]Symbol, 0, len(q.find)+len(q.with))
+	for _, f := range q.find {
+		vars = append(vars, f.variable)
+	}
+	vars = append(vars, q.with...)
+
+	// Like DataScript, the result is a set: each distinct combination of
+	// :find and :with values counts once.
+	var tuples [][]any
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		tuple := make([]any, len(vars))
+		for i, v := range vars {
+			val, ok := row[v]
+			if !ok {
+				return nil, fmt.Errorf("insufficient bindings: %s is not bound by :where", v)
+			}
+			tuple[i] = val
+		}
+		key := tupleKey(tuple)
+		if !seen[key] {
+			seen[key] = true
+			tuples = append(tuples, tuple[:len(q.find)])
+		}
+	}
+
+	if q.hasAggregates() {
+		var err error
+		if tuples, err = q.aggregate(tuples); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]any, 0, len(tuples))
+	for _, tuple := range tuples {
+		out := make([]any, len(tuple))
+		for i, f := range q.find {
+			out[i] = tuple[i]
+			if f.pull == nil {
+				continue
+			}
+			id, ok := entityID(tuple[i])
+			if !ok {
+				return nil, fmt.Errorf("pull: %s is not an entity", describe(tuple[i]))
+			}
+			pulled, err := db.Pull(id, f.pull)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = pulled
+		}
+		results = append(results, out)
+	}
+
+	switch q.shape {
+	case findCollection:
+		coll := make([]any, len(results))
+		for i, r := range results {
+			coll[i] = r.([]any)[0]
+		}
+		return coll, nil
+	case findTuple:
+		if len(results) == 0 {
+			return nil, nil
+		}
+		return results[0], nil
+	case findScalar:
+		if len(results) == 0 {
+			return nil, nil
+		}
+		return results[0].([]any)[0], nil
+	}
+	return results, nil
+}
+
+func tupleKey(tuple []any) string {
+	parts := make([]string, len(tuple))
+	for i, v := range tuple {
+		parts[i] = edn(v)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func (q *query) hasAggregates() bool {
+	for _, f := range q.find {
+		if f.aggregate != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregate groups tuples by their plain :find elements and folds the
+// aggregate elements of each group.
+func (q *query) aggregate(tuples [][]any) ([][]any, error) {
+	type group struct {
+		key    []any
+		values [][]any // per find element
+	}
+	var groups []*group
+	byKey := make(map[string]*group)
+	for _, tuple := range tuples {
+		var key []any
+		for i, f := range q.find {
+			if f.aggregate == "" {
+				key = append(key, tuple[i])
+			}
+		}
+		k := tupleKey(key)
+		g, ok := byKey[k]
+		if !ok {
+			g = &group{key: key, values: make([][]any, len(q.find))}
+			byKey[k] = g
+			groups = append(groups, g)
+		}
+		for i, f := range q.find {
+			if f.aggregate != "" {
+				g.values[i] = append(g.values[i], tuple[i])
+			}
+		}
+	}
+
+	out := make([][]any, 0, len(groups))
+	for _, g := range groups {
+		row := make([]any, len(q.find))
+		k := 0
+		for i, f := range q.find {
+			if f.aggregate == "" {
+				row[i] = g.key[k]
+				k++
+				continue
+			}
+			v, err := fold(f.aggregate, g.values[i])
+			if err != nil {
+				return nil, err
+			}
+			row[i] = v
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+// fold applies an aggregate function to a group's values.
+func fold(name string, vals []any) (any, error) {
+	switch name {
+	case "count":
+		return int64(len(vals)), nil
+	case "count-distinct", "distinct":
+		var distinct []any
+		for _, v := range vals {
+			if !contains(distinct, v) {
+				distinct = append(distinct, v)
+			}
+		}
+		if name == "distinct" {
+			return Set(distinct), nil
+		}
+		return int64(len(distinct)), nil
+	case "min", "max":
+		best := vals[0]
+		for _, v := range vals[1:] {
+			c, err := compare(v, best)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if (name == "min" && c < 0) || (name == "max" && c > 0) {
+				best = v
+			}
+		}
+		return best, nil
+	case "sum", "avg":
+		total, err := arithmetic(func(x, y float64) float64 { return x + y })(append([]any{int64(0)}, vals...))
+		if err != nil || name == "sum" {
+			return total, err
+		}
+		n, _ := number(total)
+		return n / float64(len(vals)), nil
+	}
+	return nil, fmt.Errorf("unknown aggregate %s", name)
+}
@@ -0,0 +1,330 @@
+package datalog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// function is a built-in usable in predicate and function-binding clauses.
+// Arguments arrive resolved: variables are replaced by their values and $
+// by the database.
+type function func(args []any) (any, error)
+
+// functions maps the Clojure names DataScript queries use to built-ins.
+var functions = map[string]function{
+	"=":        equalAll,
+	"==":       equalAll,
+	"not=":     negate(equalAll),
+	"!=":       negate(equalAll),
+	"<":        ordered(func(c int) bool { return c < 0 }),
+	">":        ordered(func(c int) bool { return c > 0 }),
+	"<=":       ordered(func(c int) bool { return c <= 0 }),
+	">=":       ordered(func(c int) bool { return c >= 0 }),
+	"not":      arity(1, func(a []any) (any, error) { return !truthy(a[0]), nil }),
+	"nil?":     arity(1, func(a []any) (any, error) { return a[0] == nil, nil }),
+	"some?":    arity(1, func(a []any) (any, error) { return a[0] != nil, nil }),
+	"true?":    arity(1, func(a []any) (any, error) { return a[0] == true, nil }),
+	"false?":   arity(1, func(a []any) (any, error) { return a[0] == false, nil }),
+	"identity": arity(1, func(a []any) (any, error) { return a[0], nil }),
+	"ground":   arity(1, func(a []any) (any, error) { return a[0], nil }),
+	"str":      fnStr,
+	"count":    arity(1, fnCount),
+	"get":      fnGet,
+	"contains?": arity(2, func(a []any) (any, error) {
+		return fnContains(a[0], a[1]), nil
+	}),
+	"missing?": arity(3, fnMissing),
+	"get-else": arity(4, fnGetElse),
+	"+":        arithmetic(func(x, y float64) float64 { return x + y }),
+	"-":        arithmetic(func(x, y float64) float64 { return x - y }),
+	"*":        arithmetic(func(x, y float64) float64 { return x * y }),
+	"/":        arithmetic(func(x, y float64) float64 { return x / y }),
+
+	"clojure.string/includes?":    stringPredicate(strings.Contains),
+	"clojure.string/starts-with?": stringPredicate(strings.HasPrefix),
+	"clojure.string/ends-with?":   stringPredicate(strings.HasSuffix),
+	"clojure.string/lower-case":   stringFunc(strings.ToLower),
+	"clojure.string/upper-case":   stringFunc(strings.ToUpper),
+	"clojure.string/trim":         stringFunc(strings.TrimSpace),
+	"clojure.string/blank?": arity(1, func(a []any) (any, error) {
+		s, _ := a[0].(string)
+		return strings.TrimSpace(s) == "", nil
+	}),
+
+	"re-pattern": arity(1, fnRePattern),
+	"re-find":    arity(2, fnReFind),
+	"re-matches": arity(2, fnReMatches),
+}
+
+// arity checks the argument count before calling fn.
+func arity(n int, fn function) function {
+	return func(args []any) (any, error) {
+		if len(args) != n {
+			return nil, fmt.Errorf("expected %d arguments, got %d", n, len(args))
+		}
+		return fn(args)
+	}
+}
+
+// equalAll reports whether all arguments are equal.
+func equalAll(args []any) (any, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("expected at least 1 argument")
+	}
+	for i := 1; i < len(args); i++ {
+		if !equal(args[i-1], args[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ordered chains a comparison of numbers or strings over every adjacent
+// pair of arguments, as (< a b c) does.
+func ordered(ok func(int) bool) function {
+	return func(args []any) (any, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("expected at least 1 argument")
+		}
+		for i := 1; i < len(args); i++ {
+			c, err := compare(args[i-1], args[i])
+			if err != nil {
+				return nil, err
+			}
+			if !ok(c) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+func negate(fn function) function {
+	return func(args []any) (any, error) {
+		v, err := fn(args)
+		return !truthy(v), err
+	}
+}
+
+func arithmetic(op func(x, y float64) float64) function {
+	return func(args []any) (any, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("expected at least 1 argument")
+		}
+		acc, ok := number(args[0])
+		if !ok {
+			return nil, fmt.Errorf("not a number: %s", describe(args[0]))
+		}
+		for _, a := range args[1:] {
+			n, ok := number(a)
+			if !ok {
+				return nil, fmt.Errorf("not a number: %s", describe(a))
+			}
+			acc = op(acc, n)
+		}
+		if acc == float64(int64(acc)) {
+			return int64(acc), nil
+		}
+		return acc, nil
+	}
+}
+
+func stringPredicate(pred func(s, sub string) bool) function {
+	return arity(2, func(a []any) (any, error) {
+		s, ok1 := a[0].(string)
+		sub, ok2 := a[1].(string)
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		return pred(s, sub), nil
+	})
+}
+
+func stringFunc(fn func(string) string) function {
+	return arity(1, func(a []any) (any, error) {
+		s, ok := a[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("not a string: %s", describe(a[0]))
+		}
+		return fn(s), nil
+	})
+}
+
+func fnStr(args []any) (any, error) {
+	var b strings.Builder
+	for _, a := range args {
+		b.WriteString(str(a))
+	}
+	return b.String(), nil
+}
+
+func fnCount(args []any) (any, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return int64(0), nil
+	case string:
+		return int64(len([]rune(v))), nil
+	case map[string]any:
+		return int64(len(v)), nil
+	case Map:
+		return int64(len(v)), nil
+	}
+	if items, ok := seq(args[0]); ok {
+		return int64(len(items)), nil
+	}
+	return nil, fmt.Errorf("count not supported on %s", describe(args[0]))
+}
+
+// fnGet looks a key up in a map; keyword keys match string keys by name, so
+// (get ?props :type) reads a "type" property.
+func fnGet(args []any) (any, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("expected 2 or 3 arguments, got %d", len(args))
+	}
+	if v, ok := lookupKey(args[0], args[1]); ok {
+		return v, nil
+	}
+	if len(args) == 3 {
+		return args[2], nil
+	}
+	return nil, nil
+}
+
+func lookupKey(coll, key any) (any, bool) {
+	switch m := coll.(type) {
+	case map[string]any:
+		name, ok := key.(string)
+		if k, isKw := key.(Keyword); isKw {
+			name, ok = string(k), true
+		}
+		if !ok {
+			return nil, false
+		}
+		v, found := m[name]
+		return v, found
+	case Map:
+		return m.Get(key)
+	}
+	if items, ok := seq(coll); ok {
+		if isSet(coll) {
+			if contains(items, key) {
+				return key, true
+			}
+			return nil, false
+		}
+		if i, ok := number(key); ok && i >= 0 && int(i) < len(items) {
+			return items[int(i)], true
+		}
+	}
+	return nil, false
+}
+
+func fnContains(coll, key any) bool {
+	if isSet(coll) {
+		items, _ := seq(coll)
+		return contains(items, key)
+	}
+	_, ok := lookupKey(coll, key)
+	return ok
+}
+
+func fnMissing(args []any) (any, error) {
+	db, e, attr, err := entityAttrArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return len(db.values(e, attr)) == 0, nil
+}
+
+func fnGetElse(args []any) (any, error) {
+	db, e, attr, err := entityAttrArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if vs := db.values(e, attr); len(vs) > 0 {
+		return vs[0], nil
+	}
+	return args[3], nil
+}
+
+// entityAttrArgs unpacks the ($ ?e :attr ...) arguments of missing? and get-else.
+func entityAttrArgs(args []any) (*DB, int, string, error) {
+	db, ok := args[0].(*DB)
+	if !ok {
+		return nil, 0, "", fmt.Errorf("first argument must be $")
+	}
+	e, ok := entityID(args[1])
+	if !ok {
+		return nil, 0, "", fmt.Errorf("not an entity: %s", describe(args[1]))
+	}
+	attr, ok := args[2].(Keyword)
+	if !ok {
+		return nil, 0, "", fmt.Errorf("not an attribute: %s", describe(args[2]))
+	}
+	return db, e, string(attr), nil
+}
+
+func fnRePattern(args []any) (any, error) {
+	if re, ok := args[0].(*regexp.Regexp); ok {
+		return re, nil
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("not a string: %s", describe(args[0]))
+	}
+	return regexp.Compile(s)
+}
+
+func fnReFind(args []any) (any, error) {
+	re, s, err := regexArgs(args)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	return regexResult(re.FindStringSubmatch(*s)), nil
+}
+
+func fnReMatches(args []any) (any, error) {
+	re, s, err := regexArgs(args)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	m := re.FindStringSubmatchIndex(*s)
+	if m == nil || m[0] != 0 || m[1] != len(*s) {
+		return nil, nil
+	}
+	return regexResult(re.FindStringSubmatch(*s)), nil
+}
+
+// regexArgs unpacks (re-find re s). A non-string s yields no match.
+func regexArgs(args []any) (*regexp.Regexp, *string, error) {
+	re, ok := args[0].(*regexp.Regexp)
+	if !ok {
+		v, err := fnRePattern(args[:1])
+		if err != nil {
+			return nil, nil, err
+		}
+		re = v.(*regexp.Regexp)
+	}
+	s, ok := args[1].(string)
+	if !ok {
+		return re, nil, nil
+	}
+	return re, &s, nil
+}
+
+// regexResult mirrors Clojure: the match alone without groups, otherwise a
+// vector of the match and its groups.
+func regexResult(m []string) any {
+	switch len(m) {
+	case 0:
+		return nil
+	case 1:
+		return m[0]
+	}
+	out := make(Vector, len(m))
+	for i, s := range m {
+		out[i] = s
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package datalog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxPullDepth bounds recursive pull patterns such as {:block/_parent ...}.
+const maxPullDepth = 64
+
+// Pull returns entity e shaped by a pull pattern such as
+// [:block/uuid {:block/page [:block/name]}]. Keys are attribute names;
+// references that are not expanded come back as {"db/id": n}.
+func (db *DB) Pull(e int, pattern any) (map[string]any, error) {
+	return db.pull(e, pattern, maxPullDepth)
+}
+
+func (db *DB) pull(e int, pattern any, depth int) (map[string]any, error) {
+	spec, ok := pattern.(Vector)
+	if !ok {
+		return nil, fmt.Errorf("pull pattern must be a vector, got %s", describe(pattern))
+	}
+	out := make(map[string]any)
+	for _, item := range spec {
+		switch it := item.(type) {
+		case Symbol:
+			if it != "*" {
+				return nil, fmt.Errorf("bad pull attribute %s", it)
+			}
+			out["db/id"] = int64(e)
+			for _, attr := range db.Attrs(e) {
+				out[attr] = db.pullValues(attr, db.values(e, attr))
+			}
+		case Keyword:
+			if it == "db/id" {
+				out["db/id"] = int64(e)
+				continue
+			}
+			if vals := db.attrValues(e, string(it)); len(vals) > 0 {
+				out[string(it)] = db.pullValues(string(it), vals)
+			}
+		case Map:
+			for _, entry := range it {
+				attr, ok := entry.Key.(Keyword)
+				if !ok {
+					return nil, fmt.Errorf("bad pull map key %s", describe(entry.Key))
+				}
+				sub, subDepth := entry.Val, depth-1
+				switch n := entry.Val.(type) {
+				case Symbol:
+					if n != "..." {
+						return nil, fmt.Errorf("bad pull recursion %s", n)
+					}
+					sub = pattern
+				case int64:
+					sub, subDepth = pattern, min(depth-1, int(n)-1)
+				}
+				if subDepth < 0 {
+					continue
+				}
+				if err := db.pullNested(out, e, string(attr), sub, subDepth); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported pull element %s", describe(item))
+		}
+	}
+	return out, nil
+}
+
+// pullNested expands the references in attr with a sub-pattern.
+func (db *DB) pullNested(out map[string]any, e int, attr string, sub any, depth int) error {
+	vals := db.attrValues(e, attr)
+	if len(vals) == 0 {
+		return nil
+	}
+	pulled := make([]any, 0, len(vals))
+	for _, v := range vals {
+		ref, ok := v.(Ref)
+		if !ok {
+			pulled = append(pulled, v)
+			continue
+		}
+		m, err := db.pull(int(ref), sub, depth)
+		if err != nil {
+			return err
+		}
+		pulled = append(pulled, m)
+	}
+	if db.isMany(attr) {
+		out[attr] = pulled
+	} else {
+		out[attr] = pulled[0]
+	}
+	return nil
+}
+
+// attrValues returns attr's values on e, following reverse attributes such
+// as :block/_parent to the entities that point at e.
+func (db *DB) attrValues(e int, attr string) []any {
+	if !isReverse(attr) {
+		return db.values(e, attr)
+	}
+	var refs []any
+	for _, id := range db.entitiesWith(forwardAttr(attr), Ref(e)) {
+		refs = append(refs, Ref(id))
+	}
+	return refs
+}
+
+// pullValues renders stored values: references as {"db/id": n}, and a list
+// for cardinality-many attributes.
+func (db *DB) pullValues(attr string, vals []any) any {
+	out := make([]any, len(vals))
+	for i, v := range vals {
+		if ref, ok := v.(Ref); ok {
+			out[i] = map[string]any{"db/id": int64(ref)}
+		} else {
+			out[i] = v
+		}
+	}
+	if db.isMany(attr) {
+		return out
+	}
+	return out[0]
+}
+
+func (db *DB) isMany(attr string) bool {
+	return isReverse(attr) || db.many[attr]
+}
+
+// isReverse reports whether attr names a reverse reference such as
+// "block/_parent".
+func isReverse(attr string) bool {
+	return strings.HasPrefix(Keyword(attr).Name(), "_")
+}
+
+// forwardAttr turns "block/_parent" into "block/parent".
+func forwardAttr(attr string) string {
+	i := strings.LastIndexByte(attr, '/')
+	return attr[:i+1] + attr[i+2:]
+}
+
+// JSONValue converts a query result into plain Go values shaped the way
+// Logseq's datascriptQuery API returns them: keyword keys and values lose
+// their namespace ("block/original-name" → "original-name"), references
+// become entity ids and sets become lists.
+func JSONValue(v any) any {
+	switch x := v.(type) {
+	case Keyword:
+		return x.Name()
+	case Symbol:
+		return string(x)
+	case Ref:
+		return int64(x)
+	case *regexp.Regexp:
+		return x.String()
+	case *DB:
+		return nil
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, val := range x {
+			out[Keyword(k).Name()] = JSONValue(val)
+		}
+		return out
+	case Map:
+		out := make(map[string]any, len(x))
+		for _, e := range x {
+			out[str(JSONValue(e.Key))] = JSONValue(e.Val)
+		}
+		return out
+	}
+	if items, ok := seq(v); ok {
+		out := make([]any, len(items))
+		for i, item := range items {
+			out[i] = JSONValue(item)
+		}
+		return out
+	}
+	return v
+}
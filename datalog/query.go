@@ -0,0 +1,616 @@
+package datalog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Result shapes a :find spec can ask for.
+const (
+	findRelation   = iota // :find ?a ?b
+	findCollection        // :find [?a ...]
+	findTuple             // :find [?a ?b]
+	findScalar            // :find ?a .
+)
+
+// query is a parsed :find/:in/:with/:where query.
+type query struct {
+	find  []findElem
+	shape int
+	in    []any // binding forms, $ for the database
+	with  []Symbol
+	where []any
+}
+
+// findElem is one element of :find: a variable, a pull or an aggregate.
+type findElem struct {
+	variable  Symbol
+	pull      any    // pull pattern, when a pull expression
+	aggregate string // count, count-distinct, min, max, sum, avg, distinct
+}
+
+// bindings maps query variables to their values in one result row.
+type bindings map[Symbol]any
+
+// with returns a copy of b with v bound to val.
+func (b bindings) with(v Symbol, val any) bindings {
+	out := make(bindings, len(b)+1)
+	for k, x := range b {
+		out[k] = x
+	}
+	out[v] = val
+	return out
+}
+
+// Query runs a DataScript query against db. inputs bind the :in variables
+// after $; string inputs are read as EDN, as Logseq's API does, so "\"foo\""
+// binds the string foo and "[1 2]" a vector.
+//
+// The result mirrors DataScript: a slice of tuples for a relation, a slice of
+// values for [?x ...], a single tuple for [?x ?y] and a value for ?x .
+// Pull results are maps keyed by attribute ("block/uuid"), with unexpanded
+// references as {"db/id": n}.
+func Query(db *DB, src string, inputs ...any) (any, error) {
+	form, err := ReadEDN(src)
+	if err != nil {
+		return nil, err
+	}
+	q, err := parseQuery(form)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.bindInputs(db, inputs)
+	if err != nil {
+		return nil, err
+	}
+	ev := &evaluator{db: db}
+	for _, clause := range q.where {
+		if rows, err = ev.clause(rows, clause); err != nil {
+			return nil, err
+		}
+	}
+	return q.project(db, rows)
+}
+
+// parseQuery accepts the vector form [:find ... :where ...] and the map form
+// {:find [...] :where [...]}.
+func parseQuery(form any) (*query, error) {
+	sections := make(map[Keyword][]any)
+	switch f := form.(type) {
+	case Vector:
+		var current Keyword
+		for _, item := range f {
+			if kw, ok := item.(Keyword); ok {
+				current = kw
+				sections[current] = nil
+				continue
+			}
+			if current == "" {
+				return nil, fmt.Errorf("query must start with a keyword such as :find")
+			}
+			sections[current] = append(sections[current], item)
+		}
+	case Map:
+		for _, e := range f {
+			kw, ok := e.Key.(Keyword)
+			items, isSeq := seq(e.Val)
+			if !ok || !isSeq {
+				return nil, fmt.Errorf("query map entries must map keywords to vectors")
+			}
+			sections[kw] = items
+		}
+	default:
+		return nil, fmt.Errorf("query must be a vector or map, got %s", describe(form))
+	}
+
+	for kw := range sections {
+		switch kw {
+		case "find", "in", "with", "where":
+		case "keys", "strs", "syms":
+			return nil, fmt.Errorf(":%s is not supported", kw)
+		default:
+			return nil, fmt.Errorf("unknown query section :%s", kw)
+		}
+	}
+
+	q := &query{in: sections["in"], where: sections["where"]}
+	if len(q.in) == 0 {
+		q.in = []any{Symbol("$")}
+	}
+	for _, w := range sections["with"] {
+		s, ok := w.(Symbol)
+		if !ok || !isVar(s) {
+			return nil, fmt.Errorf(":with expects variables, got %s", describe(w))
+		}
+		q.with = append(q.with, s)
+	}
+	if err := q.parseFind(sections["find"]); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *query) parseFind(items []any) error {
+	if len(items) == 0 {
+		return fmt.Errorf("query has no :find")
+	}
+	q.shape = findRelation
+	switch {
+	case len(items) == 2 && items[1] == Symbol("."):
+		q.shape, items = findScalar, items[:1]
+	case len(items) == 1:
+		if v, ok := items[0].(Vector); ok {
+			if len(v) == 2 && v[1] == Symbol("...") {
+				q.shape, items = findCollection, v[:1]
+			} else {
+				q.shape, items = findTuple, v
+			}
+		}
+	}
+	for _, item := range items {
+		elem, err := parseFindElem(item)
+		if err != nil {
+			return err
+		}
+		q.find = append(q.find, elem)
+	}
+	return nil
+}
+
+func parseFindElem(item any) (findElem, error) {
+	if s, ok := item.(Symbol); ok && isVar(s) {
+		return findElem{variable: s}, nil
+	}
+	l, ok := item.(List)
+	if !ok || len(l) < 2 {
+		return findElem{}, fmt.Errorf("bad :find element %s", describe(item))
+	}
+	head, _ := l[0].(Symbol)
+	args := l[1:]
+	if head == "pull" {
+		if len(args) == 3 && args[0] == Symbol("$") {
+			args = args[1:]
+		}
+		v, ok := args[0].(Symbol)
+		if len(args) != 2 || !ok || !isVar(v) {
+			return findElem{}, fmt.Errorf("pull expects a variable and a pattern: %s", edn(item))
+		}
+		return findElem{variable: v, pull: args[1]}, nil
+	}
+	switch head {
+	case "count", "count-distinct", "min", "max", "sum", "avg", "distinct":
+		v, ok := args[len(args)-1].(Symbol)
+		if !ok || !isVar(v) {
+			return findElem{}, fmt.Errorf("%s expects a variable: %s", head, edn(item))
+		}
+		return findElem{variable: v, aggregate: string(head)}, nil
+	}
+	return findElem{}, fmt.Errorf("unsupported :find expression %s", edn(item))
+}
+
+// bindInputs binds the :in forms to the database and inputs, producing the
+// starting rows.
+func (q *query) bindInputs(db *DB, inputs []any) ([]bindings, error) {
+	rows := []bindings{{}}
+	next := 0
+	for _, form := range q.in {
+		if form == Symbol("$") {
+			continue
+		}
+		if next >= len(inputs) {
+			return nil, fmt.Errorf("query expects %d inputs, got %d", countInputs(q.in), len(inputs))
+		}
+		val := inputs[next]
+		next++
+		if s, ok := val.(string); ok {
+			parsed, err := ReadEDN(s)
+			if err != nil {
+				return nil, fmt.Errorf("input %d: %w", next, err)
+			}
+			val = parsed
+		}
+		var out []bindings
+		for _, row := range rows {
+			bound, err := bindForm(row, form, val)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, bound...)
+		}
+		rows = out
+	}
+	if next != len(inputs) {
+		return nil, fmt.Errorf("query expects %d inputs, got %d", countInputs(q.in), len(inputs))
+	}
+	return rows, nil
+}
+
+func countInputs(in []any) int {
+	n := 0
+	for _, form := range in {
+		if form != Symbol("$") {
+			n++
+		}
+	}
+	return n
+}
+
+// bindForm destructures val into row following a binding form: ?x, _,
+// [?x ...] (collection), [?a ?b] (tuple) or [[?a ?b]] (relation). It returns
+// one row per binding; no rows when val does not unify.
+func bindForm(row bindings, form, val any) ([]bindings, error) {
+	switch f := form.(type) {
+	case Symbol:
+		if f == "_" {
+			return []bindings{row}, nil
+		}
+		if !isVar(f) {
+			return nil, fmt.Errorf("bad binding %s", f)
+		}
+		if val == nil {
+			return nil, nil
+		}
+		if bound, ok := row[f]; ok {
+			if equal(bound, val) {
+				return []bindings{row}, nil
+			}
+			return nil, nil
+		}
+		return []bindings{row.with(f, val)}, nil
+	case Vector:
+		if len(f) == 2 && f[1] == Symbol("...") {
+			if val == nil {
+				return nil, nil
+			}
+			items, ok := seq(val)
+			if !ok {
+				return nil, fmt.Errorf("collection binding %s needs a collection, got %s", edn(form), describe(val))
+			}
+			var out []bindings
+			for _, item := range items {
+				bound, err := bindForm(row, f[0], item)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, bound...)
+			}
+			return out, nil
+		}
+		if len(f) == 1 {
+			if _, ok := f[0].(Vector); ok {
+				return bindForm(row, Vector{f[0], Symbol("...")}, val)
+			}
+		}
+		items, ok := seq(val)
+		if !ok || len(items) < len(f) {
+			return nil, nil
+		}
+		rows := []bindings{row}
+		for i, sub := range f {
+			var out []bindings
+			for _, r := range rows {
+				bound, err := bindForm(r, sub, items[i])
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, bound...)
+			}
+			rows = out
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("bad binding form %s", describe(form))
+}
+
+// isVar reports whether s is a query variable such as ?b.
+func isVar(s Symbol) bool {
+	return strings.HasPrefix(string(s), "?")
+}
+
+// evaluator runs :where clauses over rows of bindings.
+type evaluator struct {
+	db *DB
+}
+
+// clause applies one :where clause to every row.
+func (ev *evaluator) clause(rows []bindings, clause any) ([]bindings, error) {
+	switch c := clause.(type) {
+	case Vector:
+		if len(c) > 0 {
+			if expr, ok := c[0].(List); ok {
+				return ev.expression(rows, expr, c[1:])
+			}
+		}
+		return ev.pattern(rows, c)
+	case List:
+		head, _ := c[0].(Symbol)
+		switch head {
+		case "not":
+			return ev.not(rows, nil, c[1:])
+		case "not-join":
+			vars, err := joinVars(c)
+			if err != nil {
+				return nil, err
+			}
+			return ev.not(rows, vars, c[2:])
+		case "or":
+			return ev.or(rows, nil, c[1:])
+		case "or-join":
+			vars, err := joinVars(c)
+			if err != nil {
+				return nil, err
+			}
+			return ev.or(rows, vars, c[2:])
+		case "and":
+			return ev.all(rows, c[1:])
+		}
+		return nil, fmt.Errorf("unsupported clause %s (rules are not supported)", edn(clause))
+	}
+	return nil, fmt.Errorf("bad :where clause %s", describe(clause))
+}
+
+// all applies clauses in order.
+func (ev *evaluator) all(rows []bindings, clauses []any) ([]bindings, error) {
+	var err error
+	for _, c := range clauses {
+		if rows, err = ev.clause(rows, c); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// joinVars returns the variable vector of a not-join or or-join clause.
+func joinVars(c List) ([]Symbol, error) {
+	v, ok := c[1].(Vector)
+	if len(c) < 3 || !ok {
+		return nil, fmt.Errorf("%s needs a vector of variables and clauses", c[0])
+	}
+	vars := make([]Symbol, 0, len(v))
+	for _, item := range v {
+		s, ok := item.(Symbol)
+		if !ok || !isVar(s) {
+			return nil, fmt.Errorf("%s: %s is not a variable", c[0], describe(item))
+		}
+		vars = append(vars, s)
+	}
+	return vars, nil
+}
+
+// project restricts row to vars; nil vars keeps the whole row.
+func project(row bindings, vars []Symbol) bindings {
+	if vars == nil {
+		return row
+	}
+	out := make(bindings, len(vars))
+	for _, v := range vars {
+		if val, ok := row[v]; ok {
+			out[v] = val
+		}
+	}
+	return out
+}
+
+// not keeps the rows for which clauses find nothing.
+func (ev *evaluator) not(rows []bindings, vars []Symbol, clauses []any) ([]bindings, error) {
+	var out []bindings
+	for _, row := range rows {
+		matched, err := ev.all([]bindings{project(row, vars)}, clauses)
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) == 0 {
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+// or unions the rows produced by each branch. With join vars, only those
+// variables flow between the row and the branch.
+func (ev *evaluator) or(rows []bindings, vars []Symbol, branches []any) ([]bindings, error) {
+	var out []bindings
+	for _, row := range rows {
+		for _, branch := range branches {
+			matched, err := ev.clause([]bindings{project(row, vars)}, branch)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range matched {
+				if vars == nil {
+					out = append(out, m)
+					continue
+				}
+				merged := row
+				for _, v := range vars {
+					if val, ok := m[v]; ok {
+						merged = merged.with(v, val)
+					}
+				}
+				out = append(out, merged)
+			}
+		}
+	}
+	return out, nil
+}
+
+// expression evaluates [(f args...)] as a predicate or [(f args...) binding]
+// as a function binding.
+func (ev *evaluator) expression(rows []bindings, expr List, binding []any) ([]bindings, error) {
+	name, ok := expr[0].(Symbol)
+	if !ok {
+		return nil, fmt.Errorf("bad function call %s", edn(expr))
+	}
+	fn, ok := functions[string(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if len(binding) > 1 {
+		return nil, fmt.Errorf("function clause %s has more than one binding", edn(expr))
+	}
+
+	var out []bindings
+	for _, row := range rows {
+		args := make([]any, len(expr)-1)
+		for i, arg := range expr[1:] {
+			val, err := ev.resolve(row, arg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			args[i] = val
+		}
+		result, err := fn(args)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(binding) == 0 {
+			if truthy(result) {
+				out = append(out, row)
+			}
+			continue
+		}
+		bound, err := bindForm(row, binding[0], result)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, bound...)
+	}
+	return out, nil
+}
+
+// resolve evaluates a function argument: $ is the database, variables must
+// already be bound, anything else is a literal.
+func (ev *evaluator) resolve(row bindings, arg any) (any, error) {
+	s, ok := arg.(Symbol)
+	switch {
+	case !ok:
+		return arg, nil
+	case s == "$":
+		return ev.db, nil
+	case isVar(s):
+		val, bound := row[s]
+		if !bound {
+			return nil, fmt.Errorf("insufficient bindings: %s is not bound", s)
+		}
+		return val, nil
+	}
+	return arg, nil
+}
+
+// pattern matches a data pattern [e a v] against the database.
+func (ev *evaluator) pattern(rows []bindings, pat Vector) ([]bindings, error) {
+	if len(pat) > 0 && pat[0] == Symbol("$") {
+		pat = pat[1:]
+	}
+	if len(pat) == 0 || len(pat) > 3 {
+		return nil, fmt.Errorf("bad data pattern %s", edn(pat))
+	}
+	for len(pat) < 3 {
+		pat = append(pat, Symbol("_"))
+	}
+
+	var out []bindings
+	for _, row := range rows {
+		e, eBound := term(row, pat[0])
+		a, aBound := term(row, pat[1])
+		v, vBound := term(row, pat[2])
+
+		emit := func(id int, attr string, val any) {
+			r := row
+			for i, x := range [3]any{Ref(id), Keyword(attr), val} {
+				s, ok := pat[i].(Symbol)
+				if !ok || !isVar(s) {
+					continue
+				}
+				if bound, ok := r[s]; ok {
+					if !equal(bound, x) {
+						return // [?e :a ?e] and the like
+					}
+					continue
+				}
+				r = r.with(s, x)
+			}
+			out = append(out, r)
+		}
+
+		var attr string
+		if aBound {
+			kw, ok := a.(Keyword)
+			if !ok {
+				return nil, fmt.Errorf("attribute must be a keyword in %s", edn(pat))
+			}
+			attr = string(kw)
+		}
+
+		switch {
+		case eBound:
+			id, ok := entityID(e)
+			if !ok {
+				continue
+			}
+			attrs := []string{attr}
+			if !aBound {
+				attrs = ev.db.Attrs(id)
+			}
+			for _, at := range attrs {
+				for _, val := range ev.db.values(id, at) {
+					if !vBound || equal(val, v) {
+						emit(id, at, val)
+					}
+				}
+			}
+		case aBound && vBound:
+			for _, id := range ev.db.entitiesWith(attr, v) {
+				emit(id, attr, v)
+			}
+		case aBound:
+			for _, d := range ev.db.byAttr[attr] {
+				emit(d.e, attr, d.v)
+			}
+		default:
+			for id := 1; id <= len(ev.db.entities); id++ {
+				for _, at := range ev.db.Attrs(id) {
+					for _, val := range ev.db.values(id, at) {
+						if !vBound || equal(val, v) {
+							emit(id, at, val)
+						}
+					}
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// term returns a pattern position's value and whether it is fixed, either
+// as a literal or as an already-bound variable.
+func term(row bindings, t any) (any, bool) {
+	s, ok := t.(Symbol)
+	if !ok {
+		return t, true
+	}
+	if s == "_" {
+		return nil, false
+	}
+	if isVar(s) {
+		val, bound := row[s]
+		return val, bound
+	}
+	return t, true
+}
+
+// entityID converts an entity reference to an id.
+func entityID(v any) (int, bool) {
+	switch id := v.(type) {
+	case Ref:
+		return int(id), true
+	case int64:
+		return int(id), true
+	case int:
+		return id, true
+	case float64:
+		return int(id), id == float64(int(id))
+	}
+	return 0, false
+}
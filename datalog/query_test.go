@@ -0,0 +1,221 @@
+package datalog
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// testDB builds a small Logseq-shaped graph:
+//
+//	page "cards" ← block "What is 2+2? #card" ← child "4"
+//	page "card"  (tag page)
+//	page "projects" (type:: project) ← block "TODO ship it" referencing the card block
+func testDB() *DB {
+	db := NewDB()
+	db.Many("block/refs")
+
+	page := func(name string, props map[string]any) int {
+		e := db.NewEntity()
+		db.Add(e, "block/name", name)
+		db.Add(e, "block/original-name", name)
+		if props != nil {
+			db.Add(e, "block/properties", props)
+		}
+		return e
+	}
+	block := func(uuid, content string, pg, parent int, refs ...int) int {
+		e := db.NewEntity()
+		db.Add(e, "block/uuid", uuid)
+		db.Add(e, "block/content", content)
+		db.Add(e, "block/page", Ref(pg))
+		db.Add(e, "block/parent", Ref(parent))
+		for _, r := range refs {
+			db.Add(e, "block/refs", Ref(r))
+		}
+		return e
+	}
+
+	cards := page("cards", nil)
+	card := page("card", nil)
+	projects := page("projects", map[string]any{"type": "project"})
+	q := block("u-q", "What is 2+2? #card", cards, cards, card)
+	block("u-a", "4", cards, q)
+	todo := block("u-t", "TODO ship it ((u-q))", projects, projects, q)
+	db.Add(todo, "block/marker", "TODO")
+	return db
+}
+
+func run(t *testing.T, db *DB, q string, inputs ...any) any {
+	t.Helper()
+	res, err := Query(db, q, inputs...)
+	if err != nil {
+		t.Fatalf("Query(%s): %v", q, err)
+	}
+	return res
+}
+
+// toJSON round-trips a result through JSONValue and encoding/json so tests
+// compare what API callers see.
+func toJSON(t *testing.T, v any) any {
+	t.Helper()
+	data, err := json.Marshal(JSONValue(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestQueryRefsAndPull(t *testing.T) {
+	db := testDB()
+	res := run(t, db, `[:find (pull ?b [:block/uuid :block/content :block/properties
+	                           {:block/page [:block/name :block/original-name]}])
+		:where
+		[?b :block/refs ?ref]
+		[?ref :block/name "card"]]`)
+
+	got := toJSON(t, res)
+	want := []any{[]any{map[string]any{
+		"uuid":    "u-q",
+		"content": "What is 2+2? #card",
+		"page":    map[string]any{"name": "cards", "original-name": "cards"},
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+}
+
+func TestQueryBlockRefByUUID(t *testing.T) {
+	db := testDB()
+	res := run(t, db, `[:find (pull ?b [:block/uuid {:block/page [:block/name]}])
+		:where
+		[?b :block/refs ?ref]
+		[?ref :block/uuid #uuid "u-q"]]`)
+	got := toJSON(t, res).([]any)
+	if len(got) != 1 || got[0].([]any)[0].(map[string]any)["uuid"] != "u-t" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestQueryParentAndReverse(t *testing.T) {
+	db := testDB()
+	res := run(t, db, `[:find (pull ?parent [:block/uuid :block/content])
+		:where
+		[?b :block/uuid "u-a"]
+		[?b :block/parent ?parent]]`)
+	got := toJSON(t, res).([]any)
+	if len(got) != 1 || got[0].([]any)[0].(map[string]any)["uuid"] != "u-q" {
+		t.Errorf("parent = %v", got)
+	}
+
+	res = run(t, db, `[:find (pull ?b [:block/content {:block/_parent [:block/content]}]) .
+		:where [?b :block/uuid "u-q"]]`)
+	children := toJSON(t, res).(map[string]any)["_parent"]
+	if !reflect.DeepEqual(children, []any{map[string]any{"content": "4"}}) {
+		t.Errorf("children = %v", children)
+	}
+}
+
+func TestQueryPredicatesAndFunctions(t *testing.T) {
+	db := testDB()
+	tests := []struct {
+		name  string
+		query string
+		want  any
+	}{
+		{"get property", `[:find [?n ...] :where
+			[?p :block/properties ?props]
+			[(get ?props :type) ?t]
+			[(contains? #{"project" "area"} ?t)]
+			[?p :block/name ?n]]`, []any{"projects"}},
+		{"includes", `[:find [?u ...] :where
+			[?b :block/content ?c]
+			[(clojure.string/includes? ?c "2+2")]
+			[?b :block/uuid ?u]]`, []any{"u-q"}},
+		{"marker", `[:find ?u . :where [?b :block/marker "TODO"] [?b :block/uuid ?u]]`, "u-t"},
+		{"not", `[:find [?u ...] :where
+			[?b :block/uuid ?u]
+			(not [?b :block/refs _])]`, []any{"u-a"}},
+		{"or", `[:find [?u ...] :where
+			[?b :block/uuid ?u]
+			(or [?b :block/marker "TODO"] [(= ?u "u-a")])]`, []any{"u-a", "u-t"}},
+		{"missing", `[:find [?u ...] :where
+			[?b :block/uuid ?u]
+			[(missing? $ ?b :block/marker)]
+			[(re-find #"^u-[a]$" ?u)]]`, []any{"u-a"}},
+		{"count", `[:find ?p (count ?b) :where
+			[?b :block/page ?pg] [?pg :block/name ?p] [(= ?p "cards")]]`, []any{[]any{"cards", float64(2)}}},
+		{"get-else", `[:find ?m . :where [?b :block/uuid "u-a"] [(get-else $ ?b :block/marker "none") ?m]]`, "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toJSON(t, run(t, db, tt.query))
+			if items, ok := got.([]any); ok && tt.name != "count" {
+				sortStrings(items)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryInputs(t *testing.T) {
+	db := testDB()
+	res := run(t, db, `[:find ?u . :in $ ?name :where [?p :block/name ?name] [?b :block/page ?p] [?b :block/marker _] [?b :block/uuid ?u]]`, `"projects"`)
+	if res != "u-t" {
+		t.Errorf("got %v", res)
+	}
+	res = run(t, db, `[:find [?n ...] :in $ [?n ...] :where [_ :block/name ?n]]`, `["card" "missing"]`)
+	if !reflect.DeepEqual(toJSON(t, res), []any{"card"}) {
+		t.Errorf("collection input: got %v", res)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	db := testDB()
+	for _, q := range []string{
+		`[:find ?b :where [?b :block/content ?c`,                // unterminated
+		`[:find ?x :where [?b :block/content _]]`,               // ?x never bound
+		`[:find ?b :where [(clojure.string/includes? ?c "x")]]`, // unbound predicate arg
+		`[:find ?b :where [?b :block/content ?c] (rule ?b)]`,    // rules
+		`[:find ?b :where [(no-such-fn ?b)]]`,
+		`[:find ?n :in $ ?n :where [_ :block/name ?n]]`, // missing input
+	} {
+		if _, err := Query(db, q); err == nil {
+			t.Errorf("expected error for %s", q)
+		}
+	}
+}
+
+func TestReadEDN(t *testing.T) {
+	v, err := ReadEDN(`[:find ?b ; comment
+		#_ignored {:a 1, :b [true nil 2.5]} #{"x"} #uuid "abc" -3 \space]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Vector{Keyword("find"), Symbol("?b"),
+		Map{{Keyword("a"), int64(1)}, {Keyword("b"), Vector{true, nil, 2.5}}},
+		Set{"x"}, "abc", int64(-3), " "}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v\nwant %#v", v, want)
+	}
+	for _, bad := range []string{`[1 2`, `(1]`, `{:a}`, `"open`, `1 2`} {
+		if _, err := ReadEDN(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func sortStrings(items []any) {
+	sort.Slice(items, func(i, j int) bool {
+		a, _ := items[i].(string)
+		b, _ := items[j].(string)
+		return a < b
+	})
+}
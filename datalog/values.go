@@ -0,0 +1,211 @@
+package datalog
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// number returns v as a float64 when it is numeric.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	case Ref:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// equal compares two values the way Clojure's = does for the types the
+// engine sees: numbers by value, collections element-wise.
+func equal(a, b any) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	if x, ok := seq(a); ok {
+		y, ok := seq(b)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		if isSet(a) || isSet(b) {
+			return sameMembers(x, y)
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	switch x := a.(type) {
+	case string, Keyword, Symbol, bool, nil:
+		return a == b
+	case *regexp.Regexp:
+		y, ok := b.(*regexp.Regexp)
+		return ok && x.String() == y.String()
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// sameMembers reports whether x and y hold the same elements in any order.
+func sameMembers(x, y []any) bool {
+	for _, a := range x {
+		if !contains(y, a) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether items holds v.
+func contains(items []any, v any) bool {
+	for _, item := range items {
+		if equal(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare orders numbers numerically and strings lexically.
+func compare(a, b any) (int, error) {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	x, xok := a.(string)
+	y, yok := b.(string)
+	if xok && yok {
+		return strings.Compare(x, y), nil
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", describe(a), describe(b))
+}
+
+// seq returns the elements of a collection value.
+func seq(v any) ([]any, bool) {
+	switch c := v.(type) {
+	case Vector:
+		return c, true
+	case List:
+		return c, true
+	case Set:
+		return c, true
+	case []any:
+		return c, true
+	case []string:
+		out := make([]any, len(c))
+		for i, s := range c {
+			out[i] = s
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func isSet(v any) bool {
+	_, ok := v.(Set)
+	return ok
+}
+
+// truthy follows Clojure: everything except nil and false is true.
+func truthy(v any) bool {
+	return v != nil && v != false
+}
+
+// str renders v the way Clojure's str does: strings as-is, nil as "",
+// everything else in its EDN form.
+func str(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if v == nil {
+		return ""
+	}
+	return edn(v)
+}
+
+// edn renders v as EDN.
+func edn(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(x)
+	case Keyword:
+		return ":" + string(x)
+	case Symbol:
+		return string(x)
+	case Ref:
+		return strconv.Itoa(int(x))
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case *regexp.Regexp:
+		return "#" + strconv.Quote(x.String())
+	case Set:
+		return "#{" + ednItems(x) + "}"
+	case List:
+		return "(" + ednItems(x) + ")"
+	case Map:
+		parts := make([]string, len(x))
+		for i, e := range x {
+			parts[i] = edn(e.Key) + " " + edn(e.Val)
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = ":" + k + " " + edn(x[k])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	if items, ok := seq(v); ok {
+		return "[" + ednItems(items) + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+func ednItems(items []any) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = edn(item)
+	}
+	return strings.Join(parts, " ")
+}
+
+// describe names a value's type for error messages.
+func describe(v any) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return "string " + strconv.Quote(v.(string))
+	case int64, float64, Ref:
+		return "number " + edn(v)
+	}
+	return edn(v)
+}
@@ -2,14 +2,16 @@ module github.com/skridlevsky/graphthulhu
 
 go 1.24.11
 
-require github.com/modelcontextprotocol/go-sdk v1.2.0
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/google/uuid v1.6.0
+	github.com/modelcontextprotocol/go-sdk v1.2.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/fsnotify/fsnotify v1.9.0 // indirect
 	github.com/google/jsonschema-go v0.3.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 	golang.org/x/oauth2 v0.30.0 // indirect
 	golang.org/x/sys v0.13.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -13,17 +13,19 @@ type OverviewStats struct {
 	JournalPages    int              `json:"journalPages"`
 	OrphanPages     int              `json:"orphanPages"`
 	MostConnected   []PageStat       `json:"mostConnected"`
+	RankedBy        string           `json:"rankedBy,omitempty"`
 	MostLinkedTo    []PageStat       `json:"mostLinkedTo"`
 	Namespaces      map[string]int   `json:"namespaces"`
 }
 
 // PageStat is a page with its connectivity score.
 type PageStat struct {
-	Name        string `json:"name"`
-	OutLinks    int    `json:"outLinks"`
-	InLinks     int    `json:"inLinks"`
-	TotalDegree int    `json:"totalDegree"`
-	BlockCount  int    `json:"blockCount"`
+	Name        string   `json:"name"`
+	OutLinks    int      `json:"outLinks"`
+	InLinks     int      `json:"inLinks"`
+	TotalDegree int      `json:"totalDegree"`
+	BlockCount  int      `json:"blockCount"`
+	Score       *float64 `json:"score,omitempty"` // centrality score, when ranked by one
 }
 
 // ConnectionResult describes how two pages are connected.
@@ -37,10 +39,9 @@ type ConnectionResult struct {
 
 // GapInfo describes a knowledge gap or sparse area.
 type GapInfo struct {
-	OrphanPages   []string   `json:"orphanPages"`
-	DeadEndPages  []string   `json:"deadEndPages"`
-	WeaklyLinked  []PageStat `json:"weaklyLinked"`
-	SingletonTags []string   `json:"singletonTags,omitempty"`
+	OrphanPages  []string   `json:"orphanPages"`
+	DeadEndPages []string   `json:"deadEndPages"`
+	WeaklyLinked []PageStat `json:"weaklyLinked"`
 }
 
 // Cluster is a group of densely connected pages.
@@ -575,7 +575,7 @@ func TestExtractLinksRecursive_Simple(t *testing.T) {
 	blocks := []types.BlockEntity{
 		{Content: "Link to [[Target]]"},
 	}
-	extractLinksRecursive(blocks, "source", g)
+	extractLinksRecursive(blocks, "source", "source", g, nil)
 	if !g.Forward["source"]["Target"] {
 		t.Errorf("Forward[source] = %v, want Target", g.Forward["source"])
 	}
@@ -597,7 +597,7 @@ func TestExtractLinksRecursive_Nested(t *testing.T) {
 			},
 		},
 	}
-	extractLinksRecursive(blocks, "source", g)
+	extractLinksRecursive(blocks, "source", "source", g, nil)
 	if !g.Forward["source"]["Top"] {
 		t.Error("missing forward link to Top")
 	}
@@ -605,3 +605,20 @@ func TestExtractLinksRecursive_Nested(t *testing.T) {
 		t.Error("missing forward link to Nested")
 	}
 }
+
+func TestExtractLinksRecursive_MarkdownLinks(t *testing.T) {
+	g := &Graph{
+		Forward:  map[string]map[string]bool{"projects/alpha": make(map[string]bool)},
+		Backward: make(map[string]map[string]bool),
+	}
+	blocks := []types.BlockEntity{
+		{Content: "See [the spec](Spec%20Doc.md) and [Hanna](../people/Hanna.md#Bio)"},
+	}
+	extractLinksRecursive(blocks, "projects/alpha", "projects/alpha", g, nil)
+	if !g.Forward["projects/alpha"]["projects/Spec Doc"] {
+		t.Errorf("Forward = %v, want projects/Spec Doc", g.Forward["projects/alpha"])
+	}
+	if !g.Backward["people/hanna"]["projects/alpha"] {
+		t.Errorf("Backward[people/hanna] = %v, want projects/alpha", g.Backward["people/hanna"])
+	}
+}
@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -11,13 +12,16 @@ import (
 	"github.com/skridlevsky/graphthulhu/types"
 )
 
-// Cache holds a recently built graph to avoid rebuilding on every analyze call.
+// Cache holds a recently built graph to avoid rebuilding on every analyze
+// call, and the centrality scores computed on it.
 type Cache struct {
 	mu      sync.Mutex
 	graph   *Graph
 	built   time.Time
 	ttl     time.Duration
 	backend backend.Backend
+	// scores: measure and seeds → centrality scores of the cached graph
+	scores map[string]map[string]float64
 }
 
 // NewCache creates a graph cache with the given TTL.
@@ -32,7 +36,44 @@ func NewCache(b backend.Backend, ttl time.Duration) *Cache {
 func (c *Cache) Get(ctx context.Context) (*Graph, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.getLocked(ctx)
+}
+
+// Centrality returns the cached graph with its centrality scores by
+// measure (see Graph.Centrality), computing them once per graph build.
+func (c *Cache) Centrality(ctx context.Context, measure string, seeds []string) (*Graph, map[string]float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, err := c.getLocked(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := measure
+	if measure == MeasurePageRank && len(seeds) > 0 {
+		lower := make([]string, len(seeds))
+		for i, s := range seeds {
+			lower[i] = strings.ToLower(s)
+		}
+		slices.Sort(lower)
+		key += "\x00" + strings.Join(slices.Compact(lower), "\x00")
+	}
+	if scores, ok := c.scores[key]; ok {
+		return g, scores, nil
+	}
 
+	scores, err := g.Centrality(measure, seeds)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.scores[key] = scores
+	return g, scores, nil
+}
+
+// getLocked returns the cached graph, rebuilding it and dropping the
+// scores computed on the old one if expired. Caller must hold c.mu.
+func (c *Cache) getLocked(ctx context.Context) (*Graph, error) {
 	if c.graph != nil && time.Since(c.built) < c.ttl {
 		return c.graph, nil
 	}
@@ -44,6 +85,7 @@ func (c *Cache) Get(ctx context.Context) (*Graph, error) {
 
 	c.graph = g
 	c.built = time.Now()
+	c.scores = make(map[string]map[string]float64)
 	return g, nil
 }
 
@@ -52,6 +94,7 @@ func (c *Cache) Invalidate() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.graph = nil
+	c.scores = nil
 }
 
 // Graph is an in-memory representation of the knowledge graph's link structure.
@@ -79,6 +122,7 @@ func Build(ctx context.Context, c backend.Backend) (*Graph, error) {
 		Pages:       make(map[string]types.PageEntity),
 		BlockCounts: make(map[string]int),
 	}
+	resolve := linkResolver(ctx, c)
 
 	for _, page := range pages {
 		if page.Name == "" {
@@ -98,12 +142,35 @@ func Build(ctx context.Context, c backend.Backend) (*Graph, error) {
 		}
 
 		g.BlockCounts[key] = countBlocksRecursive(blocks)
-		extractLinksRecursive(blocks, key, g)
+		extractLinksRecursive(blocks, page.Name, key, g, resolve)
 	}
 
 	return g, nil
 }
 
+// linkResolver returns a function mapping link targets to canonical page
+// names when the backend implements backend.PageResolver, memoizing lookups
+// for the duration of one build. Returns nil otherwise. Ambiguous or
+// unknown targets are kept as written.
+func linkResolver(ctx context.Context, c backend.Backend) func(string) string {
+	pr, ok := c.(backend.PageResolver)
+	if !ok {
+		return nil
+	}
+	memo := make(map[string]string)
+	return func(link string) string {
+		if name, ok := memo[link]; ok {
+			return name
+		}
+		name, err := pr.ResolvePageName(ctx, link)
+		if err != nil || name == "" {
+			name = link
+		}
+		memo[link] = name
+		return name
+	}
+}
+
 func countBlocksRecursive(blocks []types.BlockEntity) int {
 	count := len(blocks)
 	for _, b := range blocks {
@@ -114,10 +181,15 @@ func countBlocksRecursive(blocks []types.BlockEntity) int {
 	return count
 }
 
-func extractLinksRecursive(blocks []types.BlockEntity, sourceKey string, g *Graph) {
+// extractLinksRecursive records the links in blocks as graph edges from
+// sourceKey. resolve, when non-nil, canonicalizes each link target.
+func extractLinksRecursive(blocks []types.BlockEntity, sourceName, sourceKey string, g *Graph, resolve func(string) string) {
 	for _, b := range blocks {
-		parsed := parser.Parse(b.Content)
+		parsed := parser.ParseInPage(b.Content, sourceName)
 		for _, link := range parsed.Links {
+			if resolve != nil {
+				link = resolve(link)
+			}
 			linkKey := strings.ToLower(link)
 			g.Forward[sourceKey][link] = true
 
@@ -127,7 +199,7 @@ func extractLinksRecursive(blocks []types.BlockEntity, sourceKey string, g *Grap
 			g.Backward[linkKey][sourceKey] = true
 		}
 		if len(b.Children) > 0 {
-			extractLinksRecursive(b.Children, sourceKey, g)
+			extractLinksRecursive(b.Children, sourceName, sourceKey, g, resolve)
 		}
 	}
 }
@@ -0,0 +1,287 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Centrality measures, as the tools name them.
+const (
+	MeasureDegree      = "degree"
+	MeasurePageRank    = "pagerank"
+	MeasureBetweenness = "betweenness"
+	MeasureCloseness   = "closeness"
+)
+
+const (
+	// damping is the probability that PageRank's random reader follows a
+	// link rather than jumping to a page at random (or to a seed page).
+	damping = 0.85
+	// pageRankTolerance ends PageRank once an iteration changes the total
+	// score by less than this.
+	pageRankTolerance     = 1e-9
+	maxPageRankIterations = 100
+)
+
+// Centrality scores every page by measure, keyed by lowercase page name.
+// PageRank is personalized from seeds when there are any: the random reader
+// jumps back to a seed page rather than to any page, so pages close to the
+// seeds rank highest. Seeds are ignored by the other measures.
+func (g *Graph) Centrality(measure string, seeds []string) (map[string]float64, error) {
+	switch measure {
+	case MeasureDegree:
+		scores := make(map[string]float64, len(g.Pages))
+		for key := range g.Pages {
+			scores[key] = float64(g.TotalDegree(key))
+		}
+		return scores, nil
+	case MeasurePageRank:
+		return g.PageRank(seeds)
+	case MeasureBetweenness:
+		return g.Betweenness(), nil
+	case MeasureCloseness:
+		return g.Closeness(), nil
+	}
+	return nil, fmt.Errorf("unknown measure %q (use %s, %s, %s or %s)", measure, MeasureDegree, MeasurePageRank, MeasureBetweenness, MeasureCloseness)
+}
+
+// PageRank scores pages by the links pointing to them, weighted by the
+// scores of the linking pages. Scores sum to 1. Given seeds, it is
+// personalized from them; seeds that are not pages of the graph are an
+// error when none of them is.
+func (g *Graph) PageRank(seeds []string) (map[string]float64, error) {
+	keys, index := g.pageIndex()
+	n := len(keys)
+	if n == 0 {
+		return map[string]float64{}, nil
+	}
+
+	teleport := make([]float64, n)
+	if len(seeds) == 0 {
+		for i := range teleport {
+			teleport[i] = 1 / float64(n)
+		}
+	} else {
+		var found []int
+		for _, s := range seeds {
+			if i, ok := index[strings.ToLower(s)]; ok {
+				found = append(found, i)
+			}
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("none of the seed pages is in the graph: %s", strings.Join(seeds, ", "))
+		}
+		for _, i := range found {
+			teleport[i] += 1 / float64(len(found))
+		}
+	}
+
+	out := g.forwardAdjacency(keys, index)
+	rank := append([]float64(nil), teleport...)
+	next := make([]float64, n)
+	for iter := 0; iter < maxPageRankIterations; iter++ {
+		// Pages without links hand their score to the teleport targets.
+		dangling := 0.0
+		for i, links := range out {
+			if len(links) == 0 {
+				dangling += rank[i]
+			}
+		}
+		for i := range next {
+			next[i] = (1 - damping + damping*dangling) * teleport[i]
+		}
+		for i, links := range out {
+			share := damping * rank[i] / float64(len(links))
+			for _, j := range links {
+				next[j] += share
+			}
+		}
+
+		delta := 0.0
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+
+	scores := make(map[string]float64, n)
+	for i, key := range keys {
+		scores[key] = rank[i]
+	}
+	return scores, nil
+}
+
+// Betweenness scores pages by how many shortest paths between other pages
+// run through them, in the undirected link graph: the bridges between
+// topics. Scores are normalized to [0, 1].
+func (g *Graph) Betweenness() map[string]float64 {
+	keys, index := g.pageIndex()
+	n := len(keys)
+	adj := g.undirectedAdjacency(keys, index)
+	score := make([]float64, n)
+
+	// Brandes' algorithm: one breadth-first search per source, then the
+	// dependencies accumulated back from the farthest pages.
+	sigma := make([]float64, n)
+	dist := make([]int, n)
+	delta := make([]float64, n)
+	preds := make([][]int, n)
+	for s := 0; s < n; s++ {
+		for i := range sigma {
+			sigma[i], dist[i], delta[i], preds[i] = 0, -1, 0, preds[i][:0]
+		}
+		sigma[s], dist[s] = 1, 0
+		order := []int{s}
+		for q := 0; q < len(order); q++ {
+			v := order[q]
+			for _, w := range adj[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					order = append(order, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					preds[w] = append(preds[w], v)
+				}
+			}
+		}
+		for i := len(order) - 1; i > 0; i-- {
+			w := order[i]
+			for _, v := range preds[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			score[w] += delta[w]
+		}
+	}
+
+	// Each path was counted from both ends.
+	norm := 2.0
+	if n > 2 {
+		norm = float64((n - 1) * (n - 2))
+	}
+	scores := make(map[string]float64, n)
+	for i, key := range keys {
+		scores[key] = score[i] / norm
+	}
+	return scores
+}
+
+// Closeness scores pages by how few links separate them from the pages
+// they can reach in the undirected link graph, scaled by the share of the
+// graph they reach so that small islands do not outrank the main body.
+// Scores are in [0, 1].
+func (g *Graph) Closeness() map[string]float64 {
+	keys, index := g.pageIndex()
+	n := len(keys)
+	adj := g.undirectedAdjacency(keys, index)
+	scores := make(map[string]float64, n)
+
+	dist := make([]int, n)
+	for s := 0; s < n; s++ {
+		for i := range dist {
+			dist[i] = -1
+		}
+		dist[s] = 0
+		order := []int{s}
+		total := 0
+		for q := 0; q < len(order); q++ {
+			v := order[q]
+			for _, w := range adj[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					total += dist[w]
+					order = append(order, w)
+				}
+			}
+		}
+		reached := float64(len(order) - 1)
+		if total > 0 {
+			scores[keys[s]] = reached / float64(n-1) * reached / float64(total)
+		} else {
+			scores[keys[s]] = 0
+		}
+	}
+	return scores
+}
+
+// MostCentral returns the n pages with the highest scores, highest first,
+// ties broken by name. Journal pages are left out unless includeJournals.
+func (g *Graph) MostCentral(scores map[string]float64, n int, includeJournals bool) []PageStat {
+	var stats []PageStat
+	for key, score := range scores {
+		page, ok := g.Pages[key]
+		if !ok || (page.Journal && !includeJournals) {
+			continue
+		}
+		stats = append(stats, PageStat{
+			Name:        g.OriginalName(key),
+			OutLinks:    g.OutDegree(key),
+			InLinks:     g.InDegree(key),
+			TotalDegree: g.TotalDegree(key),
+			BlockCount:  g.BlockCounts[key],
+			Score:       &score,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if *stats[i].Score != *stats[j].Score {
+			return *stats[i].Score > *stats[j].Score
+		}
+		return strings.ToLower(stats[i].Name) < strings.ToLower(stats[j].Name)
+	})
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// pageIndex returns the graph's page keys in sorted order, so that scores
+// do not depend on map iteration, and each key's position.
+func (g *Graph) pageIndex() ([]string, map[string]int) {
+	keys := make([]string, 0, len(g.Pages))
+	for key := range g.Pages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	index := make(map[string]int, len(keys))
+	for i, key := range keys {
+		index[key] = i
+	}
+	return keys, index
+}
+
+// forwardAdjacency returns the pages each page links to, by position.
+// Links to missing pages and to the page itself are left out.
+func (g *Graph) forwardAdjacency(keys []string, index map[string]int) [][]int {
+	adj := make([][]int, len(keys))
+	for i, key := range keys {
+		seen := make(map[int]bool)
+		for linked := range g.Forward[key] {
+			if j, ok := index[strings.ToLower(linked)]; ok && j != i && !seen[j] {
+				seen[j] = true
+				adj[i] = append(adj[i], j)
+			}
+		}
+		sort.Ints(adj[i])
+	}
+	return adj
+}
+
+// undirectedAdjacency returns the pages each page links to or is linked
+// from, by position.
+func (g *Graph) undirectedAdjacency(keys []string, index map[string]int) [][]int {
+	adj := make([][]int, len(keys))
+	for i, key := range keys {
+		for neighbor := range g.allNeighbors(key) {
+			if j, ok := index[neighbor]; ok && j != i {
+				adj[i] = append(adj[i], j)
+			}
+		}
+		sort.Ints(adj[i])
+	}
+	return adj
+}
@@ -0,0 +1,208 @@
+package graph
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+// --- PageRank ---
+
+func TestPageRank_SumsToOne(t *testing.T) {
+	g := newGraph(map[string][]string{
+		"a": {"b", "c"},
+		"b": {"c"},
+		"c": {"a"},
+		"d": {}, // dangling
+	})
+	scores, err := g.PageRank(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := 0.0
+	for _, s := range scores {
+		sum += s
+	}
+	if !near(sum, 1) {
+		t.Errorf("sum = %v, want 1", sum)
+	}
+}
+
+func TestPageRank_FavoursLinkedFromImportant(t *testing.T) {
+	// The hub links to everything, so it has the highest degree, but c is
+	// the page the others point to.
+	g := newGraph(map[string][]string{
+		"hub": {"a", "b", "c", "d"},
+		"a":   {"c"},
+		"b":   {"c"},
+		"d":   {"c"},
+		"c":   {},
+	})
+	scores, err := g.PageRank(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, s := range scores {
+		if key != "c" && s >= scores["c"] {
+			t.Errorf("%s = %v, not below c = %v", key, s, scores["c"])
+		}
+	}
+}
+
+func TestPageRank_Symmetric(t *testing.T) {
+	g := newGraph(map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+	})
+	scores, _ := g.PageRank(nil)
+	for key, s := range scores {
+		if !near(s, 1.0/3) {
+			t.Errorf("%s = %v, want 1/3", key, s)
+		}
+	}
+}
+
+func TestPageRank_Personalized(t *testing.T) {
+	// Two topics linking to each other: seeded from one, its pages rank
+	// above the other's, which rank the same without seeds.
+	g := newGraph(map[string][]string{
+		"go":     {"tools"},
+		"tools":  {"go", "garden"},
+		"garden": {"plants"},
+		"plants": {"garden", "go"},
+	})
+	scores, err := g.PageRank([]string{"Garden"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores["plants"] <= scores["tools"] {
+		t.Errorf("plants = %v, tools = %v; want plants above tools", scores["plants"], scores["tools"])
+	}
+	if _, err := g.PageRank([]string{"nowhere"}); err == nil {
+		t.Error("seeds outside the graph accepted")
+	}
+}
+
+func TestPageRank_Empty(t *testing.T) {
+	g := newGraph(map[string][]string{})
+	scores, err := g.PageRank(nil)
+	if err != nil || len(scores) != 0 {
+		t.Errorf("PageRank = %v, %v", scores, err)
+	}
+}
+
+// --- Betweenness ---
+
+func TestBetweenness_Path(t *testing.T) {
+	// a - b - c: every path between a and c runs through b.
+	g := newGraph(map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+	})
+	scores := g.Betweenness()
+	if !near(scores["b"], 1) || scores["a"] != 0 || scores["c"] != 0 {
+		t.Errorf("betweenness = %v", scores)
+	}
+}
+
+func TestBetweenness_Bridge(t *testing.T) {
+	// Two triangles joined through bridge. Of the 15 pairs of other pages,
+	// bridge is on the paths of the 9 across the triangles, and a3 on those
+	// from a1 and a2 to bridge, b1, b2 and b3.
+	g := newGraph(map[string][]string{
+		"a1":     {"a2", "a3"},
+		"a2":     {"a3"},
+		"a3":     {"bridge"},
+		"bridge": {"b1"},
+		"b1":     {"b2", "b3"},
+		"b2":     {"b3"},
+	})
+	scores := g.Betweenness()
+	if !near(scores["bridge"], 9.0/15) {
+		t.Errorf("bridge = %v, want %v", scores["bridge"], 9.0/15)
+	}
+	if !near(scores["a3"], 8.0/15) {
+		t.Errorf("a3 = %v, want %v", scores["a3"], 8.0/15)
+	}
+	if scores["a1"] != 0 {
+		t.Errorf("a1 = %v, want 0", scores["a1"])
+	}
+}
+
+func TestBetweenness_SplitsEqualPaths(t *testing.T) {
+	// A square: a and c are joined through b and through d.
+	g := newGraph(map[string][]string{
+		"a": {"b", "d"},
+		"c": {"b", "d"},
+	})
+	scores := g.Betweenness()
+	// b is on half of the a-c paths: 0.5 out of 3 pairs.
+	if !near(scores["b"], 0.5/3) || !near(scores["d"], 0.5/3) {
+		t.Errorf("betweenness = %v", scores)
+	}
+}
+
+// --- Closeness ---
+
+func TestCloseness_Star(t *testing.T) {
+	g := newGraph(map[string][]string{
+		"center": {"a", "b", "c"},
+	})
+	scores := g.Closeness()
+	if !near(scores["center"], 1) {
+		t.Errorf("center = %v, want 1", scores["center"])
+	}
+	// a reaches center in 1 and b, c in 2: 3 / 5.
+	if !near(scores["a"], 0.6) {
+		t.Errorf("a = %v, want 0.6", scores["a"])
+	}
+}
+
+func TestCloseness_PenalizesIslands(t *testing.T) {
+	// x and y are next to each other but cut off from the rest.
+	g := newGraph(map[string][]string{
+		"center": {"a", "b", "c"},
+		"x":      {"y"},
+	})
+	scores := g.Closeness()
+	if scores["x"] >= scores["center"] {
+		t.Errorf("x = %v, center = %v; want the island below", scores["x"], scores["center"])
+	}
+	if scores["x"] == 0 {
+		t.Error("x = 0, want a score for reaching y")
+	}
+}
+
+// --- Centrality and MostCentral ---
+
+func TestCentrality_UnknownMeasure(t *testing.T) {
+	g := newGraph(map[string][]string{"a": {"b"}})
+	if _, err := g.Centrality("eigenvector", nil); err == nil {
+		t.Error("unknown measure accepted")
+	}
+	scores, err := g.Centrality(MeasureDegree, nil)
+	if err != nil || scores["a"] != 1 {
+		t.Errorf("degree = %v, %v", scores, err)
+	}
+}
+
+func TestMostCentral_OrderAndJournals(t *testing.T) {
+	g := newGraph(map[string][]string{
+		"2026-10-16": {"a", "b"},
+		"a":          {"b"},
+	}, "2026-10-16")
+	scores := map[string]float64{"2026-10-16": 0.5, "a": 0.2, "b": 0.2}
+
+	top := g.MostCentral(scores, 10, false)
+	if len(top) != 2 || top[0].Name != "a" || top[1].Name != "b" {
+		t.Errorf("without journals = %+v", top)
+	}
+	top = g.MostCentral(scores, 1, true)
+	if len(top) != 1 || top[0].Name != "2026-10-16" || *top[0].Score != 0.5 {
+		t.Errorf("with journals = %+v", top)
+	}
+}
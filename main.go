@@ -4,7 +4,6 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,6 +12,8 @@ import (
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/skridlevsky/graphthulhu/backend"
 	"github.com/skridlevsky/graphthulhu/client"
+	"github.com/skridlevsky/graphthulhu/oplog"
+	"github.com/skridlevsky/graphthulhu/tools"
 	"github.com/skridlevsky/graphthulhu/vault"
 )
 
@@ -33,22 +34,28 @@ func main() {
 
 	// Route to subcommand if first arg is a known command (not a flag).
 	if len(os.Args) >= 2 && !strings.HasPrefix(os.Args[1], "-") {
-		c := client.New("", "")
 		switch os.Args[1] {
 		case "serve":
 			runServe(os.Args[2:])
 		case "journal":
-			runJournal(os.Args[2:], c)
+			runJournal(os.Args[2:])
 		case "add":
-			runAdd(os.Args[2:], c)
+			runAdd(os.Args[2:])
 		case "search":
-			runSearch(os.Args[2:], c)
+			runSearch(os.Args[2:])
+		case "tools":
+			runTools(os.Args[2:])
+		case "tool":
+			// Reaches tools whose names are taken by a shortcut, like search.
+			if len(os.Args) < 3 {
+				fmt.Fprintf(os.Stderr, "Usage: graphthulhu tool TOOL [flags]\n")
+				os.Exit(1)
+			}
+			runTool(os.Args[2], os.Args[3:])
 		case "version":
 			fmt.Println(version)
 		default:
-			fmt.Fprintf(os.Stderr, "graphthulhu: unknown command %q\n\n", os.Args[1])
-			printUsage()
-			os.Exit(1)
+			runTool(os.Args[1], os.Args[2:])
 		}
 		return
 	}
@@ -59,16 +66,105 @@ func main() {
 
 func runServe(args []string) {
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
-	readOnly := fs.Bool("read-only", false, "Disable all write operations")
-	backendType := fs.String("backend", "", "Backend type: logseq (default) or obsidian")
-	vaultPath := fs.String("vault", "", "Path to Obsidian vault (required for obsidian backend)")
-	dailyFolder := fs.String("daily-folder", "daily notes", "Daily notes subfolder name (obsidian only)")
-	includeHidden := fs.Bool("include-hidden", false, "Index directories starting with '.' (obsidian only, .git is always skipped)")
-	httpAddr := fs.String("http", "", "HTTP address to listen on (e.g. :8080). Uses streamable HTTP transport instead of stdio.")
+	bf := addBackendFlags(fs)
+	readOnly := addReadOnlyFlag(fs)
+	hf := addHTTPFlags(fs)
 	fs.Parse(args)
+	if err := hf.check(nil); err != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu: %v\n", err)
+		os.Exit(1)
+	}
+
+	vc, err := bf.vaultClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu: %v\n", err)
+		os.Exit(1)
+	}
+	var b backend.Backend
+	if vc != nil {
+		defer vc.Close()
+		b = loadVaultAsync(vc)
+	} else {
+		lsClient := client.New("", "")
+		checkGraphVersionControl(lsClient)
+		b = lsClient
+	}
+
+	ops, err := bf.operationLog()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "graphthulhu: %v\n", err)
+		os.Exit(1)
+	}
+
+	// One server per scope, shared by the clients granted it.
+	servers := make(map[scope]*mcp.Server)
+	server := func(sc scope) *mcp.Server {
+		if srv, ok := servers[sc]; ok {
+			return srv
+		}
+		subs := &tools.PageSubscriptions{}
+		srv := newServer(b, sc.readOnly, ops, subs)
+		if sc.namespace != "" {
+			restrictToNamespace(srv, b, sc.namespace)
+		}
+		watchPages(context.Background(), srv, subs, b)
+		servers[sc] = srv
+		return srv
+	}
+
+	if *hf.addr != "" {
+		// Streamable HTTP transport — serves multiple clients.
+		if err := serveHTTP(hf, *readOnly, server); err != nil {
+			fmt.Fprintf(os.Stderr, "graphthulhu: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		// Default: stdio transport for MCP client integration.
+		if err := server(scope{readOnly: *readOnly}).Run(context.Background(), &mcp.StdioTransport{}); err != nil {
+			fmt.Fprintf(os.Stderr, "graphthulhu: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// backendFlags are the backend-selection flags shared by serve and the CLI
+// subcommands. Unset flags fall back to their environment variables.
+type backendFlags struct {
+	backend       *string
+	vault         *string
+	graph         *string
+	dailyFolder   *string
+	includeHidden *bool
+	indexCache    *string
+	opLog         *string
+	gitCommit     *bool
+}
+
+// addReadOnlyFlag registers the --read-only flag of serve and the tool
+// commands on fs.
+func addReadOnlyFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("read-only", false, "Disable all write operations")
+}
+
+// addBackendFlags registers the backend-selection flags on fs.
+func addBackendFlags(fs *flag.FlagSet) *backendFlags {
+	return &backendFlags{
+		backend:       fs.String("backend", "", "Backend type: logseq (default) or obsidian"),
+		vault:         fs.String("vault", "", "Path to Obsidian vault (required for obsidian backend)"),
+		graph:         fs.String("graph", "", "Path to a Logseq graph directory (logseq backend without the Logseq app)"),
+		dailyFolder:   fs.String("daily-folder", "daily notes", "Daily notes subfolder name (obsidian only)"),
+		includeHidden: fs.Bool("include-hidden", false, "Index directories starting with '.' (obsidian only, .git is always skipped)"),
+		indexCache:    fs.String("index-cache", "", "Index cache file for vault and graph directories (default: user cache dir, \"off\" to disable)"),
+		opLog:         fs.String("op-log", "", "Operation log file that undo replays writes from (default: user cache dir, \"off\" to disable)"),
+		gitCommit:     fs.Bool("git-commit", false, "Commit every write to the git repository of the vault or graph directory, creating it if needed"),
+	}
+}
 
+// target resolves the backend type and the vault or graph directory the
+// flags select. root is "" for the Logseq app's HTTP API.
+func (f *backendFlags) target() (bt, root string, err error) {
 	// Resolve backend from flag or environment.
-	bt := *backendType
+	bt = *f.backend
 	if bt == "" {
 		bt = os.Getenv("GRAPHTHULHU_BACKEND")
 	}
@@ -76,66 +172,104 @@ func runServe(args []string) {
 		bt = "logseq"
 	}
 
-	var b backend.Backend
 	switch bt {
 	case "obsidian":
-		vp := *vaultPath
+		vp := *f.vault
 		if vp == "" {
 			vp = os.Getenv("OBSIDIAN_VAULT_PATH")
 		}
 		if vp == "" {
-			fmt.Fprintf(os.Stderr, "graphthulhu: --vault or OBSIDIAN_VAULT_PATH required for obsidian backend\n")
-			os.Exit(1)
+			return "", "", fmt.Errorf("--vault or OBSIDIAN_VAULT_PATH required for obsidian backend")
 		}
-		vc := vault.New(vp, vault.WithDailyFolder(*dailyFolder), vault.WithIncludeHidden(*includeHidden))
-		defer vc.Close()
-
-		lb := backend.NewLazyBackend(vc)
-		go func() {
-			if err := vc.Load(); err != nil {
-				fmt.Fprintf(os.Stderr, "graphthulhu: failed to load vault: %v\n", err)
-				lb.MarkFailed(err)
-				return
-			}
-			vc.BuildBacklinks()
-			if err := vc.Watch(); err != nil {
-				fmt.Fprintf(os.Stderr, "graphthulhu: failed to start watcher: %v\n", err)
-				lb.MarkFailed(err)
-				return
-			}
-			fmt.Fprintf(os.Stderr, "graphthulhu: vault indexed and ready\n")
-			lb.MarkReady()
-		}()
-
-		b = lb
+		return bt, vp, nil
 	case "logseq":
-		lsClient := client.New("", "")
-		checkGraphVersionControl(lsClient)
-		b = lsClient
-	default:
-		fmt.Fprintf(os.Stderr, "graphthulhu: unknown backend %q (use logseq or obsidian)\n", bt)
-		os.Exit(1)
+		// With a graph directory, serve the files directly instead of
+		// going through the Logseq app's HTTP API.
+		gp := *f.graph
+		if gp == "" {
+			gp = os.Getenv("LOGSEQ_GRAPH_PATH")
+		}
+		return bt, gp, nil
 	}
+	return "", "", fmt.Errorf("unknown backend %q (use logseq or obsidian)", bt)
+}
 
-	srv := newServer(b, *readOnly)
+// vaultClient returns an unloaded client for the vault or graph directory
+// the flags select, or nil when they select the Logseq app's HTTP API.
+func (f *backendFlags) vaultClient() (*vault.Client, error) {
+	bt, root, err := f.target()
+	if err != nil {
+		return nil, err
+	}
+	if root == "" {
+		if *f.gitCommit {
+			return nil, fmt.Errorf("--git-commit needs a vault or graph directory")
+		}
+		return nil, nil
+	}
+	gitCommits := vault.WithGitCommits(*f.gitCommit)
+	if bt == "obsidian" {
+		return vault.New(root, vault.WithDailyFolder(*f.dailyFolder), vault.WithIncludeHidden(*f.includeHidden), indexCacheOption(*f.indexCache, root), gitCommits), nil
+	}
+	return vault.New(root, vault.WithLogseqGraph(), indexCacheOption(*f.indexCache, root), gitCommits), nil
+}
 
-	if *httpAddr != "" {
-		// Streamable HTTP transport — serves multiple clients.
-		handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
-			return srv
-		}, nil)
-		fmt.Fprintf(os.Stderr, "graphthulhu: listening on %s\n", *httpAddr)
-		if err := http.ListenAndServe(*httpAddr, handler); err != nil {
-			fmt.Fprintf(os.Stderr, "graphthulhu: %v\n", err)
-			os.Exit(1)
+// operationLog opens the operation log the --op-log flag selects, or returns
+// nil when it is off. The default log lives in the user cache directory and
+// is kept per graph.
+func (f *backendFlags) operationLog() (*oplog.Log, error) {
+	switch *f.opLog {
+	case "off":
+		return nil, nil
+	case "":
+		_, root, err := f.target()
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		// Default: stdio transport for MCP client integration.
-		if err := srv.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
-			fmt.Fprintf(os.Stderr, "graphthulhu: %v\n", err)
-			os.Exit(1)
+		key := os.Getenv("LOGSEQ_API_URL")
+		if root != "" {
+			if key, err = filepath.Abs(root); err != nil {
+				key = root
+			}
+		} else if key == "" {
+			key = "logseq-api"
 		}
+		return oplog.Open(oplog.DefaultPath(key))
+	}
+	return oplog.Open(*f.opLog)
+}
+
+// indexCacheOption resolves the --index-cache flag for the directory at root.
+func indexCacheOption(flagValue, root string) vault.Option {
+	switch flagValue {
+	case "off":
+		return vault.WithIndexCache("")
+	case "":
+		return vault.WithIndexCache(vault.DefaultIndexCachePath(root))
 	}
+	return vault.WithIndexCache(flagValue)
+}
+
+// loadVaultAsync indexes vc in the background and returns a backend that
+// answers once indexing has finished.
+func loadVaultAsync(vc *vault.Client) backend.Backend {
+	lb := backend.NewLazyBackend(vc)
+	go func() {
+		if err := vc.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "graphthulhu: failed to load vault: %v\n", err)
+			lb.MarkFailed(err)
+			return
+		}
+		vc.BuildBacklinks()
+		if err := vc.Watch(); err != nil {
+			fmt.Fprintf(os.Stderr, "graphthulhu: failed to start watcher: %v\n", err)
+			lb.MarkFailed(err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "graphthulhu: vault indexed and ready\n")
+		lb.MarkReady()
+	}()
+	return lb
 }
 
 func printUsage() {
@@ -146,19 +280,31 @@ func printUsage() {
 	fmt.Fprintf(os.Stderr, "  graphthulhu journal [flags] TEXT Append block to today's journal\n")
 	fmt.Fprintf(os.Stderr, "  graphthulhu add -p PAGE TEXT     Append block to a page\n")
 	fmt.Fprintf(os.Stderr, "  graphthulhu search QUERY         Full-text search across the graph\n")
+	fmt.Fprintf(os.Stderr, "  graphthulhu tools                List MCP tools runnable as commands\n")
+	fmt.Fprintf(os.Stderr, "  graphthulhu TOOL [flags]         Run an MCP tool, e.g. knowledge_gaps (--json for raw output)\n")
+	fmt.Fprintf(os.Stderr, "  graphthulhu tool TOOL [flags]    Same, for tools named like a command above (search)\n")
 	fmt.Fprintf(os.Stderr, "  graphthulhu version              Print version\n")
-	fmt.Fprintf(os.Stderr, "\nServe flags:\n")
+	fmt.Fprintf(os.Stderr, "\nBackend flags (all commands):\n")
 	fmt.Fprintf(os.Stderr, "  --backend logseq|obsidian       Backend type (default: logseq)\n")
 	fmt.Fprintf(os.Stderr, "  --vault PATH                    Obsidian vault path\n")
+	fmt.Fprintf(os.Stderr, "  --graph PATH                    Logseq graph directory (serves files without the Logseq app)\n")
 	fmt.Fprintf(os.Stderr, "  --daily-folder NAME             Daily notes folder (default: daily notes)\n")
 	fmt.Fprintf(os.Stderr, "  --include-hidden                Index directories starting with '.' (obsidian only)\n")
+	fmt.Fprintf(os.Stderr, "  --index-cache PATH|off          Parsed-index cache for fast restarts (default: user cache dir)\n")
+	fmt.Fprintf(os.Stderr, "  --op-log PATH|off               Operation log for undo_last/undo_operation (default: user cache dir)\n")
+	fmt.Fprintf(os.Stderr, "  --git-commit                    Commit every write to the directory's git repository\n")
+	fmt.Fprintf(os.Stderr, "\nServe flags:\n")
 	fmt.Fprintf(os.Stderr, "  --read-only                     Disable write operations\n")
 	fmt.Fprintf(os.Stderr, "  --http ADDR                     Listen on HTTP (e.g. :8080) instead of stdio\n")
+	fmt.Fprintf(os.Stderr, "  --auth FILE                     Clients allowed over HTTP, with tokens or certificates and scopes\n")
+	fmt.Fprintf(os.Stderr, "  --tls-cert FILE --tls-key FILE  Serve HTTPS\n")
+	fmt.Fprintf(os.Stderr, "  --tls-client-ca FILE            Verify client certificates against these CAs (mutual TLS)\n")
 	fmt.Fprintf(os.Stderr, "\nAll CLI commands read from stdin when no TEXT argument is given.\n")
 	fmt.Fprintf(os.Stderr, "Environment: LOGSEQ_API_URL (default http://127.0.0.1:12315)\n")
 	fmt.Fprintf(os.Stderr, "             LOGSEQ_API_TOKEN\n")
 	fmt.Fprintf(os.Stderr, "             GRAPHTHULHU_BACKEND   Backend type\n")
 	fmt.Fprintf(os.Stderr, "             OBSIDIAN_VAULT_PATH   Obsidian vault path\n")
+	fmt.Fprintf(os.Stderr, "             LOGSEQ_GRAPH_PATH     Logseq graph directory\n")
 }
 
 // checkGraphVersionControl warns on stderr if the Logseq graph is not git-controlled.
@@ -0,0 +1,255 @@
+// Package oplog records the writes graphthulhu makes to a graph together with
+// the inverse of each, so an operation can later be undone — and the undo
+// itself undone — against any backend.
+package oplog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxOperations is how many operations the log keeps; older ones are dropped.
+const maxOperations = 500
+
+// Operation is one tool call's writes, recorded as the steps that revert them.
+type Operation struct {
+	ID      int       `json:"id"`
+	Time    time.Time `json:"time"`
+	Tool    string    `json:"tool"`
+	Summary string    `json:"summary,omitempty"`
+	// Undo reverts the operation when applied in order.
+	Undo []Step `json:"undo"`
+	// Undoes is the ID of the operation this one reverted, if it is an undo.
+	Undoes int `json:"undoes,omitempty"`
+	// UndoneBy is the ID of the operation that reverted this one, while its
+	// effect is reverted.
+	UndoneBy int `json:"undoneBy,omitempty"`
+}
+
+// Step actions.
+const (
+	ActionUpdate       = "update"        // set UUID's content to Content
+	ActionRemove       = "remove"        // remove block UUID
+	ActionRestoreBlock = "restore-block" // re-create Blocks[0] at Anchor
+	ActionMove         = "move"          // move block UUID back to Anchor
+	ActionDeletePage   = "delete-page"   // delete page Page
+	ActionRestorePage  = "restore-page"  // re-create page Page
+	ActionRenamePage   = "rename-page"   // rename page Page to NewName
+	ActionRestoreFile  = "restore-file"  // write Content back to page Page's File
+)
+
+// Step is a single write that reverts part of an operation.
+type Step struct {
+	Action  string `json:"action"`
+	UUID    string `json:"uuid,omitempty"`
+	Content string `json:"content,omitempty"`
+	Page    string `json:"page,omitempty"`
+	NewName string `json:"newName,omitempty"`
+	// Anchor is where a restored or moved block goes.
+	Anchor *Anchor `json:"anchor,omitempty"`
+	// Blocks is the block tree a restore re-creates.
+	Blocks []Block `json:"blocks,omitempty"`
+	// Properties are a restored page's properties.
+	Properties map[string]any `json:"properties,omitempty"`
+	// File is the page file, relative to the graph root, a restore writes
+	// Content to on backends that keep pages in files.
+	File string `json:"file,omitempty"`
+	// Hash is the SHA-256 of File as the operation left it. A file that
+	// changed since is not restored, so later edits are never overwritten.
+	Hash string `json:"hash,omitempty"`
+}
+
+// Anchor locates a block within its page by the blocks before it, which
+// keep their identity when the block is removed or moved away.
+type Anchor struct {
+	Page   string `json:"page"`
+	Parent string `json:"parent,omitempty"` // parent block; empty at the top level
+	Left   string `json:"left,omitempty"`   // preceding sibling; empty for a first child
+}
+
+// Block is a block and its descendants as they were before a write.
+type Block struct {
+	UUID     string  `json:"uuid"`
+	Content  string  `json:"content"`
+	PreBlock bool    `json:"preBlock,omitempty"`
+	Children []Block `json:"children,omitempty"`
+}
+
+// Log is an append-only list of operations, persisted to a JSON file after
+// every change. A nil *Log records nothing; writes made through its
+// recorders go straight to the backend.
+type Log struct {
+	path string
+
+	undoMu sync.Mutex // serializes undos, so an operation is undone once
+
+	mu   sync.Mutex
+	ops  []Operation
+	next int
+}
+
+// logFile is the on-disk form of a Log.
+type logFile struct {
+	Next       int         `json:"next"`
+	Operations []Operation `json:"operations"`
+}
+
+// Open loads the log persisted at path, or starts an empty one when the file
+// does not exist. With an empty path the log is kept in memory only.
+func Open(path string) (*Log, error) {
+	l := &Log{path: path, next: 1}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read operation log: %w", err)
+	}
+	var f logFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse operation log %s: %w", path, err)
+	}
+	l.ops = f.Operations
+	l.next = max(f.Next, 1)
+	return l, nil
+}
+
+// DefaultPath returns the operation log file for the graph identified by key
+// (a vault's absolute path or an API URL) in the user cache directory.
+// Returns "" when the platform has no user cache directory.
+func DefaultPath(key string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, "graphthulhu", hex.EncodeToString(sum[:8])+".oplog")
+}
+
+// List returns up to limit operations, newest first. limit <= 0 means all.
+func (l *Log) List(limit int) []Operation {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ops := slices.Clone(l.ops)
+	slices.Reverse(ops)
+	if limit > 0 && len(ops) > limit {
+		ops = ops[:limit]
+	}
+	return ops
+}
+
+// Get returns the operation with the given ID.
+func (l *Log) Get(id int) (Operation, bool) {
+	if l == nil {
+		return Operation{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if i := l.indexLocked(id); i >= 0 {
+		return l.ops[i], true
+	}
+	return Operation{}, false
+}
+
+// LastUndoable returns the newest operation that is in effect: not undone
+// and not itself an undo.
+func (l *Log) LastUndoable() (Operation, bool) {
+	return l.last(func(op Operation) bool { return op.Undoes == 0 && op.UndoneBy == 0 })
+}
+
+// LastRedoable returns the newest undo whose original is still undone by it.
+// Undoing it redoes the original.
+func (l *Log) LastRedoable() (Operation, bool) {
+	return l.last(func(op Operation) bool {
+		if op.Undoes == 0 || op.UndoneBy != 0 {
+			return false
+		}
+		orig := l.indexLocked(op.Undoes)
+		return orig >= 0 && l.ops[orig].Undoes == 0 && l.ops[orig].UndoneBy == op.ID
+	})
+}
+
+// last returns the newest operation for which match reports true. match is
+// called with l.mu held.
+func (l *Log) last(match func(Operation) bool) (Operation, bool) {
+	if l == nil {
+		return Operation{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := len(l.ops) - 1; i >= 0; i-- {
+		if match(l.ops[i]) {
+			return l.ops[i], true
+		}
+	}
+	return Operation{}, false
+}
+
+// append assigns op an ID, adds it to the log and saves the log.
+func (l *Log) append(op Operation) (Operation, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	op.ID = l.next
+	l.next++
+	if op.Undoes != 0 {
+		if i := l.indexLocked(op.Undoes); i >= 0 {
+			// Undoing an undo brings the original operation's effect back.
+			if orig := l.indexLocked(l.ops[i].Undoes); orig >= 0 && l.ops[orig].UndoneBy == op.Undoes {
+				l.ops[orig].UndoneBy = 0
+			}
+			l.ops[i].UndoneBy = op.ID
+		}
+	}
+	l.ops = append(l.ops, op)
+	if len(l.ops) > maxOperations {
+		l.ops = slices.Delete(l.ops, 0, len(l.ops)-maxOperations)
+	}
+	return op, l.saveLocked()
+}
+
+// indexLocked returns the position of operation id in l.ops, or -1.
+// Caller must hold l.mu.
+func (l *Log) indexLocked(id int) int {
+	i, found := slices.BinarySearchFunc(l.ops, id, func(op Operation, id int) int { return op.ID - id })
+	if !found {
+		return -1
+	}
+	return i
+}
+
+// saveLocked writes the log to its file via a temp file rename.
+// Caller must hold l.mu.
+func (l *Log) saveLocked() error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(logFile{Next: l.next, Operations: l.ops})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("create operation log directory: %w", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write operation log: %w", err)
+	}
+	return os.Rename(tmp, l.path)
+}
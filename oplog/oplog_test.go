@@ -0,0 +1,433 @@
+package oplog_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/oplog"
+	"github.com/skridlevsky/graphthulhu/types"
+	"github.com/skridlevsky/graphthulhu/vault"
+	"github.com/skridlevsky/graphthulhu/vault/vaulttest"
+)
+
+// testVault indexes a vault made of files, keyed by path.
+func testVault(t *testing.T, files map[string]string) (*vault.Client, string) {
+	t.Helper()
+	return vaulttest.New(t, files)
+}
+
+// outline renders a page's block tree as indented content, one block per line.
+func outline(t *testing.T, vc *vault.Client, page string) string {
+	t.Helper()
+	blocks, err := vc.GetPageBlocksTree(context.Background(), page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	var walk func([]types.BlockEntity, string)
+	walk = func(blocks []types.BlockEntity, indent string) {
+		for _, blk := range blocks {
+			b.WriteString(indent + blk.Content + "\n")
+			walk(blk.Children, indent+"  ")
+		}
+	}
+	walk(blocks, "")
+	return b.String()
+}
+
+// blockUUID returns the UUID of the block on page with the given content.
+func blockUUID(t *testing.T, vc *vault.Client, page, content string) string {
+	t.Helper()
+	blocks, err := vc.GetPageBlocksTree(context.Background(), page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var find func([]types.BlockEntity) string
+	find = func(blocks []types.BlockEntity) string {
+		for _, b := range blocks {
+			if b.Content == content {
+				return b.UUID
+			}
+			if id := find(b.Children); id != "" {
+				return id
+			}
+		}
+		return ""
+	}
+	id := find(blocks)
+	if id == "" {
+		t.Fatalf("no block %q on %s", content, page)
+	}
+	return id
+}
+
+// undoRedo undoes op and checks page reads before afterwards, then redoes it
+// and checks page reads after.
+func undoRedo(t *testing.T, l *oplog.Log, vc *vault.Client, op oplog.Operation, page, before, after string) {
+	t.Helper()
+	ctx := context.Background()
+	undo, err := l.Undo(ctx, vc, op.ID, "test")
+	if err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if got := outline(t, vc, page); got != before {
+		t.Errorf("after undo:\n%s\nwant:\n%s", got, before)
+	}
+	if _, err := l.Undo(ctx, vc, undo.ID, "test"); err != nil {
+		t.Fatalf("redo: %v", err)
+	}
+	if got := outline(t, vc, page); got != after {
+		t.Errorf("after redo:\n%s\nwant:\n%s", got, after)
+	}
+}
+
+const outlinePage = "- a\n  - a1\n  - a2\n- b\n  - b1\n- c\n"
+
+func TestUndoUpdateBlock(t *testing.T) {
+	vc, _ := testVault(t, map[string]string{"P.md": outlinePage})
+	l, _ := oplog.Open("")
+	ctx := context.Background()
+	before := outline(t, vc, "P")
+
+	r := l.Begin(vc, "update_block", "")
+	if err := r.UpdateBlock(ctx, blockUUID(t, vc, "P", "a2"), "a2 edited"); err != nil {
+		t.Fatal(err)
+	}
+	op, _ := r.Commit()
+
+	undoRedo(t, l, vc, op, "P", before, strings.Replace(before, "a2", "a2 edited", 1))
+}
+
+func TestUndoRemoveBlock(t *testing.T) {
+	for _, target := range []string{"a", "a1", "a2", "b", "c"} {
+		t.Run(target, func(t *testing.T) {
+			vc, _ := testVault(t, map[string]string{"P.md": outlinePage})
+			l, _ := oplog.Open("")
+			ctx := context.Background()
+			before := outline(t, vc, "P")
+			id := blockUUID(t, vc, "P", target)
+
+			r := l.Begin(vc, "delete_block", "")
+			if err := r.RemoveBlock(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+			op, _ := r.Commit()
+			after := outline(t, vc, "P")
+
+			undoRedo(t, l, vc, op, "P", before, after)
+			if _, err := l.Undo(ctx, vc, op.ID, "test"); err != nil {
+				t.Fatal(err)
+			}
+			if got := blockUUID(t, vc, "P", target); got != id {
+				t.Errorf("restored block has UUID %s, want %s", got, id)
+			}
+		})
+	}
+}
+
+func TestUndoMoveBlock(t *testing.T) {
+	tests := []struct {
+		block, target string
+		opts          map[string]any
+	}{
+		{"a1", "c", map[string]any{}},
+		{"a2", "b1", map[string]any{"before": true}},
+		{"a", "c", map[string]any{"children": true}},
+		{"b1", "a", map[string]any{"before": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.block, func(t *testing.T) {
+			vc, _ := testVault(t, map[string]string{"P.md": outlinePage})
+			l, _ := oplog.Open("")
+			ctx := context.Background()
+			before := outline(t, vc, "P")
+
+			r := l.Begin(vc, "move_block", "")
+			if err := r.MoveBlock(ctx, blockUUID(t, vc, "P", tt.block), blockUUID(t, vc, "P", tt.target), tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			op, _ := r.Commit()
+			after := outline(t, vc, "P")
+			if after == before {
+				t.Fatal("move changed nothing")
+			}
+
+			undoRedo(t, l, vc, op, "P", before, after)
+		})
+	}
+}
+
+func TestUndoCreatePage(t *testing.T) {
+	vc, _ := testVault(t, map[string]string{"P.md": outlinePage})
+	l, _ := oplog.Open("")
+	ctx := context.Background()
+
+	r := l.Begin(vc, "create_page", "New")
+	if _, err := r.CreatePage(ctx, "New", map[string]any{"type": "note"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"one", "two"} {
+		if _, err := r.AppendBlockInPage(ctx, "New", content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	op, _ := r.Commit()
+	created := outline(t, vc, "New")
+
+	undo, err := l.Undo(ctx, vc, op.ID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := vc.GetPage(ctx, "New"); page != nil {
+		t.Fatal("page still exists after undo")
+	}
+	if _, err := l.Undo(ctx, vc, undo.ID, "test"); err != nil {
+		t.Fatal(err)
+	}
+	page, _ := vc.GetPage(ctx, "New")
+	if page == nil || page.Properties["type"] != "note" {
+		t.Fatalf("page after redo = %+v", page)
+	}
+	if got := outline(t, vc, "New"); got != created {
+		t.Errorf("blocks after redo = %q, want %q", got, created)
+	}
+}
+
+func TestUndoAppendCreatingPage(t *testing.T) {
+	vc, _ := testVault(t, nil)
+	l, _ := oplog.Open("")
+	ctx := context.Background()
+
+	r := l.Begin(vc, "append_blocks", "Inbox")
+	if _, err := r.AppendBlockInPage(ctx, "Inbox", "idea"); err != nil {
+		t.Fatal(err)
+	}
+	op, _ := r.Commit()
+	if len(op.Undo) != 1 || op.Undo[0].Action != oplog.ActionDeletePage {
+		t.Fatalf("undo = %+v, want the page deleted", op.Undo)
+	}
+}
+
+func TestUndoDeletePage(t *testing.T) {
+	content := "---\ntags: [x]\n---\n# Title <!-- keep -->\n\n- a\n  - a1\n"
+	vc, dir := testVault(t, map[string]string{
+		"notes/Gone.md": content,
+		"Other.md":      "See [[Gone]].\n",
+	})
+	l, _ := oplog.Open("")
+	ctx := context.Background()
+
+	r := l.Begin(vc, "delete_page", "Gone")
+	if err := r.DeletePage(ctx, "notes/Gone"); err != nil {
+		t.Fatal(err)
+	}
+	op, _ := r.Commit()
+
+	if _, err := l.Undo(ctx, vc, op.ID, "test"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "notes", "Gone.md"))
+	if err != nil || string(data) != content {
+		t.Fatalf("restored file = %q, %v", data, err)
+	}
+	if page, _ := vc.GetPage(ctx, "notes/Gone"); page == nil {
+		t.Error("restored page not indexed")
+	}
+}
+
+func TestUndoRenamePage(t *testing.T) {
+	vc, _ := testVault(t, map[string]string{
+		"Old.md":   "- a\n",
+		"Other.md": "See [[Old]].\n",
+	})
+	l, _ := oplog.Open("")
+	ctx := context.Background()
+
+	r := l.Begin(vc, "rename_page", "")
+	if err := r.RenamePage(ctx, "Old", "New"); err != nil {
+		t.Fatal(err)
+	}
+	op, _ := r.Commit()
+
+	if _, err := l.Undo(ctx, vc, op.ID, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := vc.GetPage(ctx, "Old"); page == nil {
+		t.Fatal("page not renamed back")
+	}
+	if got := outline(t, vc, "Other"); got != "See [[Old]].\n" {
+		t.Errorf("link after undo = %q", got)
+	}
+}
+
+func TestUndoBookkeeping(t *testing.T) {
+	vc, _ := testVault(t, map[string]string{"P.md": outlinePage})
+	path := filepath.Join(t.TempDir(), "ops.json")
+	l, err := oplog.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	update := func(block, content string) oplog.Operation {
+		r := l.Begin(vc, "update_block", content)
+		if err := r.UpdateBlock(ctx, blockUUID(t, vc, "P", block), content); err != nil {
+			t.Fatal(err)
+		}
+		op, ok := r.Commit()
+		if !ok {
+			t.Fatal("operation not logged")
+		}
+		return op
+	}
+	first := update("a1", "a1 edited")
+	second := update("c", "c edited")
+
+	if op, _ := l.LastUndoable(); op.ID != second.ID {
+		t.Errorf("LastUndoable = %d, want %d", op.ID, second.ID)
+	}
+	undo, err := l.Undo(ctx, vc, second.ID, "undo_last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Undo(ctx, vc, second.ID, "undo_last"); err == nil {
+		t.Error("operation undone twice")
+	}
+	if op, _ := l.LastUndoable(); op.ID != first.ID {
+		t.Errorf("LastUndoable after undo = %d, want %d", op.ID, first.ID)
+	}
+	if op, _ := l.LastRedoable(); op.ID != undo.ID {
+		t.Errorf("LastRedoable = %d, want %d", op.ID, undo.ID)
+	}
+
+	// The log survives a restart.
+	reopened, err := oplog.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids(reopened.List(0)), []int{undo.ID, second.ID, first.ID}) {
+		t.Errorf("reopened log = %v", ids(reopened.List(0)))
+	}
+	redo, err := reopened.Undo(ctx, vc, undo.ID, "redo_last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op, _ := reopened.Get(second.ID); op.UndoneBy != 0 {
+		t.Errorf("redone operation still marked undone by %d", op.UndoneBy)
+	}
+	if _, ok := reopened.LastRedoable(); ok {
+		t.Error("redo left something to redo")
+	}
+	if op, _ := reopened.LastUndoable(); op.ID != second.ID {
+		t.Errorf("LastUndoable after redo = %d, want %d (redo is %d)", op.ID, second.ID, redo.ID)
+	}
+}
+
+func TestUndoRefusesChangedFile(t *testing.T) {
+	vc, _ := testVault(t, map[string]string{"P.md": outlinePage})
+	l, _ := oplog.Open("")
+	ctx := context.Background()
+
+	r := l.Begin(vc, "update_block", "")
+	if err := r.UpdateBlock(ctx, blockUUID(t, vc, "P", "b1"), "b1 edited"); err != nil {
+		t.Fatal(err)
+	}
+	op, _ := r.Commit()
+
+	// An edit made outside the log must not be overwritten by the undo.
+	if err := vc.UpdateBlock(ctx, blockUUID(t, vc, "P", "c"), "c edited"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Undo(ctx, vc, op.ID, "test"); err == nil || !strings.Contains(err.Error(), "changed since") {
+		t.Fatalf("undo over a changed file: %v", err)
+	}
+	blockUUID(t, vc, "P", "c edited")
+	if op, _ := l.Get(op.ID); op.UndoneBy != 0 {
+		t.Error("failed undo marked the operation undone")
+	}
+}
+
+// blockBackend hides the vault's page files, so the recorder falls back to
+// the block-level inverses it uses for the Logseq API.
+type blockBackend struct{ backend.Backend }
+
+// pinnedPage is outlinePage with every block's UUID pinned, as Logseq keeps
+// them stable across edits.
+const pinnedPage = "- a <!-- id: 00000000-0000-4000-8000-00000000000a -->\n" +
+	"  - a1 <!-- id: 00000000-0000-4000-8000-0000000000a1 -->\n" +
+	"  - a2 <!-- id: 00000000-0000-4000-8000-0000000000a2 -->\n" +
+	"- b <!-- id: 00000000-0000-4000-8000-00000000000b -->\n" +
+	"  - b1 <!-- id: 00000000-0000-4000-8000-0000000000b1 -->\n" +
+	"- c <!-- id: 00000000-0000-4000-8000-00000000000c -->\n"
+
+func TestUndoBlockLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(context.Context, *oplog.Recorder, *vault.Client) error
+	}{
+		{"remove", func(ctx context.Context, r *oplog.Recorder, vc *vault.Client) error {
+			return r.RemoveBlock(ctx, blockUUID(t, vc, "P", "a"))
+		}},
+		{"update", func(ctx context.Context, r *oplog.Recorder, vc *vault.Client) error {
+			return r.UpdateBlock(ctx, blockUUID(t, vc, "P", "b1"), "b1 edited")
+		}},
+		{"move", func(ctx context.Context, r *oplog.Recorder, vc *vault.Client) error {
+			return r.MoveBlock(ctx, blockUUID(t, vc, "P", "a2"), blockUUID(t, vc, "P", "c"), map[string]any{})
+		}},
+		{"move first child", func(ctx context.Context, r *oplog.Recorder, vc *vault.Client) error {
+			return r.MoveBlock(ctx, blockUUID(t, vc, "P", "a1"), blockUUID(t, vc, "P", "b1"), map[string]any{})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vc, _ := testVault(t, map[string]string{"P.md": pinnedPage})
+			l, _ := oplog.Open("")
+			ctx := context.Background()
+			before := outline(t, vc, "P")
+
+			r := l.Begin(blockBackend{vc}, "test", "")
+			if err := tt.write(ctx, r, vc); err != nil {
+				t.Fatal(err)
+			}
+			op, _ := r.Commit()
+			for _, step := range op.Undo {
+				if step.Action == oplog.ActionRestoreFile {
+					t.Fatal("recorded a file restore without page files")
+				}
+			}
+
+			if _, err := l.Undo(ctx, blockBackend{vc}, op.ID, "test"); err != nil {
+				t.Fatal(err)
+			}
+			if got := outline(t, vc, "P"); got != before {
+				t.Errorf("after undo:\n%s\nwant:\n%s", got, before)
+			}
+		})
+	}
+}
+
+func ids(ops []oplog.Operation) []int {
+	out := make([]int, len(ops))
+	for i, op := range ops {
+		out[i] = op.ID
+	}
+	return out
+}
+
+func TestNilLogPassesThrough(t *testing.T) {
+	vc, _ := testVault(t, map[string]string{"P.md": outlinePage})
+	var l *oplog.Log
+	r := l.Begin(vc, "update_block", "")
+	if err := r.UpdateBlock(context.Background(), blockUUID(t, vc, "P", "c"), "c edited"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Commit(); ok {
+		t.Error("nil log recorded an operation")
+	}
+	blockUUID(t, vc, "P", "c edited")
+}
@@ -0,0 +1,458 @@
+package oplog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// Recorder makes one operation's writes and records how to revert each. It
+// mirrors the write methods of backend.Backend; Commit adds the operation to
+// the log. On backends that keep pages in files, a block write is reverted by
+// restoring the files it changed; elsewhere by the inverse block write.
+// Recording is best effort: a write whose prior state cannot be read still
+// happens, it just cannot be undone.
+type Recorder struct {
+	log     *Log
+	b       backend.Backend
+	files   backend.PageFileStore // nil unless b keeps pages in files
+	tool    string
+	summary string
+	undoes  int
+	undo    []Step
+	// byFile is set while a write is recorded by the files it changes
+	// rather than by its own steps.
+	byFile bool
+	// depth counts the nested units of work still open; see Nest.
+	depth int
+}
+
+// Begin starts recording an operation made by tool against b.
+func (l *Log) Begin(b backend.Backend, tool, summary string) *Recorder {
+	r := &Recorder{log: l, b: b, tool: tool, summary: summary}
+	r.files, _ = b.(backend.PageFileStore)
+	return r
+}
+
+// Commit adds the recorded operation to the log and returns it. Operations
+// that wrote nothing are not logged. On backends that commit writes to
+// version control, the operation's writes are committed too. Failing to save
+// the log or to commit is reported but does not fail the operation, whose
+// writes have already happened.
+func (r *Recorder) Commit() (Operation, bool) {
+	if r.depth > 0 {
+		r.depth--
+		return Operation{}, false
+	}
+	if vc, ok := r.b.(backend.ChangeCommitter); ok {
+		if err := vc.CommitChanges(context.Background(), r.commitMessage()); err != nil {
+			log.Printf("graphthulhu: failed to commit %s: %v", r.tool, err)
+		}
+	}
+	if r.log == nil || len(r.undo) == 0 {
+		return Operation{}, false
+	}
+	op, err := r.log.append(Operation{
+		Time:    time.Now().UTC(),
+		Tool:    r.tool,
+		Summary: r.summary,
+		Undo:    r.undo,
+		Undoes:  r.undoes,
+	})
+	if err != nil {
+		log.Printf("graphthulhu: failed to save operation log: %v", err)
+	}
+	r.undo = nil
+	return op, true
+}
+
+// commitMessage is the version control message for the operation's writes.
+func (r *Recorder) commitMessage() string {
+	if r.summary == "" {
+		return "graphthulhu: " + r.tool
+	}
+	return "graphthulhu: " + r.tool + " " + r.summary
+}
+
+// Nest opens a unit of work, such as one tool call in a batch, whose writes
+// join r's operation. It returns r; the unit's Commit only closes it, and the
+// outermost Commit logs the whole operation.
+func (r *Recorder) Nest() *Recorder {
+	r.depth++
+	return r
+}
+
+// record adds steps that revert the latest write. Later writes are reverted
+// first, so they go in front.
+func (r *Recorder) record(steps ...Step) {
+	if r.recording() {
+		r.undo = append(slices.Clone(steps), r.undo...)
+	}
+}
+
+// recording reports whether writes need their prior state captured.
+func (r *Recorder) recording() bool {
+	return r.log != nil && !r.byFile
+}
+
+// --- Write operations ---
+
+// CreatePage creates a page; undo deletes it.
+func (r *Recorder) CreatePage(ctx context.Context, name string, properties map[string]any, opts map[string]any) (*types.PageEntity, error) {
+	page, err := r.b.CreatePage(ctx, name, properties, opts)
+	if err == nil {
+		r.record(Step{Action: ActionDeletePage, Page: name})
+	}
+	return page, err
+}
+
+// AppendBlockInPage appends a block; undo removes it, or deletes the page
+// when the append created it.
+func (r *Recorder) AppendBlockInPage(ctx context.Context, page, content string) (*types.BlockEntity, error) {
+	return r.addBlock(ctx, page, func() (*types.BlockEntity, error) {
+		return r.b.AppendBlockInPage(ctx, page, content)
+	})
+}
+
+// PrependBlockInPage prepends a block; undo as for AppendBlockInPage.
+func (r *Recorder) PrependBlockInPage(ctx context.Context, page, content string) (*types.BlockEntity, error) {
+	return r.addBlock(ctx, page, func() (*types.BlockEntity, error) {
+		return r.b.PrependBlockInPage(ctx, page, content)
+	})
+}
+
+// InsertBlock inserts a block; undo removes it.
+func (r *Recorder) InsertBlock(ctx context.Context, srcBlock any, content string, opts map[string]any) (*types.BlockEntity, error) {
+	var block *types.BlockEntity
+	err := r.blockWrite(ctx, func() (err error) {
+		block, err = r.b.InsertBlock(ctx, srcBlock, content, opts)
+		if err == nil && block != nil {
+			r.record(Step{Action: ActionRemove, UUID: block.UUID})
+		}
+		return err
+	}, fmt.Sprint(srcBlock))
+	return block, err
+}
+
+// UpdateBlock replaces a block's content; undo puts the old content back.
+func (r *Recorder) UpdateBlock(ctx context.Context, uuid, content string, opts ...map[string]any) error {
+	return r.blockWrite(ctx, func() error {
+		var prior *types.BlockEntity
+		if r.recording() {
+			prior, _ = r.b.GetBlock(ctx, uuid)
+		}
+		if err := r.b.UpdateBlock(ctx, uuid, content, opts...); err != nil {
+			return err
+		}
+		if prior != nil {
+			r.record(Step{Action: ActionUpdate, UUID: uuid, Content: prior.Content})
+		}
+		return nil
+	}, uuid)
+}
+
+// RemoveBlock removes a block and its children; undo re-creates them where
+// they were, with their UUIDs.
+func (r *Recorder) RemoveBlock(ctx context.Context, uuid string) error {
+	return r.blockWrite(ctx, func() error {
+		var tree *Block
+		var anchor *Anchor
+		if r.recording() {
+			tree, anchor, _ = r.locate(ctx, uuid)
+		}
+		if err := r.b.RemoveBlock(ctx, uuid); err != nil {
+			return err
+		}
+		if tree != nil {
+			r.record(Step{Action: ActionRestoreBlock, Anchor: anchor, Blocks: []Block{*tree}})
+		}
+		return nil
+	}, uuid)
+}
+
+// MoveBlock moves a block; undo moves it back.
+func (r *Recorder) MoveBlock(ctx context.Context, uuid, targetUUID string, opts map[string]any) error {
+	return r.blockWrite(ctx, func() error {
+		var anchor *Anchor
+		if r.recording() {
+			_, anchor, _ = r.locate(ctx, uuid)
+		}
+		if err := r.b.MoveBlock(ctx, uuid, targetUUID, opts); err != nil {
+			return err
+		}
+		if anchor != nil {
+			r.record(Step{Action: ActionMove, UUID: uuid, Anchor: anchor})
+		}
+		return nil
+	}, uuid, targetUUID)
+}
+
+// DeletePage deletes a page; undo restores its file, or re-creates its
+// properties and blocks on backends without page files.
+func (r *Recorder) DeletePage(ctx context.Context, name string) error {
+	var snapshot *Step
+	if r.recording() {
+		snapshot, _ = r.snapshotPage(ctx, name)
+	}
+	if err := r.b.DeletePage(ctx, name); err != nil {
+		return err
+	}
+	if snapshot != nil {
+		r.record(*snapshot)
+	}
+	return nil
+}
+
+// RenamePage renames a page; undo renames it back.
+func (r *Recorder) RenamePage(ctx context.Context, oldName, newName string) error {
+	if err := r.b.RenamePage(ctx, oldName, newName); err != nil {
+		return err
+	}
+	r.record(Step{Action: ActionRenamePage, Page: newName, NewName: oldName})
+	return nil
+}
+
+// WritePageFile replaces the file of a page, or writes a deleted page's file
+// back; undo restores the file as it was, or deletes the page again.
+func (r *Recorder) WritePageFile(ctx context.Context, page, path, content string) error {
+	if r.files == nil {
+		return fmt.Errorf("backend does not keep pages in files")
+	}
+	if !r.pageExists(ctx, page) {
+		if err := r.files.WritePageFile(ctx, path, content); err != nil {
+			return err
+		}
+		r.record(Step{Action: ActionDeletePage, Page: page})
+		return nil
+	}
+	return r.fileWrite(ctx, func() error {
+		return r.files.WritePageFile(ctx, path, content)
+	}, page)
+}
+
+// --- Capturing prior state ---
+
+// addBlock runs add, which adds a block to page, and records its inverse.
+func (r *Recorder) addBlock(ctx context.Context, page string, add func() (*types.BlockEntity, error)) (*types.BlockEntity, error) {
+	if !r.recording() {
+		return add()
+	}
+	if !r.pageExists(ctx, page) {
+		block, err := add()
+		if err == nil {
+			r.record(Step{Action: ActionDeletePage, Page: page})
+		}
+		return block, err
+	}
+	var block *types.BlockEntity
+	err := r.fileWrite(ctx, func() (err error) {
+		block, err = add()
+		if err == nil && block != nil {
+			r.record(Step{Action: ActionRemove, UUID: block.UUID})
+		}
+		return err
+	}, page)
+	return block, err
+}
+
+// blockWrite runs write, which changes the blocks with the given UUIDs. On
+// backends that keep pages in files the steps write records are replaced by
+// restoring the files of the blocks' pages.
+func (r *Recorder) blockWrite(ctx context.Context, write func() error, uuids ...string) error {
+	if r.files == nil || !r.recording() {
+		return write()
+	}
+	var pages []string
+	for _, uuid := range uuids {
+		if block, err := r.b.GetBlock(ctx, uuid); err == nil && block != nil {
+			if page, err := r.pageName(ctx, block.Page); err == nil {
+				pages = append(pages, page)
+			}
+		}
+	}
+	return r.fileWrite(ctx, write, pages...)
+}
+
+// fileWrite runs write and records restoring the files of pages as they
+// were before it, instead of the steps write would record. Files are
+// recorded even when write fails, since it may have changed some of them.
+func (r *Recorder) fileWrite(ctx context.Context, write func() error, pages ...string) error {
+	if r.files == nil || !r.recording() {
+		return write()
+	}
+
+	type pageFile struct{ page, path, content string }
+	var before []pageFile
+	for _, page := range pages {
+		path, content, err := r.files.PageFile(ctx, page)
+		if err != nil || slices.ContainsFunc(before, func(f pageFile) bool { return f.path == path }) {
+			continue
+		}
+		before = append(before, pageFile{page, path, content})
+	}
+
+	r.byFile = true
+	err := write()
+	r.byFile = false
+
+	for _, f := range before {
+		path, now, rerr := r.files.PageFile(ctx, f.page)
+		if rerr != nil || path != f.path || now == f.content {
+			continue
+		}
+		// Successive writes to one file are reverted together.
+		if len(r.undo) > 0 && r.undo[0].Action == ActionRestoreFile && r.undo[0].File == f.path {
+			r.undo[0].Hash = hashContent(now)
+			continue
+		}
+		r.record(Step{Action: ActionRestoreFile, Page: f.page, File: f.path, Content: f.content, Hash: hashContent(now)})
+	}
+	return err
+}
+
+// hashContent returns the hex SHA-256 of a page file's content.
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// pageExists reports whether the page is in the graph.
+func (r *Recorder) pageExists(ctx context.Context, name string) bool {
+	page, err := r.b.GetPage(ctx, name)
+	return err == nil && page != nil
+}
+
+// blockExists reports whether a block with the UUID is in the graph.
+func (r *Recorder) blockExists(ctx context.Context, uuid string) bool {
+	block, err := r.b.GetBlock(ctx, uuid)
+	return err == nil && block != nil
+}
+
+// locate returns the block tree rooted at uuid and where it sits.
+func (r *Recorder) locate(ctx context.Context, uuid string) (*Block, *Anchor, error) {
+	block, err := r.b.GetBlock(ctx, uuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	if block == nil {
+		return nil, nil, fmt.Errorf("block not found: %s", uuid)
+	}
+	page, err := r.pageName(ctx, block.Page)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocks, err := r.b.GetPageBlocksTree(ctx, page)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, anchor := findBlock(blocks, uuid, Anchor{Page: page})
+	if tree == nil {
+		return nil, nil, fmt.Errorf("block %s not found on page %s", uuid, page)
+	}
+	return tree, anchor, nil
+}
+
+// pageName returns the name of the page ref points to. Logseq refers to a
+// block's page by ID only.
+func (r *Recorder) pageName(ctx context.Context, ref *types.PageRef) (string, error) {
+	if ref == nil {
+		return "", fmt.Errorf("block has no page")
+	}
+	if ref.Name != "" {
+		return ref.Name, nil
+	}
+	page, err := r.b.GetPage(ctx, ref.ID)
+	if err != nil {
+		return "", err
+	}
+	if page == nil {
+		return "", fmt.Errorf("page %d not found", ref.ID)
+	}
+	return pageTitle(page), nil
+}
+
+// snapshotPage captures what restoring a page needs.
+func (r *Recorder) snapshotPage(ctx context.Context, name string) (*Step, error) {
+	if fs, ok := r.b.(backend.PageFileStore); ok {
+		path, content, err := fs.PageFile(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return &Step{Action: ActionRestorePage, Page: name, File: path, Content: content}, nil
+	}
+
+	page, err := r.b.GetPage(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		return nil, fmt.Errorf("page not found: %s", name)
+	}
+	blocks, err := r.b.GetPageBlocksTree(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Step{
+		Action:     ActionRestorePage,
+		Page:       pageTitle(page),
+		Properties: page.Properties,
+		Blocks:     snapshotBlocks(blocks),
+	}, nil
+}
+
+// findBlock searches blocks for uuid, returning its tree and anchor.
+func findBlock(blocks []types.BlockEntity, uuid string, at Anchor) (*Block, *Anchor) {
+	for i, b := range blocks {
+		if b.UUID == uuid {
+			tree := snapshotBlock(b)
+			if i > 0 {
+				at.Left = blocks[i-1].UUID
+			}
+			return &tree, &at
+		}
+		if tree, anchor := findBlock(b.Children, uuid, Anchor{Page: at.Page, Parent: b.UUID}); tree != nil {
+			return tree, anchor
+		}
+	}
+	return nil, nil
+}
+
+func snapshotBlock(b types.BlockEntity) Block {
+	return Block{UUID: b.UUID, Content: b.Content, PreBlock: b.PreBlock, Children: snapshotBlocks(b.Children)}
+}
+
+func snapshotBlocks(blocks []types.BlockEntity) []Block {
+	if len(blocks) == 0 {
+		return nil
+	}
+	out := make([]Block, len(blocks))
+	for i, b := range blocks {
+		out[i] = snapshotBlock(b)
+	}
+	return out
+}
+
+// pageTitle returns a page's name as written.
+func pageTitle(page *types.PageEntity) string {
+	if page.OriginalName != "" {
+		return page.OriginalName
+	}
+	return page.Name
+}
+
+// pinUUID adds an id:: property to content unless it already carries one,
+// so a block appended to a page keeps its UUID.
+func pinUUID(content, uuid string) string {
+	if strings.Contains(content, "id:: "+uuid) {
+		return content
+	}
+	return content + "\nid:: " + uuid
+}
@@ -0,0 +1,295 @@
+package oplog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// Undo reverts operation id by applying its undo steps to b. The undo is
+// itself logged as a new operation, so undoing that redoes the original.
+// An undo that fails part way is logged too — as an ordinary operation the
+// caller can revert — and the original stays marked as not undone.
+func (l *Log) Undo(ctx context.Context, b backend.Backend, id int, tool string) (Operation, error) {
+	if l == nil {
+		return Operation{}, fmt.Errorf("operation log is disabled")
+	}
+	l.undoMu.Lock()
+	defer l.undoMu.Unlock()
+
+	op, ok := l.Get(id)
+	if !ok {
+		return Operation{}, fmt.Errorf("operation %d not found (the log keeps the last %d)", id, maxOperations)
+	}
+	if op.UndoneBy != 0 {
+		return Operation{}, fmt.Errorf("operation %d was already undone by operation %d", id, op.UndoneBy)
+	}
+
+	r := l.Begin(b, tool, fmt.Sprintf("undo #%d %s", op.ID, op.Tool))
+	for i, step := range op.Undo {
+		if err := r.apply(ctx, step); err != nil {
+			r.summary = fmt.Sprintf("partial undo of #%d %s", op.ID, op.Tool)
+			partial, logged := r.Commit()
+			err = fmt.Errorf("undo step %d of %d (%s): %w", i+1, len(op.Undo), step.Action, err)
+			if logged {
+				err = fmt.Errorf("%w; the steps already applied were logged as operation %d", err, partial.ID)
+			}
+			return Operation{}, err
+		}
+	}
+	r.undoes = op.ID
+	done, _ := r.Commit()
+	return done, nil
+}
+
+// Rollback reverts the writes r has recorded so far, newest first, without
+// logging anything. Steps it could not apply stay recorded, so committing r
+// afterwards logs exactly the writes still in effect.
+func (r *Recorder) Rollback(ctx context.Context) error {
+	var undo *Log
+	revert := undo.Begin(r.b, r.tool, r.summary)
+	for len(r.undo) > 0 {
+		if err := revert.apply(ctx, r.undo[0]); err != nil {
+			return fmt.Errorf("roll back %s: %w", r.undo[0].Action, err)
+		}
+		r.undo = r.undo[1:]
+	}
+	return nil
+}
+
+// apply performs one undo step, recording its own inverse.
+func (r *Recorder) apply(ctx context.Context, s Step) error {
+	switch s.Action {
+	case ActionUpdate:
+		return r.UpdateBlock(ctx, s.UUID, s.Content)
+	case ActionRemove:
+		return r.RemoveBlock(ctx, s.UUID)
+	case ActionRestoreBlock:
+		return r.restoreBlock(ctx, s)
+	case ActionMove:
+		return r.moveBack(ctx, s.UUID, s.Anchor)
+	case ActionDeletePage:
+		return r.DeletePage(ctx, s.Page)
+	case ActionRestorePage:
+		return r.restorePage(ctx, s)
+	case ActionRenamePage:
+		return r.RenamePage(ctx, s.Page, s.NewName)
+	case ActionRestoreFile:
+		return r.restoreFile(ctx, s)
+	}
+	return fmt.Errorf("unknown action %q", s.Action)
+}
+
+// restoreBlock re-creates a removed block tree at its anchor.
+func (r *Recorder) restoreBlock(ctx context.Context, s Step) error {
+	if len(s.Blocks) == 0 || s.Anchor == nil {
+		return fmt.Errorf("nothing to restore")
+	}
+	tree := s.Blocks[0]
+	created, err := r.insertAt(ctx, s.Anchor, tree)
+	if err != nil {
+		return err
+	}
+	r.record(Step{Action: ActionRemove, UUID: created})
+	return r.restoreChildren(ctx, created, tree.Children)
+}
+
+// moveBack moves a block to anchor.
+func (r *Recorder) moveBack(ctx context.Context, uuid string, at *Anchor) error {
+	if at == nil {
+		return fmt.Errorf("no position to move block %s to", uuid)
+	}
+	if at.Left != "" && r.blockExists(ctx, at.Left) {
+		return r.MoveBlock(ctx, uuid, at.Left, map[string]any{})
+	}
+	if first, err := r.firstChild(ctx, at, uuid); err != nil {
+		return err
+	} else if first != "" {
+		return r.MoveBlock(ctx, uuid, first, map[string]any{"before": true})
+	}
+	if at.Parent != "" {
+		return r.MoveBlock(ctx, uuid, at.Parent, map[string]any{"children": true})
+	}
+
+	// The page is empty: there is no block to move next to, so re-create
+	// the block tree there instead.
+	tree, from, err := r.locate(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	if err := r.b.RemoveBlock(ctx, uuid); err != nil {
+		return err
+	}
+	created, err := r.insertAt(ctx, at, *tree)
+	if err != nil {
+		return err
+	}
+	if err := r.restoreChildren(ctx, created, tree.Children); err != nil {
+		return err
+	}
+	r.record(Step{Action: ActionMove, UUID: created, Anchor: from})
+	return nil
+}
+
+// restorePage re-creates a deleted page.
+func (r *Recorder) restorePage(ctx context.Context, s Step) error {
+	if r.pageExists(ctx, s.Page) {
+		return fmt.Errorf("page %s already exists", s.Page)
+	}
+	if r.files != nil && s.File != "" {
+		if err := r.files.WritePageFile(ctx, s.File, s.Content); err != nil {
+			return err
+		}
+		r.record(Step{Action: ActionDeletePage, Page: s.Page})
+		return nil
+	}
+
+	if _, err := r.b.CreatePage(ctx, s.Page, s.Properties, nil); err != nil {
+		return err
+	}
+	r.record(Step{Action: ActionDeletePage, Page: s.Page})
+	for _, tree := range s.Blocks {
+		// CreatePage wrote the properties block already.
+		if tree.PreBlock {
+			continue
+		}
+		block, err := r.b.AppendBlockInPage(ctx, s.Page, r.pinned(ctx, tree))
+		created, err := createdUUID(block, err, tree.UUID)
+		if err != nil {
+			return err
+		}
+		if err := r.restoreChildren(ctx, created, tree.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreFile writes a page file back to what it was before a write, unless
+// it changed since.
+func (r *Recorder) restoreFile(ctx context.Context, s Step) error {
+	if r.files == nil {
+		return fmt.Errorf("backend does not keep pages in files")
+	}
+	path, now, err := r.files.PageFile(ctx, s.Page)
+	if err != nil {
+		return err
+	}
+	if path != s.File || hashContent(now) != s.Hash {
+		return fmt.Errorf("page %s has changed since; undo the later operations on it first", s.Page)
+	}
+	if err := r.files.WritePageFile(ctx, s.File, s.Content); err != nil {
+		return err
+	}
+	r.record(Step{Action: ActionRestoreFile, Page: s.Page, File: s.File, Content: now, Hash: hashContent(s.Content)})
+	return nil
+}
+
+// insertAt creates tree's root block at anchor and returns its UUID. The
+// block keeps its old UUID unless another block has taken it meanwhile.
+func (r *Recorder) insertAt(ctx context.Context, at *Anchor, tree Block) (string, error) {
+	opts := func(o map[string]any) map[string]any {
+		if !r.blockExists(ctx, tree.UUID) {
+			o["customUUID"] = tree.UUID
+		}
+		return o
+	}
+
+	if at.Left != "" && r.blockExists(ctx, at.Left) {
+		block, err := r.b.InsertBlock(ctx, at.Left, tree.Content, opts(map[string]any{"sibling": true}))
+		return createdUUID(block, err, tree.UUID)
+	}
+	first, err := r.firstChild(ctx, at, "")
+	if err != nil {
+		return "", err
+	}
+	if first != "" {
+		block, err := r.b.InsertBlock(ctx, first, tree.Content, opts(map[string]any{"sibling": true, "before": true}))
+		return createdUUID(block, err, tree.UUID)
+	}
+	if at.Parent != "" && r.blockExists(ctx, at.Parent) {
+		block, err := r.b.InsertBlock(ctx, at.Parent, tree.Content, opts(map[string]any{"sibling": false}))
+		return createdUUID(block, err, tree.UUID)
+	}
+	block, err := r.b.AppendBlockInPage(ctx, at.Page, r.pinned(ctx, tree))
+	return createdUUID(block, err, tree.UUID)
+}
+
+// restoreChildren re-creates children, in order, under the block parent.
+func (r *Recorder) restoreChildren(ctx context.Context, parent string, children []Block) error {
+	prev := ""
+	for _, child := range children {
+		opts := map[string]any{"sibling": false}
+		target := parent
+		if prev != "" {
+			opts["sibling"] = true
+			target = prev
+		}
+		if !r.blockExists(ctx, child.UUID) {
+			opts["customUUID"] = child.UUID
+		}
+		block, err := r.b.InsertBlock(ctx, target, child.Content, opts)
+		created, err := createdUUID(block, err, child.UUID)
+		if err != nil {
+			return err
+		}
+		if err := r.restoreChildren(ctx, created, child.Children); err != nil {
+			return err
+		}
+		prev = created
+	}
+	return nil
+}
+
+// firstChild returns the first block under at's parent (or at the top of its
+// page), skipping skip; "" when there is none.
+func (r *Recorder) firstChild(ctx context.Context, at *Anchor, skip string) (string, error) {
+	blocks, err := r.b.GetPageBlocksTree(ctx, at.Page)
+	if err != nil {
+		// A page removed since is re-created by appending to it.
+		if !r.pageExists(ctx, at.Page) {
+			return "", nil
+		}
+		return "", err
+	}
+	if at.Parent != "" {
+		parent, _ := findBlock(blocks, at.Parent, Anchor{})
+		if parent == nil {
+			return "", nil
+		}
+		for _, b := range parent.Children {
+			if b.UUID != skip {
+				return b.UUID, nil
+			}
+		}
+		return "", nil
+	}
+	for _, b := range blocks {
+		if b.UUID != skip {
+			return b.UUID, nil
+		}
+	}
+	return "", nil
+}
+
+// pinned returns tree's content pinned to its UUID, for appending it to a
+// page, unless another block has taken the UUID meanwhile.
+func (r *Recorder) pinned(ctx context.Context, tree Block) string {
+	if r.blockExists(ctx, tree.UUID) {
+		return tree.Content
+	}
+	return pinUUID(tree.Content, tree.UUID)
+}
+
+// createdUUID returns the UUID of the block a restore of want created.
+func createdUUID(block *types.BlockEntity, err error, want string) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	if block == nil {
+		return "", fmt.Errorf("restored block %s but got no block reference", want)
+	}
+	return block.UUID, nil
+}
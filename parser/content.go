@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"net/url"
+	"path"
 	"regexp"
 	"strings"
 
@@ -8,14 +10,17 @@ import (
 )
 
 var (
-	// [[page name]] — wiki-style page links
-	linkPattern = regexp.MustCompile(`\[\[([^\]]+)\]\]`)
+	// [[page name]], [[page#heading|alias]], ![[embed]] — wiki-style page links
+	linkPattern = regexp.MustCompile(`(!?)\[\[([^\]]+)\]\]`)
+
+	// [text](Other%20Note.md) or [text](<Other Note.md>) — standard markdown links
+	markdownLinkPattern = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*(<[^>]+>|[^)\s]+)(?:\s+"[^"]*")?\s*\)`)
 
 	// ((uuid)) — block references
 	blockRefPattern = regexp.MustCompile(`\(\(([0-9a-f-]{36})\)\)`)
 
-	// #tag or #[[multi word tag]] — tags
-	tagPattern = regexp.MustCompile(`(?:^|\s)#([a-zA-Z0-9_-]+)`)
+	// #tag, #nested/tag or #[[multi word tag]] — tags
+	tagPattern = regexp.MustCompile(`(?:^|\s)#([a-zA-Z0-9_-]+(?:/[a-zA-Z0-9_-]+)*)`)
 	tagBracketPattern = regexp.MustCompile(`#\[\[([^\]]+)\]\]`)
 
 	// key:: value — inline properties
@@ -29,10 +34,23 @@ var (
 )
 
 // Parse extracts structured data from a block's raw content string.
+// Relative markdown links are resolved against the graph root; use
+// ParseInPage when the block's page is known.
 func Parse(content string) types.ParsedContent {
+	return ParseInPage(content, "")
+}
+
+// ParseInPage is Parse for a block that lives on pageName. Relative markdown
+// links like [text](../Other%20Note.md) are resolved against the page's folder
+// (the page name up to its last "/"), matching how Obsidian resolves them
+// against the source file's directory.
+func ParseInPage(content, pageName string) types.ParsedContent {
+	refs := extractLinkRefs(content)
+	refs = append(refs, extractMarkdownLinkRefs(content, pageName)...)
 	result := types.ParsedContent{
 		Raw:             content,
-		Links:           extractLinks(content),
+		Links:           linkTargets(refs),
+		LinkRefs:        refs,
 		BlockReferences: extractBlockRefs(content),
 		Tags:            extractTags(content),
 		Properties:      extractProperties(content),
@@ -49,17 +67,162 @@ func Parse(content string) types.ParsedContent {
 	return result
 }
 
-// extractLinks finds all [[page name]] patterns in content.
-func extractLinks(content string) []string {
+// extractLinkRefs finds all [[...]] and ![[...]] patterns in content, splitting
+// Obsidian's [[target#anchor|alias]] syntax into its parts. Duplicate
+// occurrences (same target, anchor, alias and embed flag) are collapsed.
+func extractLinkRefs(content string) []types.LinkRef {
 	matches := linkPattern.FindAllStringSubmatch(content, -1)
-	links := make([]string, 0, len(matches))
-	seen := make(map[string]bool)
+	if len(matches) == 0 {
+		return nil
+	}
+	refs := make([]types.LinkRef, 0, len(matches))
+	seen := make(map[types.LinkRef]bool)
+	for _, m := range matches {
+		ref := ParseWikilink(m[2])
+		ref.Embed = m[1] == "!"
+		if ref.Target == "" && ref.Anchor == "" {
+			continue
+		}
+		if !seen[ref] {
+			refs = append(refs, ref)
+			seen[ref] = true
+		}
+	}
+	return refs
+}
+
+// extractMarkdownLinkRefs finds [text](path.md) links to other notes.
+// External URLs and links to non-markdown files are ignored.
+func extractMarkdownLinkRefs(content, pageName string) []types.LinkRef {
+	matches := markdownLinkPattern.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	var refs []types.LinkRef
+	seen := make(map[types.LinkRef]bool)
 	for _, m := range matches {
-		name := m[1]
-		if !seen[name] {
-			links = append(links, name)
-			seen[name] = true
+		target, anchor, ok := ResolveMarkdownLink(m[3], pageName)
+		if !ok {
+			continue
+		}
+		ref := types.LinkRef{
+			Target:   target,
+			Alias:    strings.TrimSpace(m[2]),
+			Anchor:   anchor,
+			Embed:    m[1] == "!",
+			Markdown: true,
+		}
+		if !seen[ref] {
+			refs = append(refs, ref)
+			seen[ref] = true
+		}
+	}
+	return refs
+}
+
+// ReplaceWikilinks returns content with every [[...]] and ![[...]] link
+// replaced by what replace returns for it. replace is given the whole link,
+// whether it is an embed and the text between the brackets.
+func ReplaceWikilinks(content string, replace func(link string, embed bool, inner string) string) string {
+	return linkPattern.ReplaceAllStringFunc(content, func(m string) string {
+		sub := linkPattern.FindStringSubmatch(m)
+		return replace(m, sub[1] == "!", sub[2])
+	})
+}
+
+// MarkdownLinkDestinations returns where the destinations of the markdown
+// links in content start and end, angle brackets included, in order.
+func MarkdownLinkDestinations(content string) [][2]int {
+	var spans [][2]int
+	for _, loc := range markdownLinkPattern.FindAllStringSubmatchIndex(content, -1) {
+		spans = append(spans, [2]int{loc[6], loc[7]})
+	}
+	return spans
+}
+
+// ResolveMarkdownLink turns the destination of a markdown link into a page
+// name. The destination is URL-decoded and resolved relative to the folder
+// of pageName ("/"-prefixed destinations are relative to the graph root).
+// Returns ok=false for external URLs, non-.md files and paths escaping the root.
+func ResolveMarkdownLink(dest, pageName string) (target, anchor string, ok bool) {
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+	if strings.Contains(dest, "://") || strings.HasPrefix(dest, "mailto:") {
+		return "", "", false
+	}
+	if i := strings.Index(dest, "#"); i >= 0 {
+		anchor = dest[i+1:]
+		dest = dest[:i]
+	}
+	if decoded, err := url.PathUnescape(dest); err == nil {
+		dest = decoded
+	}
+	if decoded, err := url.PathUnescape(anchor); err == nil {
+		anchor = decoded
+	}
+	if !strings.HasSuffix(strings.ToLower(dest), ".md") {
+		return "", "", false
+	}
+
+	var resolved string
+	if strings.HasPrefix(dest, "/") {
+		resolved = path.Clean(strings.TrimPrefix(dest, "/"))
+	} else {
+		dir := ""
+		if i := strings.LastIndex(pageName, "/"); i >= 0 {
+			dir = pageName[:i]
+		}
+		resolved = path.Clean(path.Join(dir, dest))
+	}
+	if resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return "", "", false
+	}
+	return resolved[:len(resolved)-len(".md")], anchor, true
+}
+
+// ParseWikilink splits the inner text of a [[...]] link into target page,
+// heading/block anchor and display alias. An escaped pipe (\|, used inside
+// markdown tables) is treated like a plain pipe. A trailing "#" with nothing
+// after it is kept as part of the page name (e.g. [[C#]]).
+func ParseWikilink(inner string) types.LinkRef {
+	var ref types.LinkRef
+	target, rest := SplitWikilink(inner)
+	if i := strings.Index(rest, "|"); i >= 0 {
+		ref.Alias = strings.TrimSpace(rest[i+1:])
+		rest = strings.TrimSuffix(rest[:i], "\\")
+	}
+	ref.Anchor = strings.TrimSpace(strings.TrimPrefix(rest, "#"))
+	ref.Target = strings.TrimSpace(target)
+	return ref
+}
+
+// SplitWikilink splits the inner text of a [[...]] link into the target as
+// written and the rest: the "#anchor" and "|alias" suffixes verbatim,
+// including the backslash of an escaped pipe. It is the split ParseWikilink
+// makes, for callers that rewrite the target and keep the rest.
+func SplitWikilink(inner string) (target, rest string) {
+	target = inner
+	if i := strings.Index(target, "|"); i >= 0 {
+		if i > 0 && target[i-1] == '\\' {
+			i--
+		}
+		target = target[:i]
+	}
+	if i := strings.Index(target, "#"); i >= 0 && i < len(target)-1 {
+		target = target[:i]
+	}
+	return target, inner[len(target):]
+}
+
+// linkTargets returns the distinct target page names of refs, in order.
+func linkTargets(refs []types.LinkRef) []string {
+	links := make([]string, 0, len(refs))
+	seen := make(map[string]bool)
+	for _, r := range refs {
+		if r.Target == "" || seen[r.Target] {
+			continue
 		}
+		links = append(links, r.Target)
+		seen[r.Target] = true
 	}
 	return links
 }
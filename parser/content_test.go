@@ -121,6 +121,157 @@ func TestLinks_None(t *testing.T) {
 	}
 }
 
+func TestLinks_Alias(t *testing.T) {
+	r := Parse("See [[Foo|the foo page]] for details")
+	if len(r.Links) != 1 || r.Links[0] != "Foo" {
+		t.Fatalf("Links = %v, want [Foo]", r.Links)
+	}
+	if len(r.LinkRefs) != 1 || r.LinkRefs[0].Alias != "the foo page" {
+		t.Errorf("LinkRefs = %+v, want alias 'the foo page'", r.LinkRefs)
+	}
+}
+
+func TestLinks_HeadingAndBlockAnchor(t *testing.T) {
+	r := Parse("[[Foo#Setup]] and [[Bar#^abc123|see here]]")
+	want := []string{"Foo", "Bar"}
+	if len(r.Links) != 2 || r.Links[0] != want[0] || r.Links[1] != want[1] {
+		t.Fatalf("Links = %v, want %v", r.Links, want)
+	}
+	if r.LinkRefs[0].Anchor != "Setup" {
+		t.Errorf("LinkRefs[0].Anchor = %q, want Setup", r.LinkRefs[0].Anchor)
+	}
+	if r.LinkRefs[1].Anchor != "^abc123" || r.LinkRefs[1].Alias != "see here" {
+		t.Errorf("LinkRefs[1] = %+v", r.LinkRefs[1])
+	}
+}
+
+func TestLinks_Embed(t *testing.T) {
+	r := Parse("![[Diagram]] and [[Diagram]]")
+	if len(r.Links) != 1 || r.Links[0] != "Diagram" {
+		t.Fatalf("Links = %v, want [Diagram]", r.Links)
+	}
+	if len(r.LinkRefs) != 2 {
+		t.Fatalf("LinkRefs = %+v, want embed and plain link", r.LinkRefs)
+	}
+	if !r.LinkRefs[0].Embed || r.LinkRefs[1].Embed {
+		t.Errorf("LinkRefs embed flags = %v, %v; want true, false", r.LinkRefs[0].Embed, r.LinkRefs[1].Embed)
+	}
+}
+
+func TestLinks_EscapedPipeInTable(t *testing.T) {
+	r := Parse("| [[Foo\\|bar]] | cell |")
+	if len(r.Links) != 1 || r.Links[0] != "Foo" {
+		t.Errorf("Links = %v, want [Foo]", r.Links)
+	}
+}
+
+func TestLinks_SamePageAnchor(t *testing.T) {
+	r := Parse("Jump to [[#Summary]]")
+	if len(r.Links) != 0 {
+		t.Errorf("Links = %v, want empty for same-page anchor", r.Links)
+	}
+	if len(r.LinkRefs) != 1 || r.LinkRefs[0].Target != "" || r.LinkRefs[0].Anchor != "Summary" {
+		t.Errorf("LinkRefs = %+v, want same-page anchor Summary", r.LinkRefs)
+	}
+}
+
+func TestLinks_TrailingHashKept(t *testing.T) {
+	r := Parse("Learning [[C#]]")
+	if len(r.Links) != 1 || r.Links[0] != "C#" {
+		t.Errorf("Links = %v, want [C#]", r.Links)
+	}
+}
+
+func TestSplitWikilink(t *testing.T) {
+	tests := []struct {
+		inner, target, rest string
+	}{
+		{"A", "A", ""},
+		{"A#Setup", "A", "#Setup"},
+		{"A|shown", "A", "|shown"},
+		{"A#Setup|shown", "A", "#Setup|shown"},
+		{"Foo\\|bar", "Foo", "\\|bar"},
+		{"C#", "C#", ""},
+		{"C#|x", "C#", "|x"},
+		{"C#\\|x", "C#", "\\|x"},
+	}
+	for _, tt := range tests {
+		target, rest := SplitWikilink(tt.inner)
+		if target != tt.target || rest != tt.rest {
+			t.Errorf("SplitWikilink(%q) = %q, %q; want %q, %q", tt.inner, target, rest, tt.target, tt.rest)
+		}
+		if ref := ParseWikilink(tt.inner); ref.Target != tt.target {
+			t.Errorf("ParseWikilink(%q).Target = %q, want %q", tt.inner, ref.Target, tt.target)
+		}
+	}
+	if ref := ParseWikilink("C#|x"); ref.Anchor != "" || ref.Alias != "x" {
+		t.Errorf("ParseWikilink(C#|x) = %+v", ref)
+	}
+}
+
+func TestMarkdownLinks_Relative(t *testing.T) {
+	r := ParseInPage("See [the spec](Other%20Note.md) and [bio](../people/Hanna.md#Early%20life)", "projects/alpha")
+	want := []string{"projects/Other Note", "people/Hanna"}
+	if len(r.Links) != len(want) {
+		t.Fatalf("Links = %v, want %v", r.Links, want)
+	}
+	for i := range want {
+		if r.Links[i] != want[i] {
+			t.Errorf("Links[%d] = %q, want %q", i, r.Links[i], want[i])
+		}
+	}
+	if ref := r.LinkRefs[1]; !ref.Markdown || ref.Alias != "bio" || ref.Anchor != "Early life" {
+		t.Errorf("LinkRefs[1] = %+v", ref)
+	}
+}
+
+func TestMarkdownLinks_RootAndAngleBrackets(t *testing.T) {
+	r := ParseInPage("[a](/notes/A.md) [b](<Some Note.md>)", "projects/alpha")
+	if len(r.Links) != 2 || r.Links[0] != "notes/A" || r.Links[1] != "projects/Some Note" {
+		t.Errorf("Links = %v, want [notes/A projects/Some Note]", r.Links)
+	}
+}
+
+func TestMarkdownLinks_Ignored(t *testing.T) {
+	r := ParseInPage("[web](https://example.com/a.md) [img](pic.png) [up](../../outside.md)", "projects/alpha")
+	if len(r.Links) != 0 {
+		t.Errorf("Links = %v, want none", r.Links)
+	}
+}
+
+func TestMarkdownLinks_MixedWithWikilinks(t *testing.T) {
+	r := Parse("[[Foo]] and [foo again](Foo.md)")
+	if len(r.Links) != 1 || r.Links[0] != "Foo" {
+		t.Errorf("Links = %v, want deduplicated [Foo]", r.Links)
+	}
+	if len(r.LinkRefs) != 2 {
+		t.Errorf("LinkRefs = %+v, want wikilink and markdown link", r.LinkRefs)
+	}
+}
+
+func TestReplaceWikilinks(t *testing.T) {
+	got := ReplaceWikilinks("[[a]] ![[b|c]] [d](e.md)", func(link string, embed bool, inner string) string {
+		if embed {
+			return "<" + inner + ">"
+		}
+		return link + "!"
+	})
+	if want := "[[a]]! <b|c> [d](e.md)"; got != want {
+		t.Errorf("ReplaceWikilinks = %q, want %q", got, want)
+	}
+}
+
+func TestMarkdownLinkDestinations(t *testing.T) {
+	content := `[[x]] [a](A.md) ![b](<B c.md> "title")`
+	var got []string
+	for _, span := range MarkdownLinkDestinations(content) {
+		got = append(got, content[span[0]:span[1]])
+	}
+	if len(got) != 2 || got[0] != "A.md" || got[1] != "<B c.md>" {
+		t.Errorf("destinations = %q", got)
+	}
+}
+
 // --- Block References ---
 
 func TestBlockRefs_Valid(t *testing.T) {
@@ -195,6 +346,13 @@ func TestTags_MixedSimpleAndBracket(t *testing.T) {
 	}
 }
 
+func TestTags_Nested(t *testing.T) {
+	r := Parse("#project/alpha and #area/")
+	if len(r.Tags) != 2 || r.Tags[0] != "project/alpha" || r.Tags[1] != "area" {
+		t.Errorf("Tags = %v, want [project/alpha area]", r.Tags)
+	}
+}
+
 func TestTags_NoWordBoundary(t *testing.T) {
 	// #tag requires whitespace or start-of-line before #
 	r := Parse("hello#tag")
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PropertyList normalizes a page property that may be written as a YAML
+// list, a single string or a comma-separated string ("a, b") into a list
+// of trimmed, non-empty values.
+func PropertyList(v any) []string {
+	var raw []string
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		raw = strings.Split(val, ",")
+	case []string:
+		raw = val
+	case []any:
+		for _, item := range val {
+			if item != nil {
+				raw = append(raw, fmt.Sprint(item))
+			}
+		}
+	default:
+		raw = []string{fmt.Sprint(val)}
+	}
+
+	var out []string
+	for _, s := range raw {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// PageAliases returns the aliases declared by a page's "aliases" or "alias"
+// property. Obsidian writes the former, Logseq the latter.
+func PageAliases(props map[string]any) []string {
+	return dedupe(append(PropertyList(props["aliases"]), PropertyList(props["alias"])...))
+}
+
+// PageTags returns the tags declared by a page's "tags" or "tag" property,
+// without a leading '#' or surrounding [[ ]]. Obsidian also accepts
+// space-separated tags in a single string, so those are split too.
+func PageTags(props map[string]any) []string {
+	var tags []string
+	for _, key := range []string{"tags", "tag"} {
+		v := props[key]
+		if s, ok := v.(string); ok && !strings.Contains(s, "[[") {
+			v = strings.Join(strings.Fields(s), ",")
+		}
+		for _, t := range PropertyList(v) {
+			t = strings.TrimPrefix(t, "#")
+			t = strings.TrimSuffix(strings.TrimPrefix(t, "[["), "]]")
+			if t != "" {
+				tags = append(tags, t)
+			}
+		}
+	}
+	return dedupe(tags)
+}
+
+// dedupe drops case-insensitive duplicates, keeping the first spelling.
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		key := strings.ToLower(v)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, v)
+	}
+	return out
+}
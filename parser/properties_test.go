@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPropertyList(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		want []string
+	}{
+		{"nil", nil, nil},
+		{"string", "solo", []string{"solo"}},
+		{"comma string", "a, b,,c ", []string{"a", "b", "c"}},
+		{"yaml list", []any{"a", " b ", "", nil, 3}, []string{"a", "b", "3"}},
+		{"string slice", []string{"x", "y"}, []string{"x", "y"}},
+		{"scalar", 2026, []string{"2026"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PropertyList(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PropertyList(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageAliases(t *testing.T) {
+	props := map[string]any{"aliases": []any{"GT", "graph tool"}, "alias": "gt, thulhu"}
+	want := []string{"GT", "graph tool", "thulhu"}
+	if got := PageAliases(props); !reflect.DeepEqual(got, want) {
+		t.Errorf("PageAliases = %v, want %v", got, want)
+	}
+	if got := PageAliases(nil); got != nil {
+		t.Errorf("PageAliases(nil) = %v, want nil", got)
+	}
+}
+
+func TestPageTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		props map[string]any
+		want  []string
+	}{
+		{"yaml list", map[string]any{"tags": []any{"go", "#mcp"}}, []string{"go", "mcp"}},
+		{"space separated", map[string]any{"tags": "#go mcp"}, []string{"go", "mcp"}},
+		{"comma separated", map[string]any{"tags": "go, project/alpha"}, []string{"go", "project/alpha"}},
+		{"logseq refs", map[string]any{"tags": "[[multi word]], go"}, []string{"multi word", "go"}},
+		{"singular key merged", map[string]any{"tags": []any{"go"}, "tag": "Go"}, []string{"go"}},
+		{"none", map[string]any{"type": "project"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PageTags(tt.props); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PageTags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
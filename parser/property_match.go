@@ -0,0 +1,244 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PropertyMatcher tests property values against a query_properties
+// condition. Values are typed before they are compared: numbers compare
+// numerically, ISO dates chronologically, and booleans and text as such,
+// text case-insensitively. A list matches when any of its items does.
+type PropertyMatcher struct {
+	operator string
+	operands []propertyValue
+	re       *regexp.Regexp
+}
+
+// PropertyOperators lists the operators NewPropertyMatcher accepts.
+var PropertyOperators = []string{"eq", "contains", "gt", "gte", "lt", "lte", "between", "in", "exists", "regex"}
+
+// NewPropertyMatcher returns a matcher for operator applied to values:
+// one value for eq, contains, gt, gte, lt, lte and regex, the low and
+// high bounds for between (inclusive), one or more values for in and none
+// for exists.
+func NewPropertyMatcher(operator string, values []string) (*PropertyMatcher, error) {
+	want := 1
+	switch operator {
+	case "eq", "contains", "gt", "gte", "lt", "lte", "regex":
+	case "between":
+		want = 2
+	case "in":
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%s needs at least one value", operator)
+		}
+		want = len(values)
+	case "exists":
+		want = 0
+	default:
+		return nil, fmt.Errorf("unknown operator %q (use %s)", operator, strings.Join(PropertyOperators, ", "))
+	}
+	if len(values) != want {
+		return nil, fmt.Errorf("%s needs %d value(s), got %d", operator, want, len(values))
+	}
+
+	m := &PropertyMatcher{operator: operator}
+	if operator == "regex" {
+		re, err := regexp.Compile(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		m.re = re
+		return m, nil
+	}
+	for _, v := range values {
+		m.operands = append(m.operands, typeValue(v))
+	}
+	return m, nil
+}
+
+// Match reports whether a property value satisfies the condition. The
+// property is assumed present; look it up first for exists.
+func (m *PropertyMatcher) Match(v any) bool {
+	if m.operator == "exists" {
+		return true
+	}
+	for _, item := range propertyItems(v) {
+		if m.matchItem(item) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *PropertyMatcher) matchItem(v propertyValue) bool {
+	switch m.operator {
+	case "eq":
+		return v.equal(m.operands[0])
+	case "in":
+		for _, o := range m.operands {
+			if v.equal(o) {
+				return true
+			}
+		}
+		return false
+	case "contains":
+		return strings.Contains(strings.ToLower(v.text), strings.ToLower(m.operands[0].text))
+	case "regex":
+		return m.re.MatchString(v.text)
+	case "between":
+		low, ok1 := v.compare(m.operands[0])
+		high, ok2 := v.compare(m.operands[1])
+		return ok1 && ok2 && low >= 0 && high <= 0
+	}
+	c, ok := v.compare(m.operands[0])
+	if !ok {
+		return false
+	}
+	switch m.operator {
+	case "gt":
+		return c > 0
+	case "gte":
+		return c >= 0
+	case "lt":
+		return c < 0
+	case "lte":
+		return c <= 0
+	}
+	return false
+}
+
+// propertyKind is the type inferred for a property value.
+type propertyKind int
+
+const (
+	kindText propertyKind = iota
+	kindNumber
+	kindDate
+	kindBool
+)
+
+// propertyValue is a property value with its inferred type. text is the
+// value as written, used by contains and regex and to compare values of
+// different kinds.
+type propertyValue struct {
+	kind propertyKind
+	text string
+	num  float64
+	date time.Time
+	b    bool
+}
+
+// dateLayouts are the ISO 8601 forms a text value is read as a date in.
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// typeValue infers the type of a value written as text: true and false
+// are booleans, then numbers, then ISO dates. [[Page]] links are read as
+// the page name.
+func typeValue(s string) propertyValue {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[[") && strings.HasSuffix(s, "]]") && strings.Count(s, "[[") == 1 {
+		s = s[2 : len(s)-2]
+	}
+	v := propertyValue{kind: kindText, text: s}
+	switch strings.ToLower(s) {
+	case "true":
+		v.kind, v.b = kindBool, true
+		return v
+	case "false":
+		v.kind = kindBool
+		return v
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		v.kind, v.num = kindNumber, f
+		return v
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			v.kind, v.date = kindDate, t
+			return v
+		}
+	}
+	return v
+}
+
+// propertyItems returns the typed items of a property value: the items of
+// a list (YAML lists, Logseq sets, or [a, b] written inline), otherwise
+// the value itself. Values already typed by a YAML parser keep their type.
+func propertyItems(v any) []propertyValue {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		var items []propertyValue
+		for _, item := range val {
+			items = append(items, propertyItems(item)...)
+		}
+		return items
+	case []string:
+		items := make([]propertyValue, len(val))
+		for i, item := range val {
+			items[i] = typeValue(item)
+		}
+		return items
+	case string:
+		if inner, ok := strings.CutPrefix(val, "["); ok && strings.HasSuffix(inner, "]") && !strings.HasPrefix(inner, "[") {
+			var items []propertyValue
+			for _, item := range strings.Split(strings.TrimSuffix(inner, "]"), ",") {
+				items = append(items, typeValue(strings.Trim(strings.TrimSpace(item), `"'`)))
+			}
+			return items
+		}
+		return []propertyValue{typeValue(val)}
+	case bool:
+		return []propertyValue{{kind: kindBool, text: strconv.FormatBool(val), b: val}}
+	case time.Time:
+		return []propertyValue{{kind: kindDate, text: val.Format(time.RFC3339), date: val}}
+	case int, int64, float64:
+		return []propertyValue{typeValue(fmt.Sprint(val))}
+	default:
+		return []propertyValue{{kind: kindText, text: fmt.Sprint(val)}}
+	}
+}
+
+// compare orders v against o. ok is false when the two are of different
+// kinds: "high" is neither above nor below 5.
+func (v propertyValue) compare(o propertyValue) (c int, ok bool) {
+	if v.kind != o.kind {
+		return 0, false
+	}
+	switch v.kind {
+	case kindNumber:
+		switch {
+		case v.num < o.num:
+			return -1, true
+		case v.num > o.num:
+			return 1, true
+		}
+		return 0, true
+	case kindDate:
+		return v.date.Compare(o.date), true
+	case kindBool:
+		if v.b == o.b {
+			return 0, true
+		}
+		if o.b {
+			return -1, true
+		}
+		return 1, true
+	}
+	return strings.Compare(strings.ToLower(v.text), strings.ToLower(o.text)), true
+}
+
+// equal reports whether v and o are the same value. Values of different
+// kinds are equal when they are written the same.
+func (v propertyValue) equal(o propertyValue) bool {
+	if c, ok := v.compare(o); ok {
+		return c == 0
+	}
+	return strings.EqualFold(v.text, o.text)
+}
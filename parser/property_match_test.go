@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPropertyMatcher(t *testing.T) {
+	due := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		operator string
+		values   []string
+		prop     any
+		want     bool
+	}{
+		{"gt", []string{"9"}, "10", true}, // not "10" < "9"
+		{"gt", []string{"9"}, 10, true},
+		{"lt", []string{"9"}, "high", false},
+		{"eq", []string{"10.0"}, 10, true},
+		{"eq", []string{"Alpha"}, "[[alpha]]", true},
+		{"eq", []string{"2026-03-01"}, due, true},
+		{"gte", []string{"2026-03-01T00:00:00Z"}, "2026-03-01", true},
+		{"lt", []string{"2026-02-01"}, "2026-01-31", true},
+		{"between", []string{"1", "5"}, "5", true},
+		{"between", []string{"1", "5"}, "6", false},
+		{"in", []string{"go", "rust"}, []any{"zig", "Rust"}, true},
+		{"eq", []string{"b"}, "[a, b]", true},
+		{"contains", []string{"LONG"}, "a longer text", true},
+		{"eq", []string{"true"}, true, true},
+		{"eq", []string{"yes"}, true, false},
+		{"regex", []string{`^v\d`}, "v2", true},
+		{"exists", nil, nil, true},
+	}
+	for _, tt := range tests {
+		m, err := NewPropertyMatcher(tt.operator, tt.values)
+		if err != nil {
+			t.Fatalf("%s %v: %v", tt.operator, tt.values, err)
+		}
+		if got := m.Match(tt.prop); got != tt.want {
+			t.Errorf("%v %s %v = %v, want %v", tt.prop, tt.operator, tt.values, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// .+1w, ++1m, +2d — Logseq repeaters in SCHEDULED/DEADLINE timestamps
+	repeaterPattern = regexp.MustCompile(`^(\.\+|\+\+|\+)(\d+)([dwmy])$`)
+
+	// every week, every 2 months when done — Obsidian Tasks recurrence rules
+	everyPattern = regexp.MustCompile(`^every(?:\s+(\d+))?\s+(day|week|month|year)s?(\s+when done)?$`)
+
+	// 🔁 every week, up to the next Tasks plugin emoji or the end of the line
+	recurrenceEmojiPattern = regexp.MustCompile(`🔁\x{FE0F}?\s*([^📅⏳🛫✅➕❌🔁\n]+)`)
+
+	// <2026-04-16 Thu 10:00 .+1w>, the date and what follows it
+	timestampPattern = regexp.MustCompile(`((?:SCHEDULED|DEADLINE):\s*<)(\d{4}-\d{2}-\d{2})([^>]*)>`)
+
+	// 📅 2026-04-16, ⏳ and 🛫 dates that move with a recurring task
+	taskDueEmojiPattern = regexp.MustCompile(`(📅|⏳|🛫)(\x{FE0F}?\s*)(\d{4}-\d{2}-\d{2})`)
+)
+
+// Recurrence is how often a task or decision repeats: every Every days,
+// weeks, months or years.
+type Recurrence struct {
+	Every int
+	Unit  byte // 'd', 'w', 'm' or 'y'
+	// FromDone repeats from the day the task was completed rather than
+	// from its date: Logseq's .+ repeater and the Tasks plugin's "when done".
+	FromDone bool
+	// CatchUp skips occurrences already past when the task is completed:
+	// Logseq's ++ repeater.
+	CatchUp bool
+}
+
+// ParseRecurrence reads a Logseq repeater (.+1w, ++1m, +2d) or an Obsidian
+// Tasks rule (every week, every 2 months when done).
+func ParseRecurrence(s string) (Recurrence, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if m := repeaterPattern.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		if n == 0 {
+			return Recurrence{}, fmt.Errorf("invalid repeater %q: the interval must be at least 1", s)
+		}
+		return Recurrence{Every: n, Unit: m[3][0], FromDone: m[1] == ".+", CatchUp: m[1] == "++"}, nil
+	}
+	if m := everyPattern.FindStringSubmatch(s); m != nil {
+		n := 1
+		if m[1] != "" {
+			n, _ = strconv.Atoi(m[1])
+		}
+		if n == 0 {
+			return Recurrence{}, fmt.Errorf("invalid recurrence %q: the interval must be at least 1", s)
+		}
+		return Recurrence{Every: n, Unit: m[2][0], FromDone: m[3] != ""}, nil
+	}
+	return Recurrence{}, fmt.Errorf("invalid recurrence %q: use a repeater like .+1w, ++1m or +2d, or a rule like every week or every 2 months when done", s)
+}
+
+// After returns the occurrence one interval after t. Months and years
+// that are too short for t's day end on their last day: a month after
+// January 31 is February 28.
+func (r Recurrence) After(t time.Time) time.Time {
+	return r.Add(t, 1)
+}
+
+// Add returns the occurrence n intervals after t. It counts from t, so
+// that monthly occurrences from January 31 are February 28, then March 31.
+func (r Recurrence) Add(t time.Time, n int) time.Time {
+	switch r.Unit {
+	case 'w':
+		return t.AddDate(0, 0, 7*r.Every*n)
+	case 'm':
+		return addMonths(t, r.Every*n)
+	case 'y':
+		return addMonths(t, 12*r.Every*n)
+	}
+	return t.AddDate(0, 0, r.Every*n)
+}
+
+// Intervals returns how many intervals after start the first occurrence
+// on or after t falls: 0 when start is not before t.
+func (r Recurrence) Intervals(start, t time.Time) int {
+	if !start.Before(t) {
+		return 0
+	}
+	var n int
+	switch r.Unit {
+	case 'm', 'y':
+		months := (t.Year()-start.Year())*12 + int(t.Month()-start.Month())
+		step := r.Every
+		if r.Unit == 'y' {
+			step *= 12
+		}
+		n = months / step
+	default:
+		step := r.Every
+		if r.Unit == 'w' {
+			step *= 7
+		}
+		n = int(t.Sub(start).Hours()/24) / step
+	}
+	for r.Add(start, n).Before(t) {
+		n++
+	}
+	return n
+}
+
+// addMonths adds n months to t, keeping to the last day of shorter months.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()).AddDate(0, n, 0)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// Next returns the date of the occurrence after one dated date that was
+// completed on done.
+func (r Recurrence) Next(date, done time.Time) time.Time {
+	if r.FromDone {
+		return r.After(done)
+	}
+	next := r.After(date)
+	if r.CatchUp {
+		for !next.After(done) {
+			next = r.After(next)
+		}
+	}
+	return next
+}
+
+// NextOccurrence returns the content of a recurring task's next
+// occurrence, the task having been completed on done: its repeating Logseq
+// timestamps, or its Tasks plugin dates, moved on by its recurrence and
+// its ✅ date removed. It returns false when the task does not recur.
+func NextOccurrence(content string, done time.Time) (string, bool) {
+	task, ok := ParseTask(content)
+	if !ok || task.Repeat == "" {
+		return content, false
+	}
+	r, err := ParseRecurrence(task.Repeat)
+	if err != nil {
+		return content, false
+	}
+
+	if !task.Checkbox {
+		// Logseq moves each timestamp that carries a repeater on its own.
+		next := timestampPattern.ReplaceAllStringFunc(content, func(ts string) string {
+			m := timestampPattern.FindStringSubmatch(ts)
+			fields := strings.Fields(m[3])
+			if len(fields) == 0 {
+				return ts
+			}
+			rr, err := ParseRecurrence(fields[len(fields)-1])
+			date, derr := time.Parse("2006-01-02", m[2])
+			if err != nil || derr != nil {
+				return ts
+			}
+			date = rr.Next(date, done)
+			for i, f := range fields {
+				if isWeekday(f) {
+					fields[i] = date.Format("Mon")
+				}
+			}
+			return m[1] + date.Format("2006-01-02") + " " + strings.Join(fields, " ") + ">"
+		})
+		return next, true
+	}
+
+	// The Tasks plugin moves the due date, or else the scheduled or start
+	// date, and keeps the others at the same distance from it.
+	ref, err := time.Parse("2006-01-02", cmp.Or(task.Deadline, task.Scheduled, task.Start))
+	if err != nil {
+		return content, false
+	}
+	shift := r.Next(ref, done).Sub(ref)
+	next := taskDueEmojiPattern.ReplaceAllStringFunc(content, func(s string) string {
+		m := taskDueEmojiPattern.FindStringSubmatch(s)
+		date, err := time.Parse("2006-01-02", m[3])
+		if err != nil {
+			return s
+		}
+		return m[1] + m[2] + date.Add(shift).Format("2006-01-02")
+	})
+	return SetCompletedEmoji(next, ""), true
+}
+
+// isWeekday reports whether s is a weekday as timestamps abbreviate it.
+func isWeekday(s string) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if s == d.String()[:3] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Recurrence
+	}{
+		{".+1w", Recurrence{Every: 1, Unit: 'w', FromDone: true}},
+		{"++1m", Recurrence{Every: 1, Unit: 'm', CatchUp: true}},
+		{"+2d", Recurrence{Every: 2, Unit: 'd'}},
+		{"every week", Recurrence{Every: 1, Unit: 'w'}},
+		{"every 2 months when done", Recurrence{Every: 2, Unit: 'm', FromDone: true}},
+		{"Every year", Recurrence{Every: 1, Unit: 'y'}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRecurrence(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRecurrence(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "weekly", "+0d", "every fortnight", ".+1h"} {
+		if _, err := ParseRecurrence(bad); err == nil {
+			t.Errorf("ParseRecurrence(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	date, done := day("2026-09-01"), day("2026-10-16")
+	tests := []struct {
+		repeat string
+		want   string
+	}{
+		{"+1w", "2026-09-08"},
+		{".+1w", "2026-10-23"},
+		{"++1w", "2026-10-20"},
+		{"every month", "2026-10-01"},
+	}
+	for _, tt := range tests {
+		r, _ := ParseRecurrence(tt.repeat)
+		if got := r.Next(date, done).Format("2006-01-02"); got != tt.want {
+			t.Errorf("%s: Next = %s, want %s", tt.repeat, got, tt.want)
+		}
+	}
+
+	monthly, yearly := Recurrence{Every: 1, Unit: 'm'}, Recurrence{Every: 1, Unit: 'y'}
+	if got := monthly.After(day("2026-01-31")).Format("2006-01-02"); got != "2026-02-28" {
+		t.Errorf("a month after January 31 = %s", got)
+	}
+	if got := yearly.After(day("2028-02-29")).Format("2006-01-02"); got != "2029-02-28" {
+		t.Errorf("a year after February 29 = %s", got)
+	}
+	if got := monthly.Add(day("2026-01-31"), 2).Format("2006-01-02"); got != "2026-03-31" {
+		t.Errorf("two months after January 31 = %s", got)
+	}
+}
+
+func TestRecurrenceIntervals(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tests := []struct {
+		repeat, start, t string
+		want             int
+	}{
+		{"+1d", "2024-01-01", "2026-10-16", 1019},
+		{"+2w", "2026-10-01", "2026-10-16", 2},
+		{"+1w", "2026-10-16", "2026-10-16", 0},
+		{"+1w", "2026-10-20", "2026-10-16", 0},
+		{"+1m", "2026-01-31", "2026-03-01", 2},
+		{"+1m", "2026-01-31", "2026-02-28", 1},
+		{"+1y", "2020-02-29", "2026-03-01", 7},
+	}
+	for _, tt := range tests {
+		r, _ := ParseRecurrence(tt.repeat)
+		start := day(tt.start)
+		n := r.Intervals(start, day(tt.t))
+		if n != tt.want {
+			t.Errorf("%s from %s: Intervals to %s = %d, want %d", tt.repeat, tt.start, tt.t, n, tt.want)
+		}
+		if n > 0 && !r.Add(start, n-1).Before(day(tt.t)) {
+			t.Errorf("%s from %s: occurrence %d is not before %s", tt.repeat, tt.start, n-1, tt.t)
+		}
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	done, _ := time.Parse("2006-01-02", "2026-10-16")
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"DONE review inbox\nSCHEDULED: <2026-10-12 Mon .+1w>",
+			"DONE review inbox\nSCHEDULED: <2026-10-23 Fri .+1w>"},
+		{"DONE pay rent\nDEADLINE: <2026-10-01 Thu 09:00 ++1m>",
+			"DONE pay rent\nDEADLINE: <2026-11-01 Sun 09:00 ++1m>"},
+		{"[x] water plants 🔁 every week 📅 2026-10-14 ⏳ 2026-10-13 ✅ 2026-10-16",
+			"[x] water plants 🔁 every week 📅 2026-10-21 ⏳ 2026-10-20"},
+	}
+	for _, tt := range tests {
+		got, ok := NextOccurrence(tt.content, done)
+		if !ok || got != tt.want {
+			t.Errorf("NextOccurrence(%q) = %q, %v; want %q", tt.content, got, ok, tt.want)
+		}
+	}
+	if _, ok := NextOccurrence("DONE once\nSCHEDULED: <2026-10-12 Mon>", done); ok {
+		t.Error("NextOccurrence of a task that does not recur succeeded")
+	}
+}
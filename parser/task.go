@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// SCHEDULED: <2026-04-16 Thu> and DEADLINE: <2026-04-16 Thu 10:00 .+1w>
+	taskDatePattern = regexp.MustCompile(`(?m)^\s*(SCHEDULED|DEADLINE):\s*<(\d{4}-\d{2}-\d{2})([^>]*)>`)
+
+	// 📅 2026-04-16 (due), ⏳ (scheduled), 🛫 (start) and ✅ (done), as the
+	// Obsidian Tasks plugin writes them
+	taskEmojiPattern = regexp.MustCompile(`(📅|⏳|🛫|✅)\x{FE0F}?\s*(\d{4}-\d{2}-\d{2})`)
+
+	// ✅ 2026-04-16 with the space before it
+	completedEmojiPattern = regexp.MustCompile(`\s*✅\x{FE0F}?\s*\d{4}-\d{2}-\d{2}`)
+
+	// [ ] task, [x] done, [/] in progress, [-] cancelled — Markdown checkboxes
+	checkboxPattern = regexp.MustCompile(`^\[([ xX/-])\]\s`)
+)
+
+// TaskMarkers are the task markers a block can start with.
+var TaskMarkers = []string{"TODO", "DOING", "DONE", "LATER", "NOW", "WAITING", "CANCELLED"}
+
+// checkboxMarkers maps checkbox states to the task markers they stand for.
+var checkboxMarkers = map[string]string{" ": "TODO", "x": "DONE", "X": "DONE", "/": "DOING", "-": "CANCELLED"}
+
+// Task is the task a block holds: its marker and priority, and its dates
+// from Logseq SCHEDULED/DEADLINE lines or Obsidian Tasks emoji. Dates are
+// YYYY-MM-DD, except Completed, which is the block's completed:: property
+// as written when the block has one.
+type Task struct {
+	Marker    string `json:"marker"`
+	Priority  string `json:"priority,omitempty"`
+	Scheduled string `json:"scheduled,omitempty"`
+	Deadline  string `json:"deadline,omitempty"`
+	Start     string `json:"start,omitempty"`
+	Completed string `json:"completed,omitempty"`
+	// Repeat is the recurrence of a recurring task as written: a Logseq
+	// repeater (.+1w) or a Tasks plugin rule (every week); see
+	// ParseRecurrence.
+	Repeat string `json:"repeat,omitempty"`
+	// Checkbox is set for Markdown checkbox tasks ("[ ] ..."), whose
+	// marker is read from the checkbox.
+	Checkbox bool `json:"checkbox,omitempty"`
+}
+
+// ParseTask returns the task in a block's content, and false when the block
+// is not a task: it starts with neither a marker nor a checkbox.
+func ParseTask(content string) (Task, bool) {
+	parsed := Parse(content)
+	t := Task{Marker: parsed.Marker, Priority: parsed.Priority}
+	if t.Marker == "" {
+		m := checkboxPattern.FindStringSubmatch(content)
+		if m == nil {
+			return Task{}, false
+		}
+		t.Marker, t.Checkbox = checkboxMarkers[m[1]], true
+	}
+
+	for _, m := range taskDatePattern.FindAllStringSubmatch(content, -1) {
+		if m[1] == "SCHEDULED" {
+			t.Scheduled = m[2]
+		} else {
+			t.Deadline = m[2]
+		}
+		if fields := strings.Fields(m[3]); len(fields) > 0 && repeaterPattern.MatchString(fields[len(fields)-1]) {
+			t.Repeat = fields[len(fields)-1]
+		}
+	}
+	if m := recurrenceEmojiPattern.FindStringSubmatch(content); m != nil {
+		t.Repeat = strings.TrimSpace(m[1])
+	}
+	for _, m := range taskEmojiPattern.FindAllStringSubmatch(content, -1) {
+		switch m[1] {
+		case "📅":
+			t.Deadline = m[2]
+		case "⏳":
+			t.Scheduled = m[2]
+		case "🛫":
+			t.Start = m[2]
+		case "✅":
+			t.Completed = m[2]
+		}
+	}
+	if v, ok := parsed.Properties["completed"]; ok {
+		t.Completed = v
+	}
+	return t, true
+}
+
+// SetCheckbox returns content with its checkbox set to the state of marker
+// (TODO, DOING, DONE or CANCELLED), and false when the content has no
+// checkbox or the marker has no checkbox state.
+func SetCheckbox(content, marker string) (string, bool) {
+	if !checkboxPattern.MatchString(content) {
+		return content, false
+	}
+	for state, m := range checkboxMarkers {
+		if m == marker && state != "X" {
+			return "[" + state + "]" + content[3:], true
+		}
+	}
+	return content, false
+}
+
+// SetMarker returns content with its task marker replaced by marker, or
+// prefixed with it when the content has none. An empty marker removes it.
+func SetMarker(content, marker string) string {
+	rest := StripMarker(content)
+	if marker == "" {
+		return rest
+	}
+	return marker + " " + strings.TrimLeft(rest, " ")
+}
+
+// SetCompletedEmoji returns content with the ✅ date of its first line set
+// to date, or removed when date is empty.
+func SetCompletedEmoji(content, date string) string {
+	first, rest, multiline := strings.Cut(content, "\n")
+	first = completedEmojiPattern.ReplaceAllString(first, "")
+	if date != "" {
+		first += " ✅ " + date
+	}
+	if multiline {
+		return first + "\n" + rest
+	}
+	return first
+}
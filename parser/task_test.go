@@ -0,0 +1,52 @@
+package parser
+
+import "testing"
+
+func TestParseTask(t *testing.T) {
+	tests := []struct {
+		content string
+		want    Task
+		ok      bool
+	}{
+		{"TODO [#A] ship it\nSCHEDULED: <2026-10-17 Sat>\nDEADLINE: <2026-10-19 Mon 10:00 .+1w>",
+			Task{Marker: "TODO", Priority: "A", Scheduled: "2026-10-17", Deadline: "2026-10-19", Repeat: ".+1w"}, true},
+		{"DONE write docs\ncompleted:: 2026-10-16 14:03",
+			Task{Marker: "DONE", Completed: "2026-10-16 14:03"}, true},
+		{"[ ] write docs 📅 2026-10-20 ⏳ 2026-10-18 🛫 2026-10-10",
+			Task{Marker: "TODO", Scheduled: "2026-10-18", Deadline: "2026-10-20", Start: "2026-10-10", Checkbox: true}, true},
+		{"[ ] water plants 🔁 every week when done 📅 2026-10-20",
+			Task{Marker: "TODO", Deadline: "2026-10-20", Repeat: "every week when done", Checkbox: true}, true},
+		{"[x] done thing ✅ 2026-10-01", Task{Marker: "DONE", Completed: "2026-10-01", Checkbox: true}, true},
+		{"[/] started", Task{Marker: "DOING", Checkbox: true}, true},
+		{"a note about TODO lists", Task{}, false},
+		{"[link](Other.md)", Task{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseTask(tt.content)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseTask(%q) = %+v, %v; want %+v, %v", tt.content, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSetTaskContent(t *testing.T) {
+	if got := SetMarker("TODO [#A] ship it", "DOING"); got != "DOING [#A] ship it" {
+		t.Errorf("SetMarker = %q", got)
+	}
+	if got := SetMarker("ship it", "TODO"); got != "TODO ship it" {
+		t.Errorf("SetMarker on a plain block = %q", got)
+	}
+	if got, ok := SetCheckbox("[ ] write docs 📅 2026-10-20", "DONE"); !ok || got != "[x] write docs 📅 2026-10-20" {
+		t.Errorf("SetCheckbox = %q, %v", got, ok)
+	}
+	if _, ok := SetCheckbox("[ ] write docs", "LATER"); ok {
+		t.Error("SetCheckbox accepted LATER")
+	}
+	done := SetCompletedEmoji("[x] write docs 📅 2026-10-20\nnotes", "2026-10-16")
+	if done != "[x] write docs 📅 2026-10-20 ✅ 2026-10-16\nnotes" {
+		t.Errorf("SetCompletedEmoji = %q", done)
+	}
+	if got := SetCompletedEmoji(done, ""); got != "[x] write docs 📅 2026-10-20\nnotes" {
+		t.Errorf("SetCompletedEmoji removing = %q", got)
+	}
+}
@@ -0,0 +1,50 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// relativeDay reads a (between ...) date relative to the day now: today,
+// yesterday, tomorrow, an offset such as -7d, +2w, -1m or -1y, or a date
+// written YYYY-MM-DD or YYYYMMDD.
+func relativeDay(s string, now time.Time) (time.Time, error) {
+	switch s {
+	case "today", "now":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	}
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if len(s) >= 3 && (s[0] == '-' || s[0] == '+') {
+		n, err := strconv.Atoi(s[1 : len(s)-1])
+		if err == nil {
+			if s[0] == '-' {
+				n = -n
+			}
+			switch s[len(s)-1] {
+			case 'd':
+				return now.AddDate(0, 0, n), nil
+			case 'w':
+				return now.AddDate(0, 0, 7*n), nil
+			case 'm':
+				return now.AddDate(0, n, 0), nil
+			case 'y':
+				return now.AddDate(n, 0, 0), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q: use today, yesterday, tomorrow, an offset like -7d, +2w, -1m, -1y, or YYYY-MM-DD", s)
+}
+
+// journalDay returns t as a journal day number, YYYYMMDD.
+func journalDay(t time.Time) int {
+	return t.Year()*10000 + int(t.Month())*100 + t.Day()
+}
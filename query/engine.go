@@ -0,0 +1,563 @@
+package query
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/parser"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// maxTextHits bounds the full-text search behind a text filter.
+const maxTextHits = 10000
+
+// Engine runs queries against a backend. Filters are answered with the
+// backend's indexes where it has them — TagSearcher for tags,
+// PropertySearcher for block properties, FullTextSearcher for text — and
+// by reading blocks otherwise. The pages a query can match are worked out
+// first, so only their blocks are read.
+type Engine struct {
+	client backend.Backend
+	now    func() time.Time
+}
+
+// NewEngine creates an engine over c.
+func NewEngine(c backend.Backend) *Engine {
+	return &Engine{client: c, now: time.Now}
+}
+
+// Result is what a query matched: pages for queries made only of page
+// filters, otherwise blocks. Journal pages come first, newest first, then
+// the other pages by name; blocks are in document order within a page.
+type Result struct {
+	Pages     []PageMatch  `json:"pages,omitempty"`
+	Blocks    []BlockMatch `json:"blocks,omitempty"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+// PageMatch is a page a query matched.
+type PageMatch struct {
+	Name       string         `json:"name"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+// BlockMatch is a block a query matched.
+type BlockMatch struct {
+	Page     string `json:"page"`
+	UUID     string `json:"uuid"`
+	Content  string `json:"content"`
+	Marker   string `json:"marker,omitempty"`
+	Priority string `json:"priority,omitempty"`
+}
+
+// Run evaluates q and returns at most limit matches; limit <= 0 returns
+// them all.
+func (e *Engine) Run(ctx context.Context, q *Node, limit int) (*Result, error) {
+	all, err := e.client.GetAllPages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list pages: %w", err)
+	}
+	r := &run{
+		Engine:   e,
+		ctx:      ctx,
+		pages:    make(map[string]*types.PageEntity, len(all)),
+		keys:     make(map[string]string),
+		filters:  make(map[*Node]*filter),
+		pageMode: q.PageLevel(),
+	}
+	for i := range all {
+		if all[i].Name != "" {
+			r.pages[strings.ToLower(all[i].Name)] = &all[i]
+		}
+	}
+	if err := r.prepare(q); err != nil {
+		return nil, err
+	}
+
+	candidates := r.candidates(q)
+	var pages []*types.PageEntity
+	for name, page := range r.pages {
+		if candidates == nil || candidates[name] {
+			pages = append(pages, page)
+		}
+	}
+	slices.SortFunc(pages, func(a, b *types.PageEntity) int {
+		if (a.JournalDay != 0) != (b.JournalDay != 0) {
+			if a.JournalDay != 0 {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(b.JournalDay, a.JournalDay); c != 0 {
+			return c
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	res := &Result{}
+	for _, page := range pages {
+		if r.pageMode {
+			if r.matchPage(q, page) {
+				if limit > 0 && len(res.Pages) == limit {
+					res.Truncated = true
+					break
+				}
+				res.Pages = append(res.Pages, PageMatch{Name: pageName(page), Properties: page.Properties})
+			}
+			continue
+		}
+		blocks, err := e.client.GetPageBlocksTree(ctx, page.Name)
+		if err != nil {
+			return nil, fmt.Errorf("read page %s: %w", page.Name, err)
+		}
+		if !r.matchBlocks(q, page, blocks, nil, res, limit) {
+			break
+		}
+	}
+	return res, nil
+}
+
+// run is the state of one Run.
+type run struct {
+	*Engine
+	ctx      context.Context
+	pages    map[string]*types.PageEntity // lowercase name → page
+	keys     map[string]string            // page name → key, see key
+	filters  map[*Node]*filter
+	pageMode bool
+}
+
+// filter is what a filter was prepared into. blocks and pages are nil
+// when the filter is checked against each block and page in turn.
+type filter struct {
+	blocks  map[string]bool // UUIDs of the blocks an index matched
+	pages   map[string]bool // lowercase names of the pages it can match
+	targets map[string]bool // keys of the pages [[Page]] and (page ...) name
+	match   *parser.PropertyMatcher
+	from    int // journal days of (between ...)
+	to      int
+}
+
+// prepare works out the filters of q, asking the backend's indexes where
+// it has them.
+func (r *run) prepare(q *Node) error {
+	f := &filter{}
+	r.filters[q] = f
+	switch q.Kind {
+	case And, Or, Not:
+		for _, c := range q.Children {
+			if err := r.prepare(c); err != nil {
+				return err
+			}
+		}
+
+	case Ref:
+		f.targets = r.targetKeys(q.Args)
+		f.pages = r.referencingPages(q.Args[0])
+
+	case Tag:
+		if ts, ok := r.client.(backend.TagSearcher); ok {
+			results, err := ts.FindBlocksByTag(r.ctx, q.Args[0], false)
+			if err != nil {
+				return fmt.Errorf("%s: %w", q, err)
+			}
+			f.blocks, f.pages = make(map[string]bool), make(map[string]bool)
+			for _, tr := range results {
+				for _, b := range tr.Blocks {
+					f.blocks[b.UUID] = true
+					f.pages[strings.ToLower(tr.Page)] = true
+				}
+			}
+		}
+
+	case Text:
+		// The index only narrows the blocks to read; each is then checked
+		// for the text like on any other backend.
+		fts, ok := r.client.(backend.FullTextSearcher)
+		words := searchWords(q.Args[0])
+		if ok && words != "" {
+			hits, err := fts.FullTextSearch(r.ctx, words, maxTextHits)
+			if err != nil {
+				return fmt.Errorf("%s: %w", q, err)
+			}
+			if len(hits) >= maxTextHits {
+				break // there may be more: read every block
+			}
+			f.blocks, f.pages = make(map[string]bool), make(map[string]bool)
+			for _, h := range hits {
+				f.blocks[h.UUID] = true
+				f.pages[strings.ToLower(h.PageName)] = true
+			}
+		}
+
+	case Property:
+		if ps, ok := r.client.(backend.PropertySearcher); ok {
+			results, err := ps.FindByProperty(r.ctx, q.Args[0], q.Operator, q.Values)
+			if err != nil {
+				return fmt.Errorf("%s: %w", q, err)
+			}
+			f.blocks, f.pages = make(map[string]bool), make(map[string]bool)
+			for _, pr := range results {
+				if pr.Type == "block" {
+					f.blocks[pr.UUID] = true
+					f.pages[strings.ToLower(pr.Page)] = true
+				}
+			}
+			break
+		}
+		fallthrough
+
+	case PageProperty:
+		m, err := parser.NewPropertyMatcher(q.Operator, q.Values)
+		if err != nil {
+			return fmt.Errorf("%s: %w", q, err)
+		}
+		f.match = m
+		if q.Kind == PageProperty {
+			f.pages = r.pagesWhere(func(p *types.PageEntity) bool { return r.matchPage(q, p) })
+		}
+
+	case Page:
+		f.targets = r.targetKeys(q.Args)
+		f.pages = r.pagesWhere(func(p *types.PageEntity) bool { return r.matchPage(q, p) })
+
+	case Between:
+		now := r.now()
+		from, _ := relativeDay(q.Args[0], now)
+		to, _ := relativeDay(q.Args[1], now)
+		f.from, f.to = journalDay(from), journalDay(to)
+		if f.from > f.to {
+			f.from, f.to = f.to, f.from
+		}
+		f.pages = r.pagesWhere(func(p *types.PageEntity) bool { return r.matchPage(q, p) })
+	}
+	return nil
+}
+
+// referencingPages returns the pages whose blocks link to or tag page name,
+// or nil when the backend cannot tell.
+func (r *run) referencingPages(name string) map[string]bool {
+	raw, err := r.client.GetPageLinkedReferences(r.ctx, name)
+	if err != nil {
+		return nil
+	}
+	var refs [][]json.RawMessage
+	if err := json.Unmarshal(raw, &refs); err != nil {
+		return nil
+	}
+	pages := make(map[string]bool)
+	for _, ref := range refs {
+		var page types.PageEntity
+		if len(ref) == 0 || json.Unmarshal(ref[0], &page) != nil {
+			return nil
+		}
+		pages[strings.ToLower(page.Name)] = true
+	}
+	// Linked references hold [[links]]; tags come from the tag index where
+	// the backend keeps them apart.
+	if ts, ok := r.client.(backend.TagSearcher); ok {
+		results, err := ts.FindBlocksByTag(r.ctx, name, false)
+		if err != nil {
+			return nil
+		}
+		for _, tr := range results {
+			if len(tr.Blocks) > 0 {
+				pages[strings.ToLower(tr.Page)] = true
+			}
+		}
+	}
+	return pages
+}
+
+// pagesWhere returns the lowercase names of the pages keep accepts.
+func (r *run) pagesWhere(keep func(*types.PageEntity) bool) map[string]bool {
+	pages := make(map[string]bool)
+	for name, p := range r.pages {
+		if keep(p) {
+			pages[name] = true
+		}
+	}
+	return pages
+}
+
+// candidates returns the lowercase names of the pages q can match on, or
+// nil when it can match on any page.
+func (r *run) candidates(q *Node) map[string]bool {
+	switch q.Kind {
+	case And:
+		var pages map[string]bool
+		for _, c := range q.Children {
+			cp := r.candidates(c)
+			if cp == nil {
+				continue
+			}
+			if pages == nil {
+				pages = cp
+				continue
+			}
+			for name := range pages {
+				if !cp[name] {
+					delete(pages, name)
+				}
+			}
+		}
+		return pages
+	case Or:
+		pages := make(map[string]bool)
+		for _, c := range q.Children {
+			cp := r.candidates(c)
+			if cp == nil {
+				return nil
+			}
+			for name := range cp {
+				pages[name] = true
+			}
+		}
+		return pages
+	case Not:
+		return nil
+	}
+	if pages := r.filters[q].pages; pages != nil {
+		return copySet(pages)
+	}
+	return nil
+}
+
+// copySet copies a set, so candidates can narrow it.
+func copySet(set map[string]bool) map[string]bool {
+	c := make(map[string]bool, len(set))
+	for k := range set {
+		c[k] = true
+	}
+	return c
+}
+
+// matchPage reports whether page satisfies the page filters of q.
+func (r *run) matchPage(q *Node, page *types.PageEntity) bool {
+	f := r.filters[q]
+	switch q.Kind {
+	case And:
+		for _, c := range q.Children {
+			if !r.matchPage(c, page) {
+				return false
+			}
+		}
+		return true
+	case Or:
+		for _, c := range q.Children {
+			if r.matchPage(c, page) {
+				return true
+			}
+		}
+		return false
+	case Not:
+		for _, c := range q.Children {
+			if r.matchPage(c, page) {
+				return false
+			}
+		}
+		return true
+	case Page:
+		return f.targets[r.key(page.Name)] || f.targets[strings.ToLower(page.Name)]
+	case PageProperty:
+		v, ok := page.Properties[q.Args[0]]
+		return ok && f.match.Match(v)
+	case Between:
+		return page.JournalDay >= f.from && page.JournalDay <= f.to
+	}
+	return false
+}
+
+// blockContext is a block being matched, with the page it is on.
+type blockContext struct {
+	page   *types.PageEntity
+	block  *types.BlockEntity
+	parsed types.ParsedContent
+	marker string          // task marker, from a checkbox on Markdown tasks
+	refs   map[string]bool // keys of the pages it and its ancestors reference
+}
+
+// matchBlocks adds the blocks matching q to res, descending into children,
+// which inherit the references of parents. It returns false once res has
+// limit blocks.
+func (r *run) matchBlocks(q *Node, page *types.PageEntity, blocks []types.BlockEntity, inherited map[string]bool, res *Result, limit int) bool {
+	for i := range blocks {
+		b := &blocks[i]
+		bc := &blockContext{
+			page:   page,
+			block:  b,
+			parsed: parser.ParseInPage(b.Content, page.Name),
+			refs:   copySet(inherited),
+		}
+		if task, ok := parser.ParseTask(b.Content); ok {
+			bc.marker = task.Marker
+		}
+		bc.marker = cmp.Or(b.Marker, bc.marker)
+		for _, name := range append(bc.parsed.Links, bc.parsed.Tags...) {
+			bc.refs[strings.ToLower(name)] = true
+			bc.refs[r.key(name)] = true
+		}
+		if !b.PreBlock && r.matchBlock(q, bc) {
+			if limit > 0 && len(res.Blocks) == limit {
+				res.Truncated = true
+				return false
+			}
+			res.Blocks = append(res.Blocks, BlockMatch{
+				Page:     pageName(page),
+				UUID:     b.UUID,
+				Content:  b.Content,
+				Marker:   bc.marker,
+				Priority: cmp.Or(b.Priority, bc.parsed.Priority),
+			})
+		}
+		if !r.matchBlocks(q, page, b.Children, bc.refs, res, limit) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchBlock reports whether a block satisfies q.
+func (r *run) matchBlock(q *Node, bc *blockContext) bool {
+	f := r.filters[q]
+	b := bc.block
+	switch q.Kind {
+	case And:
+		for _, c := range q.Children {
+			if !r.matchBlock(c, bc) {
+				return false
+			}
+		}
+		return true
+	case Or:
+		for _, c := range q.Children {
+			if r.matchBlock(c, bc) {
+				return true
+			}
+		}
+		return false
+	case Not:
+		for _, c := range q.Children {
+			if r.matchBlock(c, bc) {
+				return false
+			}
+		}
+		return true
+	case Ref:
+		for key := range f.targets {
+			if bc.refs[key] {
+				return true
+			}
+		}
+		return false
+	case Tag:
+		if f.blocks != nil {
+			return f.blocks[b.UUID]
+		}
+		for _, t := range bc.parsed.Tags {
+			if strings.EqualFold(t, q.Args[0]) {
+				return true
+			}
+		}
+		return false
+	case Text:
+		if f.blocks != nil && !f.blocks[b.UUID] {
+			return false
+		}
+		return strings.Contains(strings.ToLower(b.Content), strings.ToLower(q.Args[0]))
+	case Task:
+		return slices.Contains(q.Args, bc.marker)
+	case Priority:
+		return slices.Contains(q.Args, strings.ToUpper(cmp.Or(b.Priority, bc.parsed.Priority)))
+	case Property:
+		if f.blocks != nil {
+			return f.blocks[b.UUID]
+		}
+		v, ok := blockProperties(b, bc.parsed)[q.Args[0]]
+		return ok && f.match.Match(v)
+	}
+	return r.matchPage(q, bc.page)
+}
+
+// searchWords returns a full-text query matching every block that
+// contains text, for an index of words that matches whole words and, with
+// a trailing *, word prefixes: the words inside text as they are, the last
+// one as a prefix unless text ends after it, and the first one only when
+// text starts before it, as it may otherwise be the end of a longer word.
+// It returns "" when that leaves no word to search for.
+func searchWords(text string) string {
+	text = strings.ToLower(text)
+	words := strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) })
+	first, _ := utf8.DecodeRuneInString(text)
+	last, _ := utf8.DecodeLastRuneInString(text)
+	var terms []string
+	for i, w := range words {
+		switch {
+		case i == 0 && isWordRune(first):
+		case i < len(words)-1 || !isWordRune(last):
+			terms = append(terms, `"`+w+`"`)
+		case !strings.HasPrefix(w, "-") && !strings.Contains(w, "__"):
+			// A prefix cannot be quoted, so one that would read as an
+			// exclusion, or as two words, is left out.
+			terms = append(terms, w+"*")
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// isWordRune reports whether r is part of a word for full-text search.
+func isWordRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r > 127
+}
+
+// blockProperties returns the properties of a block: those the backend
+// parsed, or else its key:: value lines.
+func blockProperties(b *types.BlockEntity, parsed types.ParsedContent) map[string]any {
+	if len(b.Properties) > 0 {
+		return b.Properties
+	}
+	props := make(map[string]any, len(parsed.Properties))
+	for k, v := range parsed.Properties {
+		props[k] = v
+	}
+	return props
+}
+
+// targetKeys returns the keys of the pages names refer to.
+func (r *run) targetKeys(names []string) map[string]bool {
+	keys := make(map[string]bool)
+	for _, name := range names {
+		keys[strings.ToLower(name)] = true
+		keys[r.key(name)] = true
+	}
+	return keys
+}
+
+// key returns the lowercase canonical name of the page name refers to, as
+// the backend resolves it, so that [[Spec]] and [[projects/Spec]] compare
+// equal where they name the same page.
+func (r *run) key(name string) string {
+	if k, ok := r.keys[name]; ok {
+		return k
+	}
+	k := strings.ToLower(name)
+	if pr, ok := r.client.(backend.PageResolver); ok {
+		if resolved, err := pr.ResolvePageName(r.ctx, name); err == nil && resolved != "" {
+			k = strings.ToLower(resolved)
+		}
+	}
+	r.keys[name] = k
+	return k
+}
+
+// pageName returns the name a page is displayed with.
+func pageName(p *types.PageEntity) string {
+	return cmp.Or(p.OriginalName, p.Name)
+}
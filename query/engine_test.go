@@ -0,0 +1,115 @@
+package query
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/vault"
+	"github.com/skridlevsky/graphthulhu/vault/vaulttest"
+)
+
+// plainBackend hides the vault's indexes, so every filter is checked
+// block by block.
+type plainBackend struct{ backend.Backend }
+
+func queryVault(t *testing.T) *vault.Client {
+	t.Helper()
+	c, _ := vaulttest.New(t, map[string]string{
+		"journals/2026-10-14.md": "- TODO ship [[Project]]\n  status:: active\n- DONE release [[Project]]\n- TODO unrelated\n",
+		"journals/2026-10-01.md": "- TODO stale [[Project]]\n  status:: active\n",
+		"Project.md":             "---\ntype: project\n---\n- overview #planning\n",
+		"Ideas.md":               "---\ntype: area\n---\n- notes on [[Project]]\n  - DOING draft outline\n    status:: active\n- pick a venue #planning [#A]\n- [-] dropped idea\n",
+	}, vault.WithDailyFolder("journals"))
+	return c
+}
+
+func TestRun(t *testing.T) {
+	c := queryVault(t)
+	tests := []struct {
+		query  string
+		blocks []string // first line of each block, in result order
+		pages  []string
+	}{
+		{query: `(and [[Project]] (task TODO DOING) (property status active) (between -7d today))`,
+			blocks: []string{"TODO ship [[Project]]"}},
+		// Children inherit the references of their parents.
+		{query: `(and [[Project]] (task TODO DOING))`,
+			blocks: []string{"TODO ship [[Project]]", "TODO stale [[Project]]", "DOING draft outline"}},
+		{query: `(and [[Project]] (not (task TODO DOING)))`,
+			blocks: []string{"DONE release [[Project]]", "notes on [[Project]]"}},
+		{query: `(and #planning (priority a))`, blocks: []string{"pick a venue #planning [#A]"}},
+		{query: `(or "overview" (and (page Ideas) (task DOING)))`,
+			blocks: []string{"DOING draft outline", "overview #planning"}},
+		{query: `(and (between 2026-10-01 2026-10-10) (task TODO))`, blocks: []string{"TODO stale [[Project]]"}},
+		// Markdown checkboxes are tasks too.
+		{query: `(task CANCELLED)`, blocks: []string{"[-] dropped idea"}},
+		{query: `(page-property type project area)`, pages: []string{"Ideas", "Project"}},
+		{query: `(and (page-property type) (not (page Ideas)))`, pages: []string{"Project"}},
+		{query: `(and (page-property type area) (task DOING))`, blocks: []string{"DOING draft outline"}},
+		// Text is a substring of the block, the same with or without a
+		// full-text index: inside words, and not in the page title.
+		{query: `"view"`, blocks: []string{"overview #planning"}},
+		{query: `"draft out"`, blocks: []string{"DOING draft outline"}},
+		{query: `"notes on [[proj"`, blocks: []string{"notes on [[Project]]"}},
+		{query: `"ideas notes"`},
+	}
+
+	for _, b := range []backend.Backend{c, plainBackend{c}} {
+		e := NewEngine(b)
+		e.now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+		for _, tt := range tests {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse(%s): %v", tt.query, err)
+			}
+			res, err := e.Run(context.Background(), q, 0)
+			if err != nil {
+				t.Fatalf("Run(%s): %v", tt.query, err)
+			}
+			var blocks, pages []string
+			for _, m := range res.Blocks {
+				line, _, _ := strings.Cut(m.Content, "\n")
+				blocks = append(blocks, line)
+			}
+			for _, m := range res.Pages {
+				pages = append(pages, m.Name)
+			}
+			if !slices.Equal(blocks, tt.blocks) || !slices.Equal(pages, tt.pages) {
+				t.Errorf("%T: Run(%s) = blocks %q pages %q, want blocks %q pages %q",
+					b, tt.query, blocks, pages, tt.blocks, tt.pages)
+			}
+		}
+	}
+}
+
+func TestRunLimit(t *testing.T) {
+	q, _ := Parse(`(task TODO)`)
+	res, err := NewEngine(queryVault(t)).Run(context.Background(), q, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Blocks) != 2 || !res.Truncated {
+		t.Errorf("got %d blocks, truncated %v; want 2, true", len(res.Blocks), res.Truncated)
+	}
+}
+
+func TestSearchWords(t *testing.T) {
+	// The first word may end a longer one and the last may start one.
+	for text, want := range map[string]string{
+		"meet":        "",
+		" meet":       "meet*",
+		"here notes":  "notes*",
+		"a b c":       `"b" c*`,
+		"ship [[proj": "proj*",
+		"x -opt":      "",
+		"(done) ":     `"done"`,
+	} {
+		if got := searchWords(text); got != want {
+			t.Errorf("searchWords(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
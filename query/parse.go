@@ -0,0 +1,333 @@
+// Package query evaluates simple queries, a small declarative language
+// modelled on Logseq's simple queries, against any backend.Backend:
+//
+//	(and [[Project]] (task TODO DOING) (property status active) (between -7d today))
+//
+// A query is a list of filters, all of which must hold:
+//
+//	[[Page]]                     blocks referencing Page, or under a block that does
+//	#tag                         blocks tagged #tag
+//	"text" or text               blocks containing the text, ignoring case
+//	(task TODO DOING ...)        blocks with one of the markers
+//	(priority a b ...)           blocks with one of the priorities
+//	(property key [op] [value])  blocks with a matching key:: value property
+//	(page-property key [op] [v]) pages with a matching page property
+//	(page name ...)              the blocks of the named pages
+//	(between from to)            blocks on the journal days from to to
+//	(and ...) (or ...) (not ...) combinations
+//
+// Property filters take the operators of parser.NewPropertyMatcher, written
+// bare after the key: (property estimate gt 3). Without one, a property
+// filter with no value matches any value and one with values matches any of
+// them. A query made only of page and page-property filters returns pages;
+// any other query returns blocks.
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/skridlevsky/graphthulhu/parser"
+)
+
+// Filter kinds.
+const (
+	And          = "and"
+	Or           = "or"
+	Not          = "not"
+	Ref          = "ref"
+	Tag          = "tag"
+	Text         = "text"
+	Task         = "task"
+	Priority     = "priority"
+	Property     = "property"
+	PageProperty = "page-property"
+	Page         = "page"
+	Between      = "between"
+)
+
+// Node is a parsed query: a combination of its Children for and, or and
+// not, otherwise a filter with its arguments.
+type Node struct {
+	Kind     string
+	Args     []string
+	Children []*Node
+
+	// Operator and Values of property and page-property filters; the key
+	// is Args[0].
+	Operator string
+	Values   []string
+}
+
+// Parse parses a query. Several top-level filters are combined with and.
+func Parse(s string) (*Node, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{toks: toks}
+	var nodes []*Node
+	for p.pos < len(p.toks) {
+		n, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	switch len(nodes) {
+	case 0:
+		return nil, fmt.Errorf("empty query")
+	case 1:
+		return nodes[0], nil
+	}
+	return &Node{Kind: And, Children: nodes}, nil
+}
+
+// String renders the node back into the query language.
+func (n *Node) String() string {
+	switch n.Kind {
+	case Ref:
+		return "[[" + n.Args[0] + "]]"
+	case Tag:
+		return "#" + n.Args[0]
+	case Text:
+		return quote(n.Args[0])
+	}
+	parts := []string{n.Kind}
+	for _, c := range n.Children {
+		parts = append(parts, c.String())
+	}
+	if n.Kind == Property || n.Kind == PageProperty {
+		parts = append(parts, quote(n.Args[0]), n.Operator)
+		for _, v := range n.Values {
+			parts = append(parts, quote(v))
+		}
+	} else {
+		for _, a := range n.Args {
+			parts = append(parts, quote(a))
+		}
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// PageLevel reports whether n only filters pages, so that it returns pages
+// rather than blocks.
+func (n *Node) PageLevel() bool {
+	switch n.Kind {
+	case Page, PageProperty:
+		return true
+	case And, Or, Not:
+		for _, c := range n.Children {
+			if !c.PageLevel() {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// token is a lexical token. quoted is set for "strings", which are never
+// read as operators or keywords.
+type token struct {
+	kind   byte // '(', ')', '[' for [[links]], '#' for tags, 'a' for atoms
+	text   string
+	quoted bool
+}
+
+// tokenize splits a query into tokens.
+func tokenize(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '(' || c == ')':
+			toks = append(toks, token{kind: c})
+			i++
+		case strings.HasPrefix(s[i:], "[["), strings.HasPrefix(s[i:], "#[["):
+			start := strings.Index(s[i:], "[[") + i + 2
+			end := strings.Index(s[start:], "]]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [[ at offset %d", i)
+			}
+			kind := byte('[')
+			if c == '#' {
+				kind = '#'
+			}
+			toks = append(toks, token{kind: kind, text: strings.TrimSpace(s[start : start+end])})
+			i = start + end + 2
+		case c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				b.WriteByte(s[j])
+			}
+			if j == len(s) {
+				return nil, fmt.Errorf("unclosed string at offset %d", i)
+			}
+			toks = append(toks, token{kind: 'a', text: b.String(), quoted: true})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r,()\"", rune(s[j])) {
+				j++
+			}
+			word := s[i:j]
+			if strings.HasPrefix(word, "#") && len(word) > 1 {
+				toks = append(toks, token{kind: '#', text: word[1:]})
+			} else {
+				toks = append(toks, token{kind: 'a', text: word})
+			}
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+type queryParser struct {
+	toks []token
+	pos  int
+}
+
+// node parses one filter or combination.
+func (p *queryParser) node() (*Node, error) {
+	t := p.toks[p.pos]
+	p.pos++
+	switch t.kind {
+	case '[':
+		if t.text == "" {
+			return nil, fmt.Errorf("empty [[]] link")
+		}
+		return &Node{Kind: Ref, Args: []string{t.text}}, nil
+	case '#':
+		return &Node{Kind: Tag, Args: []string{t.text}}, nil
+	case 'a':
+		return &Node{Kind: Text, Args: []string{t.text}}, nil
+	case ')':
+		return nil, fmt.Errorf("unexpected )")
+	}
+
+	if p.pos == len(p.toks) || p.toks[p.pos].kind != 'a' || p.toks[p.pos].quoted {
+		return nil, fmt.Errorf("( must be followed by a filter name")
+	}
+	n := &Node{Kind: strings.ToLower(p.toks[p.pos].text)}
+	p.pos++
+	var args []token
+	for {
+		if p.pos == len(p.toks) {
+			return nil, fmt.Errorf("missing ) after (%s", n.Kind)
+		}
+		t := p.toks[p.pos]
+		if t.kind == ')' {
+			p.pos++
+			break
+		}
+		switch n.Kind {
+		case And, Or, Not:
+			child, err := p.node()
+			if err != nil {
+				return nil, err
+			}
+			n.Children = append(n.Children, child)
+			continue
+		}
+		if t.kind == '(' {
+			return nil, fmt.Errorf("(%s takes values, not filters", n.Kind)
+		}
+		args = append(args, t)
+		p.pos++
+	}
+	return n, n.setArgs(args)
+}
+
+// setArgs checks and stores the arguments of a filter.
+func (n *Node) setArgs(args []token) error {
+	for _, a := range args {
+		n.Args = append(n.Args, a.text)
+	}
+	switch n.Kind {
+	case And, Or, Not:
+		if len(n.Children) == 0 {
+			return fmt.Errorf("(%s) needs at least one filter", n.Kind)
+		}
+	case Task:
+		if len(n.Args) == 0 {
+			return fmt.Errorf("(task) needs at least one marker")
+		}
+		for i, m := range n.Args {
+			n.Args[i] = strings.ToUpper(m)
+			if !slices.Contains(parser.TaskMarkers, n.Args[i]) {
+				return fmt.Errorf("unknown task marker %q (use %s)", m, strings.Join(parser.TaskMarkers, ", "))
+			}
+		}
+	case Priority:
+		if len(n.Args) == 0 {
+			return fmt.Errorf("(priority) needs at least one of a, b, c")
+		}
+		for i, pr := range n.Args {
+			n.Args[i] = strings.ToUpper(pr)
+			if n.Args[i] != "A" && n.Args[i] != "B" && n.Args[i] != "C" {
+				return fmt.Errorf("unknown priority %q (use a, b, c)", pr)
+			}
+		}
+	case Property, PageProperty:
+		if len(args) == 0 {
+			return fmt.Errorf("(%s) needs a key", n.Kind)
+		}
+		n.Args = n.Args[:1]
+		values := args[1:]
+		switch {
+		case len(values) > 0 && !values[0].quoted && slices.Contains(parser.PropertyOperators, values[0].text):
+			n.Operator = values[0].text
+			values = values[1:]
+		case len(values) == 0:
+			n.Operator = "exists"
+		case len(values) == 1:
+			n.Operator = "eq"
+		default:
+			n.Operator = "in"
+		}
+		for _, v := range values {
+			n.Values = append(n.Values, v.text)
+		}
+		if _, err := parser.NewPropertyMatcher(n.Operator, n.Values); err != nil {
+			return fmt.Errorf("(%s %s): %w", n.Kind, n.Args[0], err)
+		}
+	case Page:
+		if len(n.Args) == 0 {
+			return fmt.Errorf("(page) needs a page name")
+		}
+	case Between:
+		if len(n.Args) != 2 {
+			return fmt.Errorf("(between) needs a start and an end date")
+		}
+		for _, d := range n.Args {
+			if _, err := relativeDay(d, time.Now()); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown filter (%s)", n.Kind)
+	}
+	for _, a := range args {
+		if a.kind != 'a' && n.Kind != Page {
+			return fmt.Errorf("(%s) takes plain values, not links or tags", n.Kind)
+		}
+	}
+	return nil
+}
+
+// quote writes s as a query string when it is not a plain word.
+func quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n\r,()\"#[]") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
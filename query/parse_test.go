@@ -0,0 +1,83 @@
+package query
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`(and [[Project]] (task todo DOING) (property status active) (between -7d today))`,
+			`(and [[Project]] (task TODO DOING) (property status eq active) (between -7d today))`},
+		{`[[Project]] #planning "two words"`, `(and [[Project]] #planning "two words")`},
+		{`#[[multi word]]`, `#multi word`},
+		{`(property estimate gt 3)`, `(property estimate gt 3)`},
+		{`(property status "gt")`, `(property status eq gt)`},
+		{`(property status)`, `(property status exists)`},
+		{`(page-property type project area)`, `(page-property type in project area)`},
+		{`(or (page [[Ideas]] Project) (not (priority a b)))`, `(or (page Ideas Project) (not (priority A B)))`},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", tt.query, err)
+			continue
+		}
+		if got := q.String(); got != tt.want {
+			t.Errorf("Parse(%s) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		``:                              "empty query",
+		`(and [[Project]]`:              "missing )",
+		`(task SOMEDAY)`:                "unknown task marker",
+		`(priority d)`:                  "unknown priority",
+		`(property)`:                    "needs a key",
+		`(property estimate between 1)`: "between needs 2 value(s)",
+		`(between -7d)`:                 "needs a start and an end",
+		`(between -7x today)`:           "invalid date",
+		`(sometime)`:                    "unknown filter",
+		`(task (and TODO))`:             "takes values, not filters",
+		`"open`:                         "unclosed string",
+		`[[Project`:                     "unclosed [[",
+		`)`:                             "unexpected )",
+	}
+	for query, want := range tests {
+		_, err := Parse(query)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%s) error = %v, want %q", query, err, want)
+		}
+	}
+}
+
+func TestRelativeDay(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	tests := map[string]int{
+		"today":      20261016,
+		"yesterday":  20261015,
+		"tomorrow":   20261017,
+		"-7d":        20261009,
+		"+2w":        20261030,
+		"-1m":        20260916,
+		"-1y":        20251016,
+		"2026-01-31": 20260131,
+		"20260131":   20260131,
+	}
+	for s, want := range tests {
+		d, err := relativeDay(s, now)
+		if err != nil {
+			t.Errorf("relativeDay(%s): %v", s, err)
+			continue
+		}
+		if got := journalDay(d); got != want {
+			t.Errorf("relativeDay(%s) = %d, want %d", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/tools"
+)
+
+// scope is what a client may do: read only or also write, on the whole
+// graph or on the pages of one namespace.
+type scope struct {
+	readOnly  bool
+	namespace string
+}
+
+// toolTargets names the arguments of a tool that say which pages and
+// blocks it reads or writes.
+type toolTargets struct {
+	pages     []string // page names
+	pageLists []string // lists of page names
+	prefixes  []string // page name prefixes
+	blocks    []string // block UUIDs
+	batch     bool     // operations of apply_batch, each checked in turn
+}
+
+// namespaceTools are the tools a namespace-restricted client may call:
+// those that only touch the pages and blocks their arguments name. Tools
+// that search or analyze the whole graph, journals, and the operation log
+// are left out.
+var namespaceTools = map[string]toolTargets{
+	"get_page":               {pages: []string{"name"}},
+	"get_block":              {blocks: []string{"uuid"}},
+	"list_pages":             {prefixes: []string{"namespace"}},
+	"create_page":            {pages: []string{"name"}},
+	"append_blocks":          {pages: []string{"page"}},
+	"upsert_blocks":          {pages: []string{"page"}},
+	"update_block":           {blocks: []string{"uuid"}},
+	"delete_block":           {blocks: []string{"uuid"}},
+	"move_block":             {blocks: []string{"uuid", "targetUuid"}},
+	"delete_page":            {pages: []string{"name"}},
+	"rename_page":            {pages: []string{"oldName", "newName"}},
+	"bulk_update_properties": {pageLists: []string{"pages"}},
+	"link_pages":             {pages: []string{"from", "to"}},
+	"apply_batch":            {batch: true},
+	"page_history":           {pages: []string{"page"}},
+	"restore_page_version":   {pages: []string{"page"}},
+	"set_task_state":         {blocks: []string{"uuid"}},
+	"health":                 {},
+}
+
+// restrictToNamespace confines the clients of srv to the pages of
+// namespace: every tool call and resource read is checked before it
+// reaches its handler, and tools, resources and prompts that reach beyond
+// the namespace are hidden and refused.
+func restrictToNamespace(srv *mcp.Server, b backend.Backend, namespace string) {
+	g := &namespaceGuard{client: b, namespace: namespace}
+	srv.AddReceivingMiddleware(g.middleware)
+}
+
+// namespaceGuard checks requests against a namespace.
+type namespaceGuard struct {
+	client    backend.Backend
+	namespace string
+}
+
+func (g *namespaceGuard) middleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		switch method {
+		case "tools/call":
+			params := req.GetParams().(*mcp.CallToolParamsRaw)
+			if err := g.checkTool(ctx, params.Name, params.Arguments); err != nil {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}},
+					IsError: true,
+				}, nil
+			}
+			ctx = tools.WithPageScope(ctx, func(name string) bool {
+				return inNamespace(name, g.namespace)
+			})
+		case "resources/read":
+			if err := g.checkURI(ctx, req.GetParams().(*mcp.ReadResourceParams).URI); err != nil {
+				return nil, err
+			}
+		case "resources/subscribe":
+			if err := g.checkURI(ctx, req.GetParams().(*mcp.SubscribeParams).URI); err != nil {
+				return nil, err
+			}
+		case "prompts/get":
+			return nil, fmt.Errorf("prompts are not available to clients restricted to namespace %s", g.namespace)
+		}
+
+		res, err := next(ctx, method, req)
+		if err != nil {
+			return res, err
+		}
+		switch res := res.(type) {
+		case *mcp.CallToolResult:
+			g.filterBacklinks(res)
+		case *mcp.ListToolsResult:
+			var allowed []*mcp.Tool
+			for _, t := range res.Tools {
+				if _, ok := namespaceTools[t.Name]; ok {
+					allowed = append(allowed, t)
+				}
+			}
+			res.Tools = allowed
+		case *mcp.ListResourceTemplatesResult:
+			var allowed []*mcp.ResourceTemplate
+			for _, t := range res.ResourceTemplates {
+				if t.URITemplate == tools.PageURITemplate || t.URITemplate == tools.BlockURITemplate {
+					allowed = append(allowed, t)
+				}
+			}
+			res.ResourceTemplates = allowed
+		case *mcp.ListPromptsResult:
+			res.Prompts = []*mcp.Prompt{}
+		}
+		return res, nil
+	}
+}
+
+// checkTool returns an error unless the tool may be called with args.
+func (g *namespaceGuard) checkTool(ctx context.Context, name string, raw json.RawMessage) error {
+	targets, ok := namespaceTools[name]
+	if !ok {
+		return fmt.Errorf("%s is not available to clients restricted to namespace %s", name, g.namespace)
+	}
+	args := map[string]any{}
+	if len(raw) > 0 {
+		var err error
+		if args, err = strictArgs(raw); err != nil {
+			return fmt.Errorf("invalid arguments: %v", err)
+		}
+	}
+	return g.checkArgs(ctx, name, targets, args)
+}
+
+func (g *namespaceGuard) checkArgs(ctx context.Context, tool string, targets toolTargets, args map[string]any) error {
+	arg := func(key string) (any, error) {
+		v, err := exactArg(args, key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", tool, err)
+		}
+		return v, nil
+	}
+	v, err := arg("fuzzy")
+	if err != nil {
+		return err
+	}
+	if fuzzy, _ := v.(bool); fuzzy {
+		// The closest page could be anywhere in the graph.
+		return fmt.Errorf("fuzzy is not available to clients restricted to namespace %s", g.namespace)
+	}
+	for _, key := range targets.pages {
+		v, err := arg(key)
+		if err != nil {
+			return err
+		}
+		name, _ := v.(string)
+		if err := g.checkPage(ctx, name); err != nil {
+			return fmt.Errorf("%s: %s: %w", tool, key, err)
+		}
+	}
+	for _, key := range targets.pageLists {
+		v, err := arg(key)
+		if err != nil {
+			return err
+		}
+		list, _ := v.([]any)
+		for _, v := range list {
+			name, _ := v.(string)
+			if err := g.checkPage(ctx, name); err != nil {
+				return fmt.Errorf("%s: %s: %w", tool, key, err)
+			}
+		}
+	}
+	for _, key := range targets.prefixes {
+		v, err := arg(key)
+		if err != nil {
+			return err
+		}
+		prefix, _ := v.(string)
+		if !strings.HasPrefix(strings.ToLower(prefix), strings.ToLower(g.namespace)+"/") {
+			return fmt.Errorf("%s: %s must start with %s/", tool, key, g.namespace)
+		}
+	}
+	for _, key := range targets.blocks {
+		v, err := arg(key)
+		if err != nil {
+			return err
+		}
+		uuid, _ := v.(string)
+		if err := g.checkBlock(ctx, uuid); err != nil {
+			return fmt.Errorf("%s: %s: %w", tool, key, err)
+		}
+	}
+	if targets.batch {
+		v, err := arg("operations")
+		if err != nil {
+			return err
+		}
+		ops, _ := v.([]any)
+		for i, v := range ops {
+			op, _ := v.(map[string]any)
+			step := fmt.Sprintf("operation %d", i+1)
+			toolArg, err := exactArg(op, "tool")
+			if err != nil {
+				return fmt.Errorf("%s: %w", step, err)
+			}
+			inputArg, err := exactArg(op, "input")
+			if err != nil {
+				return fmt.Errorf("%s: %w", step, err)
+			}
+			name, _ := toolArg.(string)
+			input, _ := inputArg.(map[string]any)
+			opTargets, ok := namespaceTools[name]
+			if !ok || opTargets.batch {
+				return fmt.Errorf("%s: %q is not available to clients restricted to namespace %s", step, name, g.namespace)
+			}
+			if err := g.checkArgs(ctx, fmt.Sprintf("%s (%s)", step, name), opTargets, input); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// strictArgs decodes tool arguments, refusing any object, at any depth,
+// with two keys that are equal ignoring case. Handlers decode arguments
+// with encoding/json, which matches keys case-insensitively and keeps the
+// last of duplicates, so such arguments could reach a handler with other
+// values than the ones checked.
+func strictArgs(raw json.RawMessage) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if err := checkKeys(dec); err != nil {
+		return nil, err
+	}
+	args := map[string]any{}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// checkKeys reads one JSON value from dec, returning an error if an
+// object in it repeats a key ignoring case.
+func checkKeys(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		var keys []string
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			for _, k := range keys {
+				if strings.EqualFold(k, key) {
+					return fmt.Errorf("duplicate key %q", key)
+				}
+			}
+			keys = append(keys, key)
+			if err := checkKeys(dec); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		for dec.More() {
+			if err := checkKeys(dec); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	}
+	return err
+}
+
+// exactArg returns the argument named key, refusing it under a key that
+// differs only in case, which the guard would miss and the handler read.
+func exactArg(args map[string]any, key string) (any, error) {
+	for k, v := range args {
+		if strings.EqualFold(k, key) {
+			if k != key {
+				return nil, fmt.Errorf("unknown argument %q (did you mean %q?)", k, key)
+			}
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// backlinkFields are the tool result fields listing the blocks of other
+// pages that link to a page: get_page's backlinks and the links a
+// delete_page dry run would leave dangling.
+var backlinkFields = []string{"backlinks", "linkedFrom"}
+
+// filterBacklinks drops the backlinks from pages outside the namespace
+// from a tool result, as they carry those pages' block content.
+func (g *namespaceGuard) filterBacklinks(res *mcp.CallToolResult) {
+	if res.IsError || len(res.Content) == 0 {
+		return
+	}
+	text, ok := res.Content[0].(*mcp.TextContent)
+	if !ok {
+		return
+	}
+	var result map[string]any
+	if json.Unmarshal([]byte(text.Text), &result) != nil {
+		return
+	}
+	changed := false
+	for _, field := range backlinkFields {
+		links, ok := result[field].([]any)
+		if !ok {
+			continue
+		}
+		kept := []any{}
+		for _, l := range links {
+			link, _ := l.(map[string]any)
+			if name, _ := link["pageName"].(string); inNamespace(name, g.namespace) {
+				kept = append(kept, l)
+			}
+		}
+		if len(kept) == len(links) {
+			continue
+		}
+		result[field] = kept
+		if count, ok := result["linkCount"].(float64); ok && field == "backlinks" {
+			result["linkCount"] = int(count) - len(links) + len(kept)
+		}
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	if data, err := json.MarshalIndent(result, "", "  "); err == nil {
+		text.Text = string(data)
+	}
+}
+
+// checkURI returns an error unless the resource is a page or block of the
+// namespace.
+func (g *namespaceGuard) checkURI(ctx context.Context, uri string) error {
+	if name, ok := tools.PageFromURI(uri); ok {
+		return g.checkPage(ctx, name)
+	}
+	if uuid, ok := tools.BlockFromURI(uri); ok {
+		return g.checkBlock(ctx, uuid)
+	}
+	return fmt.Errorf("%s is not available to clients restricted to namespace %s", uri, g.namespace)
+}
+
+// checkPage returns an error unless page name is in the namespace. Names
+// are resolved first on backends where a link can name a page by part of
+// its path.
+func (g *namespaceGuard) checkPage(ctx context.Context, name string) error {
+	if r, ok := g.client.(backend.PageResolver); ok {
+		if resolved, err := r.ResolvePageName(ctx, name); err == nil && resolved != "" {
+			name = resolved
+		}
+	}
+	if !inNamespace(name, g.namespace) {
+		return fmt.Errorf("page %q is outside namespace %s", name, g.namespace)
+	}
+	return nil
+}
+
+// checkBlock returns an error unless the block is on a page of the
+// namespace.
+func (g *namespaceGuard) checkBlock(ctx context.Context, uuid string) error {
+	block, err := g.client.GetBlock(ctx, uuid)
+	if err != nil || block == nil || block.Page == nil {
+		return fmt.Errorf("block %q not found", uuid)
+	}
+	name := block.Page.Name
+	if name == "" {
+		page, err := g.client.GetPage(ctx, block.Page.ID)
+		if err != nil || page == nil {
+			return fmt.Errorf("block %q not found", uuid)
+		}
+		name = page.Name
+	}
+	if !inNamespace(name, g.namespace) {
+		return fmt.Errorf("block %q is outside namespace %s", uuid, g.namespace)
+	}
+	return nil
+}
+
+// inNamespace reports whether page name is namespace or one of its pages.
+// Page names are case-insensitive. Names with . or .. segments are never
+// in a namespace, as file-based backends would resolve them elsewhere.
+func inNamespace(name, namespace string) bool {
+	name, namespace = strings.ToLower(name), strings.ToLower(namespace)
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return name == namespace || strings.HasPrefix(name, namespace+"/")
+}
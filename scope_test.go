@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/tools"
+	"github.com/skridlevsky/graphthulhu/vault/vaulttest"
+)
+
+func TestNamespaceScope(t *testing.T) {
+	ctx := context.Background()
+	vc, dir := vaulttest.New(t, map[string]string{
+		"team/Plan.md":  "- ship it\n",
+		"Secret.md":     "- hidden\n- see [[team/Plan]] for the hidden plan\n",
+		"team/Notes.md": "- about [[team/Plan]]\n",
+		"teamwork.md":   "- not the team\n",
+	})
+	t.Cleanup(func() { vc.Close() })
+
+	srv := newServer(vc, false, nil, nil)
+	restrictToNamespace(srv, vc, "team")
+	session, err := connectInProcess(ctx, srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	call := func(name string, args map[string]any) (string, bool) {
+		t.Helper()
+		res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Content[0].(*mcp.TextContent).Text, !res.IsError
+	}
+	secret, _ := vc.GetPageBlocksTree(ctx, "Secret")
+	plan, _ := vc.GetPageBlocksTree(ctx, "team/Plan")
+
+	for _, tc := range []struct {
+		tool string
+		args map[string]any
+		ok   bool
+	}{
+		{"get_page", map[string]any{"name": "team/Plan"}, true},
+		{"get_page", map[string]any{"name": "Plan"}, true}, // resolves to team/Plan
+		{"get_page", map[string]any{"name": "Secret"}, false},
+		{"get_page", map[string]any{"name": "team/../Secret"}, false},
+		{"get_page", map[string]any{"name": "team/Plan", "fuzzy": true}, false},
+		{"get_block", map[string]any{"uuid": plan[0].UUID}, true},
+		{"get_block", map[string]any{"uuid": secret[0].UUID}, false},
+		{"list_pages", map[string]any{"namespace": "team/"}, true},
+		{"list_pages", map[string]any{"namespace": "team"}, false}, // would match teamwork
+		{"list_pages", map[string]any{}, false},
+		{"search", map[string]any{"query": "hidden"}, false},
+		{"append_blocks", map[string]any{"page": "team/Plan", "blocks": []string{"more"}}, true},
+		{"move_block", map[string]any{"uuid": plan[0].UUID, "targetUuid": secret[0].UUID}, false},
+		{"link_pages", map[string]any{"from": "team/Plan", "to": "Secret"}, false},
+		{"apply_batch", map[string]any{"operations": []map[string]any{
+			{"tool": "append_blocks", "input": map[string]any{"page": "team/Plan", "blocks": []string{"ok"}}},
+			{"tool": "delete_page", "input": map[string]any{"name": "Secret"}},
+		}}, false},
+	} {
+		if text, ok := call(tc.tool, tc.args); ok != tc.ok {
+			t.Errorf("%s %v: allowed = %v, want %v: %s", tc.tool, tc.args, ok, tc.ok, text)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Secret.md")); err != nil {
+		t.Error("the rejected batch deleted a page outside the namespace")
+	}
+
+	// Backlinks from pages outside the namespace are left out.
+	for _, args := range []map[string]any{{"name": "team/Plan"}, {"name": "team/Plan", "dryRun": true}} {
+		tool := "get_page"
+		if args["dryRun"] != nil {
+			tool = "delete_page"
+		}
+		text, ok := call(tool, args)
+		if !ok {
+			t.Fatalf("%s: %s", tool, text)
+		}
+		if strings.Contains(text, "hidden plan") || !strings.Contains(text, "team/Notes") {
+			t.Errorf("%s backlinks: %s", tool, text)
+		}
+	}
+
+	// "Did you mean" suggestions only name pages of the namespace.
+	if text, _ := call("get_page", map[string]any{"name": "team/work"}); strings.Contains(text, "teamwork") {
+		t.Errorf("suggestions name a page outside the namespace: %s", text)
+	}
+	if text, _ := call("get_page", map[string]any{"name": "team/Pln"}); !strings.Contains(text, "team/Plan") {
+		t.Errorf("suggestions miss the namespace's page: %s", text)
+	}
+
+	// Handlers match keys ignoring case and keep the last duplicate, so
+	// arguments the guard might read differently are refused.
+	for _, raw := range []string{
+		`{"name":"team/Plan","Name":"Secret"}`,
+		`{"name":"team/Plan","name":"Secret"}`,
+		`{"operations":[{"tool":"delete_page","input":{"name":"team/Plan","NAME":"Secret"}}]}`,
+		`{"Operations":[{"tool":"delete_page","input":{"name":"Secret"}}]}`,
+		`{"operations":[{"tool":"health","Tool":"delete_page","input":{"name":"Secret"}}]}`,
+		`{"page":"team/Plan","Page":"Secret","blocks":[{"content":"x"}]}`,
+	} {
+		tool := "delete_page"
+		switch {
+		case strings.Contains(raw, "peration"):
+			tool = "apply_batch"
+		case strings.Contains(raw, "blocks"):
+			tool = "upsert_blocks"
+		}
+		res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: tool, Arguments: json.RawMessage(raw)})
+		if err == nil && !res.IsError {
+			t.Errorf("%s %s allowed", tool, raw)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Secret.md")); err != nil {
+		t.Error("arguments with case-variant keys deleted a page outside the namespace")
+	}
+	if blocks, _ := vc.GetPageBlocksTree(ctx, "Secret"); len(blocks) != 2 {
+		t.Errorf("Secret has %d blocks, want 2", len(blocks))
+	}
+
+	// Tools, resources and prompts beyond the namespace are not listed.
+	if tool, _ := findTool(ctx, session, "search"); tool != nil {
+		t.Error("search is listed")
+	}
+	if tool, _ := findTool(ctx, session, "get_page"); tool == nil {
+		t.Error("get_page is not listed")
+	}
+	for prompt, err := range session.Prompts(ctx, nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Errorf("prompt %s is listed", prompt.Name)
+	}
+	for tmpl, err := range session.ResourceTemplates(ctx, nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(tmpl.URITemplate, "journal") {
+			t.Errorf("%s is listed", tmpl.URITemplate)
+		}
+	}
+
+	if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: tools.PageURI("team/Plan")}); err != nil {
+		t.Error(err)
+	}
+	if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: tools.PageURI("Secret")}); err == nil {
+		t.Error("read a page outside the namespace")
+	}
+	if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "graph://journal/2026-01-31"}); err == nil {
+		t.Error("read a journal")
+	}
+}
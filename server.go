@@ -7,6 +7,8 @@ import (
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/client"
+	"github.com/skridlevsky/graphthulhu/oplog"
 	"github.com/skridlevsky/graphthulhu/tools"
 	"github.com/skridlevsky/graphthulhu/vault"
 )
@@ -14,33 +16,51 @@ import (
 // newServer creates and configures the MCP server with all tools registered.
 // If readOnly is true, write tools are not registered.
 // Tools requiring DataScript are only registered if the backend supports it.
-func newServer(b backend.Backend, readOnly bool) *mcp.Server {
+// Writes are recorded in ops for the undo tools; a nil ops disables both.
+// Page resources can be subscribed to when subs is non-nil; the caller
+// reports page changes to it.
+func newServer(b backend.Backend, readOnly bool, ops *oplog.Log, subs *tools.PageSubscriptions) *mcp.Server {
+	var opts *mcp.ServerOptions
+	if subs != nil {
+		opts = &mcp.ServerOptions{
+			SubscribeHandler:   subs.Subscribe,
+			UnsubscribeHandler: subs.Unsubscribe,
+		}
+	}
 	srv := mcp.NewServer(
 		&mcp.Implementation{
 			Name:    "graphthulhu",
 			Version: version,
 		},
-		nil,
+		opts,
 	)
 
 	_, hasDataScript := b.(backend.HasDataScript)
+	_, logseqAPI := b.(*client.Client)
 
 	nav := tools.NewNavigate(b)
 	search := tools.NewSearch(b)
 	analyze := tools.NewAnalyze(b)
 	journal := tools.NewJournal(b)
 
+	tasks := tools.NewTasks(b, ops)
+
 	var write *tools.Write
 	var decision *tools.Decision
 	if !readOnly {
-		write = tools.NewWrite(b)
-		decision = tools.NewDecision(b)
+		write = tools.NewWrite(b, ops)
+		decision = tools.NewDecision(b, ops)
+	}
+
+	irreversible := " This is irreversible."
+	if ops != nil {
+		irreversible = " Can be reverted with undo_last or undo_operation."
 	}
 
 	// --- Navigate tools (all backends) ---
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "get_page",
-		Description: "Get a Logseq page with its full recursive block tree, properties, tags, and parsed links. Every block includes extracted [[links]], ((references)), #tags, and key:: value properties. Use maxBlocks to limit output size for large pages.",
+		Description: "Get a Logseq page with its full recursive block tree, properties, tags, and parsed links. Every block includes extracted [[links]], ((references)), #tags, and key:: value properties. Use maxBlocks to limit output size for large pages. If the page is not found, the error suggests similar page names; set fuzzy to open the closest match instead.",
 	}, nav.GetPage)
 
 	mcp.AddTool(srv, &mcp.Tool{
@@ -60,7 +80,7 @@ func newServer(b backend.Backend, readOnly bool) *mcp.Server {
 
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "traverse",
-		Description: "Find paths between two pages through the link graph using BFS. Discovers how concepts are connected through intermediate pages. Returns all paths up to max_hops length.",
+		Description: "Find paths between two pages through the link graph using BFS. Discovers how concepts are connected through intermediate pages. Returns all paths up to max_hops length. Misspelled page names get suggestions, or resolve to the closest match with fuzzy.",
 	}, nav.Traverse)
 
 	// get_references requires DataScript for ancestor lookups.
@@ -74,13 +94,13 @@ func newServer(b backend.Backend, readOnly bool) *mcp.Server {
 	// --- Search tools ---
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "search",
-		Description: "Full-text search across all blocks in the knowledge graph. Returns matching blocks with surrounding context (parent chain and sibling blocks) so you understand where each match sits.",
+		Description: "Full-text search across all blocks in the knowledge graph. Returns matching blocks with surrounding context (parent chain and sibling blocks) so you understand where each match sits. On file-based graphs, results are ranked by relevance (BM25, with matches in the page title boosted) and the query supports \"exact phrases\", prefix* terms, -excluded terms and OR.",
 	}, search.Search)
 
 	// query_properties and find_by_tag use native search on Obsidian, DataScript on Logseq.
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "query_properties",
-		Description: "Find blocks and pages by property values. Search for all content with a specific property key, or filter by property value with operators (eq, contains, gt, lt).",
+		Description: "Find blocks and pages by property values, matching page properties (frontmatter) and key:: value block properties. Search for all content with a specific property key, or filter with eq, contains, gt, gte, lt, lte, between, in, exists or regex. Numbers, ISO dates, booleans and lists are compared by type, so 10 > 9 and a list matches when any item does.",
 	}, search.QueryProperties)
 
 	mcp.AddTool(srv, &mcp.Tool{
@@ -88,23 +108,33 @@ func newServer(b backend.Backend, readOnly bool) *mcp.Server {
 		Description: "Find all blocks and pages with a specific tag, including child tags in the tag hierarchy. Returns content grouped by page.",
 	}, search.FindByTag)
 
-	// query_datalog is inherently Logseq-specific.
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "query",
+		Description: "Query the graph with a simple query language in one call instead of chaining searches: (and [[Project]] (task TODO DOING) (property status active) (between -7d today)) finds open tasks under links to Project with status active on the last week's journals. Filters: [[page]] (blocks linking to or tagging page, or under one that does), #tag, \"text\", (task TODO DOING ...), (priority a b), (property key [op] [value]), (page-property key [op] [value]), (page name ...), (between from to) with today, yesterday, -7d, -2w, -1m or YYYY-MM-DD; combine with and, or and not. Property operators are those of query_properties. Queries of only page and page-property filters return pages, others return blocks.",
+	}, search.Query)
+
+	// query_datalog runs on Logseq's DataScript or the vault's in-process evaluator.
 	if hasDataScript {
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "query_datalog",
-			Description: "Execute raw DataScript/Datalog queries against the Logseq database. This is the most powerful query mechanism — can find anything. Example: [:find (pull ?b [*]) :where [?b :block/marker \"TODO\"]] finds all TODO blocks.",
+			Description: "Execute raw DataScript/Datalog queries against the knowledge graph (Logseq's database, or a built-in evaluator for file-based graphs that supports :find/:in/:where, pull, predicates, not/or and aggregates; rules are not supported). This is the most powerful query mechanism — can find anything. Example: [:find (pull ?b [*]) :where [?b :block/marker \"TODO\"]] finds all TODO blocks.",
 		}, search.QueryDatalog)
 	}
 
 	// --- Analyze tools (all backends — use graph.Build which only needs GetAllPages + GetPageBlocksTree) ---
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "graph_overview",
-		Description: "Get a high-level overview of the entire knowledge graph: total pages, blocks, links, most connected pages, orphan count, namespace breakdown. Builds an in-memory graph for analysis.",
+		Description: "Get a high-level overview of the entire knowledge graph: total pages, blocks, links, most connected pages, orphan count, namespace breakdown. Builds an in-memory graph for analysis. Most connected pages are ranked by link count unless rankBy picks pagerank, betweenness or closeness.",
 	}, analyze.GraphOverview)
 
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "centrality",
+		Description: "Rank pages by centrality rather than raw link count, which favours hub and index pages. pagerank (default): pages linked from other important pages; pass seeds to personalize it and find the pages most central to a topic. betweenness: pages bridging otherwise separate topics. closeness: pages a few links from everything. Journals are left out unless includeJournals.",
+	}, analyze.Centrality)
+
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "find_connections",
-		Description: "Discover how two pages are connected through the link graph. Returns whether they're directly linked, shortest paths between them, and shared connections (pages both link to or are linked from).",
+		Description: "Discover how two pages are connected through the link graph. Returns whether they're directly linked, shortest paths between them, and shared connections (pages both link to or are linked from). Misspelled page names get suggestions, or resolve to the closest match with fuzzy.",
 	}, analyze.FindConnections)
 
 	mcp.AddTool(srv, &mcp.Tool{
@@ -136,46 +166,51 @@ func newServer(b backend.Backend, readOnly bool) *mcp.Server {
 
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "update_block",
-			Description: "Update an existing block's content by UUID. Replaces the block's entire content with the new value. Use get_page or get_block first to find the UUID.",
+			Description: "Update an existing block's content by UUID. Replaces the block's entire content with the new value. Use get_page or get_block first to find the UUID, and pass the block's version as ifMatch so an edit made since is not overwritten.",
 		}, write.UpdateBlock)
 
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "delete_block",
-			Description: "Delete a block by UUID. Removes the block and all its children from the graph. This is irreversible.",
+			Description: "Delete a block by UUID. Removes the block and all its children from the graph." + irreversible,
 		}, write.DeleteBlock)
 
 		// upsert_blocks uses raw handler because BlockInput has recursive Children field
 		// which the schema generator can't handle.
 		srv.AddTool(&mcp.Tool{
 			Name:        "upsert_blocks",
-			Description: "Batch create blocks on a page. Supports nested children for building block hierarchies. Append or prepend to existing content.",
-			InputSchema: json.RawMessage(`{"type":"object","properties":{"page":{"type":"string","description":"Page name to add blocks to"},"blocks":{"type":"array","description":"Blocks to create. Each block has content (string), optional properties (object of strings), and optional children (array of blocks).","items":{"type":"object"}},"position":{"type":"string","description":"Where to add: append or prepend. Default: append"}},"required":["page","blocks"],"additionalProperties":false}`),
+			Description: "Batch create blocks on a page. Supports nested children for building block hierarchies. Append or prepend to existing content. Set dryRun to list the blocks it would create without writing.",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"page":{"type":"string","description":"Page name to add blocks to"},"blocks":{"type":"array","description":"Blocks to create. Each block has content (string), optional properties (object of strings), and optional children (array of blocks).","items":{"type":"object"}},"position":{"type":"string","description":"Where to add: append or prepend. Default: append"},"dryRun":{"type":"boolean","description":"Return the planned changes without writing anything. Default: false"}},"required":["page","blocks"],"additionalProperties":false}`),
 		}, write.UpsertBlocksRaw)
 
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "move_block",
-			Description: "Move a block to a new location — before, after, or as a child of another block.",
+			Description: "Move a block to a new location — before, after, or as a child of another block. Set dryRun to check the move and see the block and files it would touch without moving.",
 		}, write.MoveBlock)
 
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "delete_page",
-			Description: "Delete a page entirely from the graph. Removes the page and all its blocks. This is irreversible.",
+			Description: "Delete a page entirely from the graph. Removes the page and all its blocks. Set dryRun to see its file, block count and the links that would be left dangling without deleting." + irreversible,
 		}, write.DeletePage)
 
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "rename_page",
-			Description: "Rename a page and update all [[links]] across the graph that reference the old name. Preserves content and connections.",
+			Description: "Rename a page and update all [[links]] across the graph that reference the old name. Preserves content and connections. Set dryRun to list the file move and every line that would be rewritten, before and after, without renaming.",
 		}, write.RenamePage)
 
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "bulk_update_properties",
-			Description: "Set a property (key:: value) on multiple pages at once. Useful for backfilling metadata like type::, status::, etc. across many pages in one call.",
+			Description: "Set a property (key:: value) on multiple pages at once. Useful for backfilling metadata like type::, status::, etc. across many pages in one call. Set dryRun to see each block's content before and after without writing.",
 		}, write.BulkUpdateProperties)
 
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "link_pages",
 			Description: "Create a bidirectional connection between two pages by adding a link block to each. Optionally include context describing the relationship.",
 		}, write.LinkPages)
+
+		mcp.AddTool(srv, &mcp.Tool{
+			Name:        "apply_batch",
+			Description: "Apply several write operations in order, all or nothing. Each operation names a write tool (create_page, append_blocks, upsert_blocks, update_block, delete_block, move_block, link_pages, delete_page, rename_page, bulk_update_properties) and its input. If any fails, the ones before it are rolled back and the graph is left as it was. Use instead of separate calls when later writes depend on earlier ones.",
+		}, write.ApplyBatch)
 	}
 
 	// --- Decision tools (skipped in read-only mode) ---
@@ -187,12 +222,12 @@ func newServer(b backend.Backend, readOnly bool) *mcp.Server {
 
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "decision_create",
-			Description: "Create a new DECIDE block on a page with #decision tag and deadline. Decisions live on the page where context is richest, not on a central backlog.",
+			Description: "Create a new DECIDE block on a page with #decision tag and deadline. Decisions live on the page where context is richest, not on a central backlog. An optional repeat (+1w, every month) makes the decision recurring.",
 		}, decision.DecisionCreate)
 
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "decision_resolve",
-			Description: "Mark a decision as DONE with today's date and an optional outcome. Changes the DECIDE marker to DONE and adds resolved:: property.",
+			Description: "Mark a decision as DONE with today's date and an optional outcome. Changes the DECIDE marker to DONE and adds resolved:: property. A recurring decision (repeat:: property) gets its next occurrence added after it with the deadline moved on.",
 		}, decision.DecisionResolve)
 
 		mcp.AddTool(srv, &mcp.Tool{
@@ -206,6 +241,68 @@ func newServer(b backend.Backend, readOnly bool) *mcp.Server {
 		}, decision.AnalysisHealth)
 	}
 
+	// --- Task tools (set_task_state skipped in read-only mode) ---
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "list_tasks",
+		Description: "List tasks: blocks starting with a TODO, DOING, DONE, LATER, NOW, WAITING or CANCELLED marker, and Markdown checkboxes ([ ], [/], [x], [-]). Filter by marker (default: open tasks), priority, page or namespace, a window of scheduled or due dates, or overdue only. Reads Logseq SCHEDULED: and DEADLINE: dates and the Obsidian Tasks plugin's 📅 due, ⏳ scheduled, 🛫 start and ✅ done dates. Dated tasks come first, soonest first.",
+	}, tasks.ListTasks)
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "upcoming",
+		Description: "List open tasks and decisions falling due between two dates (default: the next 30 days), soonest first. Tasks fall due on their deadline, or scheduled date when they have none; decisions on their deadline:: property. Recurring items (Logseq repeaters like .+1w or ++1m, Obsidian Tasks 🔁 every week, decision repeat:: properties) are projected over the range, later occurrences marked projected.",
+	}, tasks.Upcoming)
+
+	if !readOnly {
+		mcp.AddTool(srv, &mcp.Tool{
+			Name:        "set_task_state",
+			Description: "Set a task's marker, or move it to the next one (TODO → DOING → DONE → TODO, LATER → NOW → DONE) when no state is given. Checkbox tasks keep their checkbox. Completing a task stamps it: ✅ date on checkbox tasks, a completed:: date and time property on the others; reopening removes the stamp. Completing a recurring task creates its next occurrence: a new checkbox task above it (🔁 every week), or the same Logseq task reopened with its repeating SCHEDULED/DEADLINE dates moved on (.+1w, ++1m, +1d).",
+		}, tasks.SetTaskState)
+	}
+
+	// --- History tools (need the operation log; undo skipped in read-only mode) ---
+	if ops != nil {
+		history := tools.NewHistory(b, ops)
+
+		mcp.AddTool(srv, &mcp.Tool{
+			Name:        "list_operations",
+			Description: "List recent write operations recorded in the operation log, newest first: ID, time, tool, a short summary, and which operations undid which. Use the IDs with undo_operation.",
+		}, history.ListOperations)
+
+		if !readOnly {
+			mcp.AddTool(srv, &mcp.Tool{
+				Name:        "undo_last",
+				Description: "Undo the most recent write operation that has not been undone: restores previous block content, re-creates deleted blocks and pages at their old position, moves blocks back and reverses renames. The undo is itself recorded, so it can be reverted with redo_last.",
+			}, history.UndoLast)
+
+			mcp.AddTool(srv, &mcp.Tool{
+				Name:        "undo_operation",
+				Description: "Undo a specific write operation by ID (see list_operations), even if later operations followed it. Undoing an undo operation redoes the original.",
+			}, history.UndoOperation)
+
+			mcp.AddTool(srv, &mcp.Tool{
+				Name:        "redo_last",
+				Description: "Reapply the most recently undone write operation.",
+			}, history.RedoLast)
+		}
+	}
+
+	// --- Page history tools (backends that keep page versions, e.g. a vault in git) ---
+	if _, ok := b.(backend.PageHistorian); ok {
+		history := tools.NewHistory(b, ops)
+
+		mcp.AddTool(srv, &mcp.Tool{
+			Name:        "page_history",
+			Description: "List the saved versions of a page, newest first: version (git commit), time, author and commit message. Follows the page across renames and also works for deleted pages. Needs the vault to be in a git repository; --git-commit commits every write.",
+		}, history.PageHistory)
+
+		if !readOnly {
+			mcp.AddTool(srv, &mcp.Tool{
+				Name:        "restore_page_version",
+				Description: "Restore a page to a saved version from page_history, re-creating it if it was deleted. The restore is recorded in the operation log, so undo_last reverts it. Use dryRun: true to see the content first.",
+			}, history.RestorePageVersion)
+		}
+	}
+
 	// --- Journal tools (all backends — search uses native fallback for non-DataScript) ---
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "journal_range",
@@ -214,12 +311,12 @@ func newServer(b backend.Backend, readOnly bool) *mcp.Server {
 
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "journal_search",
-		Description: "Search within journal entries specifically. Optionally filter by date range. Returns matching blocks with their journal date context.",
+		Description: "Search within journal entries specifically. Optionally filter by date range. Returns matching blocks with their journal date context, best matches first on file-based graphs (same query syntax as search).",
 	}, journal.JournalSearch)
 
 	// --- Flashcard tools (DataScript-only for overview/due) ---
 	if hasDataScript {
-		flashcard := tools.NewFlashcard(b)
+		flashcard := tools.NewFlashcard(b, ops)
 
 		mcp.AddTool(srv, &mcp.Tool{
 			Name:        "flashcard_overview",
@@ -240,7 +337,8 @@ func newServer(b backend.Backend, readOnly bool) *mcp.Server {
 	}
 
 	// --- Whiteboard tools (Logseq-specific) ---
-	if hasDataScript {
+	// Graph directories served from files hold no whiteboard data.
+	if logseqAPI {
 		whiteboard := tools.NewWhiteboard(b)
 
 		mcp.AddTool(srv, &mcp.Tool{
@@ -260,8 +358,8 @@ func newServer(b backend.Backend, readOnly bool) *mcp.Server {
 		Description: "Check server status: version, backend type, read-only mode, page count. Use to verify the server is alive and see its configuration.",
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input struct{}) (*mcp.CallToolResult, any, error) {
 		backendType := "logseq"
-		if _, ok := b.(backend.HasDataScript); !ok {
-			backendType = "obsidian"
+		if k, ok := b.(backend.GraphKind); ok {
+			backendType = k.GraphKind()
 		}
 
 		pages, _ := b.GetAllPages(ctx)
@@ -304,5 +402,67 @@ func newServer(b backend.Backend, readOnly bool) *mcp.Server {
 		})
 	}
 
+	// --- Resources (all backends; page subscriptions need a page watcher) ---
+	resources := tools.NewResources(b)
+	srv.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "page",
+		URITemplate: tools.PageURITemplate,
+		Description: "A page as markdown, by its percent-encoded name (e.g. graph://page/projects%2Falpha). Subscribe to be notified when the page changes, whether through graphthulhu or in another program.",
+		MIMEType:    "text/markdown",
+	}, resources.ReadPage)
+	srv.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "journal",
+		URITemplate: tools.JournalURITemplate,
+		Description: "The journal page of a day as markdown, by its date (e.g. graph://journal/2026-01-31).",
+		MIMEType:    "text/markdown",
+	}, resources.ReadJournal)
+	srv.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "block",
+		URITemplate: tools.BlockURITemplate,
+		Description: "A block and its children as a markdown outline, by the block's UUID.",
+		MIMEType:    "text/markdown",
+	}, resources.ReadBlock)
+
+	// --- Prompts (all backends) ---
+	prompts := tools.NewPrompts(b)
+	srv.AddPrompt(&mcp.Prompt{
+		Name:        "weekly_review",
+		Description: "Review a week of journals together with the open and overdue decisions, and plan the next week.",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "end", Description: "Last day of the week (YYYY-MM-DD). Default: today"},
+		},
+	}, prompts.WeeklyReview)
+	srv.AddPrompt(&mcp.Prompt{
+		Name:        "find_gaps",
+		Description: "Find orphan, dead-end and weakly linked pages and suggest links to close the gaps.",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "minBlockCount", Description: "Skip orphan pages with fewer blocks. Default: 0"},
+		},
+	}, prompts.FindGaps)
+	srv.AddPrompt(&mcp.Prompt{
+		Name:        "summarize_page",
+		Description: "Summarize a page and how the pages linking to it use it.",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "page", Description: "Page to summarize", Required: true},
+		},
+	}, prompts.SummarizePage)
+
 	return srv
 }
+
+// watchPages tells the clients of srv subscribed to a page resource when the
+// page changes, until ctx is done. Backends that cannot watch pages report
+// nothing.
+func watchPages(ctx context.Context, srv *mcp.Server, subs *tools.PageSubscriptions, b backend.Backend) {
+	w, ok := b.(backend.PageWatcher)
+	if !ok {
+		return
+	}
+	// Only watch while a page is subscribed to: the Logseq API is polled.
+	subs.WatchWhileSubscribed(ctx, func(ctx context.Context) {
+		w.WatchPages(ctx, func(change backend.PageChange) {
+			// Off the watcher's goroutine, which must not wait on slow clients.
+			go subs.Notify(ctx, srv, change)
+		})
+	})
+}
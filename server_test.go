@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/tools"
+	"github.com/skridlevsky/graphthulhu/vault"
+	"github.com/skridlevsky/graphthulhu/vault/vaulttest"
+)
+
+func TestPageResourceSubscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	vc, dir := vaulttest.New(t, map[string]string{
+		"Go.md":   "Go links to [[Rust]].\n",
+		"Rust.md": "Rust is fast.\n",
+	})
+	if err := vc.Watch(); err != nil {
+		t.Fatal(err)
+	}
+
+	subs := &tools.PageSubscriptions{}
+	srv := newServer(vc, true, nil, subs)
+	watchPages(ctx, srv, subs, vc)
+
+	updated := make(chan string, 10)
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := srv.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test"}, &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+			updated <- req.Params.URI
+		},
+	})
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	res, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: tools.PageURI("Go")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Contents[0].Text; got != "Go links to [[Rust]].\n" {
+		t.Errorf("page resource = %q", got)
+	}
+	if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: tools.PageURI("Missing")}); err == nil {
+		t.Error("read a page that does not exist")
+	}
+
+	// Page names are case-insensitive; the update uses the URI subscribed to.
+	if err := session.Subscribe(ctx, &mcp.SubscribeParams{URI: "graph://page/rust"}); err != nil {
+		t.Fatal(err)
+	}
+	rust := filepath.Join(dir, "Rust.md")
+	if err := os.WriteFile(rust, []byte("Rust is safe.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case uri := <-updated:
+		if uri != "graph://page/rust" {
+			t.Errorf("updated %q", uri)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update for the edited page")
+	}
+
+	// Unsubscribed pages are not reported.
+	if err := session.Unsubscribe(ctx, &mcp.UnsubscribeParams{URI: "graph://page/rust"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rust, []byte("Rust is fast and safe.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case uri := <-updated:
+		t.Errorf("update %q after unsubscribing", uri)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestServerBackendLabel(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		opts []vault.Option
+		lazy bool // served as runServe does, behind a LazyBackend
+		want string
+	}{
+		{nil, false, `"backend": "obsidian"`},
+		{[]vault.Option{vault.WithLogseqGraph()}, false, `"backend": "logseq"`},
+		{nil, true, `"backend": "obsidian"`},
+		{[]vault.Option{vault.WithLogseqGraph()}, true, `"backend": "logseq"`},
+	} {
+		vc, _ := vaulttest.New(t, nil, tt.opts...)
+		var b backend.Backend = vc
+		if tt.lazy {
+			b = loadVaultAsync(vc)
+		}
+		session, err := connectInProcess(ctx, newServer(b, true, nil, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer session.Close()
+
+		res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "health"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := res.Content[0].(*mcp.TextContent).Text; !strings.Contains(text, tt.want) {
+			t.Errorf("health = %s, want %s", text, tt.want)
+		}
+		// Whiteboards need the Logseq app; graph directories have none.
+		for tool, err := range session.Tools(ctx, nil) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(tool.Name, "whiteboard") {
+				t.Errorf("%s registered for a vault client", tool.Name)
+			}
+		}
+	}
+}
+
+// countingWatcher counts the WatchPages calls whose context is not done.
+type countingWatcher struct {
+	*vault.Client
+	active atomic.Int32
+}
+
+func (w *countingWatcher) WatchPages(ctx context.Context, fn func(backend.PageChange)) {
+	w.active.Add(1)
+	go func() {
+		<-ctx.Done()
+		w.active.Add(-1)
+	}()
+}
+
+func TestPagesWatchedOnlyWhileSubscribed(t *testing.T) {
+	ctx := context.Background()
+	vc, _ := vaulttest.New(t, nil)
+	w := &countingWatcher{Client: vc}
+	subs := &tools.PageSubscriptions{}
+	srv := newServer(w, true, nil, subs)
+	watchPages(ctx, srv, subs, w)
+	session, err := connectInProcess(ctx, srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor := func(want int32) {
+		t.Helper()
+		for deadline := time.Now().Add(2 * time.Second); w.active.Load() != want; time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d watches, want %d", w.active.Load(), want)
+			}
+		}
+	}
+	waitFor(0)
+	for range 2 {
+		if err := session.Subscribe(ctx, &mcp.SubscribeParams{URI: "graph://page/go"}); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(1)
+		if err := session.Unsubscribe(ctx, &mcp.UnsubscribeParams{URI: "graph://page/go"}); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(0)
+	}
+
+	// A session that ends takes its subscriptions with it.
+	if err := session.Subscribe(ctx, &mcp.SubscribeParams{URI: "graph://page/go"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(1)
+	session.Close()
+	waitFor(0)
+	if subs.Subscribed() {
+		t.Error("subscriptions left after the session ended")
+	}
+}
@@ -52,10 +52,57 @@ func (a *Analyze) GraphOverview(ctx context.Context, req *mcp.CallToolRequest, i
 
 	stats := g.Overview()
 
+	if input.RankBy != "" && input.RankBy != graph.MeasureDegree {
+		_, scores, err := a.cache.Centrality(ctx, strings.ToLower(input.RankBy), nil)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		stats.MostConnected = g.MostCentral(scores, len(stats.MostConnected), true)
+		stats.RankedBy = strings.ToLower(input.RankBy)
+	}
+
 	res, err := jsonTextResult(stats)
 	return res, nil, err
 }
 
+// Centrality ranks pages by a centrality measure, PageRank by default,
+// optionally personalized from seed pages.
+func (a *Analyze) Centrality(ctx context.Context, req *mcp.CallToolRequest, input types.CentralityInput) (*mcp.CallToolResult, any, error) {
+	measure := strings.ToLower(input.Measure)
+	if measure == "" {
+		measure = graph.MeasurePageRank
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if len(input.Seeds) > 0 {
+		if measure != graph.MeasurePageRank {
+			return errorResult("seeds personalize PageRank only: use measure pagerank"), nil, nil
+		}
+		seeds, err := resolvePageNames(ctx, a.client, false, input.Seeds...)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		input.Seeds = seeds
+	}
+
+	g, scores, err := a.cache.Centrality(ctx, measure, input.Seeds)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+
+	result := map[string]any{
+		"measure": measure,
+		"pages":   g.MostCentral(scores, limit, input.IncludeJournals),
+	}
+	if len(input.Seeds) > 0 {
+		result["seeds"] = input.Seeds
+	}
+	res, err := jsonTextResult(result)
+	return res, nil, err
+}
+
 // FindConnections finds how two pages are connected in the graph.
 func (a *Analyze) FindConnections(ctx context.Context, req *mcp.CallToolRequest, input types.FindConnectionsInput) (*mcp.CallToolResult, any, error) {
 	g, err := a.cache.Get(ctx)
@@ -65,6 +112,19 @@ func (a *Analyze) FindConnections(ctx context.Context, req *mcp.CallToolRequest,
 
 	result := g.FindConnections(input.From, input.To, input.MaxDepth)
 
+	// Nothing found may just mean a misspelled name: check both ends and
+	// retry with the resolved names.
+	if !result.DirectlyLinked && len(result.Paths) == 0 && len(result.SharedConnections) == 0 {
+		names, err := resolvePageNames(ctx, a.client, input.Fuzzy, input.From, input.To)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		if names[0] != input.From || names[1] != input.To {
+			input.From, input.To = names[0], names[1]
+			result = g.FindConnections(input.From, input.To, input.MaxDepth)
+		}
+	}
+
 	if !result.DirectlyLinked && len(result.Paths) == 0 && len(result.SharedConnections) == 0 {
 		return textResult(fmt.Sprintf("No connections found between '%s' and '%s'.", input.From, input.To)), nil, nil
 	}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/oplog"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// batchStep runs one decoded operation of a batch.
+type batchStep func(ctx context.Context, w *Write) (*mcp.CallToolResult, error)
+
+// batchTools decode the input of each write tool apply_batch can run.
+var batchTools = map[string]func(json.RawMessage) (batchStep, error){
+	"create_page":   batchTool((*Write).CreatePage),
+	"append_blocks": batchTool((*Write).AppendBlocks),
+	"upsert_blocks": batchTool(func(w *Write, ctx context.Context, _ *mcp.CallToolRequest, input types.UpsertBlocksInput) (*mcp.CallToolResult, any, error) {
+		return w.upsertBlocks(ctx, input)
+	}),
+	"update_block":           batchTool((*Write).UpdateBlock),
+	"delete_block":           batchTool((*Write).DeleteBlock),
+	"move_block":             batchTool((*Write).MoveBlock),
+	"link_pages":             batchTool((*Write).LinkPages),
+	"delete_page":            batchTool((*Write).DeletePage),
+	"rename_page":            batchTool((*Write).RenamePage),
+	"bulk_update_properties": batchTool((*Write).BulkUpdateProperties),
+}
+
+// batchTool adapts a write tool handler to a batch operation, rejecting
+// input fields the tool does not take.
+func batchTool[In any](h func(*Write, context.Context, *mcp.CallToolRequest, In) (*mcp.CallToolResult, any, error)) func(json.RawMessage) (batchStep, error) {
+	return func(raw json.RawMessage) (batchStep, error) {
+		var input In
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&input); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, w *Write) (*mcp.CallToolResult, error) {
+			res, _, err := h(w, ctx, nil, input)
+			return res, err
+		}, nil
+	}
+}
+
+// ApplyBatch runs write operations in order as a single operation. If one
+// fails, the writes of those before it are rolled back: restored file by
+// file on backends that keep pages in files, reverted by compensating
+// writes elsewhere.
+func (w *Write) ApplyBatch(ctx context.Context, req *mcp.CallToolRequest, input types.ApplyBatchInput) (*mcp.CallToolResult, any, error) {
+	if len(input.Operations) == 0 {
+		return errorResult("no operations provided"), nil, nil
+	}
+
+	// Decode every operation before writing anything.
+	steps := make([]batchStep, len(input.Operations))
+	tools := make([]string, len(input.Operations))
+	for i, op := range input.Operations {
+		decode, ok := batchTools[op.Tool]
+		if !ok {
+			return errorResult(fmt.Sprintf("operation %d: %q is not a write tool apply_batch can run", i+1, op.Tool)), nil, nil
+		}
+		raw, err := json.Marshal(op.Input)
+		if err != nil {
+			return errorResult(fmt.Sprintf("operation %d (%s): invalid input: %v", i+1, op.Tool, err)), nil, nil
+		}
+		if steps[i], err = decode(raw); err != nil {
+			return errorResult(fmt.Sprintf("operation %d (%s): invalid input: %v", i+1, op.Tool, err)), nil, nil
+		}
+		tools[i] = op.Tool
+	}
+
+	// Rolling back needs the writes recorded even with the operation log
+	// off; they are then kept in memory only.
+	ops := w.ops
+	if ops == nil {
+		ops, _ = oplog.Open("")
+	}
+	rec := ops.Begin(w.client, "apply_batch", strings.Join(tools, ", "))
+	batch := &Write{client: w.client, ops: ops, batch: rec}
+
+	results := make([]any, 0, len(steps))
+	for i, step := range steps {
+		res, err := step(ctx, batch)
+		if err == nil && !res.IsError {
+			results = append(results, toolOutput(res))
+			continue
+		}
+
+		msg := toolError(res, err)
+		if rbErr := rec.Rollback(ctx); rbErr != nil {
+			done, logged := rec.Commit()
+			msg = fmt.Sprintf("operation %d (%s) failed: %s; rolling back the operations before it failed: %v", i+1, tools[i], msg, rbErr)
+			if logged && w.ops != nil {
+				msg += fmt.Sprintf(" (the writes still in effect were logged as operation %d)", done.ID)
+			}
+			return errorResult(msg), nil, nil
+		}
+		rec.Commit()
+		return errorResult(fmt.Sprintf("operation %d (%s) failed: %s; the %d operations before it were rolled back", i+1, tools[i], msg, i)), nil, nil
+	}
+
+	result := map[string]any{
+		"applied": len(results),
+		"results": results,
+	}
+	if done, logged := rec.Commit(); logged && w.ops != nil {
+		result["operation"] = done.ID
+	}
+	res, err := jsonTextResult(result)
+	return res, nil, err
+}
+
+// toolOutput is a tool result's text, decoded when it is JSON.
+func toolOutput(res *mcp.CallToolResult) any {
+	text := resultText(res)
+	var v any
+	if json.Unmarshal([]byte(text), &v) == nil {
+		return v
+	}
+	return text
+}
+
+// toolError describes why a tool call failed.
+func toolError(res *mcp.CallToolResult, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resultText(res)
+}
+
+// resultText joins the text content of a tool result.
+func resultText(res *mcp.CallToolResult) string {
+	var parts []string
+	for _, c := range res.Content {
+		if t, ok := c.(*mcp.TextContent); ok {
+			parts = append(parts, t.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
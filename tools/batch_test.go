@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/oplog"
+	"github.com/skridlevsky/graphthulhu/types"
+	"github.com/skridlevsky/graphthulhu/vault"
+	"github.com/skridlevsky/graphthulhu/vault/vaulttest"
+)
+
+// readDir returns every file under dir by relative path.
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			data, _ := os.ReadFile(path)
+			rel, _ := filepath.Rel(dir, path)
+			files[filepath.ToSlash(rel)] = string(data)
+		}
+		return nil
+	})
+	return files
+}
+
+func batchVault(t *testing.T) (*vault.Client, string) {
+	t.Helper()
+	return vaulttest.New(t, map[string]string{
+		"Projects.md": "- alpha\n- beta\n",
+		"Notes.md":    "---\nstatus: draft\n---\n- idea\n",
+	})
+}
+
+// plainBackend hides the vault's page files, so rollback uses the
+// compensating writes it makes through the Logseq API.
+type plainBackend struct{ backend.Backend }
+
+func TestApplyBatch(t *testing.T) {
+	c, _ := batchVault(t)
+	ops, _ := oplog.Open("")
+	w := NewWrite(c, ops)
+	ctx := context.Background()
+
+	res, _, err := w.ApplyBatch(ctx, nil, types.ApplyBatchInput{Operations: []types.BatchOperation{
+		{Tool: "create_page", Input: map[string]any{"name": "Gamma", "blocks": []any{"first"}}},
+		{Tool: "upsert_blocks", Input: map[string]any{"page": "Projects", "blocks": []any{map[string]any{"content": "parent", "children": []any{map[string]any{"content": "child"}}}}}},
+		{Tool: "link_pages", Input: map[string]any{"from": "Gamma", "to": "Projects"}},
+		{Tool: "bulk_update_properties", Input: map[string]any{"pages": []any{"Notes"}, "property": "status", "value": "done"}},
+	}})
+	if err != nil || res.IsError {
+		t.Fatalf("ApplyBatch: %v %s", err, resultText(res))
+	}
+	if list := ops.List(0); len(list) != 1 || list[0].Tool != "apply_batch" {
+		t.Fatalf("logged %+v, want one apply_batch operation", list)
+	}
+
+	// The batch is undone as one operation.
+	if _, err := ops.Undo(ctx, c, ops.List(0)[0].ID, "undo_last"); err != nil {
+		t.Fatalf("undo batch: %v", err)
+	}
+	if page, _ := c.GetPage(ctx, "Gamma"); page != nil {
+		t.Error("undo left the created page")
+	}
+}
+
+func TestApplyBatchRollsBack(t *testing.T) {
+	failing := []types.BatchOperation{
+		{Tool: "create_page", Input: map[string]any{"name": "Gamma", "blocks": []any{"first"}}},
+		{Tool: "append_blocks", Input: map[string]any{"page": "Projects", "blocks": []any{"gamma"}}},
+		{Tool: "bulk_update_properties", Input: map[string]any{"pages": []any{"Notes"}, "property": "status", "value": "done"}},
+		{Tool: "rename_page", Input: map[string]any{"oldName": "Projects", "newName": "Work"}},
+		{Tool: "update_block", Input: map[string]any{"uuid": "00000000-0000-4000-8000-000000000000", "content": "x"}},
+	}
+
+	for name, wrap := range map[string]func(*vault.Client) backend.Backend{
+		"files":        func(c *vault.Client) backend.Backend { return c },
+		"compensating": func(c *vault.Client) backend.Backend { return plainBackend{c} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, dir := batchVault(t)
+			before := readDir(t, dir)
+			ops, _ := oplog.Open("")
+			w := NewWrite(wrap(c), ops)
+
+			res, _, _ := w.ApplyBatch(context.Background(), nil, types.ApplyBatchInput{Operations: failing})
+			if !res.IsError || !strings.Contains(resultText(res), "operation 5 (update_block) failed") {
+				t.Fatalf("result = %s", resultText(res))
+			}
+			after := readDir(t, dir)
+			if name == "files" && !reflect.DeepEqual(after, before) {
+				t.Errorf("files after rollback:\n%v\nwant:\n%v", after, before)
+			}
+			for _, gone := range []string{"Gamma.md", "Work.md"} {
+				if _, ok := after[gone]; ok {
+					t.Errorf("%s survived the rollback", gone)
+				}
+			}
+			if list := ops.List(0); len(list) != 0 {
+				t.Errorf("rolled back batch logged %+v", list)
+			}
+		})
+	}
+}
+
+func TestApplyBatchRejectsBeforeWriting(t *testing.T) {
+	c, dir := batchVault(t)
+	before := readDir(t, dir)
+	w := NewWrite(c, nil)
+
+	for _, op := range []types.BatchOperation{
+		{Tool: "get_page", Input: map[string]any{"name": "Notes"}},
+		{Tool: "update_block", Input: map[string]any{"uuid": "x", "contnet": "typo"}},
+	} {
+		res, _, _ := w.ApplyBatch(context.Background(), nil, types.ApplyBatchInput{Operations: []types.BatchOperation{
+			{Tool: "create_page", Input: map[string]any{"name": "Gamma"}},
+			op,
+		}})
+		if !res.IsError || !strings.Contains(resultText(res), "operation 2") {
+			t.Errorf("%s: result = %s", op.Tool, resultText(res))
+		}
+	}
+	if !reflect.DeepEqual(readDir(t, dir), before) {
+		t.Error("a rejected batch wrote files")
+	}
+}
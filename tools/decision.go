@@ -9,6 +9,7 @@ import (
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/oplog"
 	"github.com/skridlevsky/graphthulhu/parser"
 	"github.com/skridlevsky/graphthulhu/types"
 )
@@ -16,11 +17,13 @@ import (
 // Decision implements decision protocol MCP tools.
 type Decision struct {
 	client backend.Backend
+	ops    *oplog.Log
 }
 
-// NewDecision creates a new Decision tool handler.
-func NewDecision(c backend.Backend) *Decision {
-	return &Decision{client: c}
+// NewDecision creates a new Decision tool handler. Writes are recorded in
+// ops, which may be nil.
+func NewDecision(c backend.Backend, ops *oplog.Log) *Decision {
+	return &Decision{client: c, ops: ops}
 }
 
 // decisionBlock is a parsed decision found in the graph.
@@ -36,6 +39,7 @@ type decisionBlock struct {
 	Overdue    bool   `json:"overdue"`
 	Deferred   int    `json:"deferred,omitempty"`
 	DeferredOn string `json:"deferredOn,omitempty"`
+	Repeat     string `json:"repeat,omitempty"`
 }
 
 // findDecisions queries all #decision tagged blocks and parses them.
@@ -157,6 +161,9 @@ func parseDecisionBlock(block types.BlockEntity, today time.Time) (decisionBlock
 		if v, ok := parsed.Properties["deferred-on"]; ok {
 			db.DeferredOn = fmt.Sprint(v)
 		}
+		if v, ok := parsed.Properties["repeat"]; ok {
+			db.Repeat = fmt.Sprint(v)
+		}
 	}
 
 	// Calculate days left for unresolved decisions with deadlines.
@@ -217,6 +224,12 @@ func (d *Decision) DecisionCheck(ctx context.Context, req *mcp.CallToolRequest,
 
 // DecisionCreate creates a new DECIDE block with #decision tag and deadline.
 func (d *Decision) DecisionCreate(ctx context.Context, req *mcp.CallToolRequest, input types.DecisionCreateInput) (*mcp.CallToolResult, any, error) {
+	if input.Repeat != "" {
+		if _, err := parser.ParseRecurrence(input.Repeat); err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+	}
+
 	content := fmt.Sprintf("DECIDE %s #decision", input.Question)
 	content += fmt.Sprintf("\ndeadline:: %s", input.Deadline)
 
@@ -226,8 +239,14 @@ func (d *Decision) DecisionCreate(ctx context.Context, req *mcp.CallToolRequest,
 	if input.Context != "" {
 		content += fmt.Sprintf("\ncontext:: %s", input.Context)
 	}
+	if input.Repeat != "" {
+		content += fmt.Sprintf("\nrepeat:: %s", input.Repeat)
+	}
+
+	rec := d.ops.Begin(d.client, "decision_create", input.Page)
+	defer rec.Commit()
 
-	block, err := d.client.AppendBlockInPage(ctx, input.Page, content)
+	block, err := rec.AppendBlockInPage(ctx, input.Page, content)
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to create decision: %v", err)), nil, nil
 	}
@@ -241,12 +260,17 @@ func (d *Decision) DecisionCreate(ctx context.Context, req *mcp.CallToolRequest,
 	if block != nil {
 		result["uuid"] = block.UUID
 	}
+	if input.Repeat != "" {
+		result["repeat"] = input.Repeat
+	}
 
 	res, err := jsonTextResult(result)
 	return res, nil, err
 }
 
 // DecisionResolve marks a decision as DONE with resolution date and outcome.
+// Resolving a decision with a repeat:: property adds its next occurrence
+// after it, due one interval on.
 func (d *Decision) DecisionResolve(ctx context.Context, req *mcp.CallToolRequest, input types.DecisionResolveInput) (*mcp.CallToolResult, any, error) {
 	block, err := d.client.GetBlock(ctx, input.UUID)
 	if err != nil {
@@ -269,19 +293,59 @@ func (d *Decision) DecisionResolve(ctx context.Context, req *mcp.CallToolRequest
 		content = upsertProperty(content, "outcome", input.Outcome)
 	}
 
-	if err := d.client.UpdateBlock(ctx, input.UUID, content); err != nil {
+	rec := d.ops.Begin(d.client, "decision_resolve", input.UUID)
+	defer rec.Commit()
+
+	if err := rec.UpdateBlock(ctx, input.UUID, content); err != nil {
 		return errorResult(fmt.Sprintf("failed to update block: %v", err)), nil, nil
 	}
 
-	res, err := jsonTextResult(map[string]any{
+	result := map[string]any{
 		"resolved": true,
 		"uuid":     input.UUID,
 		"date":     today,
 		"outcome":  input.Outcome,
-	})
+	}
+	// A decision resolved again does not recur again.
+	if next, deadline, ok := nextDecision(block.Content, today); ok && !strings.HasPrefix(block.Content, "DONE ") {
+		nb, err := rec.InsertBlock(ctx, input.UUID, next, map[string]any{"sibling": true})
+		if err != nil {
+			return errorResult(fmt.Sprintf("resolved, but failed to add the next occurrence: %v", err)), nil, nil
+		}
+		occurrence := map[string]any{"deadline": deadline}
+		if nb != nil {
+			occurrence["uuid"] = nb.UUID
+		}
+		result["next"] = occurrence
+	}
+
+	res, err := jsonTextResult(result)
 	return res, nil, err
 }
 
+// nextDecision returns the content of the next occurrence of an open
+// recurring decision resolved today and its deadline, and false when the
+// decision does not recur. The occurrence carries none of the resolution
+// or deferral properties.
+func nextDecision(content, today string) (string, string, bool) {
+	parsed := parser.Parse(content)
+	r, err := parser.ParseRecurrence(parsed.Properties["repeat"])
+	if err != nil {
+		return "", "", false
+	}
+	deadline, err := time.Parse("2006-01-02", parsed.Properties["deadline"])
+	if err != nil {
+		return "", "", false
+	}
+	done, _ := time.Parse("2006-01-02", today)
+	next := r.Next(deadline, done).Format("2006-01-02")
+
+	for _, key := range []string{"resolved", "outcome", "deferred", "deferred-on"} {
+		content = removeProperty(content, key)
+	}
+	return upsertProperty(content, "deadline", next), next, true
+}
+
 // DecisionDefer pushes a deadline with a reason and increments defer count.
 func (d *Decision) DecisionDefer(ctx context.Context, req *mcp.CallToolRequest, input types.DecisionDeferInput) (*mcp.CallToolResult, any, error) {
 	block, err := d.client.GetBlock(ctx, input.UUID)
@@ -307,14 +371,17 @@ func (d *Decision) DecisionDefer(ctx context.Context, req *mcp.CallToolRequest,
 	content = upsertProperty(content, "deferred", fmt.Sprintf("%d", deferCount))
 	content = upsertProperty(content, "deferred-on", today)
 
-	if err := d.client.UpdateBlock(ctx, input.UUID, content); err != nil {
+	rec := d.ops.Begin(d.client, "decision_defer", input.UUID)
+	defer rec.Commit()
+
+	if err := rec.UpdateBlock(ctx, input.UUID, content); err != nil {
 		return errorResult(fmt.Sprintf("failed to update block: %v", err)), nil, nil
 	}
 
 	// Add reason as child block.
 	if input.Reason != "" {
 		reasonContent := fmt.Sprintf("Deferred %s: %s", today, input.Reason)
-		d.client.InsertBlock(ctx, input.UUID, reasonContent, map[string]any{"sibling": false})
+		rec.InsertBlock(ctx, input.UUID, reasonContent, map[string]any{"sibling": false})
 	}
 
 	result := map[string]any{
@@ -362,9 +429,9 @@ func (d *Decision) AnalysisHealth(ctx context.Context, req *mcp.CallToolRequest,
 		outgoing := 0
 		hasDecision := false
 		for _, b := range blocks {
-			p := parser.Parse(b.Content)
+			p := parser.ParseInPage(b.Content, name)
 			outgoing += len(p.Links)
-			outgoing += countLinksInTree(b.Children)
+			outgoing += countLinksInTree(b.Children, name)
 			if strings.HasPrefix(b.Content, "DECIDE ") {
 				hasDecision = true
 			}
@@ -429,12 +496,12 @@ func (d *Decision) findAnalysisPages(ctx context.Context) ([]string, error) {
 		var names []string
 		seen := make(map[string]bool)
 		for _, t := range targetTypes {
-			results, err := searcher.FindByProperty(ctx, "type", t, "eq")
+			results, err := searcher.FindByProperty(ctx, "type", "eq", []string{t})
 			if err != nil {
 				continue
 			}
 			for _, r := range results {
-				if !seen[r.Name] {
+				if r.Type == "page" && !seen[r.Name] {
 					names = append(names, r.Name)
 					seen[r.Name] = true
 				}
@@ -475,13 +542,13 @@ func (d *Decision) findAnalysisPages(ctx context.Context) ([]string, error) {
 	return names, nil
 }
 
-// countLinksInTree recursively counts [[links]] in a block tree.
-func countLinksInTree(blocks []types.BlockEntity) int {
+// countLinksInTree recursively counts [[links]] and markdown note links in a block tree.
+func countLinksInTree(blocks []types.BlockEntity, pageName string) int {
 	count := 0
 	for _, b := range blocks {
-		p := parser.Parse(b.Content)
+		p := parser.ParseInPage(b.Content, pageName)
 		count += len(p.Links)
-		count += countLinksInTree(b.Children)
+		count += countLinksInTree(b.Children, pageName)
 	}
 	return count
 }
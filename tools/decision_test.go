@@ -113,7 +113,7 @@ func TestCountLinksInTree(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := countLinksInTree(tt.blocks)
+			got := countLinksInTree(tt.blocks, "")
 			if got != tt.want {
 				t.Errorf("countLinksInTree() = %d, want %d", got, tt.want)
 			}
@@ -321,3 +321,17 @@ func TestParseDecisionBlock(t *testing.T) {
 		})
 	}
 }
+
+func TestNextDecision(t *testing.T) {
+	content := "DECIDE Review budget #decision\ndeadline:: 2026-01-31\nrepeat:: +1m\ndeferred:: 1\ndeferred-on:: 2026-01-20"
+	next, deadline, ok := nextDecision(content, "2026-02-02")
+	if !ok || deadline != "2026-02-28" {
+		t.Fatalf("nextDecision = %q, %q, %v", next, deadline, ok)
+	}
+	if want := "DECIDE Review budget #decision\ndeadline:: 2026-02-28\nrepeat:: +1m"; next != want {
+		t.Errorf("next = %q, want %q", next, want)
+	}
+	if _, _, ok := nextDecision("DECIDE Once #decision\ndeadline:: 2026-01-31", "2026-02-02"); ok {
+		t.Error("nextDecision of a decision that does not recur succeeded")
+	}
+}
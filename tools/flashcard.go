@@ -8,17 +8,20 @@ import (
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/oplog"
 	"github.com/skridlevsky/graphthulhu/types"
 )
 
 // Flashcard implements flashcard/SRS MCP tools.
 type Flashcard struct {
 	client backend.Backend
+	ops    *oplog.Log
 }
 
-// NewFlashcard creates a new Flashcard tool handler.
-func NewFlashcard(c backend.Backend) *Flashcard {
-	return &Flashcard{client: c}
+// NewFlashcard creates a new Flashcard tool handler. Writes are recorded in
+// ops, which may be nil.
+func NewFlashcard(c backend.Backend, ops *oplog.Log) *Flashcard {
+	return &Flashcard{client: c, ops: ops}
 }
 
 type cardData struct {
@@ -129,7 +132,10 @@ func (f *Flashcard) FlashcardDue(ctx context.Context, req *mcp.CallToolRequest,
 func (f *Flashcard) FlashcardCreate(ctx context.Context, req *mcp.CallToolRequest, input types.FlashcardCreateInput) (*mcp.CallToolResult, any, error) {
 	frontContent := input.Front + " #card"
 
-	frontBlock, err := f.client.AppendBlockInPage(ctx, input.Page, frontContent)
+	rec := f.ops.Begin(f.client, "flashcard_create", input.Page)
+	defer rec.Commit()
+
+	frontBlock, err := rec.AppendBlockInPage(ctx, input.Page, frontContent)
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to create card front: %v", err)), nil, nil
 	}
@@ -138,7 +144,7 @@ func (f *Flashcard) FlashcardCreate(ctx context.Context, req *mcp.CallToolReques
 		return errorResult("created card but got no block reference"), nil, nil
 	}
 
-	_, err = f.client.InsertBlock(ctx, frontBlock.UUID, input.Back, map[string]any{
+	_, err = rec.InsertBlock(ctx, frontBlock.UUID, input.Back, map[string]any{
 		"isPageBlock": false,
 	})
 	if err != nil {
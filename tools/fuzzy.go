@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/parser"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+const (
+	// minNameSimilarity is the lowest similarity a page name needs to be
+	// suggested for a misspelled one.
+	minNameSimilarity = 0.5
+	// maxSuggestions caps the "did you mean" list.
+	maxSuggestions = 5
+)
+
+// pageSuggestion is a page name scored against a name that was not found.
+type pageSuggestion struct {
+	Name  string
+	Score float64
+}
+
+// pageScopeKey is the context key of the page filter WithPageScope attaches.
+type pageScopeKey struct{}
+
+// WithPageScope returns a context under which page-name suggestions, and
+// the pages fuzzy lookups resolve to, are limited to pages for which
+// inScope returns true. A client restricted to part of the graph must not
+// learn other page names from a "did you mean" list.
+func WithPageScope(ctx context.Context, inScope func(name string) bool) context.Context {
+	return context.WithValue(ctx, pageScopeKey{}, inScope)
+}
+
+// scopedPages returns the pages of ctx's page scope, or all pages if ctx
+// has none.
+func scopedPages(ctx context.Context, pages []types.PageEntity) []types.PageEntity {
+	inScope, ok := ctx.Value(pageScopeKey{}).(func(string) bool)
+	if !ok {
+		return pages
+	}
+	var kept []types.PageEntity
+	for _, p := range pages {
+		if inScope(p.Name) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// resolveMissingPage handles a page name that a lookup did not find. With
+// fuzzy set and one page matching clearly best, it returns that page's name.
+// Otherwise the error says the page was not found and lists the closest
+// page names, so the caller can retry with the right one.
+func resolveMissingPage(ctx context.Context, c backend.Backend, name string, fuzzy bool) (string, error) {
+	pages, err := c.GetAllPages(ctx)
+	if err != nil {
+		return "", fmt.Errorf("page not found: %s", name)
+	}
+	suggestions := suggestPages(name, scopedPages(ctx, pages))
+	if fuzzy && len(suggestions) > 0 &&
+		(len(suggestions) == 1 || suggestions[0].Score > suggestions[1].Score) {
+		return suggestions[0].Name, nil
+	}
+	if len(suggestions) == 0 {
+		return "", fmt.Errorf("page not found: %s", name)
+	}
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = fmt.Sprintf("%q", s.Name)
+	}
+	return "", fmt.Errorf("page not found: %s — did you mean %s?", name, strings.Join(quoted, ", "))
+}
+
+// resolvePageNames checks that each named page exists and resolves the
+// missing ones with resolveMissingPage. Existing names are returned as given.
+func resolvePageNames(ctx context.Context, c backend.Backend, fuzzy bool, names ...string) ([]string, error) {
+	resolved := make([]string, len(names))
+	for i, name := range names {
+		page, err := c.GetPage(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("page not found: %s — %v", name, err)
+		}
+		if page != nil {
+			resolved[i] = name
+			continue
+		}
+		if resolved[i], err = resolveMissingPage(ctx, c, name, fuzzy); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// suggestPages ranks pages by how closely their name, last namespace
+// segment or an alias matches name, best first. Only pages reaching
+// minNameSimilarity are returned, at most maxSuggestions of them.
+func suggestPages(name string, pages []types.PageEntity) []pageSuggestion {
+	query := foldName(name)
+	queryBase := baseName(query)
+	var out []pageSuggestion
+	for _, p := range pages {
+		if p.Name == "" {
+			continue
+		}
+		display := p.OriginalName
+		if display == "" {
+			display = p.Name
+		}
+		full := foldName(p.Name)
+		score := nameSimilarity(query, full)
+		// A matching last segment suggests the right page in the wrong
+		// namespace, which ranks below a match on the whole name.
+		if base := baseName(full); base != full {
+			score = max(score, 0.9*nameSimilarity(query, base), 0.9*nameSimilarity(queryBase, base))
+		}
+		for _, alias := range parser.PageAliases(p.Properties) {
+			score = max(score, nameSimilarity(query, foldName(alias)))
+		}
+		if score >= minNameSimilarity {
+			out = append(out, pageSuggestion{Name: display, Score: score})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Name < out[j].Name
+	})
+	if len(out) > maxSuggestions {
+		out = out[:maxSuggestions]
+	}
+	return out
+}
+
+// foldName normalizes a page name for comparison: lowercase, with dashes and
+// underscores read as spaces and runs of spaces collapsed.
+func foldName(s string) string {
+	s = strings.ToLower(s)
+	s = strings.NewReplacer("-", " ", "_", " ").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// baseName returns the last namespace segment of a folded page name.
+func baseName(s string) string {
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		return strings.TrimSpace(s[i+1:])
+	}
+	return s
+}
+
+// nameSimilarity scores two folded names from 0 to 1. It takes the better of
+// edit distance, which catches typos in short names, and trigram overlap,
+// which catches reordered or partly missing words in long ones.
+func nameSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	edit := 1 - float64(levenshtein(ra, rb))/float64(longest)
+	return max(edit, trigramSimilarity(a, b))
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// trigramSimilarity returns the Dice coefficient of the trigram sets of a
+// and b, padded so that short names and word starts still form trigrams.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ta)+len(tb))
+}
+
+func trigrams(s string) map[string]bool {
+	rs := []rune("  " + s + " ")
+	set := make(map[string]bool, len(rs))
+	for i := 0; i+3 <= len(rs); i++ {
+		set[string(rs[i:i+3])] = true
+	}
+	return set
+}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/skridlevsky/graphthulhu/types"
+	"github.com/skridlevsky/graphthulhu/vault/vaulttest"
+)
+
+func TestSuggestPages(t *testing.T) {
+	pages := []types.PageEntity{
+		{Name: "kubernetes", OriginalName: "Kubernetes"},
+		{Name: "projects/openchaos", OriginalName: "projects/openchaos"},
+		{Name: "archive/openchaos notes", OriginalName: "archive/openchaos notes"},
+		{Name: "graphthulhu", OriginalName: "graphthulhu", Properties: map[string]any{"aliases": []any{"GT"}}},
+		{Name: "weekly review", OriginalName: "Weekly Review"},
+		{Name: "go"},
+	}
+	tests := []struct {
+		query string
+		want  string // best suggestion, "" for none
+	}{
+		{"kubernets", "Kubernetes"},
+		{"KUBERNETES", "Kubernetes"},
+		{"openchaos", "projects/openchaos"},
+		{"work/openchaos", "projects/openchaos"},
+		{"gt", "graphthulhu"},
+		{"weekly-review", "Weekly Review"},
+		{"review weekly", "Weekly Review"},
+		{"zzzz", ""},
+	}
+	for _, tt := range tests {
+		got := suggestPages(tt.query, pages)
+		best := ""
+		if len(got) > 0 {
+			best = got[0].Name
+		}
+		if best != tt.want {
+			t.Errorf("suggestPages(%q) best = %q, want %q (all: %v)", tt.query, best, tt.want, got)
+		}
+		for i := 1; i < len(got); i++ {
+			if got[i].Score > got[i-1].Score {
+				t.Errorf("suggestPages(%q) not sorted: %v", tt.query, got)
+			}
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"über", "uber", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePageNames(t *testing.T) {
+	c, _ := vaulttest.New(t, map[string]string{
+		"Kubernetes.md":   "# Kubernetes\n\nSee [[Docker]].\n",
+		"Docker.md":       "# Docker\n",
+		"Kubernetes 2.md": "# Kubernetes 2\n",
+	})
+	ctx := context.Background()
+
+	names, err := resolvePageNames(ctx, c, false, "kubernetes", "Docker")
+	if err != nil || names[0] != "kubernetes" || names[1] != "Docker" {
+		t.Errorf("existing names = %v, %v", names, err)
+	}
+
+	_, err = resolvePageNames(ctx, c, false, "Dokcer")
+	if err == nil || !strings.Contains(err.Error(), `did you mean "Docker"`) {
+		t.Errorf("miss without fuzzy: %v", err)
+	}
+
+	names, err = resolvePageNames(ctx, c, true, "Dokcer")
+	if err != nil || names[0] != "Docker" {
+		t.Errorf("fuzzy = %v, %v", names, err)
+	}
+
+	// Two equally close pages are not guessed between.
+	_, err = resolvePageNames(ctx, c, true, "Kubernetes 3")
+	if err == nil || !strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("ambiguous fuzzy match resolved: %v", err)
+	}
+
+	_, err = resolvePageNames(ctx, c, true, "zzzz")
+	if err == nil || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("no suggestions expected: %v", err)
+	}
+
+	// Pages outside the scope are neither suggested nor resolved to.
+	scoped := WithPageScope(ctx, func(name string) bool { return strings.HasPrefix(name, "kubernetes") })
+	_, err = resolvePageNames(scoped, c, true, "Dokcer")
+	if err == nil || strings.Contains(err.Error(), "Docker") {
+		t.Errorf("out-of-scope page suggested: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/oplog"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// History implements the operation log tools — listing recorded writes and
+// undoing or redoing them — and the page version tools.
+type History struct {
+	client backend.Backend
+	ops    *oplog.Log
+}
+
+// NewHistory creates a new History tool handler.
+func NewHistory(c backend.Backend, ops *oplog.Log) *History {
+	return &History{client: c, ops: ops}
+}
+
+// ListOperations lists recorded operations, newest first.
+func (h *History) ListOperations(ctx context.Context, req *mcp.CallToolRequest, input types.ListOperationsInput) (*mcp.CallToolResult, any, error) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	ops := h.ops.List(limit)
+	if len(ops) == 0 {
+		return textResult("No operations recorded yet."), nil, nil
+	}
+
+	summaries := make([]map[string]any, 0, len(ops))
+	for _, op := range ops {
+		summaries = append(summaries, operationSummary(op))
+	}
+	res, err := jsonTextResult(map[string]any{
+		"count":      len(summaries),
+		"operations": summaries,
+	})
+	return res, nil, err
+}
+
+// UndoLast undoes the newest operation that has not been undone.
+func (h *History) UndoLast(ctx context.Context, req *mcp.CallToolRequest, input types.UndoLastInput) (*mcp.CallToolResult, any, error) {
+	op, ok := h.ops.LastUndoable()
+	if !ok {
+		return errorResult("nothing to undo"), nil, nil
+	}
+	return h.undo(ctx, op, "undo_last")
+}
+
+// UndoOperation undoes the operation with the given ID.
+func (h *History) UndoOperation(ctx context.Context, req *mcp.CallToolRequest, input types.UndoOperationInput) (*mcp.CallToolResult, any, error) {
+	op, ok := h.ops.Get(input.ID)
+	if !ok {
+		return errorResult(fmt.Sprintf("operation %d not found", input.ID)), nil, nil
+	}
+	return h.undo(ctx, op, "undo_operation")
+}
+
+// RedoLast reapplies the most recently undone operation.
+func (h *History) RedoLast(ctx context.Context, req *mcp.CallToolRequest, input types.RedoLastInput) (*mcp.CallToolResult, any, error) {
+	op, ok := h.ops.LastRedoable()
+	if !ok {
+		return errorResult("nothing to redo"), nil, nil
+	}
+	return h.undo(ctx, op, "redo_last")
+}
+
+func (h *History) undo(ctx context.Context, op oplog.Operation, tool string) (*mcp.CallToolResult, any, error) {
+	done, err := h.ops.Undo(ctx, h.client, op.ID, tool)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to undo operation %d: %v", op.ID, err)), nil, nil
+	}
+
+	// Undoing an undo redoes the operation it reverted.
+	result := map[string]any{
+		"undone": operationSummary(op),
+		"redone": op.Undoes != 0,
+	}
+	if done.ID != 0 {
+		result["operation"] = done.ID
+	}
+	res, err := jsonTextResult(result)
+	return res, nil, err
+}
+
+// PageHistory lists the saved versions of a page, newest first.
+func (h *History) PageHistory(ctx context.Context, req *mcp.CallToolRequest, input types.PageHistoryInput) (*mcp.CallToolResult, any, error) {
+	ph, ok := h.client.(backend.PageHistorian)
+	if !ok {
+		return errorResult("page history is not available for this backend"), nil, nil
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	versions, err := ph.PageHistory(ctx, input.Page, limit)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to read history of %s: %v", input.Page, err)), nil, nil
+	}
+	if len(versions) == 0 {
+		return errorResult(fmt.Sprintf("no saved versions of page %s", input.Page)), nil, nil
+	}
+	for i := range versions {
+		versions[i].Time = versions[i].Time.Local()
+	}
+	res, err := jsonTextResult(map[string]any{
+		"page":     input.Page,
+		"count":    len(versions),
+		"versions": versions,
+	})
+	return res, nil, err
+}
+
+// RestorePageVersion writes a page back as it was at a saved version. A
+// deleted page is re-created. The restore is recorded like any other write,
+// so it can be undone.
+func (h *History) RestorePageVersion(ctx context.Context, req *mcp.CallToolRequest, input types.RestorePageVersionInput) (*mcp.CallToolResult, any, error) {
+	ph, ok := h.client.(backend.PageHistorian)
+	if !ok {
+		return errorResult("page history is not available for this backend"), nil, nil
+	}
+	path, content, err := ph.PageAtVersion(ctx, input.Page, input.Version)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to read %s at version %s: %v", input.Page, input.Version, err)), nil, nil
+	}
+
+	result := map[string]any{
+		"page":    input.Page,
+		"version": input.Version,
+		"file":    path,
+	}
+	if input.DryRun {
+		result["dryRun"] = true
+		result["content"] = content
+		res, err := jsonTextResult(result)
+		return res, nil, err
+	}
+
+	rec := h.ops.Begin(h.client, "restore_page_version", input.Page+" @ "+input.Version)
+	if err := rec.WritePageFile(ctx, input.Page, path, content); err != nil {
+		rec.Commit()
+		return errorResult(fmt.Sprintf("failed to restore %s: %v", input.Page, err)), nil, nil
+	}
+	if done, logged := rec.Commit(); logged {
+		result["operation"] = done.ID
+	}
+	result["restored"] = true
+	res, err := jsonTextResult(result)
+	return res, nil, err
+}
+
+// operationSummary is the listing form of an operation.
+func operationSummary(op oplog.Operation) map[string]any {
+	s := map[string]any{
+		"id":    op.ID,
+		"time":  op.Time.Local().Format(time.RFC3339),
+		"tool":  op.Tool,
+		"steps": len(op.Undo),
+	}
+	if op.Summary != "" {
+		s["summary"] = op.Summary
+	}
+	if op.Undoes != 0 {
+		s["undoes"] = op.Undoes
+	}
+	if op.UndoneBy != 0 {
+		s["undoneBy"] = op.UndoneBy
+	}
+	return s
+}
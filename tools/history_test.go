@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/oplog"
+	"github.com/skridlevsky/graphthulhu/types"
+	"github.com/skridlevsky/graphthulhu/vault"
+	"github.com/skridlevsky/graphthulhu/vault/vaulttest"
+)
+
+func TestRestorePageVersion(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	c, dir := vaulttest.New(t, map[string]string{"Projects.md": "- alpha\n"}, vault.WithGitCommits(true))
+	path := filepath.Join(dir, "Projects.md")
+	ctx := context.Background()
+	ops, _ := oplog.Open("")
+
+	// Each write tool call is committed on its own.
+	blocks, _ := c.GetPageBlocksTree(ctx, "Projects")
+	w := NewWrite(c, ops)
+	if res, _, _ := w.UpdateBlock(ctx, nil, types.UpdateBlockInput{UUID: blocks[0].UUID, Content: "alpha 2"}); res.IsError {
+		t.Fatal(resultText(res))
+	}
+	if res, _, _ := w.DeletePage(ctx, nil, types.DeletePageInput{Name: "Projects"}); res.IsError {
+		t.Fatal(resultText(res))
+	}
+
+	h := NewHistory(c, ops)
+	res, _, _ := h.PageHistory(ctx, nil, types.PageHistoryInput{Page: "Projects"})
+	var history struct{ Versions []backend.PageVersion }
+	if err := json.Unmarshal([]byte(resultText(res)), &history); err != nil {
+		t.Fatalf("%v: %s", err, resultText(res))
+	}
+	if len(history.Versions) != 3 || !strings.HasPrefix(history.Versions[1].Message, "graphthulhu: update_block") {
+		t.Fatalf("history = %+v", history.Versions)
+	}
+
+	// Restoring the first version re-creates the deleted page, and can be
+	// undone like any other write.
+	first := history.Versions[2].Version
+	res, _, _ = h.RestorePageVersion(ctx, nil, types.RestorePageVersionInput{Page: "Projects", Version: first})
+	if res.IsError {
+		t.Fatal(resultText(res))
+	}
+	if data, _ := os.ReadFile(path); string(data) != "- alpha\n" {
+		t.Errorf("restored %q", data)
+	}
+	if res, _, _ := h.UndoLast(ctx, nil, types.UndoLastInput{}); res.IsError {
+		t.Fatal(resultText(res))
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("undo left the restored page")
+	}
+	if res, _, _ := h.PageHistory(ctx, nil, types.PageHistoryInput{Page: "Projects", Limit: 1}); !strings.Contains(resultText(res), "undo_last") {
+		t.Errorf("undo was not committed: %s", resultText(res))
+	}
+}
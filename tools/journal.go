@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -42,22 +43,8 @@ func (j *Journal) JournalRange(ctx context.Context, req *mcp.CallToolRequest, in
 	var journals []map[string]any
 
 	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
-		// Try common Logseq journal page name formats.
-		pageNames := journalPageNames(d)
-
-		var foundPage *types.PageEntity
-		var foundName string
-
-		for _, name := range pageNames {
-			page, err := j.client.GetPage(ctx, name)
-			if err == nil && page != nil {
-				foundPage = page
-				foundName = name
-				break
-			}
-		}
-
-		if foundPage == nil {
+		foundName := findJournalPage(ctx, j.client, d)
+		if foundName == "" {
 			continue
 		}
 
@@ -69,7 +56,7 @@ func (j *Journal) JournalRange(ctx context.Context, req *mcp.CallToolRequest, in
 		if input.IncludeBlocks {
 			blocks, err := j.client.GetPageBlocksTree(ctx, foundName)
 			if err == nil {
-				enriched := enrichBlockTree(blocks, -1, 0)
+				enriched := enrichBlockTree(blocks, foundName, -1, 0)
 				entry["blocks"] = enriched
 				entry["blockCount"] = countBlocks(enriched)
 			}
@@ -87,6 +74,18 @@ func (j *Journal) JournalRange(ctx context.Context, req *mcp.CallToolRequest, in
 	return res, nil, err
 }
 
+// findJournalPage returns the name of the journal page for day d, trying
+// the common Logseq journal page name formats. Returns "" when there is none.
+func findJournalPage(ctx context.Context, c backend.Backend, d time.Time) string {
+	for _, name := range journalPageNames(d) {
+		page, err := c.GetPage(ctx, name)
+		if err == nil && page != nil {
+			return name
+		}
+	}
+	return ""
+}
+
 // journalPageNames returns the candidate Logseq journal page names for a date,
 // ordered most-common-first. Logseq lets users pick a date format, so we try
 // the defaults first, then "day-first" variants that several locales prefer
@@ -140,18 +139,33 @@ func (j *Journal) JournalSearch(ctx context.Context, req *mcp.CallToolRequest, i
 			return errorResult(fmt.Sprintf("journal search failed: %v", err)), nil, nil
 		}
 
-		var matches []map[string]any
+		type match struct {
+			fields map[string]any
+			score  float64
+		}
+		var found []match
 		for _, r := range results {
-			for _, block := range r.Blocks {
-				parsed := parser.Parse(block.Content)
-				matches = append(matches, map[string]any{
+			for i, block := range r.Blocks {
+				parsed := parser.ParseInPage(block.Content, r.Page)
+				m := match{fields: map[string]any{
 					"content": block.Content,
 					"parsed":  parsed,
 					"page":    r.Page,
 					"date":    r.Date,
-				})
+				}}
+				if i < len(r.Scores) {
+					m.score = r.Scores[i]
+					m.fields["score"] = m.score
+				}
+				found = append(found, m)
 			}
 		}
+		// Ranked backends score every block; put the best hits first.
+		sort.SliceStable(found, func(i, j int) bool { return found[i].score > found[j].score })
+		matches := make([]map[string]any, len(found))
+		for i, m := range found {
+			matches[i] = m.fields
+		}
 
 		res, err := jsonTextResult(map[string]any{
 			"query":   input.Query,
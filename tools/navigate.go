@@ -28,8 +28,16 @@ func (n *Navigate) GetPage(ctx context.Context, req *mcp.CallToolRequest, input
 	if err != nil {
 		return errorResult(fmt.Sprintf("page not found: %s — %v", input.Name, err)), nil, nil
 	}
+	resolvedFrom := ""
 	if page == nil {
-		return errorResult(fmt.Sprintf("page not found: %s", input.Name)), nil, nil
+		name, err := resolveMissingPage(ctx, n.client, input.Name, input.Fuzzy)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		if page, err = n.client.GetPage(ctx, name); err != nil || page == nil {
+			return errorResult(fmt.Sprintf("page not found: %s", name)), nil, nil
+		}
+		resolvedFrom, input.Name = input.Name, name
 	}
 
 	blocks, err := n.client.GetPageBlocksTree(ctx, input.Name)
@@ -42,7 +50,7 @@ func (n *Navigate) GetPage(ctx context.Context, req *mcp.CallToolRequest, input
 		depth = -1 // unlimited by default
 	}
 
-	enrichedBlocks := enrichBlockTree(blocks, depth, 0)
+	enrichedBlocks := enrichBlockTree(blocks, page.Name, depth, 0)
 
 	totalBlocks := countBlocks(enrichedBlocks)
 	truncated := false
@@ -53,7 +61,7 @@ func (n *Navigate) GetPage(ctx context.Context, req *mcp.CallToolRequest, input
 	}
 
 	outgoing := collectOutgoingLinks(enrichedBlocks)
-	backlinks := n.getBacklinks(ctx, input.Name)
+	backlinks := getBacklinks(ctx, n.client, input.Name)
 
 	result := map[string]any{
 		"page":          page,
@@ -61,6 +69,9 @@ func (n *Navigate) GetPage(ctx context.Context, req *mcp.CallToolRequest, input
 		"backlinks":     backlinks,
 		"linkCount":     len(outgoing) + len(backlinks),
 	}
+	if resolvedFrom != "" {
+		result["resolvedFrom"] = resolvedFrom
+	}
 
 	if input.Compact {
 		// Compact mode: blocks as plain strings with UUIDs.
@@ -97,7 +108,11 @@ func (n *Navigate) GetBlock(ctx context.Context, req *mcp.CallToolRequest, input
 		return errorResult(fmt.Sprintf("block not found: %s", input.UUID)), nil, nil
 	}
 
-	enriched := enrichBlock(*block)
+	pageName := ""
+	if block.Page != nil {
+		pageName = block.Page.Name
+	}
+	enriched := enrichBlock(*block, pageName)
 
 	if input.IncludeAncestors {
 		ancestors, err := n.getAncestors(ctx, input.UUID)
@@ -192,13 +207,16 @@ func (n *Navigate) GetLinks(ctx context.Context, req *mcp.CallToolRequest, input
 	if direction == "forward" || direction == "both" {
 		blocks, err := n.client.GetPageBlocksTree(ctx, input.Name)
 		if err == nil {
-			outgoing := collectAllLinks(blocks)
+			outgoing := collectAllLinks(blocks, input.Name)
 			result["outgoingLinks"] = outgoing
+			if refs := collectAllLinkRefs(blocks, input.Name); len(refs) > 0 {
+				result["outgoingLinkRefs"] = refs
+			}
 		}
 	}
 
 	if direction == "backward" || direction == "both" {
-		backlinks := n.getBacklinks(ctx, input.Name)
+		backlinks := getBacklinks(ctx, n.client, input.Name)
 		result["backlinks"] = backlinks
 	}
 
@@ -231,6 +249,21 @@ func (n *Navigate) Traverse(ctx context.Context, req *mcp.CallToolRequest, input
 
 	paths := n.bfs(ctx, input.From, input.To, maxHops)
 
+	// No path may just mean a misspelled name: check both ends and retry
+	// with the resolved names.
+	var resolvedFrom []string
+	if len(paths) == 0 {
+		names, err := resolvePageNames(ctx, n.client, input.Fuzzy, input.From, input.To)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		if names[0] != input.From || names[1] != input.To {
+			resolvedFrom = []string{input.From, input.To}
+			input.From, input.To = names[0], names[1]
+			paths = n.bfs(ctx, input.From, input.To, maxHops)
+		}
+	}
+
 	if len(paths) == 0 {
 		return textResult(fmt.Sprintf("No path found between '%s' and '%s' within %d hops.", input.From, input.To, maxHops)), nil, nil
 	}
@@ -241,6 +274,9 @@ func (n *Navigate) Traverse(ctx context.Context, req *mcp.CallToolRequest, input
 		"pathsFound": len(paths),
 		"paths":      paths,
 	}
+	if resolvedFrom != nil {
+		result["resolvedFrom"] = resolvedFrom
+	}
 
 	res, err := jsonTextResult(result)
 	return res, nil, err
@@ -274,7 +310,7 @@ func (n *Navigate) bfs(ctx context.Context, from, to string, maxHops int) [][]st
 			continue
 		}
 
-		links := collectAllLinks(blocks)
+		links := collectAllLinks(blocks, current.path[len(current.path)-1])
 		for _, link := range links {
 			linkLower := strings.ToLower(link)
 			if linkLower == toLower {
@@ -293,8 +329,9 @@ func (n *Navigate) bfs(ctx context.Context, from, to string, maxHops int) [][]st
 	return paths
 }
 
-func (n *Navigate) getBacklinks(ctx context.Context, name string) []types.BackLink {
-	raw, err := n.client.GetPageLinkedReferences(ctx, name)
+// getBacklinks returns the blocks on other pages that link to page name.
+func getBacklinks(ctx context.Context, c backend.Backend, name string) []types.BackLink {
+	raw, err := c.GetPageLinkedReferences(ctx, name)
 	if err != nil {
 		return nil
 	}
@@ -445,16 +482,20 @@ func truncateEnrichedChildren(blocks []types.BlockEntity, remaining *int) []type
 	return result
 }
 
-func enrichBlockTree(blocks []types.BlockEntity, maxDepth, currentDepth int) []types.EnrichedBlock {
+// enrichBlockTree parses every block in the tree. pageName is the page the
+// blocks live on, used to resolve relative markdown links.
+func enrichBlockTree(blocks []types.BlockEntity, pageName string, maxDepth, currentDepth int) []types.EnrichedBlock {
 	if maxDepth >= 0 && currentDepth > maxDepth {
 		return nil
 	}
 
 	enriched := make([]types.EnrichedBlock, 0, len(blocks))
 	for _, b := range blocks {
-		eb := enrichBlock(b)
+		eb := enrichBlock(b, pageName)
+		// Children are rebuilt from the depth-limited tree below.
+		eb.BlockEntity.Children = nil
 		if len(b.Children) > 0 {
-			childEnriched := enrichBlockTree(b.Children, maxDepth, currentDepth+1)
+			childEnriched := enrichBlockTree(b.Children, pageName, maxDepth, currentDepth+1)
 			for _, ce := range childEnriched {
 				eb.BlockEntity.Children = append(eb.BlockEntity.Children, ce.BlockEntity)
 			}
@@ -464,13 +505,30 @@ func enrichBlockTree(blocks []types.BlockEntity, maxDepth, currentDepth int) []t
 	return enriched
 }
 
-func enrichBlock(b types.BlockEntity) types.EnrichedBlock {
+func enrichBlock(b types.BlockEntity, pageName string) types.EnrichedBlock {
+	b.Version = backend.BlockVersion(b.Content)
+	b.Children = withVersions(b.Children)
 	return types.EnrichedBlock{
 		BlockEntity: b,
-		Parsed:      parser.Parse(b.Content),
+		Parsed:      parser.ParseInPage(b.Content, pageName),
 	}
 }
 
+// withVersions returns a copy of blocks and their descendants with each
+// block's version set.
+func withVersions(blocks []types.BlockEntity) []types.BlockEntity {
+	if blocks == nil {
+		return nil
+	}
+	out := make([]types.BlockEntity, len(blocks))
+	for i, b := range blocks {
+		b.Version = backend.BlockVersion(b.Content)
+		b.Children = withVersions(b.Children)
+		out[i] = b
+	}
+	return out
+}
+
 func collectOutgoingLinks(blocks []types.EnrichedBlock) []string {
 	seen := make(map[string]bool)
 	var links []string
@@ -485,13 +543,13 @@ func collectOutgoingLinks(blocks []types.EnrichedBlock) []string {
 	return links
 }
 
-func collectAllLinks(blocks []types.BlockEntity) []string {
+func collectAllLinks(blocks []types.BlockEntity, pageName string) []string {
 	seen := make(map[string]bool)
 	var links []string
 	var walk func([]types.BlockEntity)
 	walk = func(bs []types.BlockEntity) {
 		for _, b := range bs {
-			parsed := parser.Parse(b.Content)
+			parsed := parser.ParseInPage(b.Content, pageName)
 			for _, link := range parsed.Links {
 				if !seen[link] {
 					links = append(links, link)
@@ -507,6 +565,29 @@ func collectAllLinks(blocks []types.BlockEntity) []string {
 	return links
 }
 
+// collectAllLinkRefs returns every distinct link occurrence in the tree with
+// its alias, anchor and embed flag, for clients that need more than the target.
+func collectAllLinkRefs(blocks []types.BlockEntity, pageName string) []types.LinkRef {
+	seen := make(map[types.LinkRef]bool)
+	var refs []types.LinkRef
+	var walk func([]types.BlockEntity)
+	walk = func(bs []types.BlockEntity) {
+		for _, b := range bs {
+			for _, ref := range parser.ParseInPage(b.Content, pageName).LinkRefs {
+				if !seen[ref] {
+					refs = append(refs, ref)
+					seen[ref] = true
+				}
+			}
+			if len(b.Children) > 0 {
+				walk(b.Children)
+			}
+		}
+	}
+	walk(blocks)
+	return refs
+}
+
 func countBlocks(blocks []types.EnrichedBlock) int {
 	count := len(blocks)
 	for _, b := range blocks {
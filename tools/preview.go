@@ -0,0 +1,229 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// Dry runs of the destructive and bulk write tools. Each runs the checks
+// the write would and reports what it would change, without writing.
+
+// previewUpsert reports the blocks upsert_blocks would create.
+func (w *Write) previewUpsert(ctx context.Context, input types.UpsertBlocksInput, position string) (*mcp.CallToolResult, any, error) {
+	page, err := w.client.GetPage(ctx, input.Page)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to read page '%s': %v", input.Page, err)), nil, nil
+	}
+
+	var plan func([]types.BlockInput) []map[string]any
+	count := 0
+	plan = func(blocks []types.BlockInput) []map[string]any {
+		out := make([]map[string]any, 0, len(blocks))
+		for _, b := range blocks {
+			count++
+			block := map[string]any{"content": blockContent(b)}
+			if len(b.Children) > 0 {
+				block["children"] = plan(b.Children)
+			}
+			out = append(out, block)
+		}
+		return out
+	}
+
+	result := map[string]any{
+		"dryRun":      true,
+		"page":        input.Page,
+		"position":    position,
+		"createsPage": page == nil,
+		"blocks":      plan(input.Blocks),
+		"blockCount":  count,
+	}
+	if page != nil {
+		if file := w.pageFile(ctx, input.Page); file != "" {
+			result["file"] = file
+		}
+	}
+	res, err := jsonTextResult(result)
+	return res, nil, err
+}
+
+// previewMove reports the block move_block would move and where to.
+func (w *Write) previewMove(ctx context.Context, input types.MoveBlockInput, position string) (*mcp.CallToolResult, any, error) {
+	if input.UUID == input.TargetUUID {
+		return errorResult(fmt.Sprintf("failed to move block: cannot move block relative to itself: %s", input.UUID)), nil, nil
+	}
+	src, err := w.client.GetBlock(ctx, input.UUID, map[string]any{"includeChildren": true})
+	if err != nil || src == nil {
+		return errorResult(fmt.Sprintf("failed to move block: source block not found: %s", input.UUID)), nil, nil
+	}
+	tgt, err := w.client.GetBlock(ctx, input.TargetUUID)
+	if err != nil || tgt == nil {
+		return errorResult(fmt.Sprintf("failed to move block: target block not found: %s", input.TargetUUID)), nil, nil
+	}
+	if containsBlock(src.Children, input.TargetUUID) {
+		return errorResult(fmt.Sprintf("failed to move block: cannot move block into its own children: %s", input.UUID)), nil, nil
+	}
+
+	block := w.blockSummary(ctx, src)
+	block["descendants"] = countBlocksRaw(src.Children)
+	res, err := jsonTextResult(map[string]any{
+		"dryRun":   true,
+		"block":    block,
+		"target":   w.blockSummary(ctx, tgt),
+		"position": position,
+	})
+	return res, nil, err
+}
+
+// previewDeletePage reports the page delete_page would remove and the links
+// to it that would be left dangling.
+func (w *Write) previewDeletePage(ctx context.Context, input types.DeletePageInput) (*mcp.CallToolResult, any, error) {
+	page, err := w.client.GetPage(ctx, input.Name)
+	if err != nil || page == nil {
+		return errorResult(fmt.Sprintf("failed to delete page '%s': page not found", input.Name)), nil, nil
+	}
+	blocks, err := w.client.GetPageBlocksTree(ctx, input.Name)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to read page '%s': %v", input.Name, err)), nil, nil
+	}
+
+	result := map[string]any{
+		"dryRun":     true,
+		"delete":     input.Name,
+		"blocks":     countBlocksRaw(blocks),
+		"linkedFrom": getBacklinks(ctx, w.client, input.Name),
+	}
+	if file := w.pageFile(ctx, input.Name); file != "" {
+		result["file"] = file
+	}
+	res, err := jsonTextResult(result)
+	return res, nil, err
+}
+
+// previewRename reports the file rename_page would move and every line it
+// would rewrite. Backends that rewrite links themselves plan the rename;
+// otherwise the blocks linking to the page are rewritten here the way
+// Logseq does.
+func (w *Write) previewRename(ctx context.Context, input types.RenamePageInput) (*mcp.CallToolResult, any, error) {
+	fail := func(err error) (*mcp.CallToolResult, any, error) {
+		return errorResult(fmt.Sprintf("failed to rename '%s' to '%s': %v", input.OldName, input.NewName, err)), nil, nil
+	}
+
+	var plan *backend.RenamePlan
+	if planner, ok := w.client.(backend.RenamePlanner); ok {
+		p, err := planner.PlanRenamePage(ctx, input.OldName, input.NewName)
+		if err != nil {
+			return fail(err)
+		}
+		plan = p
+	} else {
+		if page, err := w.client.GetPage(ctx, input.OldName); err != nil || page == nil {
+			return fail(fmt.Errorf("page not found: %s", input.OldName))
+		}
+		if page, _ := w.client.GetPage(ctx, input.NewName); page != nil {
+			return fail(fmt.Errorf("target page already exists: %s", input.NewName))
+		}
+		plan = &backend.RenamePlan{From: input.OldName, To: input.NewName, Links: []backend.LineChange{}}
+		for _, bl := range getBacklinks(ctx, w.client, input.OldName) {
+			for _, b := range bl.Blocks {
+				if after := renameLinks(b.Content, input.OldName, input.NewName); after != b.Content {
+					plan.Links = append(plan.Links, backend.LineChange{Page: bl.PageName, Before: b.Content, After: after})
+				}
+			}
+		}
+	}
+
+	var pages []string
+	for _, l := range plan.Links {
+		if !slices.Contains(pages, l.Page) {
+			pages = append(pages, l.Page)
+		}
+	}
+	res, err := jsonTextResult(map[string]any{
+		"dryRun":       true,
+		"rename":       plan,
+		"linkCount":    len(plan.Links),
+		"pagesTouched": pages,
+	})
+	return res, nil, err
+}
+
+// renameLinks points [[oldName]] and #oldName links in content at newName.
+func renameLinks(content, oldName, newName string) string {
+	quoted := regexp.QuoteMeta(oldName)
+	content = regexp.MustCompile(`(?i)\[\[`+quoted+`\]\]`).ReplaceAllLiteralString(content, "[["+newName+"]]")
+
+	tag := "#" + newName
+	if strings.ContainsAny(newName, " \t[]") {
+		tag = "#[[" + newName + "]]"
+	}
+	tagPattern := regexp.MustCompile(`(?i)(^|\s)#` + quoted + `($|[\s,.;:!?)])`)
+	return tagPattern.ReplaceAllStringFunc(content, func(m string) string {
+		sub := tagPattern.FindStringSubmatch(m)
+		return sub[1] + tag + sub[2]
+	})
+}
+
+// blockSummary describes a block by its content and where it lives.
+func (w *Write) blockSummary(ctx context.Context, b *types.BlockEntity) map[string]any {
+	s := map[string]any{
+		"uuid":    b.UUID,
+		"content": b.Content,
+	}
+	if page := w.blockPage(ctx, b); page != "" {
+		s["page"] = page
+		if file := w.pageFile(ctx, page); file != "" {
+			s["file"] = file
+		}
+	}
+	return s
+}
+
+// blockPage returns the name of the page b is on, or "".
+func (w *Write) blockPage(ctx context.Context, b *types.BlockEntity) string {
+	if b.Page == nil {
+		return ""
+	}
+	if b.Page.Name != "" {
+		return b.Page.Name
+	}
+	page, err := w.client.GetPage(ctx, b.Page.ID)
+	if err != nil || page == nil {
+		return ""
+	}
+	if page.OriginalName != "" {
+		return page.OriginalName
+	}
+	return page.Name
+}
+
+// pageFile returns the file page name is kept in, relative to the graph
+// root, or "" when the backend does not keep pages in files.
+func (w *Write) pageFile(ctx context.Context, name string) string {
+	fs, ok := w.client.(backend.PageFileStore)
+	if !ok {
+		return ""
+	}
+	path, _, err := fs.PageFile(ctx, name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// containsBlock reports whether uuid is among blocks or their descendants.
+func containsBlock(blocks []types.BlockEntity, uuid string) bool {
+	for _, b := range blocks {
+		if b.UUID == uuid || containsBlock(b.Children, uuid) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/types"
+	"github.com/skridlevsky/graphthulhu/vault/vaulttest"
+)
+
+func TestDryRunsWriteNothing(t *testing.T) {
+	files := map[string]string{
+		"Old.md":   "---\nstatus: draft\n---\n- a\n  - a1\n- b\n",
+		"Other.md": "- See [[Old]].\n",
+	}
+	c, dir := vaulttest.New(t, files)
+	ctx := context.Background()
+	w := NewWrite(c, nil)
+
+	blocks, _ := c.GetPageBlocksTree(ctx, "Old")
+	var a, b string
+	for _, blk := range blocks {
+		switch blk.Content {
+		case "a":
+			a = blk.UUID
+		case "b":
+			b = blk.UUID
+		}
+	}
+
+	run := func(res *mcp.CallToolResult, _ any, err error) map[string]any {
+		t.Helper()
+		if err != nil || res.IsError {
+			t.Fatalf("%v %+v", err, res.Content)
+		}
+		var out map[string]any
+		if err := json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out["dryRun"] != true {
+			t.Errorf("not marked as a dry run: %v", out)
+		}
+		return out
+	}
+
+	rename := run(w.RenamePage(ctx, nil, types.RenamePageInput{OldName: "Old", NewName: "New", DryRun: true}))
+	if rename["linkCount"] != 1.0 {
+		t.Errorf("rename plan = %v", rename)
+	}
+	del := run(w.DeletePage(ctx, nil, types.DeletePageInput{Name: "Old", DryRun: true}))
+	if del["file"] != "Old.md" || del["blocks"] != 3.0 {
+		t.Errorf("delete plan = %v", del)
+	}
+	move := run(w.MoveBlock(ctx, nil, types.MoveBlockInput{UUID: a, TargetUUID: b, DryRun: true}))
+	if move["block"].(map[string]any)["descendants"] != 1.0 {
+		t.Errorf("move plan = %v", move)
+	}
+	run(w.upsertBlocks(ctx, types.UpsertBlocksInput{Page: "Old", Blocks: []types.BlockInput{{Content: "c"}}, DryRun: true}))
+	bulk := run(w.BulkUpdateProperties(ctx, nil, types.BulkUpdatePropertiesInput{Pages: []string{"Old", "Other"}, Property: "type", Value: "note", DryRun: true}))
+	if changes := bulk["changes"].([]any); len(changes) != 2 {
+		t.Errorf("bulk plan = %v", bulk)
+	}
+
+	for name, want := range files {
+		got, _ := os.ReadFile(filepath.Join(dir, name))
+		if string(got) != want {
+			t.Errorf("%s changed by a dry run:\n%s", name, got)
+		}
+	}
+
+	// Checks the write would make still fail the dry run.
+	res, _, _ := w.MoveBlock(ctx, nil, types.MoveBlockInput{UUID: a, TargetUUID: a, DryRun: true})
+	if !res.IsError {
+		t.Error("dry run of a move onto itself succeeded")
+	}
+	res, _, _ = w.RenamePage(ctx, nil, types.RenamePageInput{OldName: "Old", NewName: "Other", DryRun: true})
+	if !res.IsError {
+		t.Error("dry run of a rename onto an existing page succeeded")
+	}
+}
+
+func TestRenameLinks(t *testing.T) {
+	tests := []struct {
+		content, oldName, newName, want string
+	}{
+		{"See [[old page]] and [[Old Page]].", "Old Page", "New", "See [[New]] and [[New]]."},
+		{"#Old, #Older and #[[Old]]", "old", "New", "#New, #Older and #[[New]]"},
+		{"tagged #old", "old", "New Name", "tagged #[[New Name]]"},
+		{"[[Old page]]s and [[Old page 2]]", "Old page", "New", "[[New]]s and [[Old page 2]]"},
+	}
+	for _, tt := range tests {
+		if got := renameLinks(tt.content, tt.oldName, tt.newName); got != tt.want {
+			t.Errorf("renameLinks(%q, %q, %q) = %q, want %q", tt.content, tt.oldName, tt.newName, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// Prompts implements MCP prompts: ready-made requests that gather what a
+// task needs with the read tools and hand it to the model with
+// instructions, so a client can start a review without choosing tools.
+type Prompts struct {
+	nav      *Navigate
+	analyze  *Analyze
+	journal  *Journal
+	decision *Decision
+}
+
+// NewPrompts creates a new Prompts handler.
+func NewPrompts(c backend.Backend) *Prompts {
+	return &Prompts{
+		nav:      NewNavigate(c),
+		analyze:  NewAnalyze(c),
+		journal:  NewJournal(c),
+		decision: NewDecision(c, nil),
+	}
+}
+
+// WeeklyReview gathers the journals of the seven days ending on the "end"
+// argument (default today) and the open decisions.
+func (p *Prompts) WeeklyReview(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	end := time.Now()
+	if arg := req.Params.Arguments["end"]; arg != "" {
+		var err error
+		if end, err = time.Parse("2006-01-02", arg); err != nil {
+			return nil, fmt.Errorf("invalid end date %q: use YYYY-MM-DD", arg)
+		}
+	}
+	from := end.AddDate(0, 0, -6).Format("2006-01-02")
+	to := end.Format("2006-01-02")
+
+	journals, err := toolText(p.journal.JournalRange(ctx, nil, types.JournalRangeInput{From: from, To: to, IncludeBlocks: true}))
+	if err != nil {
+		return nil, err
+	}
+	decisions, err := toolText(p.decision.DecisionCheck(ctx, nil, types.DecisionCheckInput{}))
+	if err != nil {
+		return nil, err
+	}
+
+	return promptResult(fmt.Sprintf("Weekly review %s to %s", from, to),
+		"Review my week from "+from+" to "+to+". Summarize what I worked on, what got done and what was left open, "+
+			"and recurring themes across the days. Then go through the decisions: call out overdue ones and any the "+
+			"journals bear on. End with three priorities for next week.\n\n"+
+			"## Journals\n\n"+journals+"\n\n## Decisions\n\n"+decisions), nil
+}
+
+// FindGaps gathers the orphan pages, dead ends and weakly linked pages of
+// the graph. The optional "minBlockCount" argument skips smaller orphans.
+func (p *Prompts) FindGaps(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	input := types.KnowledgeGapsInput{ExcludeNumeric: true}
+	if arg := req.Params.Arguments["minBlockCount"]; arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid minBlockCount %q: use a non-negative number", arg)
+		}
+		input.MinBlockCount = n
+	}
+
+	gaps, err := toolText(p.analyze.KnowledgeGaps(ctx, nil, input))
+	if err != nil {
+		return nil, err
+	}
+
+	return promptResult("Knowledge gaps",
+		"Here are the gaps in my knowledge graph: orphan pages nothing links to, dead-end pages that link nowhere, "+
+			"and weakly linked pages. Group them by topic, say which look like they belong together or to an existing "+
+			"page, and suggest concrete links to add. Point out orphans that look abandoned and could be deleted.\n\n"+
+			"## Gaps\n\n"+gaps), nil
+}
+
+// SummarizePage gathers a page and the pages linking to it. The "page"
+// argument is required.
+func (p *Prompts) SummarizePage(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	name := req.Params.Arguments["page"]
+	if name == "" {
+		return nil, errors.New("page is required")
+	}
+
+	page, err := toolText(p.nav.GetPage(ctx, nil, types.GetPageInput{Name: name, Compact: true}))
+	if err != nil {
+		return nil, err
+	}
+	backlinks, err := toolText(p.nav.GetLinks(ctx, nil, types.GetLinksInput{Name: name, Direction: "backward"}))
+	if err != nil {
+		return nil, err
+	}
+
+	return promptResult("Summary of "+name,
+		"Summarize the page \""+name+"\": its main points, open questions and TODOs. Then use the backlinks to "+
+			"explain how the page fits into the rest of the graph: which pages depend on it and in what context.\n\n"+
+			"## Page\n\n"+page+"\n\n## Backlinks\n\n"+backlinks), nil
+}
+
+// toolText returns the text of a tool result, or its message as an error
+// when the tool failed.
+func toolText(res *mcp.CallToolResult, _ any, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	if res.IsError {
+		return "", errors.New(resultText(res))
+	}
+	return resultText(res), nil
+}
+
+func promptResult(description, text string) *mcp.GetPromptResult {
+	return &mcp.GetPromptResult{
+		Description: description,
+		Messages: []*mcp.PromptMessage{{
+			Role:    "user",
+			Content: &mcp.TextContent{Text: text},
+		}},
+	}
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestPrompts(t *testing.T) {
+	c, _ := batchVault(t)
+	ctx := context.Background()
+	if _, err := c.CreatePage(ctx, "2026-01-31", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AppendBlockInPage(ctx, "2026-01-31", "planned [[Projects]]"); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPrompts(c)
+
+	get := func(handler mcp.PromptHandler, args map[string]string) (string, error) {
+		res, err := handler(ctx, &mcp.GetPromptRequest{Params: &mcp.GetPromptParams{Arguments: args}})
+		if err != nil {
+			return "", err
+		}
+		return res.Messages[0].Content.(*mcp.TextContent).Text, nil
+	}
+
+	text, err := get(p.WeeklyReview, map[string]string{"end": "2026-02-03"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"2026-01-28 to 2026-02-03", "planned [[Projects]]", "## Decisions"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("weekly review lacks %q:\n%s", want, text)
+		}
+	}
+	if _, err := get(p.WeeklyReview, map[string]string{"end": "soon"}); err == nil {
+		t.Error("accepted a malformed end date")
+	}
+
+	if text, err := get(p.FindGaps, nil); err != nil || !strings.Contains(text, "Notes") {
+		t.Errorf("find gaps = %q, %v", text, err)
+	}
+
+	text, err = get(p.SummarizePage, map[string]string{"page": "Projects"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "alpha") || !strings.Contains(text, "2026-01-31") {
+		t.Errorf("summary lacks the page or its backlinks:\n%s", text)
+	}
+	if _, err := get(p.SummarizePage, map[string]string{"page": "Missing"}); err == nil {
+		t.Error("summarized a missing page")
+	}
+	if _, err := get(p.SummarizePage, nil); err == nil {
+		t.Error("summarized without a page")
+	}
+}
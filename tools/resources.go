@@ -0,0 +1,311 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// Graph resources: every page is readable as graph://page/{name}, with the
+// name percent-encoded, every journal day as graph://journal/{date} and
+// every block with its children as graph://block/{uuid}. Clients can
+// subscribe to page resources to be told when the page changes.
+
+// URI templates of the graph resources.
+const (
+	PageURITemplate    = "graph://page/{name}"
+	JournalURITemplate = "graph://journal/{date}"
+	BlockURITemplate   = "graph://block/{uuid}"
+)
+
+const (
+	pageURIPrefix    = "graph://page/"
+	journalURIPrefix = "graph://journal/"
+	blockURIPrefix   = "graph://block/"
+)
+
+// PageURI returns the resource URI of a page.
+func PageURI(name string) string {
+	return pageURIPrefix + url.PathEscape(name)
+}
+
+// PageFromURI returns the page name a page resource URI refers to.
+func PageFromURI(uri string) (string, bool) {
+	return uriParam(uri, pageURIPrefix)
+}
+
+// BlockFromURI returns the block UUID a block resource URI refers to.
+func BlockFromURI(uri string) (string, bool) {
+	return uriParam(uri, blockURIPrefix)
+}
+
+// uriParam returns the unescaped part of uri after prefix.
+func uriParam(uri, prefix string) (string, bool) {
+	escaped, ok := strings.CutPrefix(uri, prefix)
+	if !ok || escaped == "" {
+		return "", false
+	}
+	param, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", false
+	}
+	return param, true
+}
+
+// Resources implements the MCP resource handlers.
+type Resources struct {
+	client backend.Backend
+}
+
+// NewResources creates a new Resources handler.
+func NewResources(c backend.Backend) *Resources {
+	return &Resources{client: c}
+}
+
+// ReadPage reads a page resource as markdown: the page's file on backends
+// that keep pages in files, otherwise its block tree as an outline.
+func (r *Resources) ReadPage(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	name, ok := PageFromURI(uri)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	return r.readPage(ctx, uri, name)
+}
+
+// ReadJournal reads a journal resource: the journal page of a day, given as
+// YYYY-MM-DD, as ReadPage does.
+func (r *Resources) ReadJournal(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	date, ok := uriParam(uri, journalURIPrefix)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q: use YYYY-MM-DD", date)
+	}
+	name := findJournalPage(ctx, r.client, day)
+	if name == "" {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	return r.readPage(ctx, uri, name)
+}
+
+// ReadBlock reads a block resource: the block and its descendants as a
+// markdown outline.
+func (r *Resources) ReadBlock(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	uuid, ok := BlockFromURI(uri)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	block, err := r.client.GetBlock(ctx, uuid, map[string]any{"includeChildren": true})
+	if err != nil {
+		return nil, fmt.Errorf("read block %s: %w", uuid, err)
+	}
+	if block == nil {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	var b strings.Builder
+	writeOutline(&b, []types.BlockEntity{*block}, 0)
+	return markdownResource(uri, b.String()), nil
+}
+
+// readPage reads page name as the resource with the given uri.
+func (r *Resources) readPage(ctx context.Context, uri, name string) (*mcp.ReadResourceResult, error) {
+	page, err := r.client.GetPage(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("read page %s: %w", name, err)
+	}
+	if page == nil {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	if fs, ok := r.client.(backend.PageFileStore); ok {
+		_, text, err := fs.PageFile(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("read page %s: %w", name, err)
+		}
+		return markdownResource(uri, text), nil
+	}
+	blocks, err := r.client.GetPageBlocksTree(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("read page %s: %w", name, err)
+	}
+	var b strings.Builder
+	writeOutline(&b, blocks, 0)
+	return markdownResource(uri, b.String()), nil
+}
+
+func markdownResource(uri, text string) *mcp.ReadResourceResult {
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{
+		URI:      uri,
+		MIMEType: "text/markdown",
+		Text:     text,
+	}}}
+}
+
+// writeOutline renders blocks as a markdown outline, continuation lines of
+// multi-line blocks indented under their bullet.
+func writeOutline(b *strings.Builder, blocks []types.BlockEntity, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, block := range blocks {
+		for i, line := range strings.Split(block.Content, "\n") {
+			if i == 0 {
+				b.WriteString(indent + "- " + line + "\n")
+			} else {
+				b.WriteString(indent + "  " + line + "\n")
+			}
+		}
+		writeOutline(b, block.Children, depth+1)
+	}
+}
+
+// PageSubscriptions tracks the page resources clients subscribed to. The
+// MCP server sends an update only to the exact URI a client subscribed
+// with, while page names are case-insensitive and can be encoded several
+// ways, so a change is reported under every URI naming the page.
+// Subscriptions are dropped when their session ends, as the MCP server does
+// not report that through Unsubscribe.
+type PageSubscriptions struct {
+	mu       sync.Mutex
+	uris     map[string]map[string]map[*mcp.ServerSession]bool // lowercase page name → URI → sessions
+	sessions map[*mcp.ServerSession]bool                       // sessions with subscriptions
+
+	// The watch WatchWhileSubscribed runs, its context and how to stop it
+	// while it runs.
+	watch    func(context.Context)
+	watchCtx context.Context
+	stop     context.CancelFunc
+}
+
+// WatchWhileSubscribed calls watch with the first subscription, passing a
+// context that ends with ctx or when the last subscription is dropped.
+// watch is called again should subscriptions resume. It must not block.
+func (s *PageSubscriptions) WatchWhileSubscribed(ctx context.Context, watch func(context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watch, s.watchCtx = watch, ctx
+	s.updateWatchLocked()
+}
+
+// updateWatchLocked starts the watch when there are subscriptions and stops
+// it when there are none.
+func (s *PageSubscriptions) updateWatchLocked() {
+	switch {
+	case len(s.uris) == 0 && s.stop != nil:
+		s.stop()
+		s.stop = nil
+	case len(s.uris) > 0 && s.stop == nil && s.watch != nil && s.watchCtx.Err() == nil:
+		var ctx context.Context
+		ctx, s.stop = context.WithCancel(s.watchCtx)
+		s.watch(ctx)
+	}
+}
+
+// Subscribe records a subscription. Only page resources can be subscribed to.
+func (s *PageSubscriptions) Subscribe(ctx context.Context, req *mcp.SubscribeRequest) error {
+	uri := req.Params.URI
+	name, ok := PageFromURI(uri)
+	if !ok {
+		return mcp.ResourceNotFoundError(uri)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.uris == nil {
+		s.uris = make(map[string]map[string]map[*mcp.ServerSession]bool)
+		s.sessions = make(map[*mcp.ServerSession]bool)
+	}
+	key := strings.ToLower(name)
+	if s.uris[key] == nil {
+		s.uris[key] = make(map[string]map[*mcp.ServerSession]bool)
+	}
+	if s.uris[key][uri] == nil {
+		s.uris[key][uri] = make(map[*mcp.ServerSession]bool)
+	}
+	s.uris[key][uri][req.Session] = true
+	if ss := req.Session; ss != nil && !s.sessions[ss] {
+		s.sessions[ss] = true
+		go func() {
+			ss.Wait()
+			s.dropSession(ss)
+		}()
+	}
+	s.updateWatchLocked()
+	return nil
+}
+
+// Unsubscribe drops a subscription.
+func (s *PageSubscriptions) Unsubscribe(ctx context.Context, req *mcp.UnsubscribeRequest) error {
+	uri := req.Params.URI
+	name, ok := PageFromURI(uri)
+	if !ok {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(strings.ToLower(name), uri, req.Session)
+	s.updateWatchLocked()
+	return nil
+}
+
+// dropSession drops the subscriptions of a session that ended.
+func (s *PageSubscriptions) dropSession(ss *mcp.ServerSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, ss)
+	for key, uris := range s.uris {
+		for uri := range uris {
+			s.removeLocked(key, uri, ss)
+		}
+	}
+	s.updateWatchLocked()
+}
+
+// removeLocked drops the subscription of session ss to uri, which names the
+// page with lowercase name key.
+func (s *PageSubscriptions) removeLocked(key, uri string, ss *mcp.ServerSession) {
+	delete(s.uris[key][uri], ss)
+	if len(s.uris[key][uri]) == 0 {
+		delete(s.uris[key], uri)
+	}
+	if len(s.uris[key]) == 0 {
+		delete(s.uris, key)
+	}
+}
+
+// Subscribed reports whether any page is subscribed to.
+func (s *PageSubscriptions) Subscribed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.uris) > 0
+}
+
+// URIs returns the subscribed URIs that name page.
+func (s *PageSubscriptions) URIs(page string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uris := make([]string, 0, len(s.uris[strings.ToLower(page)]))
+	for uri := range s.uris[strings.ToLower(page)] {
+		uris = append(uris, uri)
+	}
+	slices.Sort(uris)
+	return uris
+}
+
+// Notify sends a resource update for every subscribed URI naming the
+// changed page. Sessions that did not subscribe to a URI are not told.
+func (s *PageSubscriptions) Notify(ctx context.Context, srv *mcp.Server, change backend.PageChange) {
+	for _, uri := range s.URIs(change.Page) {
+		srv.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: uri})
+	}
+}
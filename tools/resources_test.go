@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestReadPage(t *testing.T) {
+	c, _ := batchVault(t)
+	ctx := context.Background()
+
+	if uri := PageURI("projects/Q3 plan"); uri != "graph://page/projects%2FQ3%20plan" {
+		t.Errorf("PageURI = %q", uri)
+	}
+	if name, ok := PageFromURI(PageURI("projects/Q3 plan")); !ok || name != "projects/Q3 plan" {
+		t.Errorf("PageFromURI = %q, %v", name, ok)
+	}
+
+	read := func(r *Resources, uri string) string {
+		t.Helper()
+		res, err := r.ReadPage(ctx, &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: uri}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Contents[0].Text
+	}
+	// Page files are served as they are; other backends get an outline.
+	if got := read(NewResources(c), PageURI("Notes")); got != "---\nstatus: draft\n---\n- idea\n" {
+		t.Errorf("file = %q", got)
+	}
+	if got := read(NewResources(plainBackend{c}), PageURI("projects")); got != "- alpha\n- beta\n" {
+		t.Errorf("outline = %q", got)
+	}
+
+	_, err := NewResources(c).ReadPage(ctx, &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: PageURI("Missing")}})
+	if err == nil {
+		t.Error("read a missing page")
+	}
+}
+
+func TestReadJournalAndBlock(t *testing.T) {
+	c, dir := batchVault(t)
+	ctx := context.Background()
+	want := "- standup\n  - shipped\n    the release\n"
+	if err := os.WriteFile(filepath.Join(dir, "2026-01-31.md"), []byte(want), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	blocks, _ := c.GetPageBlocksTree(ctx, "2026-01-31")
+
+	r := NewResources(plainBackend{c})
+	read := func(read mcp.ResourceHandler, uri string) (string, error) {
+		res, err := read(ctx, &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: uri}})
+		if err != nil {
+			return "", err
+		}
+		return res.Contents[0].Text, nil
+	}
+
+	if got, err := read(r.ReadJournal, "graph://journal/2026-01-31"); err != nil || got != want {
+		t.Errorf("journal = %q, %v", got, err)
+	}
+	if _, err := read(r.ReadJournal, "graph://journal/2026-02-01"); err == nil {
+		t.Error("read a day without a journal")
+	}
+	if _, err := read(r.ReadJournal, "graph://journal/yesterday"); err == nil {
+		t.Error("accepted a malformed date")
+	}
+
+	if got, err := read(r.ReadBlock, "graph://block/"+blocks[0].UUID); err != nil || got != want {
+		t.Errorf("block = %q, %v", got, err)
+	}
+	if _, err := read(r.ReadBlock, "graph://block/no-such-block"); err == nil {
+		t.Error("read a missing block")
+	}
+}
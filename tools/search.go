@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/skridlevsky/graphthulhu/backend"
 	"github.com/skridlevsky/graphthulhu/parser"
+	"github.com/skridlevsky/graphthulhu/query"
 	"github.com/skridlevsky/graphthulhu/types"
 )
 
@@ -52,21 +54,18 @@ func (s *Search) searchIndexed(ctx context.Context, searcher backend.FullTextSea
 
 	var results []map[string]any
 	for _, hit := range hits {
-		if input.Compact {
-			results = append(results, map[string]any{
-				"page":    hit.PageName,
-				"uuid":    hit.UUID,
-				"content": hit.Content,
-			})
-		} else {
-			parsed := parser.Parse(hit.Content)
-			results = append(results, map[string]any{
-				"page":    hit.PageName,
-				"uuid":    hit.UUID,
-				"content": hit.Content,
-				"parsed":  parsed,
-			})
+		result := map[string]any{
+			"page":    hit.PageName,
+			"uuid":    hit.UUID,
+			"content": hit.Content,
+		}
+		if hit.Score != 0 {
+			result["score"] = hit.Score
+		}
+		if !input.Compact {
+			result["parsed"] = parser.ParseInPage(hit.Content, hit.PageName)
 		}
+		results = append(results, result)
 	}
 
 	res, err := jsonTextResult(map[string]any{
@@ -133,48 +132,153 @@ func (s *Search) searchBruteForce(ctx context.Context, input types.SearchInput,
 // QueryProperties finds blocks/pages by property values.
 func (s *Search) QueryProperties(ctx context.Context, req *mcp.CallToolRequest, input types.QueryPropertiesInput) (*mcp.CallToolResult, any, error) {
 	operator := input.Operator
-	if operator == "" {
+	values := input.Values
+	if len(values) == 0 && input.Value != "" {
+		values = []string{input.Value}
+	}
+	switch {
+	case operator == "" && len(values) == 0:
+		operator = "exists"
+	case operator == "":
 		operator = "eq"
 	}
 
-	// Use native property search if the backend supports it (e.g. Obsidian).
+	var results []backend.PropertyResult
+	var err error
 	if searcher, ok := s.client.(backend.PropertySearcher); ok {
-		results, err := searcher.FindByProperty(ctx, input.Property, input.Value, operator)
-		if err != nil {
-			return errorResult(fmt.Sprintf("property search failed: %v", err)), nil, nil
-		}
-		res, err := jsonTextResult(map[string]any{
-			"property": input.Property,
-			"value":    input.Value,
-			"operator": operator,
-			"count":    len(results),
-			"results":  results,
-		})
-		return res, nil, err
+		// Use native property search if the backend supports it (e.g. Obsidian).
+		results, err = searcher.FindByProperty(ctx, input.Property, operator, values)
+	} else {
+		// Fall back to DataScript (Logseq).
+		results, err = s.findByPropertyViaDataScript(ctx, input.Property, operator, values)
+	}
+	if err != nil {
+		return errorResult(fmt.Sprintf("property search failed: %v", err)), nil, nil
 	}
 
-	// Fall back to DataScript (Logseq).
-	var query string
-	if input.Value == "" {
-		query = fmt.Sprintf(`[:find (pull ?b [:block/uuid :block/content :block/properties {:block/page [:block/name :block/original-name]}])
-			:where
-			[?b :block/properties ?props]
-			[(get ?props :%s)]]`, input.Property)
-	} else {
-		query = fmt.Sprintf(`[:find (pull ?b [:block/uuid :block/content :block/properties {:block/page [:block/name :block/original-name]}])
-			:where
-			[?b :block/properties ?props]
-			[(get ?props :%s) ?v]
-			[(str ?v) ?vs]
-			[(clojure.string/includes? ?vs "%s")]]`, input.Property, input.Value)
+	out := map[string]any{
+		"property": input.Property,
+		"operator": operator,
+		"count":    len(results),
+		"results":  results,
+	}
+	if len(values) > 0 {
+		out["values"] = values
+	}
+	res, err := jsonTextResult(out)
+	return res, nil, err
+}
+
+// propertyKeyPattern matches the property keys that can be spliced into a
+// DataScript query as a keyword.
+var propertyKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_\-.?*+!]+$`)
+
+// findByPropertyViaDataScript finds the pages and blocks with property key
+// through DataScript and filters their values in Go, so values are compared
+// by type as on the vault backends.
+func (s *Search) findByPropertyViaDataScript(ctx context.Context, key, operator string, values []string) ([]backend.PropertyResult, error) {
+	match, err := parser.NewPropertyMatcher(operator, values)
+	if err != nil {
+		return nil, err
+	}
+	if !propertyKeyPattern.MatchString(key) {
+		return nil, fmt.Errorf("invalid property key %q", key)
 	}
+	key = strings.ToLower(key)
 
+	query := fmt.Sprintf(`[:find (pull ?b [:block/uuid :block/name :block/original-name :block/content
+			:block/properties :block/pre-block? {:block/page [:block/name :block/original-name]}])
+		:where
+		[?b :block/properties ?props]
+		[(get ?props :%s)]]`, key)
 	raw, err := s.client.DatascriptQuery(ctx, query)
 	if err != nil {
-		return errorResult(fmt.Sprintf("property query failed: %v", err)), nil, nil
+		return nil, err
+	}
+	var rows [][]json.RawMessage
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("parse results: %w", err)
 	}
 
-	res, err := jsonRawTextResult(raw)
+	var results []backend.PropertyResult
+	for _, row := range rows {
+		if len(row) == 0 {
+			continue
+		}
+		var e struct {
+			UUID         string         `json:"uuid"`
+			Name         string         `json:"name"`
+			OriginalName string         `json:"original-name"`
+			Content      string         `json:"content"`
+			Properties   map[string]any `json:"properties"`
+			PreBlock     bool           `json:"pre-block?"`
+			Page         *struct {
+				Name         string `json:"name"`
+				OriginalName string `json:"original-name"`
+			} `json:"page"`
+		}
+		if err := json.Unmarshal(row[0], &e); err != nil || e.PreBlock {
+			continue
+		}
+		if v, ok := e.Properties[key]; !ok || !match.Match(v) {
+			continue
+		}
+		if e.Page == nil {
+			name := e.OriginalName
+			if name == "" {
+				name = e.Name
+			}
+			results = append(results, backend.PropertyResult{
+				Type:       "page",
+				Name:       name,
+				Properties: e.Properties,
+			})
+			continue
+		}
+		page := e.Page.OriginalName
+		if page == "" {
+			page = e.Page.Name
+		}
+		results = append(results, backend.PropertyResult{
+			Type:       "block",
+			UUID:       e.UUID,
+			Content:    e.Content,
+			Page:       page,
+			Properties: e.Properties,
+		})
+	}
+	return results, nil
+}
+
+// Query evaluates a simple query with the query engine, which answers
+// filters from the backend's tag, property and full-text indexes where it
+// has them.
+func (s *Search) Query(ctx context.Context, req *mcp.CallToolRequest, input types.QueryInput) (*mcp.CallToolResult, any, error) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	q, err := query.Parse(input.Query)
+	if err != nil {
+		return errorResult(fmt.Sprintf("invalid query: %v", err)), nil, nil
+	}
+	result, err := query.NewEngine(s.client).Run(ctx, q, limit)
+	if err != nil {
+		return errorResult(fmt.Sprintf("query failed: %v", err)), nil, nil
+	}
+
+	out := map[string]any{"query": q.String()}
+	if q.PageLevel() {
+		out["count"] = len(result.Pages)
+		out["pages"] = append([]query.PageMatch{}, result.Pages...)
+	} else {
+		out["count"] = len(result.Blocks)
+		out["blocks"] = append([]query.BlockMatch{}, result.Blocks...)
+	}
+	if result.Truncated {
+		out["truncated"] = true
+	}
+	res, err := jsonTextResult(out)
 	return res, nil, err
 }
 
@@ -202,10 +306,18 @@ func (s *Search) FindByTag(ctx context.Context, req *mcp.CallToolRequest, input
 			return errorResult(fmt.Sprintf("tag search failed: %v", err)), nil, nil
 		}
 
+		// Pages tagged in frontmatter are results of their own, ahead of
+		// their tagged blocks.
 		var enriched []map[string]any
 		for _, r := range results {
+			if r.PageTagged {
+				enriched = append(enriched, map[string]any{
+					"page":       r.Page,
+					"pageTagged": true,
+				})
+			}
 			for _, block := range r.Blocks {
-				parsed := parser.Parse(block.Content)
+				parsed := parser.ParseInPage(block.Content, r.Page)
 				enriched = append(enriched, map[string]any{
 					"uuid":    block.UUID,
 					"content": block.Content,
@@ -328,7 +440,7 @@ func searchBlocksRecursive(blocks []types.BlockEntity, terms []string, pageName
 				}
 			}
 
-			parsed := parser.Parse(b.Content)
+			parsed := parser.ParseInPage(b.Content, pageName)
 			match := map[string]any{
 				"page":        pageName,
 				"uuid":        b.UUID,
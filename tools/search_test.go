@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+func TestQueryPropertiesViaDataScript(t *testing.T) {
+	c, dir := batchVault(t)
+	content := "---\npriority: 10\n---\n- estimate:: 12\n- estimate:: 3\n"
+	if err := os.WriteFile(filepath.Join(dir, "Tasks.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Without a PropertySearcher the query goes through DataScript, and
+	// values are still compared by type.
+	query := func(input types.QueryPropertiesInput) []backend.PropertyResult {
+		t.Helper()
+		res, _, _ := NewSearch(plainBackend{c}).QueryProperties(ctx, nil, input)
+		if res.IsError {
+			t.Fatal(resultText(res))
+		}
+		var out struct{ Results []backend.PropertyResult }
+		if err := json.Unmarshal([]byte(resultText(res)), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out.Results
+	}
+	if got := query(types.QueryPropertiesInput{Property: "estimate", Operator: "gt", Value: "9"}); len(got) != 1 || got[0].Type != "block" || got[0].Page != "Tasks" {
+		t.Errorf("block results = %+v", got)
+	}
+	if got := query(types.QueryPropertiesInput{Property: "priority", Operator: "between", Values: []string{"9", "11"}}); len(got) != 1 || got[0].Name != "Tasks" {
+		t.Errorf("page results = %+v", got)
+	}
+	if got := query(types.QueryPropertiesInput{Property: "status"}); len(got) != 1 || got[0].Name != "Notes" {
+		t.Errorf("exists results = %+v", got)
+	}
+
+	res, _, _ := NewSearch(plainBackend{c}).QueryProperties(ctx, nil, types.QueryPropertiesInput{Property: "status]] [?x", Value: "x"})
+	if !res.IsError {
+		t.Error("spliced an invalid property key into the query")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	c, _ := batchVault(t)
+	s := NewSearch(c)
+	ctx := context.Background()
+
+	res, _, _ := s.Query(ctx, nil, types.QueryInput{Query: `(or "alpha" (page Notes))`})
+	var blocks struct {
+		Count  int
+		Blocks []struct{ Page, Content string }
+	}
+	if err := json.Unmarshal([]byte(resultText(res)), &blocks); err != nil {
+		t.Fatal(resultText(res))
+	}
+	if blocks.Count != 2 || blocks.Blocks[0].Content != "idea" || blocks.Blocks[1].Content != "alpha" {
+		t.Errorf("blocks = %+v", blocks)
+	}
+
+	res, _, _ = s.Query(ctx, nil, types.QueryInput{Query: `(page-property status draft)`})
+	var pages struct {
+		Count int
+		Pages []struct{ Name string }
+	}
+	if err := json.Unmarshal([]byte(resultText(res)), &pages); err != nil {
+		t.Fatal(resultText(res))
+	}
+	if pages.Count != 1 || pages.Pages[0].Name != "Notes" {
+		t.Errorf("pages = %+v", pages)
+	}
+
+	res, _, _ = s.Query(ctx, nil, types.QueryInput{Query: `(task MAYBE)`})
+	if !res.IsError {
+		t.Errorf("invalid query = %s", resultText(res))
+	}
+}
+
+func TestFindByTagCountsTaggedPages(t *testing.T) {
+	c, dir := batchVault(t)
+	for name, content := range map[string]string{
+		"Go.md":   "---\ntags: [lang]\n---\n- fast\n",
+		"Rust.md": "---\ntags: [lang]\n---\n- safe #lang\n",
+		"Zig.md":  "- small #lang\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	res, _, _ := NewSearch(c).FindByTag(context.Background(), nil, types.FindByTagInput{Tag: "lang"})
+	var out struct {
+		Count   int
+		Results []struct {
+			Page       string
+			PageTagged bool
+			UUID       string
+		}
+	}
+	if err := json.Unmarshal([]byte(resultText(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	// Go is tagged only in frontmatter, Rust in both places and Zig in a block.
+	got := map[string]int{}
+	for _, r := range out.Results {
+		switch {
+		case r.PageTagged:
+			got[r.Page+" page"]++
+		case r.UUID != "":
+			got[r.Page+" block"]++
+		}
+	}
+	want := map[string]int{"Go page": 1, "Rust page": 1, "Rust block": 1, "Zig block": 1}
+	if out.Count != 4 || len(out.Results) != 4 || !reflect.DeepEqual(got, want) {
+		t.Errorf("find_by_tag = %d results %v", out.Count, got)
+	}
+}
@@ -0,0 +1,474 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/oplog"
+	"github.com/skridlevsky/graphthulhu/parser"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// Tasks implements the task MCP tools over blocks with a task marker or a
+// Markdown checkbox.
+type Tasks struct {
+	client backend.Backend
+	ops    *oplog.Log
+}
+
+// NewTasks creates a new Tasks tool handler. Writes are recorded in ops,
+// which may be nil.
+func NewTasks(c backend.Backend, ops *oplog.Log) *Tasks {
+	return &Tasks{client: c, ops: ops}
+}
+
+// taskBlock is a task found in the graph.
+type taskBlock struct {
+	UUID    string `json:"uuid"`
+	Page    string `json:"page"`
+	Content string `json:"content"`
+	parser.Task
+	Overdue bool `json:"overdue,omitempty"`
+}
+
+// nextMarker is the marker set_task_state moves a task to when no state is
+// given. Blocks that are not tasks become TODO.
+var nextMarker = map[string]string{
+	"":          "TODO",
+	"TODO":      "DOING",
+	"DOING":     "DONE",
+	"LATER":     "NOW",
+	"NOW":       "DONE",
+	"WAITING":   "DOING",
+	"DONE":      "TODO",
+	"CANCELLED": "TODO",
+}
+
+// openTask reports whether a marker is of a task still to be done.
+func openTask(marker string) bool {
+	return marker != "DONE" && marker != "CANCELLED"
+}
+
+// ListTasks lists tasks, filtered by marker, priority, page or namespace
+// and date. Tasks with a date come first, soonest first.
+func (t *Tasks) ListTasks(ctx context.Context, req *mcp.CallToolRequest, input types.ListTasksInput) (*mcp.CallToolResult, any, error) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	markers := make([]string, len(input.Markers))
+	for i, m := range input.Markers {
+		markers[i] = strings.ToUpper(m)
+		if !slices.Contains(parser.TaskMarkers, markers[i]) {
+			return errorResult(fmt.Sprintf("unknown marker %q (use %s)", m, strings.Join(parser.TaskMarkers, ", "))), nil, nil
+		}
+	}
+	for _, d := range []string{input.From, input.To} {
+		if _, err := time.Parse("2006-01-02", d); d != "" && err != nil {
+			return errorResult(fmt.Sprintf("invalid date %q: use YYYY-MM-DD", d)), nil, nil
+		}
+	}
+
+	tasks, err := t.findTasks(ctx, input.Page, input.Namespace)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+
+	today := time.Now().Format("2006-01-02")
+	var matched []taskBlock
+	overdue := 0
+	for _, task := range tasks {
+		due := cmp.Or(task.Deadline, task.Scheduled)
+		task.Overdue = openTask(task.Marker) && due != "" && due < today
+		switch {
+		case len(markers) > 0 && !slices.Contains(markers, task.Marker),
+			len(markers) == 0 && !openTask(task.Marker),
+			input.Priority != "" && !strings.EqualFold(task.Priority, input.Priority),
+			input.Overdue && !task.Overdue,
+			(input.From != "" || input.To != "") && !inWindow(task, input.From, input.To):
+			continue
+		}
+		if task.Overdue {
+			overdue++
+		}
+		matched = append(matched, task)
+	}
+
+	slices.SortStableFunc(matched, func(a, b taskBlock) int {
+		da, db := cmp.Or(a.Deadline, a.Scheduled), cmp.Or(b.Deadline, b.Scheduled)
+		if (da == "") != (db == "") {
+			if da == "" {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(da, db)
+	})
+	result := map[string]any{
+		"count":   len(matched),
+		"overdue": overdue,
+	}
+	if len(matched) > limit {
+		matched = matched[:limit]
+		result["truncated"] = true
+	}
+	result["tasks"] = append([]taskBlock{}, matched...)
+
+	res, err := jsonTextResult(result)
+	return res, nil, err
+}
+
+// inWindow reports whether a task is scheduled or due between from and to,
+// either of which may be empty.
+func inWindow(task taskBlock, from, to string) bool {
+	for _, d := range []string{task.Scheduled, task.Deadline} {
+		if d != "" && (from == "" || d >= from) && (to == "" || d <= to) {
+			return true
+		}
+	}
+	return false
+}
+
+// findTasks returns the tasks on page, or on the pages of namespace, or in
+// the whole graph, in document order. Whole graphs are read page by page
+// on backends that search in memory (TagSearcher) and found with DataScript
+// on Logseq.
+func (t *Tasks) findTasks(ctx context.Context, page, namespace string) ([]taskBlock, error) {
+	_, inMemory := t.client.(backend.TagSearcher)
+	var names []string
+	switch {
+	case page != "":
+		p, err := t.client.GetPage(ctx, page)
+		if err != nil || p == nil {
+			return nil, fmt.Errorf("page not found: %s", page)
+		}
+		names = []string{cmp.Or(p.OriginalName, p.Name)}
+	case !inMemory:
+		return t.findTasksViaDataScript(ctx, namespace)
+	default:
+		pages, err := t.client.GetAllPages(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pages: %w", err)
+		}
+		for _, p := range pages {
+			name := cmp.Or(p.OriginalName, p.Name)
+			if name != "" && inPageNamespace(name, namespace) {
+				names = append(names, name)
+			}
+		}
+		slices.SortFunc(names, func(a, b string) int {
+			return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+		})
+	}
+
+	var tasks []taskBlock
+	for _, name := range names {
+		blocks, err := t.client.GetPageBlocksTree(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %s: %w", name, err)
+		}
+		tasks = collectTasks(blocks, name, tasks)
+	}
+	return tasks, nil
+}
+
+// collectTasks appends the tasks among blocks and their children.
+func collectTasks(blocks []types.BlockEntity, page string, tasks []taskBlock) []taskBlock {
+	for _, b := range blocks {
+		if task, ok := parser.ParseTask(b.Content); ok && !b.PreBlock {
+			tasks = append(tasks, taskBlock{UUID: b.UUID, Page: page, Content: b.Content, Task: task})
+		}
+		tasks = collectTasks(b.Children, page, tasks)
+	}
+	return tasks
+}
+
+// findTasksViaDataScript finds the blocks with a marker through DataScript.
+func (t *Tasks) findTasksViaDataScript(ctx context.Context, namespace string) ([]taskBlock, error) {
+	query := `[:find (pull ?b [:block/uuid :block/content
+			{:block/page [:block/name :block/original-name]}])
+		:where
+		[?b :block/marker]]`
+	raw, err := t.client.DatascriptQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("task query failed: %w", err)
+	}
+	var rows [][]json.RawMessage
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("parse results: %w", err)
+	}
+
+	var tasks []taskBlock
+	for _, row := range rows {
+		if len(row) == 0 {
+			continue
+		}
+		var e struct {
+			UUID    string `json:"uuid"`
+			Content string `json:"content"`
+			Page    *struct {
+				Name         string `json:"name"`
+				OriginalName string `json:"original-name"`
+			} `json:"page"`
+		}
+		if err := json.Unmarshal(row[0], &e); err != nil || e.Page == nil {
+			continue
+		}
+		page := cmp.Or(e.Page.OriginalName, e.Page.Name)
+		task, ok := parser.ParseTask(e.Content)
+		if !ok || !inPageNamespace(page, namespace) {
+			continue
+		}
+		tasks = append(tasks, taskBlock{UUID: e.UUID, Page: page, Content: e.Content, Task: task})
+	}
+	slices.SortStableFunc(tasks, func(a, b taskBlock) int {
+		return strings.Compare(strings.ToLower(a.Page), strings.ToLower(b.Page))
+	})
+	return tasks, nil
+}
+
+// inPageNamespace reports whether page is namespace or one of its pages.
+// Every page is in the empty namespace.
+func inPageNamespace(page, namespace string) bool {
+	page, namespace = strings.ToLower(page), strings.ToLower(strings.Trim(namespace, "/"))
+	return namespace == "" || page == namespace || strings.HasPrefix(page, namespace+"/")
+}
+
+// SetTaskState sets the marker of a task, or moves it to the next marker
+// of its cycle. Completing a task stamps it with the time: a ✅ date on
+// Markdown checkbox tasks, as the Obsidian Tasks plugin writes it, and a
+// completed:: property on the others. Reopening it removes the stamp.
+//
+// Completing a recurring task does what its tool does: a checkbox task
+// gets its next occurrence added above it, and a Logseq task is reopened
+// in place with its repeating dates moved on.
+func (t *Tasks) SetTaskState(ctx context.Context, req *mcp.CallToolRequest, input types.SetTaskStateInput) (*mcp.CallToolResult, any, error) {
+	ctx, err := checkBlockVersion(ctx, t.client, input.UUID, input.IfMatch)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to update task %s: %v", input.UUID, err)), nil, nil
+	}
+	block, err := t.client.GetBlock(ctx, input.UUID)
+	if err != nil || block == nil {
+		return errorResult(fmt.Sprintf("block not found: %s", input.UUID)), nil, nil
+	}
+
+	task, _ := parser.ParseTask(block.Content)
+	state := strings.ToUpper(input.State)
+	if state == "" {
+		state = nextMarker[task.Marker]
+	} else if !slices.Contains(parser.TaskMarkers, state) {
+		return errorResult(fmt.Sprintf("unknown state %q (use %s)", input.State, strings.Join(parser.TaskMarkers, ", "))), nil, nil
+	}
+
+	now := time.Now()
+	today, _ := time.Parse("2006-01-02", now.Format("2006-01-02"))
+	next, recurs := "", false
+	if state == "DONE" && openTask(task.Marker) {
+		next, recurs = parser.NextOccurrence(block.Content, today)
+	}
+
+	content := block.Content
+	switch {
+	case recurs && !task.Checkbox:
+		reopen := "TODO"
+		if task.Marker == "LATER" || task.Marker == "NOW" {
+			reopen = "LATER"
+		}
+		content = removeProperty(parser.SetMarker(next, reopen), "completed")
+	case task.Checkbox:
+		var ok bool
+		if content, ok = parser.SetCheckbox(content, state); !ok {
+			return errorResult(fmt.Sprintf("checkbox tasks can only be TODO, DOING, DONE or CANCELLED, not %s", state)), nil, nil
+		}
+		switch {
+		case state != "DONE":
+			content = parser.SetCompletedEmoji(content, "")
+		case task.Marker != "DONE":
+			content = parser.SetCompletedEmoji(content, now.Format("2006-01-02"))
+		}
+	default:
+		content = parser.SetMarker(content, state)
+		switch {
+		case state != "DONE":
+			content = removeProperty(content, "completed")
+		case task.Marker != "DONE":
+			content = upsertProperty(content, "completed", now.Format("2006-01-02 15:04"))
+		}
+	}
+
+	result := map[string]any{
+		"uuid":     input.UUID,
+		"previous": task.Marker,
+		"state":    state,
+	}
+	if content == block.Content {
+		result["unchanged"] = true
+		result["version"] = backend.BlockVersion(content)
+		res, err := jsonTextResult(result)
+		return res, nil, err
+	}
+
+	rec := t.ops.Begin(t.client, "set_task_state", fmt.Sprintf("%s → %s", input.UUID, state))
+	defer rec.Commit()
+
+	if err := rec.UpdateBlock(ctx, input.UUID, content); err != nil {
+		return errorResult(fmt.Sprintf("failed to update task %s: %v", input.UUID, err)), nil, nil
+	}
+	result["content"] = content
+	if block, err := t.client.GetBlock(ctx, input.UUID); err == nil && block != nil {
+		result["version"] = backend.BlockVersion(block.Content)
+	}
+
+	if recurs {
+		occurrence := map[string]any{"uuid": input.UUID, "content": content}
+		if task.Checkbox {
+			next, _ = parser.SetCheckbox(next, "TODO")
+			nb, err := rec.InsertBlock(ctx, input.UUID, next, map[string]any{"sibling": true, "before": true})
+			if err != nil {
+				return errorResult(fmt.Sprintf("completed %s, but failed to add its next occurrence: %v", input.UUID, err)), nil, nil
+			}
+			occurrence = map[string]any{"content": next}
+			if nb != nil {
+				occurrence["uuid"] = nb.UUID
+			}
+		}
+		nt, _ := parser.ParseTask(next)
+		occurrence["date"] = cmp.Or(nt.Deadline, nt.Scheduled, nt.Start)
+		result["next"] = occurrence
+	}
+	res, err := jsonTextResult(result)
+	return res, nil, err
+}
+
+// maxOccurrences caps how many occurrences of one recurring item upcoming
+// projects, so that a daily repeat over a long range stays bounded.
+const maxOccurrences = 400
+
+// occurrence is a task or decision falling due on a date.
+type occurrence struct {
+	Date    string `json:"date"`
+	Kind    string `json:"kind"`
+	UUID    string `json:"uuid"`
+	Page    string `json:"page"`
+	Content string `json:"content"`
+	Marker  string `json:"marker"`
+	Repeat  string `json:"repeat,omitempty"`
+	// Projected marks the later occurrences of a recurring item, which are
+	// not in the graph yet.
+	Projected bool `json:"projected,omitempty"`
+}
+
+// Upcoming lists the open tasks and decisions falling due between two
+// dates, projecting recurring ones over the range. A task falls due on its
+// deadline, or its scheduled date when it has none. Occurrences of repeats
+// counted from completion are projected from the current date, as if each
+// were completed on the day it falls due.
+func (t *Tasks) Upcoming(ctx context.Context, req *mcp.CallToolRequest, input types.UpcomingInput) (*mcp.CallToolResult, any, error) {
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	from, err := time.Parse("2006-01-02", cmp.Or(input.From, time.Now().Format("2006-01-02")))
+	if err != nil {
+		return errorResult(fmt.Sprintf("invalid date %q: use YYYY-MM-DD", input.From)), nil, nil
+	}
+	to := from.AddDate(0, 0, 30)
+	if input.To != "" {
+		if to, err = time.Parse("2006-01-02", input.To); err != nil {
+			return errorResult(fmt.Sprintf("invalid date %q: use YYYY-MM-DD", input.To)), nil, nil
+		}
+	}
+	if to.Before(from) {
+		return errorResult("to is before from"), nil, nil
+	}
+
+	tasks, err := t.findTasks(ctx, "", input.Namespace)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+	var occurrences []occurrence
+	for _, task := range tasks {
+		if !openTask(task.Marker) {
+			continue
+		}
+		item := occurrence{Kind: "task", UUID: task.UUID, Page: task.Page, Content: task.Content, Marker: task.Marker, Repeat: task.Repeat}
+		occurrences = project(occurrences, item, cmp.Or(task.Deadline, task.Scheduled), from, to)
+	}
+
+	decisions, err := NewDecision(t.client, nil).findDecisions(ctx)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+	for _, d := range decisions {
+		if d.Marker == "DONE" || d.Resolved != "" || !inPageNamespace(d.Page, input.Namespace) {
+			continue
+		}
+		item := occurrence{Kind: "decision", UUID: d.UUID, Page: d.Page, Content: d.Content, Marker: d.Marker, Repeat: d.Repeat}
+		occurrences = project(occurrences, item, d.Deadline, from, to)
+	}
+
+	slices.SortStableFunc(occurrences, func(a, b occurrence) int {
+		return cmp.Or(cmp.Compare(a.Date, b.Date), strings.Compare(strings.ToLower(a.Page), strings.ToLower(b.Page)))
+	})
+	result := map[string]any{
+		"from":  from.Format("2006-01-02"),
+		"to":    to.Format("2006-01-02"),
+		"count": len(occurrences),
+	}
+	if len(occurrences) > limit {
+		occurrences = occurrences[:limit]
+		result["truncated"] = true
+	}
+	result["occurrences"] = append([]occurrence{}, occurrences...)
+
+	res, err := jsonTextResult(result)
+	return res, nil, err
+}
+
+// project appends the occurrences of item between from and to, the first
+// falling due on date: only that one unless the item recurs. Recurring
+// items start from their first occurrence on or after from, and at most
+// maxOccurrences of each are appended.
+func project(occurrences []occurrence, item occurrence, date string, from, to time.Time) []occurrence {
+	start, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return occurrences
+	}
+	r, err := parser.ParseRecurrence(item.Repeat)
+	if item.Repeat == "" || err != nil {
+		if !start.Before(from) && !start.After(to) {
+			item.Date = date
+			occurrences = append(occurrences, item)
+		}
+		return occurrences
+	}
+	for n, i := r.Intervals(start, from), 0; i < maxOccurrences; n, i = n+1, i+1 {
+		d := r.Add(start, n)
+		if d.After(to) {
+			break
+		}
+		item.Date = d.Format("2006-01-02")
+		item.Projected = n > 0
+		occurrences = append(occurrences, item)
+	}
+	return occurrences
+}
+
+// removeProperty drops the key:: line from a block's content.
+func removeProperty(content, key string) string {
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), key+"::") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
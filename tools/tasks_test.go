@@ -0,0 +1,248 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+func TestListTasks(t *testing.T) {
+	c, dir := batchVault(t)
+	content := "- TODO [#A] renew passport\n  DEADLINE: <2020-01-10 Fri>\n" +
+		"- LATER plan trip\n  SCHEDULED: <2099-03-01 Sun>\n" +
+		"- DONE file taxes\n" +
+		"- [ ] call the bank 📅 2099-02-01\n"
+	if err := os.MkdirAll(filepath.Join(dir, "home"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "home", "Errands.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	list := func(s *Tasks, input types.ListTasksInput) []string {
+		t.Helper()
+		res, _, _ := s.ListTasks(ctx, nil, input)
+		if res.IsError {
+			t.Fatal(resultText(res))
+		}
+		var out struct{ Tasks []struct{ Content string } }
+		if err := json.Unmarshal([]byte(resultText(res)), &out); err != nil {
+			t.Fatal(err)
+		}
+		var firstLines []string
+		for _, task := range out.Tasks {
+			line, _, _ := strings.Cut(task.Content, "\n")
+			firstLines = append(firstLines, line)
+		}
+		return firstLines
+	}
+
+	tasks := NewTasks(c, nil)
+	tests := []struct {
+		input types.ListTasksInput
+		want  string
+	}{
+		{types.ListTasksInput{}, "TODO [#A] renew passport|[ ] call the bank 📅 2099-02-01|LATER plan trip"},
+		{types.ListTasksInput{Markers: []string{"done"}}, "DONE file taxes"},
+		{types.ListTasksInput{Priority: "a"}, "TODO [#A] renew passport"},
+		{types.ListTasksInput{Overdue: true}, "TODO [#A] renew passport"},
+		{types.ListTasksInput{From: "2099-01-01", To: "2099-02-28"}, "[ ] call the bank 📅 2099-02-01"},
+		{types.ListTasksInput{Namespace: "work"}, ""},
+		{types.ListTasksInput{Namespace: "home", Markers: []string{"LATER"}}, "LATER plan trip"},
+		{types.ListTasksInput{Page: "Notes"}, ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(list(tasks, tt.input), "|"); got != tt.want {
+			t.Errorf("list_tasks %+v = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	// Without a tag index the tasks are found with DataScript.
+	if got := strings.Join(list(NewTasks(plainBackend{c}, nil), types.ListTasksInput{Namespace: "home"}), "|"); got != "TODO [#A] renew passport|LATER plan trip" {
+		t.Errorf("list_tasks via DataScript = %q", got)
+	}
+}
+
+func TestSetTaskState(t *testing.T) {
+	c, dir := batchVault(t)
+	content := "- TODO write report\n- [ ] call the bank 📅 2099-02-01\n"
+	if err := os.WriteFile(filepath.Join(dir, "Work.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	blocks, err := c.GetPageBlocksTree(ctx, "Work")
+	if err != nil || len(blocks) != 2 {
+		t.Fatalf("blocks = %v, %v", blocks, err)
+	}
+	tasks := NewTasks(c, nil)
+	today := time.Now().Format("2006-01-02")
+
+	set := func(uuid, state string) string {
+		t.Helper()
+		res, _, _ := tasks.SetTaskState(ctx, nil, types.SetTaskStateInput{UUID: uuid, State: state})
+		if res.IsError {
+			t.Fatal(resultText(res))
+		}
+		block, err := c.GetBlock(ctx, uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return block.Content
+	}
+
+	report := blocks[0].UUID
+	if got := set(report, ""); got != "DOING write report" {
+		t.Errorf("cycle from TODO = %q", got)
+	}
+	if got := set(report, ""); !strings.HasPrefix(got, "DONE write report\ncompleted:: "+today+" ") {
+		t.Errorf("cycle from DOING = %q", got)
+	}
+	if got := set(report, "todo"); got != "TODO write report" {
+		t.Errorf("reopened = %q", got)
+	}
+
+	bank := blocks[1].UUID
+	if got := set(bank, "DONE"); got != "[x] call the bank 📅 2099-02-01 ✅ "+today {
+		t.Errorf("checkbox done = %q", got)
+	}
+	if got := set(bank, ""); got != "[ ] call the bank 📅 2099-02-01" {
+		t.Errorf("checkbox reopened = %q", got)
+	}
+	res, _, _ := tasks.SetTaskState(ctx, nil, types.SetTaskStateInput{UUID: bank, State: "LATER"})
+	if !res.IsError {
+		t.Errorf("checkbox set to LATER: %s", resultText(res))
+	}
+	res, _, _ = tasks.SetTaskState(ctx, nil, types.SetTaskStateInput{UUID: bank, IfMatch: "stale"})
+	if !res.IsError || !strings.Contains(resultText(res), "conflict") {
+		t.Errorf("stale ifMatch: %s", resultText(res))
+	}
+}
+
+func TestSetTaskStateRecurring(t *testing.T) {
+	c, dir := batchVault(t)
+	content := "- TODO review inbox\n  SCHEDULED: <2020-01-06 Mon .+1w>\n- [ ] water plants 🔁 every week 📅 2020-01-08\n"
+	if err := os.WriteFile(filepath.Join(dir, "Chores.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	blocks, err := c.GetPageBlocksTree(ctx, "Chores")
+	if err != nil || len(blocks) != 2 {
+		t.Fatalf("blocks = %v, %v", blocks, err)
+	}
+	tasks := NewTasks(c, nil)
+	now := time.Now()
+	today := now.Format("2006-01-02")
+
+	// A Logseq task is reopened with its date moved a week past today.
+	res, _, _ := tasks.SetTaskState(ctx, nil, types.SetTaskStateInput{UUID: blocks[0].UUID, State: "DONE"})
+	if res.IsError {
+		t.Fatal(resultText(res))
+	}
+	nextWeek := now.AddDate(0, 0, 7)
+	want := "TODO review inbox\nSCHEDULED: <" + nextWeek.Format("2006-01-02 Mon") + " .+1w>"
+	if block, _ := c.GetBlock(ctx, blocks[0].UUID); block.Content != want {
+		t.Errorf("recurring Logseq task = %q, want %q", block.Content, want)
+	}
+
+	// A checkbox task is completed and its next occurrence added above it.
+	res, _, _ = tasks.SetTaskState(ctx, nil, types.SetTaskStateInput{UUID: blocks[1].UUID, State: "DONE"})
+	if res.IsError {
+		t.Fatal(resultText(res))
+	}
+	var out struct {
+		Next struct{ UUID, Content, Date string }
+	}
+	if err := json.Unmarshal([]byte(resultText(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Next.Content != "[ ] water plants 🔁 every week 📅 2020-01-15" || out.Next.Date != "2020-01-15" {
+		t.Errorf("next occurrence = %+v", out.Next)
+	}
+	blocks, _ = c.GetPageBlocksTree(ctx, "Chores")
+	var got []string
+	for _, b := range blocks {
+		got = append(got, b.Content)
+	}
+	wantBlocks := []string{want, "[ ] water plants 🔁 every week 📅 2020-01-15", "[x] water plants 🔁 every week 📅 2020-01-08 ✅ " + today}
+	if strings.Join(got, "|") != strings.Join(wantBlocks, "|") {
+		t.Errorf("blocks = %q, want %q", got, wantBlocks)
+	}
+	if len(blocks) == 3 && blocks[1].UUID != out.Next.UUID {
+		t.Errorf("next uuid = %s, want %s", out.Next.UUID, blocks[1].UUID)
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	c, dir := batchVault(t)
+	content := "- TODO standup notes\n  SCHEDULED: <2030-01-07 Mon +1w>\n" +
+		"- [ ] renew domain 📅 2030-01-20\n" +
+		"- [x] old chore 📅 2030-01-09\n" +
+		"- DECIDE pick a venue #decision\n  deadline:: 2030-01-15\n  repeat:: every 2 weeks\n"
+	if err := os.WriteFile(filepath.Join(dir, "Planning.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "garden"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	chores := "- TODO water plants\n  SCHEDULED: <2020-01-01 Wed +1d>\n"
+	if err := os.WriteFile(filepath.Join(dir, "garden", "Chores.md"), []byte(chores), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A daily task started long before the range still falls due in it.
+	res, _, _ := NewTasks(c, nil).Upcoming(context.Background(), nil, types.UpcomingInput{From: "2030-01-10", To: "2030-01-12", Namespace: "garden"})
+	if text := resultText(res); res.IsError || !strings.Contains(text, `"count": 3`) {
+		t.Errorf("daily task from 2020: %s", text)
+	}
+
+	res, _, _ = NewTasks(c, nil).Upcoming(context.Background(), nil, types.UpcomingInput{From: "2030-01-10", To: "2030-01-31", Namespace: "planning"})
+	if res.IsError {
+		t.Fatal(resultText(res))
+	}
+	var out struct {
+		Count       int
+		Occurrences []struct {
+			Date, Kind string
+			Projected  bool
+		}
+	}
+	if err := json.Unmarshal([]byte(resultText(res)), &out); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range out.Occurrences {
+		s := o.Date + " " + o.Kind
+		if o.Projected {
+			s += "*"
+		}
+		got = append(got, s)
+	}
+	want := "2030-01-14 task*|2030-01-15 decision|2030-01-20 task|2030-01-21 task*|2030-01-28 task*|2030-01-29 decision*"
+	if strings.Join(got, "|") != want {
+		t.Errorf("upcoming = %q, want %q", strings.Join(got, "|"), want)
+	}
+
+	res, _, _ = NewTasks(c, nil).Upcoming(context.Background(), nil, types.UpcomingInput{From: "2030-02-01", To: "2030-01-01"})
+	if !res.IsError {
+		t.Errorf("inverted range: %s", resultText(res))
+	}
+}
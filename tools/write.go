@@ -4,32 +4,86 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/oplog"
 	"github.com/skridlevsky/graphthulhu/types"
 )
 
-// Write implements write MCP tools.
+// Write implements write MCP tools. Every write is recorded in ops so it
+// can be undone.
 type Write struct {
 	client backend.Backend
+	ops    *oplog.Log
+	// batch is set while apply_batch runs the tools: their writes join its
+	// operation so they can be rolled back together.
+	batch *oplog.Recorder
 }
 
-// NewWrite creates a new Write tool handler.
-func NewWrite(c backend.Backend) *Write {
-	return &Write{client: c}
+// NewWrite creates a new Write tool handler. ops may be nil to record nothing.
+func NewWrite(c backend.Backend, ops *oplog.Log) *Write {
+	return &Write{client: c, ops: ops}
+}
+
+// begin starts recording a tool's writes, as part of the running batch if
+// there is one.
+func (w *Write) begin(tool, summary string) *oplog.Recorder {
+	if w.batch != nil {
+		return w.batch.Nest()
+	}
+	return w.ops.Begin(w.client, tool, summary)
+}
+
+// checkVersion enforces an ifMatch precondition: block uuid must still have
+// the version the caller read. An empty ifMatch always passes. The returned
+// context carries the precondition to the write, for backends that check it
+// again under their write lock.
+func (w *Write) checkVersion(ctx context.Context, uuid, ifMatch string) (context.Context, error) {
+	return checkBlockVersion(ctx, w.client, uuid, ifMatch)
+}
+
+// checkBlockVersion is checkVersion for the write tools outside Write.
+func checkBlockVersion(ctx context.Context, c backend.Backend, uuid, ifMatch string) (context.Context, error) {
+	if ifMatch == "" {
+		return ctx, nil
+	}
+	ctx = backend.WithIfMatch(ctx, uuid, ifMatch)
+	var current string
+	if v, ok := c.(backend.BlockVersioner); ok {
+		version, err := v.CurrentBlockVersion(ctx, uuid)
+		if err != nil {
+			return ctx, err
+		}
+		current = version
+	} else {
+		block, err := c.GetBlock(ctx, uuid)
+		if err != nil {
+			return ctx, err
+		}
+		if block == nil {
+			return ctx, fmt.Errorf("block not found: %s", uuid)
+		}
+		current = backend.BlockVersion(block.Content)
+	}
+	return ctx, backend.CheckIfMatch(ctx, uuid, current)
 }
 
 // CreatePage creates a new page with optional properties and initial blocks.
 func (w *Write) CreatePage(ctx context.Context, req *mcp.CallToolRequest, input types.CreatePageInput) (*mcp.CallToolResult, any, error) {
-	page, err := w.client.CreatePage(ctx, input.Name, input.Properties, nil)
+	rec := w.begin("create_page", input.Name)
+	defer rec.Commit()
+
+	page, err := rec.CreatePage(ctx, input.Name, input.Properties, nil)
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to create page '%s': %v", input.Name, err)), nil, nil
 	}
 
 	for _, content := range input.Blocks {
-		_, err := w.client.AppendBlockInPage(ctx, input.Name, content)
+		_, err := rec.AppendBlockInPage(ctx, input.Name, content)
 		if err != nil {
 			return errorResult(fmt.Sprintf("created page but failed to add block: %v", err)), nil, nil
 		}
@@ -54,10 +108,13 @@ func (w *Write) AppendBlocks(ctx context.Context, req *mcp.CallToolRequest, inpu
 		return errorResult("no blocks provided"), nil, nil
 	}
 
+	rec := w.begin("append_blocks", input.Page)
+	defer rec.Commit()
+
 	var createdUUIDs []string
 
 	for _, content := range input.Blocks {
-		block, err := w.client.AppendBlockInPage(ctx, input.Page, content)
+		block, err := rec.AppendBlockInPage(ctx, input.Page, content)
 		if err != nil {
 			return errorResult(fmt.Sprintf("failed to append block to '%s': %v (appended %d before failure)", input.Page, err, len(createdUUIDs))), nil, nil
 		}
@@ -90,22 +147,25 @@ func (w *Write) upsertBlocks(ctx context.Context, input types.UpsertBlocksInput)
 	if position == "" {
 		position = "append"
 	}
+	if input.DryRun {
+		return w.previewUpsert(ctx, input, position)
+	}
+
+	rec := w.begin("upsert_blocks", input.Page)
+	defer rec.Commit()
 
 	var createdUUIDs []string
 
 	for _, block := range input.Blocks {
-		content := block.Content
-		for k, v := range block.Properties {
-			content += fmt.Sprintf("\n%s:: %s", k, v)
-		}
+		content := blockContent(block)
 
 		var created *types.BlockEntity
 		var err error
 
 		if position == "prepend" {
-			created, err = w.client.PrependBlockInPage(ctx, input.Page, content)
+			created, err = rec.PrependBlockInPage(ctx, input.Page, content)
 		} else {
-			created, err = w.client.AppendBlockInPage(ctx, input.Page, content)
+			created, err = rec.AppendBlockInPage(ctx, input.Page, content)
 		}
 
 		if err != nil {
@@ -116,7 +176,7 @@ func (w *Write) upsertBlocks(ctx context.Context, input types.UpsertBlocksInput)
 			createdUUIDs = append(createdUUIDs, created.UUID)
 
 			if len(block.Children) > 0 {
-				childUUIDs, err := w.insertChildren(ctx, created.UUID, block.Children)
+				childUUIDs, err := w.insertChildren(ctx, rec, created.UUID, block.Children)
 				if err != nil {
 					return errorResult(fmt.Sprintf("failed to create child blocks: %v", err)), nil, nil
 				}
@@ -135,21 +195,41 @@ func (w *Write) upsertBlocks(ctx context.Context, input types.UpsertBlocksInput)
 
 // UpdateBlock updates an existing block's content.
 func (w *Write) UpdateBlock(ctx context.Context, req *mcp.CallToolRequest, input types.UpdateBlockInput) (*mcp.CallToolResult, any, error) {
-	err := w.client.UpdateBlock(ctx, input.UUID, input.Content)
+	ctx, err := w.checkVersion(ctx, input.UUID, input.IfMatch)
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to update block %s: %v", input.UUID, err)), nil, nil
 	}
 
-	res, err := jsonTextResult(map[string]any{
+	rec := w.begin("update_block", input.UUID)
+	defer rec.Commit()
+
+	err = rec.UpdateBlock(ctx, input.UUID, input.Content)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to update block %s: %v", input.UUID, err)), nil, nil
+	}
+
+	result := map[string]any{
 		"updated": true,
 		"uuid":    input.UUID,
-	})
+	}
+	if block, err := w.client.GetBlock(ctx, input.UUID); err == nil && block != nil {
+		result["version"] = backend.BlockVersion(block.Content)
+	}
+	res, err := jsonTextResult(result)
 	return res, nil, err
 }
 
 // DeleteBlock removes a block from the graph.
 func (w *Write) DeleteBlock(ctx context.Context, req *mcp.CallToolRequest, input types.DeleteBlockInput) (*mcp.CallToolResult, any, error) {
-	err := w.client.RemoveBlock(ctx, input.UUID)
+	ctx, err := w.checkVersion(ctx, input.UUID, input.IfMatch)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to delete block %s: %v", input.UUID, err)), nil, nil
+	}
+
+	rec := w.begin("delete_block", input.UUID)
+	defer rec.Commit()
+
+	err = rec.RemoveBlock(ctx, input.UUID)
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to delete block %s: %v", input.UUID, err)), nil, nil
 	}
@@ -175,8 +255,18 @@ func (w *Write) MoveBlock(ctx context.Context, req *mcp.CallToolRequest, input t
 	case "child":
 		opts["children"] = true
 	}
+	ctx, err := w.checkVersion(ctx, input.UUID, input.IfMatch)
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to move block: %v", err)), nil, nil
+	}
+	if input.DryRun {
+		return w.previewMove(ctx, input, position)
+	}
+
+	rec := w.begin("move_block", input.UUID)
+	defer rec.Commit()
 
-	err := w.client.MoveBlock(ctx, input.UUID, input.TargetUUID, opts)
+	err = rec.MoveBlock(ctx, input.UUID, input.TargetUUID, opts)
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to move block: %v", err)), nil, nil
 	}
@@ -197,7 +287,10 @@ func (w *Write) LinkPages(ctx context.Context, req *mcp.CallToolRequest, input t
 		fromContent = fmt.Sprintf("%s — [[%s]]", input.Context, input.To)
 	}
 
-	fromBlock, err := w.client.AppendBlockInPage(ctx, input.From, fromContent)
+	rec := w.begin("link_pages", input.From+" ↔ "+input.To)
+	defer rec.Commit()
+
+	fromBlock, err := rec.AppendBlockInPage(ctx, input.From, fromContent)
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to add link in '%s': %v", input.From, err)), nil, nil
 	}
@@ -207,7 +300,7 @@ func (w *Write) LinkPages(ctx context.Context, req *mcp.CallToolRequest, input t
 		toContent = fmt.Sprintf("%s — [[%s]]", input.Context, input.From)
 	}
 
-	toBlock, err := w.client.AppendBlockInPage(ctx, input.To, toContent)
+	toBlock, err := rec.AppendBlockInPage(ctx, input.To, toContent)
 	if err != nil {
 		return errorResult(fmt.Sprintf("linked from '%s' but failed to link back from '%s': %v", input.From, input.To, err)), nil, nil
 	}
@@ -230,7 +323,14 @@ func (w *Write) LinkPages(ctx context.Context, req *mcp.CallToolRequest, input t
 
 // DeletePage removes a page from the graph.
 func (w *Write) DeletePage(ctx context.Context, req *mcp.CallToolRequest, input types.DeletePageInput) (*mcp.CallToolResult, any, error) {
-	err := w.client.DeletePage(ctx, input.Name)
+	if input.DryRun {
+		return w.previewDeletePage(ctx, input)
+	}
+
+	rec := w.begin("delete_page", input.Name)
+	defer rec.Commit()
+
+	err := rec.DeletePage(ctx, input.Name)
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to delete page '%s': %v", input.Name, err)), nil, nil
 	}
@@ -244,7 +344,14 @@ func (w *Write) DeletePage(ctx context.Context, req *mcp.CallToolRequest, input
 
 // RenamePage renames a page and updates all links across the graph.
 func (w *Write) RenamePage(ctx context.Context, req *mcp.CallToolRequest, input types.RenamePageInput) (*mcp.CallToolResult, any, error) {
-	err := w.client.RenamePage(ctx, input.OldName, input.NewName)
+	if input.DryRun {
+		return w.previewRename(ctx, input)
+	}
+
+	rec := w.begin("rename_page", input.OldName+" → "+input.NewName)
+	defer rec.Commit()
+
+	err := rec.RenamePage(ctx, input.OldName, input.NewName)
 	if err != nil {
 		return errorResult(fmt.Sprintf("failed to rename '%s' to '%s': %v", input.OldName, input.NewName, err)), nil, nil
 	}
@@ -263,8 +370,12 @@ func (w *Write) BulkUpdateProperties(ctx context.Context, req *mcp.CallToolReque
 		return errorResult("no pages specified"), nil, nil
 	}
 
+	rec := w.begin("bulk_update_properties", input.Property+":: "+input.Value)
+	defer rec.Commit()
+
 	var updated []string
 	var failed []string
+	var changes []map[string]any
 
 	for _, pageName := range input.Pages {
 		// Get the page's first block (property block in Logseq).
@@ -274,34 +385,45 @@ func (w *Write) BulkUpdateProperties(ctx context.Context, req *mcp.CallToolReque
 			continue
 		}
 
-		// Find or create the property in the first block.
 		firstBlock := blocks[0]
-		content := firstBlock.Content
-		propLine := fmt.Sprintf("%s:: %s", input.Property, input.Value)
-
-		// Check if property already exists.
-		lines := strings.Split(content, "\n")
-		found := false
-		for i, line := range lines {
-			if strings.HasPrefix(line, input.Property+":: ") {
-				lines[i] = propLine
-				found = true
-				break
+		newContent := setPropertyLine(firstBlock.Content, input.Property, input.Value)
+		if input.DryRun {
+			change := map[string]any{
+				"page":   pageName,
+				"uuid":   firstBlock.UUID,
+				"before": firstBlock.Content,
+				"after":  newContent,
 			}
+			if file := w.pageFile(ctx, pageName); file != "" {
+				change["file"] = file
+			}
+			changes = append(changes, change)
+			continue
 		}
-		if !found {
-			// Append property line.
-			lines = append(lines, propLine)
-		}
-
-		newContent := strings.Join(lines, "\n")
-		if err := w.client.UpdateBlock(ctx, firstBlock.UUID, newContent); err != nil {
+		if err := rec.UpdateBlock(ctx, firstBlock.UUID, newContent); err != nil {
 			failed = append(failed, pageName)
 			continue
 		}
 		updated = append(updated, pageName)
 	}
 
+	// A batch is all or nothing, so pages it could not update fail it.
+	if w.batch != nil && len(failed) > 0 {
+		return errorResult(fmt.Sprintf("failed to set %s on: %s", input.Property, strings.Join(failed, ", "))), nil, nil
+	}
+
+	if input.DryRun {
+		res, err := jsonTextResult(map[string]any{
+			"dryRun":      true,
+			"property":    input.Property,
+			"value":       input.Value,
+			"changes":     changes,
+			"failed":      failed,
+			"failedCount": len(failed),
+		})
+		return res, nil, err
+	}
+
 	res, err := jsonTextResult(map[string]any{
 		"property":     input.Property,
 		"value":        input.Value,
@@ -313,16 +435,25 @@ func (w *Write) BulkUpdateProperties(ctx context.Context, req *mcp.CallToolReque
 	return res, nil, err
 }
 
-func (w *Write) insertChildren(ctx context.Context, parentUUID string, children []types.BlockInput) ([]string, error) {
+// setPropertyLine sets property:: value in a block's content, replacing the
+// property's line if it has one and appending it otherwise.
+func setPropertyLine(content, property, value string) string {
+	propLine := fmt.Sprintf("%s:: %s", property, value)
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, property+":: ") {
+			lines[i] = propLine
+			return strings.Join(lines, "\n")
+		}
+	}
+	return strings.Join(append(lines, propLine), "\n")
+}
+
+func (w *Write) insertChildren(ctx context.Context, rec *oplog.Recorder, parentUUID string, children []types.BlockInput) ([]string, error) {
 	var uuids []string
 
 	for _, child := range children {
-		content := child.Content
-		for k, v := range child.Properties {
-			content += fmt.Sprintf("\n%s:: %s", k, v)
-		}
-
-		created, err := w.client.InsertBlock(ctx, parentUUID, content, map[string]any{
+		created, err := rec.InsertBlock(ctx, parentUUID, blockContent(child), map[string]any{
 			"isPageBlock": false,
 		})
 		if err != nil {
@@ -333,7 +464,7 @@ func (w *Write) insertChildren(ctx context.Context, parentUUID string, children
 			uuids = append(uuids, created.UUID)
 
 			if len(child.Children) > 0 {
-				childUUIDs, err := w.insertChildren(ctx, created.UUID, child.Children)
+				childUUIDs, err := w.insertChildren(ctx, rec, created.UUID, child.Children)
 				if err != nil {
 					return uuids, err
 				}
@@ -344,3 +475,13 @@ func (w *Write) insertChildren(ctx context.Context, parentUUID string, children
 
 	return uuids, nil
 }
+
+// blockContent is the content a BlockInput is written as: its text followed
+// by its properties as key:: value lines, in key order.
+func blockContent(b types.BlockInput) string {
+	content := b.Content
+	for _, k := range slices.Sorted(maps.Keys(b.Properties)) {
+		content += fmt.Sprintf("\n%s:: %s", k, b.Properties[k])
+	}
+	return content
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+func TestIfMatch(t *testing.T) {
+	c, dir := batchVault(t)
+	ctx := context.Background()
+
+	blocks, _ := c.GetPageBlocksTree(ctx, "Projects")
+	alpha, beta := blocks[0], blocks[1]
+
+	// get_block reports the version writes are checked against.
+	n := NewNavigate(c)
+	res, _, _ := n.GetBlock(ctx, nil, types.GetBlockInput{UUID: alpha.UUID})
+	var got types.EnrichedBlock
+	if err := json.Unmarshal([]byte(resultText(res)), &got); err != nil {
+		t.Fatal(err)
+	}
+	version := got.Version
+	if version == "" || version != backend.BlockVersion("alpha") {
+		t.Fatalf("get_block version = %q", version)
+	}
+
+	w := NewWrite(c, nil)
+
+	// Someone edits the block in another program; the index has not caught up.
+	path := filepath.Join(dir, "Projects.md")
+	if err := os.WriteFile(path, []byte("- alpha, edited by hand\n- beta\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, _, _ = w.UpdateBlock(ctx, nil, types.UpdateBlockInput{UUID: alpha.UUID, Content: "alpha by agent", IfMatch: version})
+	if !res.IsError || !strings.Contains(resultText(res), "conflict") {
+		t.Fatalf("stale update: %s", resultText(res))
+	}
+	if data, _ := os.ReadFile(path); string(data) != "- alpha, edited by hand\n- beta\n" {
+		t.Errorf("stale update wrote %q", data)
+	}
+	res, _, _ = w.DeleteBlock(ctx, nil, types.DeleteBlockInput{UUID: alpha.UUID, IfMatch: version})
+	if !res.IsError {
+		t.Error("stale delete succeeded")
+	}
+
+	// An unchanged block is written, and the result carries its new version.
+	res, _, _ = w.UpdateBlock(ctx, nil, types.UpdateBlockInput{UUID: beta.UUID, Content: "beta 2", IfMatch: backend.BlockVersion("beta")})
+	if res.IsError || !strings.Contains(resultText(res), backend.BlockVersion("beta 2")) {
+		t.Fatalf("update: %s", resultText(res))
+	}
+
+	// Without page files the check reads the block from the backend.
+	w = NewWrite(plainBackend{c}, nil)
+	res, _, _ = w.MoveBlock(ctx, nil, types.MoveBlockInput{UUID: beta.UUID, TargetUUID: alpha.UUID, Position: "before", IfMatch: backend.BlockVersion("beta")})
+	if !res.IsError || !strings.Contains(resultText(res), "conflict") {
+		t.Errorf("stale move: %s", resultText(res))
+	}
+}
@@ -38,6 +38,9 @@ type BlockEntity struct {
 	PathRefs        []PageRef         `json:"pathRefs,omitempty"`
 	Refs            []PageRef         `json:"refs,omitempty"`
 	PreBlock        bool              `json:"preBlock,omitempty"`
+	// Version is a hash of Content, set by graphthulhu on the blocks its
+	// read tools return, for the ifMatch precondition of block writes.
+	Version         string            `json:"version,omitempty"`
 }
 
 // UnmarshalJSON handles two Logseq formats for children:
@@ -132,6 +135,7 @@ func (b *BlockRef) UnmarshalJSON(data []byte) error {
 type ParsedContent struct {
 	Raw             string   `json:"raw"`
 	Links           []string `json:"links"`           // [[page name]]
+	LinkRefs        []LinkRef `json:"linkRefs,omitempty"` // [[page#anchor|alias]], ![[embed]]
 	BlockReferences []string `json:"blockReferences"` // ((uuid))
 	Tags            []string `json:"tags"`            // #tag
 	Properties      map[string]string `json:"properties,omitempty"` // key:: value
@@ -139,6 +143,17 @@ type ParsedContent struct {
 	Priority        string   `json:"priority,omitempty"`  // [#A], [#B], [#C]
 }
 
+// LinkRef is a single link occurrence with its Obsidian decorations.
+// Target is the page the link resolves to; it is empty for same-page anchors
+// like [[#Heading]].
+type LinkRef struct {
+	Target   string `json:"target"`
+	Alias    string `json:"alias,omitempty"`    // [[target|alias]] or the [text] of a markdown link
+	Anchor   string `json:"anchor,omitempty"`   // [[target#Heading]] or [[target#^blockid]]
+	Embed    bool   `json:"embed,omitempty"`    // ![[target]]
+	Markdown bool   `json:"markdown,omitempty"` // [text](relative/path.md)
+}
+
 // EnrichedBlock extends BlockEntity with parsed content and ancestor chain.
 type EnrichedBlock struct {
 	BlockEntity
@@ -7,6 +7,7 @@ type GetPageInput struct {
 	Depth     int    `json:"depth,omitempty" jsonschema:"Max block tree depth (-1 for unlimited). Default: -1"`
 	MaxBlocks int    `json:"maxBlocks,omitempty" jsonschema:"Max total blocks to return. Truncates with a flag when exceeded. Default: unlimited"`
 	Compact   bool   `json:"compact,omitempty" jsonschema:"Return blocks as plain strings instead of enriched objects. Saves ~60%% tokens. Default: false"`
+	Fuzzy     bool   `json:"fuzzy,omitempty" jsonschema:"If no page has this name, use the closest matching page instead of failing. Default: false"`
 }
 
 type GetBlockInput struct {
@@ -36,21 +37,23 @@ type TraverseInput struct {
 	From    string `json:"from" jsonschema:"Starting page name"`
 	To      string `json:"to" jsonschema:"Target page name"`
 	MaxHops int    `json:"maxHops,omitempty" jsonschema:"Maximum traversal depth. Default: 4"`
+	Fuzzy   bool   `json:"fuzzy,omitempty" jsonschema:"If a page name is not found, use the closest matching page instead of failing. Default: false"`
 }
 
 // --- Search tool inputs ---
 
 type SearchInput struct {
-	Query        string `json:"query" jsonschema:"Search text to find across all blocks"`
+	Query        string `json:"query" jsonschema:"Search text to find across all blocks. Supports \"exact phrase\", prefix*, -exclude and OR"`
 	ContextLines int    `json:"contextLines,omitempty" jsonschema:"Number of parent/sibling blocks for context. Default: 2"`
 	Limit        int    `json:"limit,omitempty" jsonschema:"Max results. Default: 20"`
 	Compact      bool   `json:"compact,omitempty" jsonschema:"Return minimal results (uuid, content, page) without parsed metadata. Saves ~50%% tokens. Default: false"`
 }
 
 type QueryPropertiesInput struct {
-	Property string `json:"property" jsonschema:"Property key to search for"`
-	Value    string `json:"value,omitempty" jsonschema:"Property value to match (omit to find all with this property)"`
-	Operator string `json:"operator,omitempty" jsonschema:"Comparison: eq or contains or gt or lt. Default: eq"`
+	Property string   `json:"property" jsonschema:"Property key to search for"`
+	Value    string   `json:"value,omitempty" jsonschema:"Property value to compare with (omit to find all with this property)"`
+	Values   []string `json:"values,omitempty" jsonschema:"Values for between (low and high, inclusive) and in (any of them)"`
+	Operator string   `json:"operator,omitempty" jsonschema:"Comparison: eq, contains, gt, gte, lt, lte, between, in, exists or regex. Numbers, ISO dates and booleans compare by type. Default: eq, or exists without a value"`
 }
 
 type QueryDatalogInput struct {
@@ -58,6 +61,11 @@ type QueryDatalogInput struct {
 	Inputs []string `json:"inputs,omitempty" jsonschema:"Query input bindings (string representations)"`
 }
 
+type QueryInput struct {
+	Query string `json:"query" jsonschema:"Simple query, e.g. (and [[Project]] (task TODO DOING) (property status active) (between -7d today)). Filters: [[page]], #tag, \"text\", (task ...), (priority ...), (property key [op] [value]), (page-property key [op] [value]), (page name), (between from to), combined with and, or, not"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Max results. Default: 100"`
+}
+
 type FindByTagInput struct {
 	Tag             string `json:"tag" jsonschema:"Tag name to search for"`
 	IncludeChildren bool   `json:"includeChildren,omitempty" jsonschema:"Include child tags in hierarchy. Default: true"`
@@ -66,12 +74,22 @@ type FindByTagInput struct {
 // --- Analyze tool inputs ---
 
 // GraphOverviewInput has no required params — returns global stats.
-type GraphOverviewInput struct{}
+type GraphOverviewInput struct {
+	RankBy string `json:"rankBy,omitempty" jsonschema:"Rank the most connected pages by degree, pagerank, betweenness or closeness. Default: degree"`
+}
+
+type CentralityInput struct {
+	Measure         string   `json:"measure,omitempty" jsonschema:"pagerank (pages linked from important pages), betweenness (bridges between topics), closeness (few links from everything) or degree. Default: pagerank"`
+	Seeds           []string `json:"seeds,omitempty" jsonschema:"Personalize PageRank from these pages: rank the pages most central to them rather than to the whole graph"`
+	IncludeJournals bool     `json:"includeJournals,omitempty" jsonschema:"Include journal pages in the ranking. Default: false"`
+	Limit           int      `json:"limit,omitempty" jsonschema:"Max pages. Default: 20"`
+}
 
 type FindConnectionsInput struct {
 	From     string `json:"from" jsonschema:"Starting page name"`
 	To       string `json:"to" jsonschema:"Target page name"`
 	MaxDepth int    `json:"maxDepth,omitempty" jsonschema:"Max search depth. Default: 5"`
+	Fuzzy    bool   `json:"fuzzy,omitempty" jsonschema:"If a page name is not found, use the closest matching page instead of failing. Default: false"`
 }
 
 // KnowledgeGapsInput controls knowledge gap analysis filtering.
@@ -107,6 +125,7 @@ type UpsertBlocksInput struct {
 	Page     string       `json:"page" jsonschema:"Page name to add blocks to"`
 	Blocks   []BlockInput `json:"blocks" jsonschema:"Blocks to create or update"`
 	Position string       `json:"position,omitempty" jsonschema:"Where to add: append or prepend. Default: append"`
+	DryRun   bool         `json:"dryRun,omitempty" jsonschema:"Return the planned changes without writing anything. Default: false"`
 }
 
 type BlockInput struct {
@@ -118,31 +137,38 @@ type BlockInput struct {
 type UpdateBlockInput struct {
 	UUID    string `json:"uuid" jsonschema:"UUID of block to update"`
 	Content string `json:"content" jsonschema:"New content for the block (replaces existing content entirely)"`
+	IfMatch string `json:"ifMatch,omitempty" jsonschema:"Only write if the block still has this version (from get_page or get_block); fails with a conflict if it changed since"`
 }
 
 type DeleteBlockInput struct {
-	UUID string `json:"uuid" jsonschema:"UUID of block to delete"`
+	UUID    string `json:"uuid" jsonschema:"UUID of block to delete"`
+	IfMatch string `json:"ifMatch,omitempty" jsonschema:"Only write if the block still has this version (from get_page or get_block); fails with a conflict if it changed since"`
 }
 
 type MoveBlockInput struct {
 	UUID       string `json:"uuid" jsonschema:"UUID of block to move"`
 	TargetUUID string `json:"targetUuid" jsonschema:"UUID of target block"`
 	Position   string `json:"position,omitempty" jsonschema:"Placement: before or after or child. Default: child"`
+	IfMatch    string `json:"ifMatch,omitempty" jsonschema:"Only write if the block still has this version (from get_page or get_block); fails with a conflict if it changed since"`
+	DryRun     bool   `json:"dryRun,omitempty" jsonschema:"Return the planned changes without writing anything. Default: false"`
 }
 
 type DeletePageInput struct {
-	Name string `json:"name" jsonschema:"Page name to delete"`
+	Name   string `json:"name" jsonschema:"Page name to delete"`
+	DryRun bool   `json:"dryRun,omitempty" jsonschema:"Return the planned changes without writing anything. Default: false"`
 }
 
 type RenamePageInput struct {
 	OldName string `json:"oldName" jsonschema:"Current page name"`
 	NewName string `json:"newName" jsonschema:"New page name"`
+	DryRun  bool   `json:"dryRun,omitempty" jsonschema:"Return the planned changes without writing anything. Default: false"`
 }
 
 type BulkUpdatePropertiesInput struct {
 	Pages    []string       `json:"pages" jsonschema:"List of page names to update"`
 	Property string         `json:"property" jsonschema:"Property key to set"`
 	Value    string         `json:"value" jsonschema:"Property value to set"`
+	DryRun   bool           `json:"dryRun,omitempty" jsonschema:"Return the planned changes without writing anything. Default: false"`
 }
 
 type LinkPagesInput struct {
@@ -151,6 +177,15 @@ type LinkPagesInput struct {
 	Context string `json:"context,omitempty" jsonschema:"Description of the relationship between pages"`
 }
 
+type ApplyBatchInput struct {
+	Operations []BatchOperation `json:"operations" jsonschema:"Write operations to apply in order. If one fails, the ones before it are rolled back"`
+}
+
+type BatchOperation struct {
+	Tool  string         `json:"tool" jsonschema:"Write tool to run: create_page, append_blocks, upsert_blocks, update_block, delete_block, move_block, link_pages, delete_page, rename_page or bulk_update_properties"`
+	Input map[string]any `json:"input" jsonschema:"The tool's input, exactly as the tool itself takes it"`
+}
+
 // --- Flashcard tool inputs ---
 
 // FlashcardOverviewInput has no required params — returns SRS statistics.
@@ -187,6 +222,7 @@ type DecisionCreateInput struct {
 	Deadline string   `json:"deadline" jsonschema:"Decision deadline (YYYY-MM-DD)"`
 	Options  []string `json:"options,omitempty" jsonschema:"Available choices"`
 	Context  string   `json:"context,omitempty" jsonschema:"Brief context for the decision"`
+	Repeat   string   `json:"repeat,omitempty" jsonschema:"Make the decision recurring: a Logseq repeater (+1w, .+1m) or a rule like 'every 2 weeks'. Resolving it adds the next occurrence"`
 }
 
 type DecisionResolveInput struct {
@@ -203,6 +239,32 @@ type DecisionDeferInput struct {
 // AnalysisHealthInput has no required params — audits all analysis/strategy pages.
 type AnalysisHealthInput struct{}
 
+// --- Task tool inputs ---
+
+type ListTasksInput struct {
+	Markers   []string `json:"markers,omitempty" jsonschema:"Task markers to list: TODO, DOING, DONE, LATER, NOW, WAITING, CANCELLED. Default: the open ones (all but DONE and CANCELLED)"`
+	Priority  string   `json:"priority,omitempty" jsonschema:"Only tasks with this priority: A, B or C"`
+	Page      string   `json:"page,omitempty" jsonschema:"Only tasks on this page"`
+	Namespace string   `json:"namespace,omitempty" jsonschema:"Only tasks on the pages of this namespace (e.g. projects lists projects and projects/...)"`
+	From      string   `json:"from,omitempty" jsonschema:"Only tasks scheduled or due on or after this date (YYYY-MM-DD)"`
+	To        string   `json:"to,omitempty" jsonschema:"Only tasks scheduled or due on or before this date (YYYY-MM-DD)"`
+	Overdue   bool     `json:"overdue,omitempty" jsonschema:"Only open tasks whose deadline, or scheduled date when they have none, is before today"`
+	Limit     int      `json:"limit,omitempty" jsonschema:"Max results. Default: 100"`
+}
+
+type SetTaskStateInput struct {
+	UUID    string `json:"uuid" jsonschema:"UUID of the task block"`
+	State   string `json:"state,omitempty" jsonschema:"New marker: TODO, DOING, DONE, LATER, NOW, WAITING or CANCELLED. Default: the next in the cycle TODO → DOING → DONE → TODO (LATER → NOW → DONE); a block that is not a task becomes TODO"`
+	IfMatch string `json:"ifMatch,omitempty" jsonschema:"Only write if the block still has this version (from get_page or get_block); fails with a conflict if it changed since"`
+}
+
+type UpcomingInput struct {
+	From      string `json:"from,omitempty" jsonschema:"Start of the range (YYYY-MM-DD). Default: today"`
+	To        string `json:"to,omitempty" jsonschema:"End of the range (YYYY-MM-DD). Default: 30 days after from"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"Only tasks and decisions on the pages of this namespace"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Max occurrences. Default: 100"`
+}
+
 // --- Journal tool inputs ---
 
 type JournalRangeInput struct {
@@ -216,3 +278,30 @@ type JournalSearchInput struct {
 	From  string `json:"from,omitempty" jsonschema:"Start date filter (YYYY-MM-DD)"`
 	To    string `json:"to,omitempty" jsonschema:"End date filter (YYYY-MM-DD)"`
 }
+
+// --- History tool inputs ---
+
+type ListOperationsInput struct {
+	Limit int `json:"limit,omitempty" jsonschema:"Maximum operations to return, newest first. Default: 20"`
+}
+
+// UndoLastInput has no params — undoes the newest operation not yet undone.
+type UndoLastInput struct{}
+
+type UndoOperationInput struct {
+	ID int `json:"id" jsonschema:"ID of the operation to undo, from list_operations. Undoing an undo redoes the original"`
+}
+
+// RedoLastInput has no params — reapplies the most recently undone operation.
+type RedoLastInput struct{}
+
+type PageHistoryInput struct {
+	Page  string `json:"page" jsonschema:"Page name. A deleted page's history is found by the file it was kept in"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Maximum versions to return, newest first. Default: 20"`
+}
+
+type RestorePageVersionInput struct {
+	Page    string `json:"page" jsonschema:"Page name"`
+	Version string `json:"version" jsonschema:"Version to restore, from page_history (a commit hash or a prefix of one)"`
+	DryRun  bool   `json:"dryRun,omitempty" jsonschema:"Return the content the page would be restored to without writing it"`
+}
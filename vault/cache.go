@@ -0,0 +1,224 @@
+package vault
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// indexCacheVersion is bumped whenever the cache layout or the way files are
+// parsed changes, so caches written by older builds are ignored.
+const indexCacheVersion = 2
+
+func init() {
+	// Frontmatter values are decoded into interfaces; gob needs the concrete
+	// types YAML produces registered to encode them.
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+	gob.Register([]string{})
+	gob.Register(time.Time{})
+}
+
+// WithIndexCache persists the parsed vault to path. Load then re-parses only
+// files whose size or modification time changed since the cache was written,
+// and BuildBacklinks writes the cache back when anything changed. A missing,
+// corrupt or outdated cache falls back to a full load.
+func WithIndexCache(path string) Option {
+	return func(c *Client) { c.cachePath = path }
+}
+
+// DefaultIndexCachePath returns the index cache file for vaultPath in the
+// user cache directory, named after a hash of the vault's absolute path.
+// Returns "" when the platform has no user cache directory.
+func DefaultIndexCachePath(vaultPath string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	abs, err := filepath.Abs(vaultPath)
+	if err != nil {
+		abs = vaultPath
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, "graphthulhu", hex.EncodeToString(sum[:8])+".index")
+}
+
+// blockDerived is what indexing extracts from a block's content.
+type blockDerived struct {
+	Links []string // link targets, as parser.ParseInPage returns them
+	Terms []string // search terms with repeats, see searchTerms
+}
+
+// indexCache is the on-disk form of the parsed vault.
+type indexCache struct {
+	Version  int
+	Settings string                // see cacheSettings
+	Files    map[string]cachedFile // vault-relative path → parsed file
+}
+
+// cachedFile is a parsed file, valid while the file's size and modification
+// time are unchanged.
+type cachedFile struct {
+	Size    int64
+	ModTime int64 // UnixNano
+	Page    types.PageEntity
+	Blocks  []types.BlockEntity
+	Derived map[string]blockDerived // block UUID → derived data
+}
+
+// cacheSettings describes the options that change how files parse. A cache
+// written under different settings is not reused.
+func (c *Client) cacheSettings() string {
+	abs, err := filepath.Abs(c.vaultPath)
+	if err != nil {
+		abs = c.vaultPath
+	}
+	return strings.Join([]string{
+		abs,
+		fmt.Sprint(c.logseq),
+		c.dailyFolder,
+		c.journalTitleFormat,
+		c.journalFileFormat,
+	}, "\x00")
+}
+
+// readIndexCache returns the cached files, or nil when no cache is configured
+// or the cache cannot be used.
+func (c *Client) readIndexCache() map[string]cachedFile {
+	if c.cachePath == "" {
+		return nil
+	}
+	f, err := os.Open(c.cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("graphthulhu: ignoring index cache: %v", err)
+		return nil
+	}
+	defer f.Close()
+
+	var cache indexCache
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&cache); err != nil {
+		log.Printf("graphthulhu: ignoring corrupt index cache %s: %v", c.cachePath, err)
+		return nil
+	}
+	if cache.Version != indexCacheVersion || cache.Settings != c.cacheSettings() {
+		return nil
+	}
+	return cache.Files
+}
+
+// restoreFile indexes a page from the cache instead of parsing its file.
+func (c *Client) restoreFile(relPath string, f cachedFile) {
+	page := &cachedPage{
+		entity:    f.Page,
+		lowerName: strings.ToLower(f.Page.Name),
+		filePath:  relPath,
+		blocks:    f.Blocks,
+		size:      f.Size,
+		modTime:   f.ModTime,
+		derived:   f.Derived,
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applyPageIndex(page)
+}
+
+// matches reports whether the cached parse is still current for info.
+func (f cachedFile) matches(info os.FileInfo) bool {
+	return f.Size == info.Size() && f.ModTime == info.ModTime().UnixNano()
+}
+
+// deriveBlocksLocked extracts links and search terms for every page that was
+// parsed from disk, so they can be cached. Caller must hold c.mu.
+func (c *Client) deriveBlocksLocked() {
+	for key, page := range c.pages {
+		if key != page.lowerName || page.derived != nil {
+			continue
+		}
+		page.derived = deriveBlocks(page)
+	}
+}
+
+// deriveBlocks returns the derived data of a page's blocks keyed by UUID, or
+// nil if two blocks share a UUID and cannot be told apart.
+func deriveBlocks(page *cachedPage) map[string]blockDerived {
+	derived := make(map[string]blockDerived)
+	var walk func([]types.BlockEntity) bool
+	walk = func(blocks []types.BlockEntity) bool {
+		for i := range blocks {
+			b := &blocks[i]
+			if _, dup := derived[b.UUID]; dup {
+				return false
+			}
+			derived[b.UUID] = blockDerived{
+				Links: page.blockLinks(b),
+				Terms: page.blockTerms(b),
+			}
+			if !walk(b.Children) {
+				return false
+			}
+		}
+		return true
+	}
+	if !walk(page.blocks) {
+		return nil
+	}
+	return derived
+}
+
+// writeIndexCacheLocked saves every indexed page to the cache file. The file
+// is replaced atomically so a crash never leaves a truncated cache behind.
+// Caller must hold c.mu.
+func (c *Client) writeIndexCacheLocked() error {
+	cache := indexCache{
+		Version:  indexCacheVersion,
+		Settings: c.cacheSettings(),
+		Files:    make(map[string]cachedFile, len(c.pages)),
+	}
+	for key, page := range c.pages {
+		if key != page.lowerName {
+			continue // alias
+		}
+		cache.Files[page.filePath] = cachedFile{
+			Size:    page.size,
+			ModTime: page.modTime,
+			Page:    page.entity,
+			Blocks:  page.blocks,
+			Derived: page.derived,
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.cachePath), 0o755); err != nil {
+		return err
+	}
+	tmp := c.cachePath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = gob.NewEncoder(w).Encode(&cache)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, c.cachePath)
+}
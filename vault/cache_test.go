@@ -0,0 +1,183 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadCached indexes dir with an index cache at cachePath and reports how
+// many pages Load restored from the cache rather than parsing.
+func loadCached(t *testing.T, dir, cachePath string, opts ...Option) (*Client, int) {
+	t.Helper()
+	c := New(dir, append(opts, WithIndexCache(cachePath))...)
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// Until BuildBacklinks derives them, only restored pages carry derived data.
+	restored := 0
+	for key, page := range c.pages {
+		if key == page.lowerName && page.derived != nil {
+			restored++
+		}
+	}
+	c.BuildBacklinks()
+	return c, restored
+}
+
+// snapshot renders everything the tools can see of the vault as JSON.
+func snapshot(t *testing.T, c *Client) string {
+	t.Helper()
+	ctx := context.Background()
+	pages, _ := c.GetAllPages(ctx)
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Name < pages[j].Name })
+	out := map[string]any{"pages": pages}
+	for _, p := range pages {
+		blocks, _ := c.GetPageBlocksTree(ctx, p.Name)
+		refs, _ := c.GetPageLinkedReferences(ctx, p.Name)
+		var groups []json.RawMessage
+		json.Unmarshal(refs, &groups)
+		out["blocks/"+p.Name] = blocks
+		out["refs/"+p.Name] = sortedJSON(t, groups)
+	}
+	for _, q := range []string{"graphthulhu", "backend", "decision"} {
+		hits, _ := c.FullTextSearch(ctx, q, 50)
+		out["search/"+q] = sortedJSON(t, hits)
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// sortedJSON renders each element of items as JSON, sorted, since backlinks
+// and search hits come back in map iteration order.
+func sortedJSON[T any](t *testing.T, items []T) []string {
+	t.Helper()
+	out := make([]string, len(items))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = string(data)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestIndexCacheRoundTrip(t *testing.T) {
+	dir := testWritableVault(t).vaultPath
+	cachePath := filepath.Join(t.TempDir(), "vault.index")
+
+	cold, n := loadCached(t, dir, cachePath)
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("cache not written: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("cold load restored %d pages", n)
+	}
+
+	warm, n := loadCached(t, dir, cachePath)
+	pages, _ := warm.GetAllPages(context.Background())
+	if n != len(pages) {
+		t.Errorf("warm load restored %d of %d pages", n, len(pages))
+	}
+	if got, want := snapshot(t, warm), snapshot(t, cold); got != want {
+		t.Errorf("warm index differs from cold index\nwarm: %s\ncold: %s", got, want)
+	}
+}
+
+func TestIndexCacheReparsesChangedFiles(t *testing.T) {
+	dir := testWritableVault(t).vaultPath
+	cachePath := filepath.Join(t.TempDir(), "vault.index")
+	loadCached(t, dir, cachePath)
+
+	hanna := filepath.Join(dir, "people", "Hanna.md")
+	data, _ := os.ReadFile(hanna)
+	data = []byte(strings.Replace(string(data), "[[projects/openchaos]]", "[[Go]]", 1))
+	if err := os.WriteFile(hanna, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(hanna, later, later)
+	os.Remove(filepath.Join(dir, "projects", "openchaos.md"))
+	os.WriteFile(filepath.Join(dir, "fresh.md"), []byte("# Fresh\n\nLinks to [[Go]].\n"), 0o644)
+
+	c, n := loadCached(t, dir, cachePath)
+	if n != 4 {
+		t.Errorf("restored %d pages, want the 4 unchanged ones", n)
+	}
+	ctx := context.Background()
+	if p, _ := c.GetPage(ctx, "projects/openchaos"); p != nil {
+		t.Error("deleted page restored from cache")
+	}
+	if p, _ := c.GetPage(ctx, "fresh"); p == nil {
+		t.Error("new page missing")
+	}
+	var refs [][]json.RawMessage
+	raw, _ := c.GetPageLinkedReferences(ctx, "Go")
+	json.Unmarshal(raw, &refs)
+	pagesLinking := map[string]bool{}
+	for _, r := range refs {
+		var page struct{ Name string }
+		json.Unmarshal(r[0], &page)
+		pagesLinking[page.Name] = true
+	}
+	for _, want := range []string{"people/Hanna", "fresh"} {
+		if !pagesLinking[want] {
+			t.Errorf("Go backlinks missing %s: %s", want, raw)
+		}
+	}
+
+	// The rewritten cache reflects the changes.
+	again, _ := loadCached(t, dir, cachePath)
+	if got, want := snapshot(t, again), snapshot(t, c); got != want {
+		t.Error("cache written after changes differs from the loaded index")
+	}
+}
+
+func TestIndexCacheFallsBack(t *testing.T) {
+	dir := testWritableVault(t).vaultPath
+	ref, _ := loadCached(t, dir, filepath.Join(t.TempDir(), "ref.index"))
+	want := snapshot(t, ref)
+
+	t.Run("corrupt", func(t *testing.T) {
+		cachePath := filepath.Join(t.TempDir(), "vault.index")
+		os.WriteFile(cachePath, []byte("not a cache"), 0o644)
+		c, n := loadCached(t, dir, cachePath)
+		if n != 0 {
+			t.Error("pages restored from corrupt cache")
+		}
+		if snapshot(t, c) != want {
+			t.Error("index differs after corrupt cache")
+		}
+		if _, n := loadCached(t, dir, cachePath); n == 0 {
+			t.Error("corrupt cache was not replaced")
+		}
+	})
+
+	t.Run("settings changed", func(t *testing.T) {
+		cachePath := filepath.Join(t.TempDir(), "vault.index")
+		loadCached(t, dir, cachePath)
+		if _, n := loadCached(t, dir, cachePath, WithDailyFolder("journal")); n != 0 {
+			t.Error("cache reused with a different daily folder")
+		}
+	})
+}
+
+func TestDefaultIndexCachePath(t *testing.T) {
+	a, b := DefaultIndexCachePath("/vaults/a"), DefaultIndexCachePath("/vaults/b")
+	if a == "" {
+		t.Skip("no user cache dir")
+	}
+	if a == b || filepath.Dir(a) != filepath.Dir(b) {
+		t.Errorf("paths %q and %q", a, b)
+	}
+}
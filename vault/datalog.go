@@ -0,0 +1,219 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/skridlevsky/graphthulhu/datalog"
+	"github.com/skridlevsky/graphthulhu/parser"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// DatascriptQuery evaluates a DataScript query against the in-memory index
+// with the datalog package. Pages and blocks are exposed under Logseq's
+// attribute names (:block/name, :block/content, :block/refs, :block/page,
+// :block/parent, :block/properties, :block/marker, ...), so queries written
+// for Logseq work unchanged within the supported subset.
+func (c *Client) DatascriptQuery(_ context.Context, query string, inputs ...any) (json.RawMessage, error) {
+	// The database is immutable once built; rebuildLinksLocked drops it on
+	// every change and the next query builds a fresh one.
+	c.mu.Lock()
+	if c.datalogDB == nil {
+		c.datalogDB = c.buildDatalogLocked()
+	}
+	db := c.datalogDB
+	c.mu.Unlock()
+
+	result, err := datalog.Query(db, query, inputs...)
+	if err != nil {
+		return nil, fmt.Errorf("datalog: %w", err)
+	}
+	return json.Marshal(datalog.JSONValue(result))
+}
+
+// HasDataScript marks the vault as answering DataScript queries.
+// Implements backend.HasDataScript.
+func (c *Client) HasDataScript() {}
+
+// datalogBuilder assigns entity ids to pages and blocks while the vault is
+// converted into a datalog.DB.
+type datalogBuilder struct {
+	c      *Client
+	db     *datalog.DB
+	pages  map[string]int // lowercase page name → entity
+	blocks map[string]int // block uuid → entity
+}
+
+// buildDatalogLocked converts every page and block into datalog entities.
+// Link and tag targets without a page file become name-only pages, as they
+// do in Logseq. Caller must hold c.mu.
+func (c *Client) buildDatalogLocked() *datalog.DB {
+	b := &datalogBuilder{
+		c:      c,
+		db:     datalog.NewDB(),
+		pages:  make(map[string]int),
+		blocks: make(map[string]int),
+	}
+	b.db.Many("block/refs", "block/path-refs")
+
+	// Sorted so entity ids are stable between builds of the same vault.
+	var pages []*cachedPage
+	for key, page := range c.pages {
+		if key == page.lowerName {
+			pages = append(pages, page)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].lowerName < pages[j].lowerName })
+
+	for _, page := range pages {
+		b.addPage(page)
+	}
+	// Allocate every block before adding refs so ((uuid)) references to
+	// blocks later in the vault resolve.
+	for _, page := range pages {
+		b.allocBlocks(page.blocks)
+	}
+	for _, page := range pages {
+		pageID := b.pages[page.lowerName]
+		b.addBlocks(page, page.blocks, pageID, nil)
+	}
+	return b.db
+}
+
+func (b *datalogBuilder) addPage(page *cachedPage) {
+	e := b.db.NewEntity()
+	b.pages[page.lowerName] = e
+	p := page.entity
+	b.db.Add(e, "block/name", page.lowerName)
+	b.db.Add(e, "block/original-name", p.OriginalName)
+	b.db.Add(e, "block/journal?", p.Journal)
+	if p.JournalDay != 0 {
+		b.db.Add(e, "block/journal-day", p.JournalDay)
+	}
+	if p.UUID != "" {
+		b.db.Add(e, "block/uuid", p.UUID)
+	}
+	if len(p.Properties) > 0 {
+		b.db.Add(e, "block/properties", p.Properties)
+	}
+	if p.CreatedAt != 0 {
+		b.db.Add(e, "block/created-at", p.CreatedAt)
+	}
+	if p.UpdatedAt != 0 {
+		b.db.Add(e, "block/updated-at", p.UpdatedAt)
+	}
+}
+
+// pageRef returns the entity for a link target, creating a name-only page
+// when the target has no file.
+func (b *datalogBuilder) pageRef(target string) int {
+	key := strings.ToLower(b.c.linkKeyLocked(target))
+	if e, ok := b.pages[key]; ok {
+		return e
+	}
+	e := b.db.NewEntity()
+	b.pages[key] = e
+	b.db.Add(e, "block/name", key)
+	b.db.Add(e, "block/original-name", target)
+	b.db.Add(e, "block/journal?", false)
+	return e
+}
+
+func (b *datalogBuilder) allocBlocks(blocks []types.BlockEntity) {
+	for i := range blocks {
+		b.blocks[blocks[i].UUID] = b.db.NewEntity()
+		b.allocBlocks(blocks[i].Children)
+	}
+}
+
+// addBlocks adds blocks under parent (the page itself for top-level
+// blocks). pathRefs carries the page and the refs of every ancestor, which
+// Logseq exposes as :block/path-refs.
+func (b *datalogBuilder) addBlocks(page *cachedPage, blocks []types.BlockEntity, parent int, pathRefs []int) {
+	pageID := b.pages[page.lowerName]
+	if pathRefs == nil {
+		pathRefs = []int{pageID}
+	}
+	for i := range blocks {
+		blk := &blocks[i]
+		e := b.blocks[blk.UUID]
+		parsed := parser.ParseInPage(blk.Content, page.entity.Name)
+
+		b.db.Add(e, "block/uuid", blk.UUID)
+		b.db.Add(e, "block/content", blk.Content)
+		b.db.Add(e, "block/page", datalog.Ref(pageID))
+		b.db.Add(e, "block/parent", datalog.Ref(parent))
+		if blk.PreBlock {
+			b.db.Add(e, "block/pre-block?", true)
+		}
+		if parsed.Marker != "" {
+			b.db.Add(e, "block/marker", parsed.Marker)
+		}
+		if parsed.Priority != "" {
+			b.db.Add(e, "block/priority", parsed.Priority)
+		}
+		if props := blockProperties(blk, parsed); len(props) > 0 {
+			b.db.Add(e, "block/properties", props)
+		}
+
+		refs := b.blockRefs(parsed)
+		for _, r := range refs {
+			b.db.Add(e, "block/refs", datalog.Ref(r))
+		}
+		childPath := append(append([]int(nil), pathRefs...), refs...)
+		for _, r := range dedupeInts(childPath) {
+			b.db.Add(e, "block/path-refs", datalog.Ref(r))
+		}
+
+		b.addBlocks(page, blk.Children, e, childPath)
+	}
+}
+
+// blockRefs returns the pages a block links or tags and the blocks it
+// references with ((uuid)).
+func (b *datalogBuilder) blockRefs(parsed types.ParsedContent) []int {
+	var refs []int
+	for _, link := range parsed.Links {
+		refs = append(refs, b.pageRef(link))
+	}
+	for _, tag := range parsed.Tags {
+		refs = append(refs, b.pageRef(tag))
+	}
+	for _, uuid := range parsed.BlockReferences {
+		if e, ok := b.blocks[uuid]; ok {
+			refs = append(refs, e)
+		}
+	}
+	return dedupeInts(refs)
+}
+
+// blockProperties returns a block's properties: those set while indexing,
+// otherwise its key:: value lines.
+func blockProperties(blk *types.BlockEntity, parsed types.ParsedContent) map[string]any {
+	if len(blk.Properties) > 0 {
+		return blk.Properties
+	}
+	if len(parsed.Properties) == 0 {
+		return nil
+	}
+	props := make(map[string]any, len(parsed.Properties))
+	for k, v := range parsed.Properties {
+		props[k] = v
+	}
+	return props
+}
+
+func dedupeInts(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	out := ids[:0:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
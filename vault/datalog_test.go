@@ -0,0 +1,108 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func datalogQuery(t *testing.T, c *Client, query string, inputs ...any) any {
+	t.Helper()
+	raw, err := c.DatascriptQuery(context.Background(), query, inputs...)
+	if err != nil {
+		t.Fatalf("DatascriptQuery: %v", err)
+	}
+	var out any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("unmarshal %s: %v", raw, err)
+	}
+	return out
+}
+
+func TestDatascriptQueryTagRefs(t *testing.T) {
+	c := testVault(t)
+
+	// The query Decision.findDecisionsViaDataScript runs.
+	res := datalogQuery(t, c, `[:find (pull ?b [:block/uuid :block/content
+		{:block/page [:block/name :block/original-name]}])
+		:where
+		[?b :block/refs ?ref]
+		[?ref :block/name "decision"]]`).([]any)
+	if len(res) != 1 {
+		t.Fatalf("got %d decision blocks, want 1: %v", len(res), res)
+	}
+	block := res[0].([]any)[0].(map[string]any)
+	if block["content"] != "Reviewed #decision for focus" {
+		t.Errorf("content = %v", block["content"])
+	}
+	page := block["page"].(map[string]any)
+	if page["original-name"] != "daily notes/2026-01-31" {
+		t.Errorf("page = %v", page)
+	}
+	if _, ok := c.blockIndex[block["uuid"].(string)]; !ok {
+		t.Errorf("uuid %v is not a vault block", block["uuid"])
+	}
+}
+
+func TestDatascriptQueryPageProperties(t *testing.T) {
+	c := testVault(t)
+	res := datalogQuery(t, c, `[:find [?name ...]
+		:where
+		[?p :block/properties ?props]
+		[(get ?props :type) ?t]
+		[(= ?t "project")]
+		[?p :block/original-name ?name]]`).([]any)
+	found := false
+	for _, n := range res {
+		if n == "projects/graphthulhu" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("projects/graphthulhu not in %v", res)
+	}
+}
+
+func TestDatascriptQueryLinksAndParents(t *testing.T) {
+	c := testVault(t)
+
+	// [[Go]] has no file but still gets a page entity, as in Logseq.
+	res := datalogQuery(t, c, `[:find ?c .
+		:where [?p :block/name "go"] [?b :block/refs ?p] [?b :block/content ?c]]`)
+	if s, _ := res.(string); !strings.HasPrefix(s, "## Architecture") {
+		t.Errorf("block referencing go = %v", res)
+	}
+
+	res = datalogQuery(t, c, `[:find ?pc .
+		:where
+		[?b :block/content "Full-text search"]
+		[?b :block/parent ?parent]
+		[?parent :block/content ?pc]]`)
+	if res != "## Features" {
+		t.Errorf("parent content = %v", res)
+	}
+}
+
+func TestDatascriptQuerySeesWrites(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+	query := `[:find [?c ...] :where [?b :block/marker "TODO"] [?b :block/content ?c]]`
+	if res := datalogQuery(t, c, query); len(res.([]any)) != 0 {
+		t.Fatalf("unexpected TODOs before write: %v", res)
+	}
+	if _, err := c.AppendBlockInPage(ctx, "tasks", "TODO call Hanna"); err != nil {
+		t.Fatal(err)
+	}
+	res := datalogQuery(t, c, query).([]any)
+	if len(res) != 1 || res[0] != "TODO call Hanna" {
+		t.Errorf("TODOs after write = %v", res)
+	}
+}
+
+func TestDatascriptQueryError(t *testing.T) {
+	c := testVault(t)
+	if _, err := c.DatascriptQuery(context.Background(), `[:find ?b :where [?b :block/content`); err == nil {
+		t.Error("expected error for malformed query")
+	}
+}
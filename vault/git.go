@@ -0,0 +1,273 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skridlevsky/graphthulhu/backend"
+)
+
+// Git integration: with WithGitCommits, every write is committed to the git
+// repository the vault is in, and page history is read back from it. Only
+// the local repository is used; nothing is fetched or pushed.
+
+// gitIdentity is the committer used when git has no user configured, so
+// commits never fail for lack of one.
+var gitIdentity = []string{"-c", "user.name=graphthulhu", "-c", "user.email=graphthulhu@localhost"}
+
+// WithGitCommits makes the client commit the files each write changes to the
+// vault's git repository (see CommitChanges). Load creates the repository
+// when the vault is not in one yet.
+func WithGitCommits(enable bool) Option {
+	return func(c *Client) { c.gitCommits = enable }
+}
+
+// git runs git in the vault directory and returns its output.
+func (c *Client) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", c.vaultPath}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// prepareGit makes sure the vault is in a git repository, creating one with
+// the vault's current files committed when it is not, and picks the
+// identity commits are made with.
+func (c *Client) prepareGit(ctx context.Context) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git commits need git installed: %w", err)
+	}
+	if name, _ := c.git(ctx, "config", "user.name"); strings.TrimSpace(name) == "" {
+		c.gitArgs = gitIdentity
+	} else if email, _ := c.git(ctx, "config", "user.email"); strings.TrimSpace(email) == "" {
+		c.gitArgs = gitIdentity
+	}
+
+	if _, err := c.git(ctx, "rev-parse", "--show-toplevel"); err == nil {
+		return nil
+	}
+	if _, err := c.git(ctx, "init", "--quiet"); err != nil {
+		return err
+	}
+	if _, err := c.git(ctx, "add", "--all", "--", "."); err != nil {
+		return err
+	}
+	_, err := c.git(ctx, append(slices.Clone(c.gitArgs), "commit", "--quiet", "--allow-empty", "-m", "graphthulhu: track vault")...)
+	return err
+}
+
+// writeFile writes a page file atomically and notes it for the next commit.
+// Caller must hold c.mu for write.
+func (c *Client) writeFile(absPath, content string) error {
+	if err := atomicWrite(absPath, content); err != nil {
+		return err
+	}
+	c.noteChange(absPath)
+	return nil
+}
+
+// noteChange records a file a write changed, for the next commit. absPath
+// is the file's absolute path, as safePath returns it. Caller must hold
+// c.mu for write.
+func (c *Client) noteChange(absPath string) {
+	if !c.gitCommits {
+		return
+	}
+	root, err := filepath.Abs(c.vaultPath)
+	if err != nil {
+		return
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil {
+		return
+	}
+	if c.changed == nil {
+		c.changed = make(map[string]bool)
+	}
+	c.changed[filepath.ToSlash(rel)] = true
+}
+
+// CommitChanges implements backend.ChangeCommitter. It commits the files
+// written since the last commit, and only those: edits made to other files
+// outside graphthulhu are left for their author to commit. The commit
+// message is message followed by the pages touched. Writes that were
+// reverted before the commit leave nothing to commit.
+func (c *Client) CommitChanges(ctx context.Context, message string) error {
+	// Commits run one at a time, as git allows, but without c.mu: reads and
+	// writes go on while git runs.
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	c.mu.Lock()
+	if !c.gitCommits || len(c.changed) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	paths := slices.Sorted(func(yield func(string) bool) {
+		for p := range c.changed {
+			if !yield(p) {
+				return
+			}
+		}
+	})
+	c.changed = nil
+	pageNames := make(map[string]string)
+	for _, p := range paths {
+		if !strings.HasSuffix(p, ".md") {
+			continue
+		}
+		name, _ := c.pageNameForFile(p)
+		if page := c.pageByFileLocked(p); page != nil {
+			name = page.entity.OriginalName
+		}
+		pageNames[p] = name
+	}
+	gitArgs := c.gitArgs
+	c.mu.Unlock()
+
+	// A file created and deleted again since the last commit is unknown to
+	// git, which rejects pathspecs that match nothing.
+	tracked, err := c.git(ctx, append([]string{"ls-files", "--"}, paths...)...)
+	if err != nil {
+		return err
+	}
+	known := strings.Split(tracked, "\n")
+	paths = slices.DeleteFunc(paths, func(p string) bool {
+		_, err := os.Stat(filepath.Join(c.vaultPath, filepath.FromSlash(p)))
+		return err != nil && !slices.Contains(known, p)
+	})
+	if len(paths) == 0 {
+		return nil
+	}
+
+	if _, err := c.git(ctx, append([]string{"add", "--all", "--"}, paths...)...); err != nil {
+		return err
+	}
+	// diff --quiet exits non-zero exactly when something is staged.
+	if _, err := c.git(ctx, append([]string{"diff", "--cached", "--quiet", "--"}, paths...)...); err == nil {
+		return nil
+	}
+
+	var pages []string
+	for _, p := range paths {
+		if name, ok := pageNames[p]; ok {
+			pages = append(pages, name)
+		}
+	}
+	if len(pages) > 0 {
+		message += "\n\nPages: " + strings.Join(pages, ", ")
+	}
+
+	args := append(slices.Clone(gitArgs), "commit", "--quiet", "-m", message, "--only", "--")
+	_, err = c.git(ctx, append(args, paths...)...)
+	return err
+}
+
+// pagePathLocked returns the file page name is kept in: its current file, or
+// the file a page of that name would be created in. Caller must hold c.mu.
+func (c *Client) pagePathLocked(name string) (string, error) {
+	cached, err := c.resolvePageLocked(name)
+	if err != nil {
+		return "", err
+	}
+	if cached != nil {
+		return cached.filePath, nil
+	}
+	return c.pageFile(name), nil
+}
+
+// PageHistory implements backend.PageHistorian. It lists the commits that
+// changed the page's file, following it across renames, newest first. A
+// deleted page's history is found by the file it was kept in.
+func (c *Client) PageHistory(ctx context.Context, name string, limit int) ([]backend.PageVersion, error) {
+	c.mu.RLock()
+	relPath, err := c.pagePathLocked(name)
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"log", "--follow", "--format=%H%x1f%aI%x1f%an%x1f%s"}
+	if limit > 0 {
+		args = append(args, "-n", strconv.Itoa(limit))
+	}
+	out, err := c.git(ctx, append(args, "--", relPath)...)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []backend.PageVersion{}
+	for line := range strings.SplitSeq(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, fields[1])
+		versions = append(versions, backend.PageVersion{
+			Version: fields[0],
+			Time:    t,
+			Author:  fields[2],
+			Message: fields[3],
+		})
+	}
+	return versions, nil
+}
+
+// PageAtVersion implements backend.PageHistorian. version is a commit
+// from PageHistory, or a prefix of one; the page's file is read under the
+// name it had at that commit. It returns the page's current file path and
+// its content at version.
+func (c *Client) PageAtVersion(ctx context.Context, name, version string) (string, string, error) {
+	c.mu.RLock()
+	relPath, err := c.pagePathLocked(name)
+	c.mu.RUnlock()
+	if err != nil {
+		return "", "", err
+	}
+	if version == "" || strings.HasPrefix(version, "-") {
+		return "", "", fmt.Errorf("invalid version %q", version)
+	}
+	commit, err := c.git(ctx, "rev-parse", "--verify", "--quiet", version+"^{commit}")
+	if err != nil {
+		return "", "", fmt.Errorf("version %s not found", version)
+	}
+	commit = strings.TrimSpace(commit)
+
+	// Each commit in the file's history is listed with the name the file
+	// had in it: "\x1e<commit>\x00\n<path>\x00". With -z paths are neither
+	// quoted nor split at spaces.
+	oldPath := relPath
+	out, err := c.git(ctx, "log", "--follow", "--relative", "--name-only", "-z", "--format=%x1e%H", "--", relPath)
+	if err != nil {
+		return "", "", err
+	}
+	for entry := range strings.SplitSeq(out, "\x1e") {
+		hash, path, _ := strings.Cut(entry, "\x00")
+		path = strings.TrimSuffix(strings.TrimPrefix(path, "\n"), "\x00")
+		if hash == commit && path != "" {
+			oldPath = path
+			break
+		}
+	}
+
+	content, err := c.git(ctx, "show", commit+":./"+oldPath)
+	if err != nil {
+		return "", "", fmt.Errorf("page %s at %s: %w", name, version, err)
+	}
+	return relPath, content, nil
+}
@@ -0,0 +1,176 @@
+package vault
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitVault returns a loaded vault with git commits enabled in a directory
+// that is not yet a git repository.
+func gitVault(t *testing.T, files map[string]string) (*Client, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Keep the test independent of the user's git configuration.
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	c := New(dir, WithGitCommits(true))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	c.BuildBacklinks()
+	return c, dir
+}
+
+// gitLog returns the subjects of the repository's commits, newest first.
+func gitLog(t *testing.T, c *Client) []string {
+	t.Helper()
+	out, err := c.git(context.Background(), "log", "--format=%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(out), "\n")
+}
+
+func TestGitCommits(t *testing.T) {
+	c, dir := gitVault(t, map[string]string{"Projects.md": "- alpha\n"})
+	ctx := context.Background()
+
+	if got := gitLog(t, c); len(got) != 1 || got[0] != "graphthulhu: track vault" {
+		t.Fatalf("initial commits = %q", got)
+	}
+
+	// A file edited outside graphthulhu is left for its author to commit.
+	if err := os.WriteFile(filepath.Join(dir, "Scratch.md"), []byte("- mine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.AppendBlockInPage(ctx, "Projects", "beta"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreatePage(ctx, "Ideas", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CommitChanges(ctx, "graphthulhu: test"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := c.git(ctx, "log", "-1", "--format=%B", "--name-only")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"graphthulhu: test", "Pages: Ideas, Projects", "Ideas.md", "Projects.md"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("commit %q lacks %q", out, want)
+		}
+	}
+	if strings.Contains(out, "Scratch.md") {
+		t.Errorf("commit included a file graphthulhu did not write:\n%s", out)
+	}
+
+	// A page created and deleted again leaves nothing to commit.
+	if _, err := c.CreatePage(ctx, "Temp", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeletePage(ctx, "Temp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CommitChanges(ctx, "graphthulhu: nothing"); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitLog(t, c); len(got) != 2 {
+		t.Errorf("commits = %q, want 2", got)
+	}
+}
+
+func TestPageHistory(t *testing.T) {
+	c, _ := gitVault(t, map[string]string{"Draft.md": "- first\n"})
+	ctx := context.Background()
+
+	blocks, _ := c.GetPageBlocksTree(ctx, "Draft")
+	if err := c.UpdateBlock(ctx, blocks[0].UUID, "second"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CommitChanges(ctx, "graphthulhu: update_block"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RenamePage(ctx, "Draft", "Final"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CommitChanges(ctx, "graphthulhu: rename_page"); err != nil {
+		t.Fatal(err)
+	}
+
+	// History follows the page across its rename.
+	versions, err := c.PageHistory(ctx, "Final", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("versions = %+v, want 3", versions)
+	}
+	if versions[0].Message != "graphthulhu: rename_page" || versions[0].Author != "graphthulhu" || versions[0].Time.IsZero() {
+		t.Errorf("newest version = %+v", versions[0])
+	}
+	if limited, _ := c.PageHistory(ctx, "Final", 1); len(limited) != 1 {
+		t.Errorf("limit 1 returned %d versions", len(limited))
+	}
+
+	// The oldest version is read from the file the page had then.
+	path, content, err := c.PageAtVersion(ctx, "Final", versions[2].Version[:10])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "Final.md" || content != "- first\n" {
+		t.Errorf("PageAtVersion = %q, %q", path, content)
+	}
+
+	// A deleted page's history is still found by its file.
+	if err := c.DeletePage(ctx, "Final"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CommitChanges(ctx, "graphthulhu: delete_page"); err != nil {
+		t.Fatal(err)
+	}
+	if versions, _ := c.PageHistory(ctx, "Final", 0); len(versions) != 4 {
+		t.Errorf("deleted page has %d versions, want 4", len(versions))
+	}
+	if _, content, _ := c.PageAtVersion(ctx, "Final", "HEAD~1"); !strings.HasPrefix(content, "- second") {
+		t.Errorf("before deletion = %q", content)
+	}
+
+	if _, _, err := c.PageAtVersion(ctx, "Final", "--output=x"); err == nil {
+		t.Error("accepted an option as a version")
+	}
+}
+
+func TestPageAtVersionSpacedName(t *testing.T) {
+	c, _ := gitVault(t, map[string]string{"My Draft.md": "- first\n"})
+	ctx := context.Background()
+
+	if err := c.RenamePage(ctx, "My Draft", "Final Draft"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CommitChanges(ctx, "graphthulhu: rename_page"); err != nil {
+		t.Fatal(err)
+	}
+	versions, err := c.PageHistory(ctx, "Final Draft", 0)
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("versions = %+v, %v", versions, err)
+	}
+	_, content, err := c.PageAtVersion(ctx, "Final Draft", versions[1].Version)
+	if err != nil || content != "- first\n" {
+		t.Errorf("PageAtVersion = %q, %v", content, err)
+	}
+}
@@ -14,25 +14,33 @@ type backlink struct {
 }
 
 // buildBacklinks scans all pages' block trees and builds a reverse link index.
+// linkKey maps a link target to its index key, so [[Spec]] and
+// [[projects/alpha/Spec]] land on the same page.
 // Returns: map[lowercase target page name] → []backlink
-func buildBacklinks(pages map[string]*cachedPage) map[string][]backlink {
+func buildBacklinks(pages map[string]*cachedPage, linkKey func(target string) string) map[string][]backlink {
 	index := make(map[string][]backlink)
 
+	seen := make(map[string]bool)
 	for _, page := range pages {
-		scanBlocksForLinks(page.lowerName, page.blocks, index)
+		if seen[page.lowerName] {
+			continue // skip alias duplicates
+		}
+		seen[page.lowerName] = true
+		scanBlocksForLinks(page, page.blocks, index, linkKey)
 	}
 
 	return index
 }
 
-// scanBlocksForLinks recursively extracts [[links]] from blocks and records backlinks.
-func scanBlocksForLinks(sourcePage string, blocks []types.BlockEntity, index map[string][]backlink) {
-	for _, b := range blocks {
-		parsed := parser.Parse(b.Content)
-		for _, link := range parsed.Links {
-			targetKey := toLower(link)
+// scanBlocksForLinks recursively records a backlink for every [[link]] and
+// relative markdown link in blocks, which belong to page.
+func scanBlocksForLinks(page *cachedPage, blocks []types.BlockEntity, index map[string][]backlink, linkKey func(string) string) {
+	for i := range blocks {
+		b := &blocks[i]
+		for _, link := range page.blockLinks(b) {
+			targetKey := linkKey(link)
 			index[targetKey] = append(index[targetKey], backlink{
-				fromPage: sourcePage,
+				fromPage: page.lowerName,
 				block: types.BlockSummary{
 					UUID:    b.UUID,
 					Content: b.Content,
@@ -40,11 +48,20 @@ func scanBlocksForLinks(sourcePage string, blocks []types.BlockEntity, index map
 			})
 		}
 		if len(b.Children) > 0 {
-			scanBlocksForLinks(sourcePage, b.Children, index)
+			scanBlocksForLinks(page, b.Children, index, linkKey)
 		}
 	}
 }
 
+// blockLinks returns the link targets of one of the page's blocks. Relative
+// markdown links resolve against the page's own name.
+func (p *cachedPage) blockLinks(b *types.BlockEntity) []string {
+	if d, ok := p.derived[b.UUID]; ok {
+		return d.Links
+	}
+	return parser.ParseInPage(b.Content, p.entity.Name).Links
+}
+
 // toLower normalizes page names to lowercase using full Unicode support.
 func toLower(s string) string {
 	return strings.ToLower(s)
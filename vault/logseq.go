@@ -0,0 +1,328 @@
+package vault
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/skridlevsky/graphthulhu/parser"
+	"github.com/skridlevsky/graphthulhu/types"
+)
+
+// Logseq file graphs keep pages in pages/ and journals/, encode namespaces in
+// file names ("a___b.md" is page "a/b"), name journal pages after their date
+// and store metadata as key:: value properties rather than YAML frontmatter.
+// WithLogseqGraph switches the client to that layout so a graph directory can
+// be served without the Logseq app running.
+
+const (
+	logseqPagesDir    = "pages"
+	logseqJournalsDir = "journals"
+
+	// Logseq's defaults when config.edn does not override them.
+	defaultJournalTitleFormat = "MMM do, yyyy"
+	defaultJournalFileFormat  = "yyyy_MM_dd"
+)
+
+// WithLogseqGraph treats the directory as a Logseq file graph: only pages/
+// and journals/ are indexed, file names are decoded the way Logseq encodes
+// them, title:: and id:: properties are honoured, and writes produce outliner
+// markdown with id:: properties instead of HTML comments.
+func WithLogseqGraph() Option {
+	return func(c *Client) {
+		c.logseq = true
+		c.dailyFolder = logseqJournalsDir
+		c.journalTitleFormat = defaultJournalTitleFormat
+		c.journalFileFormat = defaultJournalFileFormat
+	}
+}
+
+// GraphKind reports whether the client serves a Logseq file graph or an
+// Obsidian vault. Implements backend.GraphKind.
+func (c *Client) GraphKind() string {
+	if c.logseq {
+		return "logseq"
+	}
+	return "obsidian"
+}
+
+var (
+	ednTitleFormatPattern = regexp.MustCompile(`:journal/page-title-format\s+"([^"]+)"`)
+	ednFileFormatPattern  = regexp.MustCompile(`:journal/file-name-format\s+"([^"]+)"`)
+)
+
+// loadLogseqConfig reads the journal date formats from logseq/config.edn.
+// A missing or unreadable config leaves Logseq's defaults in place.
+func (c *Client) loadLogseqConfig() {
+	data, err := os.ReadFile(filepath.Join(c.vaultPath, "logseq", "config.edn"))
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// Drop ";;" comments, which hold commented-out examples of both keys.
+		line, _, _ = strings.Cut(line, ";")
+		if m := ednTitleFormatPattern.FindStringSubmatch(line); m != nil {
+			c.journalTitleFormat = m[1]
+		}
+		if m := ednFileFormatPattern.FindStringSubmatch(line); m != nil {
+			c.journalFileFormat = m[1]
+		}
+	}
+}
+
+// logseqIndexable reports whether relPath lives in pages/ or journals/.
+func logseqIndexable(relPath string) bool {
+	dir, _, _ := strings.Cut(filepath.ToSlash(relPath), "/")
+	return dir == logseqPagesDir || dir == logseqJournalsDir
+}
+
+// logseqPageName returns the page name for a file in a Logseq graph and, for
+// journal files, the day as yyyymmdd.
+func (c *Client) logseqPageName(relPath string) (string, int) {
+	slashed := filepath.ToSlash(relPath)
+	base := strings.TrimSuffix(path.Base(slashed), ".md")
+	if strings.HasPrefix(slashed, logseqJournalsDir+"/") {
+		if t, err := time.Parse(logseqDateLayout(c.journalFileFormat), base); err == nil {
+			return formatLogseqDate(t, c.journalTitleFormat), journalDay(t)
+		}
+	}
+	return decodeLogseqFileName(base), 0
+}
+
+// logseqPageFile returns the vault-relative file a new page should live in:
+// journals/ for names that parse as a journal title, pages/ otherwise.
+func (c *Client) logseqPageFile(name string) string {
+	if t, ok := c.parseJournalTitle(name); ok {
+		return path.Join(logseqJournalsDir, formatLogseqDate(t, c.journalFileFormat)+".md")
+	}
+	return path.Join(logseqPagesDir, encodeLogseqFileName(name)+".md")
+}
+
+// parseJournalTitle parses name with the graph's journal title format.
+func (c *Client) parseJournalTitle(name string) (time.Time, bool) {
+	t, err := time.Parse(logseqDateLayout(c.journalTitleFormat), ordinalPattern.ReplaceAllString(strings.TrimSpace(name), "$1"))
+	return t, err == nil
+}
+
+// ordinalPattern matches an ordinal day such as "1st" or "23rd".
+var ordinalPattern = regexp.MustCompile(`\b(\d{1,2})(?:st|nd|rd|th)\b`)
+
+// journalDay returns t as a yyyymmdd integer, Logseq's :block/journal-day.
+func journalDay(t time.Time) int {
+	return t.Year()*10000 + int(t.Month())*100 + t.Day()
+}
+
+// logseqDateTokens are the date-fns tokens Logseq date formats use, longest
+// first so "MMMM" wins over "MMM".
+var logseqDateTokens = []string{"yyyy", "yy", "MMMM", "MMM", "MM", "M", "do", "dd", "d", "EEEE", "EEE", "EE", "E"}
+
+// splitLogseqDateFormat splits a date-fns format into tokens and literal text.
+func splitLogseqDateFormat(format string) []string {
+	var parts []string
+	for i := 0; i < len(format); {
+		tok := ""
+		for _, t := range logseqDateTokens {
+			if strings.HasPrefix(format[i:], t) {
+				tok = t
+				break
+			}
+		}
+		if tok == "" {
+			tok = format[i : i+1]
+		}
+		parts = append(parts, tok)
+		i += len(tok)
+	}
+	return parts
+}
+
+// logseqDateLayouts maps date-fns tokens to Go time layouts. Ordinal days
+// ("do") parse as plain days; strip ordinals before parsing.
+var logseqDateLayouts = map[string]string{
+	"yyyy": "2006", "yy": "06",
+	"MMMM": "January", "MMM": "Jan", "MM": "01", "M": "1",
+	"do": "2", "dd": "02", "d": "2",
+	"EEEE": "Monday", "EEE": "Mon", "EE": "Mon", "E": "Mon",
+}
+
+// logseqDateLayout converts a date-fns format to a Go time layout.
+func logseqDateLayout(format string) string {
+	var b strings.Builder
+	for _, part := range splitLogseqDateFormat(format) {
+		if layout, ok := logseqDateLayouts[part]; ok {
+			b.WriteString(layout)
+		} else {
+			b.WriteString(part)
+		}
+	}
+	return b.String()
+}
+
+// formatLogseqDate renders t with a date-fns format such as "MMM do, yyyy".
+func formatLogseqDate(t time.Time, format string) string {
+	var b strings.Builder
+	for _, part := range splitLogseqDateFormat(format) {
+		layout, ok := logseqDateLayouts[part]
+		switch {
+		case part == "do":
+			fmt.Fprintf(&b, "%d%s", t.Day(), ordinalSuffix(t.Day()))
+		case ok:
+			b.WriteString(t.Format(layout))
+		default:
+			b.WriteString(part)
+		}
+	}
+	return b.String()
+}
+
+// ordinalSuffix returns the English ordinal suffix for a day of the month.
+func ordinalSuffix(day int) string {
+	if day%100 >= 11 && day%100 <= 13 {
+		return "th"
+	}
+	switch day % 10 {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	default:
+		return "th"
+	}
+}
+
+// encodeLogseqFileName maps a page name to Logseq's :triple-lowbar file name:
+// namespace separators become "___" and characters unsafe in file names are
+// percent-escaped.
+func encodeLogseqFileName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == '/':
+			b.WriteString("___")
+		case strings.ContainsRune(`<>:"\|?*#%`, r):
+			fmt.Fprintf(&b, "%%%02X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// decodeLogseqFileName reverses encodeLogseqFileName. Legacy "%2F"
+// namespace escapes decode to "/" as well.
+func decodeLogseqFileName(base string) string {
+	name := strings.ReplaceAll(base, "___", "/")
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return name
+}
+
+// applyLogseqProperties merges the page properties Logseq keeps in the first
+// block (key:: value lines before the first bullet) into props, marking that
+// block as the page's pre-block, and fills every block's Properties from its
+// own key:: value lines.
+func applyLogseqProperties(props map[string]any, blocks []types.BlockEntity) map[string]any {
+	if len(blocks) > 0 && isPropertyBlock(blocks[0].Content) {
+		blocks[0].PreBlock = true
+		if props == nil {
+			props = make(map[string]any)
+		}
+		for k, v := range parser.Parse(blocks[0].Content).Properties {
+			props[k] = v
+		}
+	}
+	setBlockProperties(blocks)
+	return props
+}
+
+// isPropertyBlock reports whether every non-blank line of content is a
+// key:: value property.
+func isPropertyBlock(content string) bool {
+	found := false
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(parser.Parse(line).Properties) == 0 {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// setBlockProperties fills each block's Properties from its key:: value lines.
+func setBlockProperties(blocks []types.BlockEntity) {
+	for i := range blocks {
+		if props := parser.Parse(blocks[i].Content).Properties; len(props) > 0 {
+			blocks[i].Properties = make(map[string]any, len(props))
+			for k, v := range props {
+				blocks[i].Properties[k] = v
+			}
+		}
+		setBlockProperties(blocks[i].Children)
+	}
+}
+
+// renderLogseqProperties renders page properties as the key:: value lines
+// Logseq writes at the top of a page, followed by a blank line.
+func renderLogseqProperties(properties map[string]any) string {
+	if len(properties) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s:: %s\n", k, strings.Join(parser.PropertyList(properties[k]), ", "))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// titlePropertyPattern matches a page's title:: property line.
+var titlePropertyPattern = regexp.MustCompile(`(?m)^title::.*$`)
+
+// embedUUID pins blockUUID in content: as an id:: property under the first
+// line of a list item in a Logseq graph, as an HTML comment otherwise.
+func (c *Client) embedUUID(content, blockUUID string) string {
+	first, rest, hasRest := strings.Cut(content, "\n")
+	indent, bullet, _, ok := listItem(first)
+	if !c.logseq || !ok {
+		return embedUUID(content, blockUUID)
+	}
+	prop := indent + strings.Repeat(" ", len(bullet)) + "id:: " + blockUUID
+	if !hasRest {
+		return first + "\n" + prop
+	}
+	return first + "\n" + prop + "\n" + rest
+}
+
+// continueOutline is the package-level continueOutline, except that in a
+// Logseq graph every block is a list item so content is always rendered as one.
+func (c *Client) continueOutline(span blockSpan, ok bool, content string) string {
+	if !c.logseq {
+		return continueOutline(span, ok, content)
+	}
+	if _, _, _, isItem := listItem(strings.SplitN(content, "\n", 2)[0]); isItem {
+		return content
+	}
+	bullet := "- "
+	if ok && span.isListItem() {
+		bullet = span.bullet
+	}
+	return renderListItem("", bullet, content)
+}
@@ -0,0 +1,243 @@
+package vault
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBlockUUID = "6650a1b2-0000-4000-8000-000000000001"
+
+// testLogseqGraph builds a small Logseq file graph in a temp directory.
+func testLogseqGraph(t *testing.T, config string) *Client {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"pages/projects___graphthulhu.md": "type:: project\ntags:: go, mcp\n\n- Backend work\n\t- Parse [[Logseq]] files\n\t  id:: " + testBlockUUID + "\n- Done #shipped\n",
+		"pages/Logseq.md":                 "title:: Logseq App\n\n- Outliner\n",
+		"pages/a%3Fb.md":                  "- escaped\n",
+		"journals/2026_01_31.md":          "- Worked on [[projects/graphthulhu]]\n",
+		"logseq/bak/pages/old.md":         "- backup copy\n",
+		"notes/stray.md":                  "- outside pages/\n",
+	}
+	if config != "" {
+		files["logseq/config.edn"] = config
+	}
+	for rel, content := range files {
+		abs := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := New(dir, WithLogseqGraph())
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	c.BuildBacklinks()
+	return c
+}
+
+func TestLogseqGraphPages(t *testing.T) {
+	c := testLogseqGraph(t, "")
+	ctx := context.Background()
+
+	pages, _ := c.GetAllPages(ctx)
+	names := make(map[string]bool)
+	for _, p := range pages {
+		names[p.Name] = true
+	}
+	for _, want := range []string{"projects/graphthulhu", "Logseq App", "a?b", "Jan 31st, 2026"} {
+		if !names[want] {
+			t.Errorf("missing page %q, have %v", want, names)
+		}
+	}
+	if len(pages) != 4 {
+		t.Errorf("expected 4 pages (bak/ and notes/ skipped), got %d: %v", len(pages), names)
+	}
+
+	page, _ := c.GetPage(ctx, "projects/graphthulhu")
+	if page == nil {
+		t.Fatal("projects/graphthulhu not found")
+	}
+	if page.Properties["type"] != "project" {
+		t.Errorf("type property = %v, want project", page.Properties["type"])
+	}
+
+	blocks, _ := c.GetPageBlocksTree(ctx, "projects/graphthulhu")
+	if len(blocks) != 3 || !blocks[0].PreBlock {
+		t.Fatalf("expected pre-block plus 2 blocks, got %+v", blocks)
+	}
+	child := blocks[1].Children
+	if len(child) != 1 || child[0].UUID != testBlockUUID {
+		t.Fatalf("expected child with id:: UUID, got %+v", child)
+	}
+	if strings.Contains(child[0].Content, "id::") {
+		t.Errorf("id:: property should be stripped from content: %q", child[0].Content)
+	}
+
+	// Namespaced names match exactly; no Obsidian-style basename fallback.
+	if page, _ := c.GetPage(ctx, "graphthulhu"); page != nil {
+		t.Errorf("basename lookup should not resolve in a Logseq graph, got %s", page.Name)
+	}
+}
+
+func TestLogseqGraphJournals(t *testing.T) {
+	c := testLogseqGraph(t, "")
+	ctx := context.Background()
+
+	page, _ := c.GetPage(ctx, "Jan 31st, 2026")
+	if page == nil {
+		t.Fatal("journal page not found")
+	}
+	if !page.Journal || page.JournalDay != 20260131 {
+		t.Errorf("journal = %v, day = %d", page.Journal, page.JournalDay)
+	}
+
+	results, err := c.SearchJournals(ctx, "worked", "2026-01-01", "2026-01-31")
+	if err != nil {
+		t.Fatalf("SearchJournals: %v", err)
+	}
+	if len(results) != 1 || results[0].Date != "2026-01-31" {
+		t.Errorf("SearchJournals = %+v", results)
+	}
+}
+
+func TestLogseqGraphConfigFormats(t *testing.T) {
+	config := `{:meta/version 1
+ ;; :journal/page-title-format "MMM do, yyyy"
+ :journal/page-title-format "yyyy-MM-dd"
+ :journal/file-name-format "yyyy_MM_dd"}`
+	c := testLogseqGraph(t, config)
+
+	page, _ := c.GetPage(context.Background(), "2026-01-31")
+	if page == nil || page.JournalDay != 20260131 {
+		t.Fatalf("expected journal titled 2026-01-31, got %+v", page)
+	}
+}
+
+func TestLogseqGraphWrites(t *testing.T) {
+	c := testLogseqGraph(t, "")
+	ctx := context.Background()
+
+	if _, err := c.CreatePage(ctx, "area/health", map[string]any{"type": "area"}, nil); err != nil {
+		t.Fatalf("CreatePage: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(c.vaultPath, "pages", "area___health.md"))
+	if err != nil {
+		t.Fatalf("namespaced page file: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "type:: area\n") {
+		t.Errorf("expected property pre-block, got %q", data)
+	}
+
+	block, err := c.AppendBlockInPage(ctx, "area/health", "Run 5k")
+	if err != nil {
+		t.Fatalf("AppendBlockInPage: %v", err)
+	}
+	if _, err := c.InsertBlock(ctx, block.UUID, "Tuesday", nil); err != nil {
+		t.Fatalf("InsertBlock: %v", err)
+	}
+	if _, err := c.PrependBlockInPage(ctx, "area/health", "Stretch"); err != nil {
+		t.Fatalf("PrependBlockInPage: %v", err)
+	}
+
+	data, _ = os.ReadFile(filepath.Join(c.vaultPath, "pages", "area___health.md"))
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{"type:: area", "", "- Stretch", "  id:: ", "- Run 5k", "  id:: " + block.UUID, "\t- Tuesday", "\t  id:: "}
+	if len(lines) != len(want) {
+		t.Fatalf("file = %q", data)
+	}
+	for i := range want {
+		if !strings.HasPrefix(lines[i], want[i]) {
+			t.Errorf("line %d = %q, want prefix %q", i, lines[i], want[i])
+		}
+	}
+	if strings.Contains(string(data), "<!--") {
+		t.Errorf("Logseq graph should not get HTML comment UUIDs: %q", data)
+	}
+
+	blocks, _ := c.GetPageBlocksTree(ctx, "area/health")
+	if len(blocks) != 3 || blocks[2].UUID != block.UUID || len(blocks[2].Children) != 1 {
+		t.Errorf("unexpected tree after writes: %+v", blocks)
+	}
+
+	// New journal pages land in journals/ under the configured file name.
+	if _, err := c.AppendBlockInPage(ctx, "Feb 1st, 2026", "New day"); err != nil {
+		t.Fatalf("AppendBlockInPage journal: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(c.vaultPath, "journals", "2026_02_01.md")); err != nil {
+		t.Errorf("journal file not created: %v", err)
+	}
+}
+
+func TestLogseqGraphRenameUpdatesTitle(t *testing.T) {
+	c := testLogseqGraph(t, "")
+	ctx := context.Background()
+
+	if err := c.RenamePage(ctx, "Logseq App", "Logseq Desktop"); err != nil {
+		t.Fatalf("RenamePage: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(c.vaultPath, "pages", "Logseq Desktop.md"))
+	if err != nil {
+		t.Fatalf("renamed file: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "title:: Logseq Desktop\n") {
+		t.Errorf("title:: not updated: %q", data)
+	}
+	if page, _ := c.GetPage(ctx, "Logseq Desktop"); page == nil {
+		t.Error("renamed page not found")
+	}
+	if page, _ := c.GetPage(ctx, "Logseq App"); page != nil {
+		t.Error("old name still resolves")
+	}
+}
+
+func TestLogseqFileNames(t *testing.T) {
+	tests := []struct{ name, file string }{
+		{"plain", "plain"},
+		{"a/b/c", "a___b___c"},
+		{"what?", "what%3F"},
+		{"50%", "50%25"},
+	}
+	for _, tt := range tests {
+		if got := encodeLogseqFileName(tt.name); got != tt.file {
+			t.Errorf("encode(%q) = %q, want %q", tt.name, got, tt.file)
+		}
+		if got := decodeLogseqFileName(tt.file); got != tt.name {
+			t.Errorf("decode(%q) = %q, want %q", tt.file, got, tt.name)
+		}
+	}
+	if got := decodeLogseqFileName("a%2Fb"); got != "a/b" {
+		t.Errorf("legacy namespace escape decoded to %q", got)
+	}
+}
+
+func TestFormatLogseqDate(t *testing.T) {
+	day := time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct{ format, want string }{
+		{"MMM do, yyyy", "Mar 2nd, 2026"},
+		{"yyyy_MM_dd", "2026_03_02"},
+		{"EEEE, MMMM d, yyyy", "Monday, March 2, 2026"},
+		{"dd-MM-yyyy", "02-03-2026"},
+	}
+	for _, tt := range tests {
+		got := formatLogseqDate(day, tt.format)
+		if got != tt.want {
+			t.Errorf("formatLogseqDate(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+		parsed, err := time.Parse(logseqDateLayout(tt.format), ordinalPattern.ReplaceAllString(got, "$1"))
+		if err != nil || !parsed.Equal(day) {
+			t.Errorf("round trip %q: %v, %v", tt.format, parsed, err)
+		}
+	}
+	if ordinalSuffix(11) != "th" || ordinalSuffix(21) != "st" || ordinalSuffix(23) != "rd" {
+		t.Error("ordinalSuffix mismatch")
+	}
+}
@@ -12,90 +12,280 @@ import (
 // parseMarkdownBlocks parses markdown body (frontmatter already stripped) into a
 // block tree compatible with types.BlockEntity. Headings create hierarchically
 // nested blocks based on level (H1 > H2 > H3, etc). Content between headings
-// is included in the preceding heading block's Content.
+// is included in the preceding heading block's Content. List items ("- ",
+// "* ", "+ ", "1. ") become child blocks of the enclosing heading, nested by
+// indentation, so Logseq-style outlines keep their hierarchy.
 //
 // filepath is used as a seed for deterministic block UUID generation.
 func parseMarkdownBlocks(filepath, body string) []types.BlockEntity {
+	blocks, _ := parseMarkdownOutline(filepath, body)
+	return blocks
+}
+
+// blockSpan locates a parsed block within the lines of the markdown body.
+type blockSpan struct {
+	start      int    // first line of the block
+	contentEnd int    // line after the block's own content
+	end        int    // line after the block's last descendant
+	indent     string // leading whitespace before a list bullet
+	bullet     string // list marker plus following whitespace; empty for non-list blocks
+}
+
+// isListItem reports whether the span belongs to a list-item block.
+func (s blockSpan) isListItem() bool {
+	return s.bullet != ""
+}
+
+// parseMarkdownOutline is parseMarkdownBlocks that also returns where each
+// block lives in body, keyed by UUID. Write operations use the spans to edit
+// list items in place without disturbing indentation.
+func parseMarkdownOutline(filepath, body string) ([]types.BlockEntity, map[string]blockSpan) {
 	if strings.TrimSpace(body) == "" {
-		return nil
+		return nil, nil
 	}
 
 	lines := strings.Split(body, "\n")
 
-	// Collect sections: each heading starts a new section.
+	// Collect sections: each heading, list item or paragraph following a
+	// list starts a new section. Every line belongs to exactly one section,
+	// so a section's lines map back to the file one-to-one.
 	type section struct {
-		level    int    // 0 = pre-heading, 1-6 = H1-H6
-		startLine int   // line number in the original file (for UUID seed)
-		lines    []string
+		level     int    // 0 = text, 1-6 = H1-H6; unused for list items
+		indent    string // list items only
+		bullet    string // list items only
+		startLine int    // line number in the original file (for UUID seed)
+		lines     []string
 	}
 
 	var sections []section
 	current := section{level: 0, startLine: 0}
+	flush := func() {
+		if len(current.lines) > 0 || current.level > 0 || current.bullet != "" {
+			sections = append(sections, current)
+		}
+	}
+	// continuation strips a list item's content indentation from line.
+	continuation := func(line string) string {
+		if current.bullet == "" {
+			return line
+		}
+		return dedent(line, indentWidth(current.indent)+len(current.bullet))
+	}
 
+	fence := ""
+	prevBlank := false
 	for i, line := range lines {
-		if lvl := headingLevel(line); lvl > 0 {
-			// Save accumulated section.
-			if len(current.lines) > 0 || current.level > 0 {
-				sections = append(sections, current)
+		blank := strings.TrimSpace(line) == ""
+		if fence != "" {
+			// Inside a code fence nothing is a heading or list item.
+			current.lines = append(current.lines, continuation(line))
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
 			}
+			prevBlank = blank
+			continue
+		}
+
+		indent, bullet, text, isItem := listItem(line)
+		lvl := headingLevel(line)
+		inItem := current.bullet != ""
+
+		switch {
+		case isItem:
+			flush()
+			current = section{indent: indent, bullet: bullet, startLine: i, lines: []string{text}}
+		case blank:
+			current.lines = append(current.lines, continuation(line))
+		case inItem && (indentWidth(line) > indentWidth(current.indent) || (!prevBlank && lvl == 0)):
+			// Indented or lazy continuation of the list item.
+			current.lines = append(current.lines, continuation(line))
+		case lvl > 0:
+			flush()
 			current = section{level: lvl, startLine: i, lines: []string{line}}
-		} else {
+		case inItem:
+			// Unindented text after a blank line ends the list.
+			flush()
+			current = section{startLine: i, lines: []string{line}}
+		default:
 			current.lines = append(current.lines, line)
 		}
+
+		fence = fenceMarker(line)
+		prevBlank = blank
 	}
-	// Save final section.
-	if len(current.lines) > 0 || current.level > 0 {
-		sections = append(sections, current)
-	}
+	flush()
 
 	// Build blocks from sections.
 	type stackEntry struct {
-		block *types.BlockEntity
-		level int
+		block  *types.BlockEntity
+		level  int // heading level; 0 for list items
+		indent int // list item indent width
 	}
 
 	var roots []types.BlockEntity
 	var stack []stackEntry
+	spans := make(map[string]blockSpan)
+	pinned := embeddedUUIDs(body)
+
+	// attach adds block under the top of the stack, or as a root.
+	attach := func(block types.BlockEntity) *types.BlockEntity {
+		if len(stack) == 0 {
+			roots = append(roots, block)
+			return &roots[len(roots)-1]
+		}
+		parent := stack[len(stack)-1].block
+		parent.Children = append(parent.Children, block)
+		return &parent.Children[len(parent.Children)-1]
+	}
 
 	for _, sec := range sections {
 		rawContent := strings.TrimRight(strings.Join(sec.lines, "\n"), "\n ")
-		if rawContent == "" && sec.level == 0 {
+		if rawContent == "" && sec.level == 0 && sec.bullet == "" {
 			continue
 		}
 
 		// Extract or generate UUID, and get cleaned content
-		blockUUID, cleanContent := getOrCreateUUID(filepath, sec.startLine, rawContent)
+		blockUUID, cleanContent := getOrCreateUUID(filepath, sec.startLine, rawContent, pinned)
 
 		block := types.BlockEntity{
 			UUID:    blockUUID,
 			Content: cleanContent,
 		}
 
-		if sec.level == 0 {
-			// Pre-heading content is always a root block.
-			roots = append(roots, block)
-			continue
+		contentEnd := sec.startLine + len(sec.lines)
+		for contentEnd > sec.startLine+1 && strings.TrimSpace(lines[contentEnd-1]) == "" {
+			contentEnd--
+		}
+		spans[blockUUID] = blockSpan{
+			start:      sec.startLine,
+			contentEnd: contentEnd,
+			indent:     sec.indent,
+			bullet:     sec.bullet,
 		}
 
-		// Pop stack until we find a parent with lower heading level.
-		for len(stack) > 0 && stack[len(stack)-1].level >= sec.level {
-			stack = stack[:len(stack)-1]
+		switch {
+		case sec.bullet != "":
+			// Pop until the top is a heading or a less indented list item.
+			width := indentWidth(sec.indent)
+			for len(stack) > 0 && stack[len(stack)-1].level == 0 && stack[len(stack)-1].indent >= width {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, stackEntry{block: attach(block), indent: width})
+		case sec.level == 0:
+			// Text is never a parent; it ends any open list.
+			for len(stack) > 0 && stack[len(stack)-1].level == 0 {
+				stack = stack[:len(stack)-1]
+			}
+			attach(block)
+		default:
+			// Pop stack until we find a parent with lower heading level.
+			for len(stack) > 0 && (stack[len(stack)-1].level == 0 || stack[len(stack)-1].level >= sec.level) {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, stackEntry{block: attach(block), level: sec.level})
 		}
+	}
 
-		if len(stack) == 0 {
-			// Top-level heading block.
-			roots = append(roots, block)
-			stack = append(stack, stackEntry{block: &roots[len(roots)-1], level: sec.level})
-		} else {
-			// Child of the block at top of stack.
-			parent := stack[len(stack)-1].block
-			parent.Children = append(parent.Children, block)
-			child := &parent.Children[len(parent.Children)-1]
-			stack = append(stack, stackEntry{block: child, level: sec.level})
+	fillSpanEnds(roots, spans)
+	return roots, spans
+}
+
+// fillSpanEnds sets each span's end to cover the block's descendants and
+// returns the largest end among blocks.
+func fillSpanEnds(blocks []types.BlockEntity, spans map[string]blockSpan) int {
+	maxEnd := 0
+	for _, b := range blocks {
+		span := spans[b.UUID]
+		span.end = max(span.contentEnd, fillSpanEnds(b.Children, spans))
+		spans[b.UUID] = span
+		maxEnd = max(maxEnd, span.end)
+	}
+	return maxEnd
+}
+
+// listItemPattern matches a bullet ("-", "*", "+") or ordered ("1.", "1)")
+// list marker with its indentation and the whitespace after it.
+var listItemPattern = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])([ \t]+|$)`)
+
+// listItem reports whether line starts a list item, returning its
+// indentation, bullet (marker plus trailing whitespace) and item text.
+func listItem(line string) (indent, bullet, text string, ok bool) {
+	m := listItemPattern.FindStringSubmatch(line)
+	if m == nil || isThematicBreak(line) {
+		return "", "", "", false
+	}
+	return m[1], m[2] + m[3], line[len(m[0]):], true
+}
+
+// isThematicBreak reports whether line is a horizontal rule such as
+// "---" or "* * *", which takes precedence over a list item.
+func isThematicBreak(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return false
+	}
+	ch := trimmed[0]
+	if ch != '-' && ch != '*' && ch != '_' {
+		return false
+	}
+	count := 0
+	for i := 0; i < len(trimmed); i++ {
+		switch trimmed[i] {
+		case ch:
+			count++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
+
+// fenceMarker returns the opening ``` or ~~~ run if line starts a code fence.
+func fenceMarker(line string) string {
+	trimmed := strings.TrimSpace(line)
+	for _, f := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, f) {
+			return f
 		}
 	}
+	return ""
+}
+
+// indentWidth returns the visual width of line's leading whitespace,
+// counting tabs to the next multiple of 4.
+func indentWidth(line string) int {
+	width := 0
+	for _, ch := range line {
+		switch ch {
+		case ' ':
+			width++
+		case '\t':
+			width += 4 - width%4
+		default:
+			return width
+		}
+	}
+	return width
+}
 
-	return roots
+// dedent removes up to width columns of leading whitespace from line.
+func dedent(line string, width int) string {
+	col := 0
+	for i, ch := range line {
+		if col >= width {
+			return line[i:]
+		}
+		switch ch {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - col%4
+		default:
+			return line[i:]
+		}
+	}
+	return ""
 }
 
 // headingLevel returns the heading level (1-6) for a markdown heading line,
@@ -139,43 +329,71 @@ func deterministicUUID(filepath string, lineNumber int) string {
 }
 
 // uuidCommentPattern matches HTML comments containing UUIDs: <!-- id: UUID -->
-var uuidCommentPattern = regexp.MustCompile(`<!--\s*id:\s*([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})\s*-->`)
+// Leading spaces are included so stripping a trailing comment leaves no residue.
+var uuidCommentPattern = regexp.MustCompile(`[ \t]*<!--\s*id:\s*([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})\s*-->`)
 
-// extractUUID attempts to extract a UUID from an HTML comment in the content.
-// Returns the UUID and the content with the comment stripped.
-// If no UUID comment is found, returns empty string and original content.
+// idPropertyPattern matches a Logseq block id property on its own line: id:: UUID
+var idPropertyPattern = regexp.MustCompile(`(?m)^[ \t]*id::[ \t]*([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})[ \t]*(?:\n|$)`)
+
+// extractUUID attempts to extract a UUID from an HTML comment or a Logseq
+// id:: property in the content.
+// Returns the UUID and the content with the comment or property stripped.
+// If no UUID is found, returns empty string and original content.
 func extractUUID(content string) (string, string) {
-	matches := uuidCommentPattern.FindStringSubmatch(content)
-	if len(matches) >= 2 {
-		uuid := matches[1]
-		// Remove the HTML comment from content
-		cleanContent := uuidCommentPattern.ReplaceAllString(content, "")
-		cleanContent = strings.TrimSpace(cleanContent)
-		return uuid, cleanContent
+	for _, pattern := range []*regexp.Regexp{uuidCommentPattern, idPropertyPattern} {
+		matches := pattern.FindStringSubmatch(content)
+		if len(matches) >= 2 {
+			uuid := matches[1]
+			// Remove the comment or property from content
+			cleanContent := pattern.ReplaceAllString(content, "")
+			cleanContent = strings.TrimSpace(cleanContent)
+			return uuid, cleanContent
+		}
 	}
 	return "", content
 }
 
+// embeddedUUIDs returns every UUID pinned in body by a comment or id:: property.
+func embeddedUUIDs(body string) map[string]bool {
+	pinned := make(map[string]bool)
+	for _, pattern := range []*regexp.Regexp{uuidCommentPattern, idPropertyPattern} {
+		for _, m := range pattern.FindAllStringSubmatch(body, -1) {
+			pinned[m[1]] = true
+		}
+	}
+	return pinned
+}
+
 // embedUUID adds a UUID HTML comment to the content.
-// For headings, it adds at the end of the heading line.
+// For headings and list items, it adds at the end of the first line.
 // For other content, it adds as a standalone line at the beginning.
 func embedUUID(content, blockUUID string) string {
-	comment := fmt.Sprintf("<!-- id: %s -->", blockUUID)
-	
-	// If content starts with a heading, add comment at end of heading line
+	comment := uuidComment(blockUUID)
+
 	lines := strings.Split(content, "\n")
 	if len(lines) > 0 && headingLevel(lines[0]) > 0 {
 		lines[0] = strings.TrimSpace(lines[0]) + " " + comment
 		return strings.Join(lines, "\n")
 	}
-	
+	if _, _, _, ok := listItem(lines[0]); ok {
+		lines[0] = strings.TrimRight(lines[0], " \t") + " " + comment
+		return strings.Join(lines, "\n")
+	}
+
 	// Otherwise add as standalone line at beginning
 	return comment + "\n" + content
 }
 
+// uuidComment renders the HTML comment that pins a block's UUID in the file.
+func uuidComment(blockUUID string) string {
+	return fmt.Sprintf("<!-- id: %s -->", blockUUID)
+}
+
 // getOrCreateUUID extracts UUID from content, or generates a new one.
 // Returns the UUID and cleaned content (with UUID comment removed).
-func getOrCreateUUID(filepath string, lineNumber int, content string) (string, string) {
+// Generated UUIDs skip those in pinned (UUIDs embedded elsewhere in the file):
+// a block pinned after moving keeps the UUID derived from its old line.
+func getOrCreateUUID(filepath string, lineNumber int, content string, pinned map[string]bool) (string, string) {
 	// Try to extract existing UUID
 	blockUUID, cleanContent := extractUUID(content)
 	if blockUUID != "" {
@@ -184,5 +402,9 @@ func getOrCreateUUID(filepath string, lineNumber int, content string) (string, s
 	
 	// No embedded UUID found, use deterministic UUID as fallback
 	// This provides backward compatibility
-	return deterministicUUID(filepath, lineNumber), cleanContent
+	blockUUID = deterministicUUID(filepath, lineNumber)
+	for n := 1; pinned[blockUUID]; n++ {
+		blockUUID = deterministicUUID(fmt.Sprintf("%s#%d", filepath, n), lineNumber)
+	}
+	return blockUUID, cleanContent
 }
@@ -234,6 +234,150 @@ func TestParseMarkdownBlocks(t *testing.T) {
 	})
 }
 
+func TestParseMarkdownBlocksLists(t *testing.T) {
+	t.Run("nested bullets", func(t *testing.T) {
+		body := "- Parent\n  - Child 1\n    - Grandchild\n  - Child 2\n- Sibling"
+		blocks := parseMarkdownBlocks("test.md", body)
+		if len(blocks) != 2 {
+			t.Fatalf("expected 2 roots, got %d", len(blocks))
+		}
+		if blocks[0].Content != "Parent" || blocks[1].Content != "Sibling" {
+			t.Errorf("roots = %q, %q", blocks[0].Content, blocks[1].Content)
+		}
+		if len(blocks[0].Children) != 2 {
+			t.Fatalf("parent should have 2 children, got %d", len(blocks[0].Children))
+		}
+		child := blocks[0].Children[0]
+		if child.Content != "Child 1" || len(child.Children) != 1 || child.Children[0].Content != "Grandchild" {
+			t.Errorf("child 1 = %+v", child)
+		}
+	})
+
+	t.Run("tabs, stars and numbers", func(t *testing.T) {
+		body := "1. First\n\t* Nested\n2) Second"
+		blocks := parseMarkdownBlocks("test.md", body)
+		if len(blocks) != 2 {
+			t.Fatalf("expected 2 roots, got %d", len(blocks))
+		}
+		if len(blocks[0].Children) != 1 || blocks[0].Children[0].Content != "Nested" {
+			t.Errorf("first item children = %+v", blocks[0].Children)
+		}
+		if blocks[1].Content != "Second" {
+			t.Errorf("second = %q", blocks[1].Content)
+		}
+	})
+
+	t.Run("continuation lines dedented", func(t *testing.T) {
+		body := "- Item\n  tags:: a, b\n  - Child\n    more child"
+		blocks := parseMarkdownBlocks("test.md", body)
+		if len(blocks) != 1 {
+			t.Fatalf("expected 1 root, got %d", len(blocks))
+		}
+		if blocks[0].Content != "Item\ntags:: a, b" {
+			t.Errorf("item = %q", blocks[0].Content)
+		}
+		if blocks[0].Children[0].Content != "Child\nmore child" {
+			t.Errorf("child = %q", blocks[0].Children[0].Content)
+		}
+	})
+
+	t.Run("lists nest under headings", func(t *testing.T) {
+		body := "# Title\nIntro\n\n- A\n- B\n## Sub\n- C"
+		blocks := parseMarkdownBlocks("test.md", body)
+		if len(blocks) != 1 {
+			t.Fatalf("expected 1 root, got %d", len(blocks))
+		}
+		h1 := blocks[0]
+		if h1.Content != "# Title\nIntro" {
+			t.Errorf("heading = %q", h1.Content)
+		}
+		if len(h1.Children) != 3 {
+			t.Fatalf("heading should have 3 children (A, B, Sub), got %d", len(h1.Children))
+		}
+		sub := h1.Children[2]
+		if sub.Content != "## Sub" || len(sub.Children) != 1 || sub.Children[0].Content != "C" {
+			t.Errorf("sub = %+v", sub)
+		}
+	})
+
+	t.Run("paragraph after list", func(t *testing.T) {
+		body := "- A\nlazy\n\nAfter the list"
+		blocks := parseMarkdownBlocks("test.md", body)
+		if len(blocks) != 2 {
+			t.Fatalf("expected 2 roots, got %d", len(blocks))
+		}
+		if blocks[0].Content != "A\nlazy" {
+			t.Errorf("item = %q", blocks[0].Content)
+		}
+		if blocks[1].Content != "After the list" {
+			t.Errorf("paragraph = %q", blocks[1].Content)
+		}
+	})
+
+	t.Run("code fences and rules are not items", func(t *testing.T) {
+		body := "- Code:\n  ```\n  - not an item\n  # not a heading\n  ```\n\n---\n"
+		blocks := parseMarkdownBlocks("test.md", body)
+		if len(blocks) != 2 {
+			t.Fatalf("expected item and rule, got %d blocks", len(blocks))
+		}
+		want := "Code:\n```\n- not an item\n# not a heading\n```"
+		if blocks[0].Content != want || len(blocks[0].Children) != 0 {
+			t.Errorf("item = %q with %d children", blocks[0].Content, len(blocks[0].Children))
+		}
+	})
+
+	t.Run("embedded UUID on item line", func(t *testing.T) {
+		id := "12345678-1234-1234-1234-123456789abc"
+		blocks := parseMarkdownBlocks("test.md", "- Item <!-- id: "+id+" -->\n  more")
+		if blocks[0].UUID != id || blocks[0].Content != "Item\nmore" {
+			t.Errorf("block = %q %q", blocks[0].UUID, blocks[0].Content)
+		}
+	})
+}
+
+func TestParseMarkdownOutlineSpans(t *testing.T) {
+	body := "# H\n\n- A\n  - B\n    text\n\n- C"
+	blocks, spans := parseMarkdownOutline("test.md", body)
+
+	h := spans[blocks[0].UUID]
+	if h.start != 0 || h.contentEnd != 1 || h.end != 7 || h.isListItem() {
+		t.Errorf("heading span = %+v", h)
+	}
+	a := spans[blocks[0].Children[0].UUID]
+	if a.start != 2 || a.contentEnd != 3 || a.end != 5 || a.bullet != "- " {
+		t.Errorf("A span = %+v", a)
+	}
+	b := spans[blocks[0].Children[0].Children[0].UUID]
+	if b.start != 3 || b.end != 5 || b.indent != "  " {
+		t.Errorf("B span = %+v", b)
+	}
+}
+
+func TestListItem(t *testing.T) {
+	tests := []struct {
+		line, indent, bullet, text string
+		ok                         bool
+	}{
+		{"- item", "", "- ", "item", true},
+		{"  * item", "  ", "* ", "item", true},
+		{"\t+ item", "\t", "+ ", "item", true},
+		{"12. item", "", "12. ", "item", true},
+		{"3) item", "", "3) ", "item", true},
+		{"-", "", "-", "", true},
+		{"-item", "", "", "", false},
+		{"**bold**", "", "", "", false},
+		{"---", "", "", "", false},
+		{"* * *", "", "", "", false},
+		{"1.5 million", "", "", "", false},
+	}
+	for _, tt := range tests {
+		indent, bullet, text, ok := listItem(tt.line)
+		if ok != tt.ok || indent != tt.indent || bullet != tt.bullet || text != tt.text {
+			t.Errorf("listItem(%q) = %q, %q, %q, %v", tt.line, indent, bullet, text, ok)
+		}
+	}
+}
+
 func collectUUIDs(blocks []types.BlockEntity) []string {
 	var uuids []string
 	for _, b := range blocks {
@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// outlineFile is a page file split into its frontmatter and body lines, with
+// every block located by UUID. Writes that touch list items edit these lines
+// in place so indentation, bullets and nested children survive the round trip.
+type outlineFile struct {
+	absPath string
+	content string               // file content as read from disk
+	prefix  string               // frontmatter exactly as on disk
+	lines   []string             // body lines
+	spans   map[string]blockSpan // uuid → location in lines
+	tabs    bool                 // indent new children with tabs, as Logseq does
+}
+
+// readOutline reads a page's file and locates its blocks. The file is parsed
+// fresh, so a block that moved since indexing is reported as not found rather
+// than edited at a stale position. Caller must hold c.mu.
+func (c *Client) readOutline(page *cachedPage) (*outlineFile, error) {
+	absPath, err := c.safePath(page.filePath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	content := string(data)
+	_, body := parseFrontmatter(content)
+	_, spans := parseMarkdownOutline(page.filePath, body)
+	return &outlineFile{
+		absPath: absPath,
+		content: content,
+		prefix:  content[:len(content)-len(body)],
+		lines:   strings.Split(body, "\n"),
+		spans:   spans,
+		tabs:    c.logseq,
+	}, nil
+}
+
+// writeOutline writes o back to disk and re-indexes page. Caller must hold c.mu.
+func (c *Client) writeOutline(page *cachedPage, o *outlineFile) error {
+	content := o.String()
+	if err := c.writeFile(o.absPath, content); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	info, _ := os.Stat(o.absPath)
+	c.indexFileCore(page.filePath, content, info)
+	return nil
+}
+
+// String renders the file with its current body lines.
+func (o *outlineFile) String() string {
+	return o.prefix + strings.Join(o.lines, "\n")
+}
+
+// span returns the location of the block with the given UUID.
+func (o *outlineFile) span(uuid string) (blockSpan, error) {
+	span, ok := o.spans[uuid]
+	if !ok {
+		return blockSpan{}, fmt.Errorf("block %s not found in file (may have been modified externally)", uuid)
+	}
+	return span, nil
+}
+
+// splice replaces lines [start, end) with repl. Spans are not updated.
+func (o *outlineFile) splice(start, end int, repl []string) {
+	o.lines = slices.Concat(o.lines[:start], repl, o.lines[end:])
+}
+
+// firstBlock returns the span of the block that starts earliest in the body.
+func (o *outlineFile) firstBlock() (blockSpan, bool) {
+	var first blockSpan
+	found := false
+	for _, s := range o.spans {
+		if !found || s.start < first.start {
+			first, found = s, true
+		}
+	}
+	return first, found
+}
+
+// lastBlock returns the span of the block that starts latest in the body.
+func (o *outlineFile) lastBlock() (blockSpan, bool) {
+	var last blockSpan
+	found := false
+	for _, s := range o.spans {
+		if !found || s.start > last.start {
+			last, found = s, true
+		}
+	}
+	return last, found
+}
+
+// childItem returns the indentation and bullet for a new child of the list
+// item at span, following its existing children when it has any.
+func (o *outlineFile) childItem(span blockSpan) (indent, bullet string) {
+	for _, line := range o.lines[span.contentEnd:span.end] {
+		if indent, bullet, _, ok := listItem(line); ok {
+			return indent, bullet
+		}
+	}
+	if o.tabs || strings.Contains(span.indent, "\t") {
+		return span.indent + "\t", span.bullet
+	}
+	return span.indent + strings.Repeat(" ", len(span.bullet)), span.bullet
+}
+
+// renderListItem renders content as a list item, aligning continuation lines
+// under the item text.
+func renderListItem(indent, bullet, content string) string {
+	if !strings.HasSuffix(bullet, " ") && !strings.HasSuffix(bullet, "\t") {
+		bullet += " "
+	}
+	pad := indent + strings.Repeat(" ", len(bullet))
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = indent + bullet + line
+		case strings.TrimSpace(line) != "":
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// continueOutline renders plain content as a list item styled after span when
+// span is a list item, so adding to an outline page yields a bullet rather
+// than a paragraph swallowed by the list. Headings and content that is already
+// a list item pass through unchanged.
+func continueOutline(span blockSpan, ok bool, content string) string {
+	if !ok || !span.isListItem() {
+		return content
+	}
+	first := strings.SplitN(content, "\n", 2)[0]
+	if _, _, _, isItem := listItem(first); isItem || headingLevel(first) > 0 {
+		return content
+	}
+	return renderListItem("", span.bullet, content)
+}
+
+// reindent moves lines from an indentation of width columns to indent.
+func reindent(lines []string, width int, indent string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			out[i] = indent + dedent(line, width)
+		}
+	}
+	return out
+}
@@ -0,0 +1,200 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/skridlevsky/graphthulhu/parser"
+)
+
+// ErrAmbiguousPage is returned when a shortened link target such as [[Spec]]
+// matches more than one page path. Obsidian would pick one; we refuse to guess.
+var ErrAmbiguousPage = errors.New("ambiguous page name")
+
+// basenameKey returns the lowercase last path segment of a page name.
+func basenameKey(lowerName string) string {
+	return path.Base(lowerName)
+}
+
+// indexBasenameLocked records page under its basename, replacing any previous
+// entry for the same page. Caller must hold c.mu for write.
+func (c *Client) indexBasenameLocked(page *cachedPage) {
+	c.unindexBasenameLocked(page.lowerName)
+	key := basenameKey(page.lowerName)
+	c.basenames[key] = append(c.basenames[key], page)
+}
+
+// unindexBasenameLocked drops a page from the basename index. Caller must hold c.mu for write.
+func (c *Client) unindexBasenameLocked(lowerName string) {
+	key := basenameKey(lowerName)
+	list := c.basenames[key]
+	for i, p := range list {
+		if p.lowerName == lowerName {
+			list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(c.basenames, key)
+	} else {
+		c.basenames[key] = list
+	}
+}
+
+// resolvePageLocked maps a page name or link target to a page the way
+// Obsidian does: an exact path or alias wins, otherwise the target is matched
+// as a path suffix ("Spec", "alpha/Spec") against every page. Returns nil
+// when nothing matches and ErrAmbiguousPage when several pages do.
+// Caller must hold c.mu.
+func (c *Client) resolvePageLocked(name string) (*cachedPage, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if page, ok := c.pages[key]; ok {
+		return page, nil
+	}
+	key = strings.TrimPrefix(strings.TrimSuffix(key, ".md"), "/")
+	if key == "" {
+		return nil, nil
+	}
+	if page, ok := c.pages[key]; ok {
+		return page, nil
+	}
+	// Logseq namespaces are part of the page name, so links never resolve
+	// by basename.
+	if c.logseq {
+		return nil, nil
+	}
+
+	var matches []*cachedPage
+	for _, page := range c.basenames[basenameKey(key)] {
+		if page.lowerName == key || strings.HasSuffix(page.lowerName, "/"+key) {
+			matches = append(matches, page)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.entity.Name
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("%w: %q matches %s", ErrAmbiguousPage, name, strings.Join(names, ", "))
+}
+
+// linkKeyLocked returns the backlink key for a link target: the canonical
+// lowercase page name when the target resolves, otherwise the target itself.
+// Ambiguous targets stay unresolved rather than being credited to one page.
+// Caller must hold c.mu.
+func (c *Client) linkKeyLocked(target string) string {
+	if page, err := c.resolvePageLocked(target); err == nil && page != nil {
+		return page.lowerName
+	}
+	return toLower(target)
+}
+
+// shortestLinkNameLocked returns the form a shortened link to newName should
+// take once page has been renamed: the bare basename when no other page or
+// alias would claim it, the full path otherwise. Caller must hold c.mu.
+func (c *Client) shortestLinkNameLocked(page *cachedPage, newName string) string {
+	base := path.Base(newName)
+	lowerBase := strings.ToLower(base)
+	if other, ok := c.pages[lowerBase]; ok && other != page {
+		return newName
+	}
+	for _, other := range c.basenames[lowerBase] {
+		if other != page {
+			return newName
+		}
+	}
+	return base
+}
+
+// rewriteLinksLocked rewrites every wikilink and relative markdown link in
+// content that points at page so it points at newName instead. sourceName is
+// the page the content belongs to, used to resolve relative markdown paths.
+// Wikilinks written as the full path get the new full path, shortened ones
+// get shortName; links through an alias are left alone because the alias
+// moves with the page. Caller must hold c.mu.
+func (c *Client) rewriteLinksLocked(content, sourceName string, page *cachedPage, newName, shortName string) string {
+	content = parser.ReplaceWikilinks(content, func(m string, embed bool, inner string) string {
+		rawTarget, rest := parser.SplitWikilink(inner)
+		target := strings.TrimSpace(rawTarget)
+		lower := strings.ToLower(target)
+		suffix := ""
+		if strings.HasSuffix(lower, ".md") {
+			lower = strings.TrimSuffix(lower, ".md")
+			suffix = target[len(target)-3:]
+		}
+
+		var replacement string
+		switch {
+		case lower == page.lowerName:
+			replacement = newName
+		case c.pages[lower] != nil:
+			return m // alias or another page
+		default:
+			resolved, err := c.resolvePageLocked(target)
+			if err != nil || resolved != page {
+				return m
+			}
+			replacement = shortName
+		}
+		if embed {
+			return "![[" + replacement + suffix + rest + "]]"
+		}
+		return "[[" + replacement + suffix + rest + "]]"
+	})
+
+	var b strings.Builder
+	last := 0
+	for _, span := range parser.MarkdownLinkDestinations(content) {
+		dest := content[span[0]:span[1]]
+		target, _, ok := parser.ResolveMarkdownLink(dest, sourceName)
+		if !ok || strings.ToLower(target) != page.lowerName {
+			continue
+		}
+		b.WriteString(content[last:span[0]])
+		b.WriteString(markdownDestination(dest, sourceName, newName))
+		last = span[1]
+	}
+	if last == 0 {
+		return content
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// markdownDestination builds the destination of a markdown link to newName,
+// keeping the style of the original: root-relative or relative to the source
+// page, angle-bracketed or percent-encoded, and any #anchor.
+func markdownDestination(orig, sourceName, newName string) string {
+	angle := strings.HasPrefix(orig, "<")
+	raw := strings.TrimSuffix(strings.TrimPrefix(orig, "<"), ">")
+	anchor := ""
+	if i := strings.Index(raw, "#"); i >= 0 {
+		anchor = raw[i:]
+	}
+
+	var p string
+	if strings.HasPrefix(raw, "/") {
+		p = "/" + newName + ".md"
+	} else {
+		rel, err := filepath.Rel(filepath.FromSlash(path.Dir(sourceName)), filepath.FromSlash(newName+".md"))
+		if err != nil {
+			rel = newName + ".md"
+		}
+		p = filepath.ToSlash(rel)
+	}
+
+	if angle {
+		return "<" + p + anchor + ">"
+	}
+	return strings.ReplaceAll(p, " ", "%20") + anchor
+}
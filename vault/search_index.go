@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"math"
 	"sort"
 	"strings"
 	"sync"
@@ -9,28 +10,57 @@ import (
 	"github.com/skridlevsky/graphthulhu/types"
 )
 
-// SearchIndex is a simple inverted index for full-text search.
-// Maps lowercase terms to the blocks containing them.
+// BM25 parameters. Each block is a document with two fields, its own text
+// and its page's title; title matches are weighted titleBoost times a body
+// match (BM25F).
+const (
+	bm25K1     = 1.2
+	bm25B      = 0.75
+	titleBoost = 3.0
+)
+
+// SearchIndex is an inverted index over blocks with BM25 ranking.
+// See parseSearchQuery for the query syntax.
 type SearchIndex struct {
-	mu      sync.RWMutex
-	// term → list of block references
-	index   map[string][]blockRef
-	// page lowercase → set of terms (for efficient removal on reindex)
-	pageTerms map[string]map[string]bool
+	mu sync.RWMutex
+	// term → document → occurrences in the block's text
+	postings map[string]map[int]int
+	// term → lowercase names of pages whose title contains it
+	titles map[string]map[string]bool
+	docs   map[int]*searchDoc
+	// page lowercase → title field and documents (for removal on reindex)
+	pages  map[string]*searchPage
+	nextID int
+
+	// Field lengths summed over all documents, for BM25 length normalization.
+	bodyLen  int
+	titleLen int
+}
+
+// searchDoc is an indexed block.
+type searchDoc struct {
+	page    *searchPage
+	uuid    string
+	content string
+	terms   []string // distinct terms, for removal
+	length  int      // number of terms in the block's text
 }
 
-// blockRef identifies a block within a page.
-type blockRef struct {
-	pageName string // original case page name
-	uuid     string
-	content  string
+// searchPage is the title field shared by a page's blocks.
+type searchPage struct {
+	name   string         // original case page name
+	terms  map[string]int // title term → occurrences
+	length int
+	docs   []int
 }
 
 // NewSearchIndex creates an empty search index.
 func NewSearchIndex() *SearchIndex {
 	return &SearchIndex{
-		index:     make(map[string][]blockRef),
-		pageTerms: make(map[string]map[string]bool),
+		postings: make(map[string]map[int]int),
+		titles:   make(map[string]map[string]bool),
+		docs:     make(map[int]*searchDoc),
+		pages:    make(map[string]*searchPage),
 	}
 }
 
@@ -39,8 +69,11 @@ func (si *SearchIndex) BuildFrom(pages map[string]*cachedPage) {
 	si.mu.Lock()
 	defer si.mu.Unlock()
 
-	si.index = make(map[string][]blockRef)
-	si.pageTerms = make(map[string]map[string]bool)
+	si.postings = make(map[string]map[int]int)
+	si.titles = make(map[string]map[string]bool)
+	si.docs = make(map[int]*searchDoc)
+	si.pages = make(map[string]*searchPage)
+	si.bodyLen, si.titleLen = 0, 0
 
 	seen := make(map[string]bool)
 	for _, page := range pages {
@@ -68,155 +101,369 @@ func (si *SearchIndex) RemovePage(lowerName string) {
 	si.removePageLocked(lowerName)
 }
 
-// Search finds blocks matching all terms in the query (AND semantics).
-// Returns results sorted by relevance (number of term hits).
+// Search returns the blocks matching query, best first. limit <= 0 means
+// the default of 20.
 func (si *SearchIndex) Search(query string, limit int) []SearchResult {
+	if limit <= 0 {
+		limit = 20
+	}
+	return si.SearchPages(query, limit, nil)
+}
+
+// SearchPages is Search restricted to pages for which keep returns true
+// (keep receives the lowercase page name; nil keeps every page). limit <= 0
+// returns every match.
+func (si *SearchIndex) SearchPages(query string, limit int, keep func(lowerName string) bool) []SearchResult {
 	si.mu.RLock()
 	defer si.mu.RUnlock()
 
-	terms := tokenize(query)
-	if len(terms) == 0 {
+	q := parseSearchQuery(query)
+	if len(q.groups) == 0 {
 		return nil
 	}
-
-	if limit <= 0 {
-		limit = 20
+	si.expandPrefixesLocked(&q)
+
+	// A block must match one alternative of every group.
+	var candidates map[int]bool
+	for _, group := range q.groups {
+		matched := make(map[int]bool)
+		for _, alt := range group {
+			for id := range si.matchLocked(alt) {
+				if candidates == nil || candidates[id] {
+					matched[id] = true
+				}
+			}
+		}
+		candidates = matched
+		if len(candidates) == 0 {
+			return nil
+		}
 	}
-
-	// Find blocks that contain ALL terms.
-	// Start with the rarest term for efficiency.
-	var rarest string
-	rarestCount := int(^uint(0) >> 1) // max int
-	for _, t := range terms {
-		if count := len(si.index[t]); count < rarestCount {
-			rarestCount = count
-			rarest = t
+	for _, alt := range q.exclude {
+		for id := range si.matchLocked(alt) {
+			delete(candidates, id)
 		}
 	}
 
-	// Candidates from rarest term.
-	candidates := si.index[rarest]
-	if len(candidates) == 0 {
-		return nil
+	type hit struct {
+		id     int
+		result SearchResult
 	}
-
-	// Build a set of UUIDs for each other term.
-	otherTermSets := make([]map[string]bool, 0, len(terms)-1)
-	for _, t := range terms {
-		if t == rarest {
+	var hits []hit
+	idf := make(map[string]float64)
+	for id := range candidates {
+		doc := si.docs[id]
+		if keep != nil && !keep(strings.ToLower(doc.page.name)) {
 			continue
 		}
-		set := make(map[string]bool)
-		for _, ref := range si.index[t] {
-			set[ref.uuid] = true
+		// Title matches alone would return every block of a page.
+		if !si.bodyMatchLocked(q, id) {
+			continue
 		}
-		otherTermSets = append(otherTermSets, set)
+		hits = append(hits, hit{id, SearchResult{
+			PageName: doc.page.name,
+			UUID:     doc.uuid,
+			Content:  doc.content,
+			Score:    si.scoreLocked(q, id, idf),
+		}})
 	}
 
-	// Filter candidates that appear in ALL other term sets.
-	var results []SearchResult
-	for _, ref := range candidates {
-		inAll := true
-		for _, set := range otherTermSets {
-			if !set[ref.uuid] {
-				inAll = false
-				break
-			}
-		}
-		if !inAll {
-			continue
+	// Best score first; ties in page name and block order for stable output.
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.result.Score != b.result.Score {
+			return a.result.Score > b.result.Score
 		}
-
-		results = append(results, SearchResult{
-			PageName: ref.pageName,
-			UUID:     ref.uuid,
-			Content:  ref.content,
-		})
-
-		if len(results) >= limit {
-			break
+		if a.result.PageName != b.result.PageName {
+			return a.result.PageName < b.result.PageName
 		}
+		return a.id < b.id
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	results := make([]SearchResult, len(hits))
+	for i, h := range hits {
+		results[i] = h.result
 	}
-
 	return results
 }
 
 // SearchResult is a block that matched a search query.
 type SearchResult struct {
-	PageName string `json:"page"`
-	UUID     string `json:"uuid"`
-	Content  string `json:"content"`
+	PageName string  `json:"page"`
+	UUID     string  `json:"uuid"`
+	Content  string  `json:"content"`
+	Score    float64 `json:"score"`
 }
 
 // --- Internal ---
 
 func (si *SearchIndex) indexPageLocked(page *cachedPage) {
-	terms := make(map[string]bool)
-	si.indexBlocksLocked(page.blocks, page.entity.OriginalName, terms)
-	si.pageTerms[page.lowerName] = terms
+	sp := &searchPage{
+		name:  page.entity.OriginalName,
+		terms: make(map[string]int),
+	}
+	for _, term := range tokenize(sp.name) {
+		sp.terms[term]++
+		sp.length++
+	}
+	for term := range sp.terms {
+		if si.titles[term] == nil {
+			si.titles[term] = make(map[string]bool)
+		}
+		si.titles[term][page.lowerName] = true
+	}
+	si.pages[page.lowerName] = sp
+	si.indexBlocksLocked(page, sp, page.blocks)
 }
 
-func (si *SearchIndex) indexBlocksLocked(blocks []types.BlockEntity, pageName string, terms map[string]bool) {
-	for _, b := range blocks {
-		blockTerms := tokenize(b.Content)
+func (si *SearchIndex) indexBlocksLocked(page *cachedPage, sp *searchPage, blocks []types.BlockEntity) {
+	for i := range blocks {
+		b := &blocks[i]
+		terms := page.blockTerms(b)
+		freq := make(map[string]int, len(terms))
+		for _, term := range terms {
+			freq[term]++
+		}
 
-		// Also index parsed links and tags as terms.
-		parsed := parser.Parse(b.Content)
-		for _, link := range parsed.Links {
-			for _, t := range tokenize(link) {
-				blockTerms = append(blockTerms, t)
+		id := si.nextID
+		si.nextID++
+		doc := &searchDoc{
+			page:    sp,
+			uuid:    b.UUID,
+			content: b.Content,
+			length:  len(terms),
+		}
+		for term, n := range freq {
+			if si.postings[term] == nil {
+				si.postings[term] = make(map[int]int)
 			}
+			si.postings[term][id] = n
+			doc.terms = append(doc.terms, term)
+		}
+		si.docs[id] = doc
+		sp.docs = append(sp.docs, id)
+		si.bodyLen += doc.length
+		si.titleLen += sp.length
+
+		if len(b.Children) > 0 {
+			si.indexBlocksLocked(page, sp, b.Children)
 		}
-		for _, tag := range parsed.Tags {
-			for _, t := range tokenize(tag) {
-				blockTerms = append(blockTerms, t)
+	}
+}
+
+func (si *SearchIndex) removePageLocked(lowerName string) {
+	sp, ok := si.pages[lowerName]
+	if !ok {
+		return
+	}
+
+	for _, id := range sp.docs {
+		doc := si.docs[id]
+		for _, term := range doc.terms {
+			delete(si.postings[term], id)
+			if len(si.postings[term]) == 0 {
+				delete(si.postings, term)
 			}
 		}
+		si.bodyLen -= doc.length
+		si.titleLen -= sp.length
+		delete(si.docs, id)
+	}
+	for term := range sp.terms {
+		delete(si.titles[term], lowerName)
+		if len(si.titles[term]) == 0 {
+			delete(si.titles, term)
+		}
+	}
 
-		ref := blockRef{
-			pageName: pageName,
-			uuid:     b.UUID,
-			content:  b.Content,
+	delete(si.pages, lowerName)
+}
+
+// termDocsLocked returns the documents containing term in either field.
+func (si *SearchIndex) termDocsLocked(term string) map[int]bool {
+	docs := make(map[int]bool, len(si.postings[term]))
+	for id := range si.postings[term] {
+		docs[id] = true
+	}
+	for page := range si.titles[term] {
+		for _, id := range si.pages[page].docs {
+			docs[id] = true
 		}
+	}
+	return docs
+}
 
-		seen := make(map[string]bool)
-		for _, term := range blockTerms {
-			if seen[term] {
+// expandPrefixesLocked replaces each prefix in q with the indexed terms
+// that start with it.
+func (si *SearchIndex) expandPrefixesLocked(q *searchQuery) {
+	expand := func(alts []queryAlt) {
+		for i := range alts {
+			if !alts[i].prefix {
 				continue
 			}
-			seen[term] = true
-			terms[term] = true
-			si.index[term] = append(si.index[term], ref)
+			prefix := alts[i].terms[0]
+			var terms []string
+			for term := range si.postings {
+				if strings.HasPrefix(term, prefix) {
+					terms = append(terms, term)
+				}
+			}
+			for term := range si.titles {
+				if _, inBody := si.postings[term]; !inBody && strings.HasPrefix(term, prefix) {
+					terms = append(terms, term)
+				}
+			}
+			alts[i].terms = terms
 		}
+	}
+	for _, group := range q.groups {
+		expand(group)
+	}
+	expand(q.exclude)
+}
 
-		if len(b.Children) > 0 {
-			si.indexBlocksLocked(b.Children, pageName, terms)
+// matchLocked returns the documents an alternative matches.
+func (si *SearchIndex) matchLocked(alt queryAlt) map[int]bool {
+	if alt.prefix {
+		docs := make(map[int]bool)
+		for _, term := range alt.terms {
+			for id := range si.termDocsLocked(term) {
+				docs[id] = true
+			}
+		}
+		return docs
+	}
+
+	docs := si.termDocsLocked(alt.terms[0])
+	for _, term := range alt.terms[1:] {
+		next := si.termDocsLocked(term)
+		for id := range docs {
+			if !next[id] {
+				delete(docs, id)
+			}
 		}
 	}
+	if len(alt.terms) > 1 {
+		for id := range docs {
+			doc := si.docs[id]
+			if !containsPhrase(tokenize(doc.content), alt.terms) && !containsPhrase(tokenize(doc.page.name), alt.terms) {
+				delete(docs, id)
+			}
+		}
+	}
+	return docs
 }
 
-func (si *SearchIndex) removePageLocked(lowerName string) {
-	terms, ok := si.pageTerms[lowerName]
+// bodyMatchLocked reports whether any term of the query's matching
+// alternatives occurs in the block's own text.
+func (si *SearchIndex) bodyMatchLocked(q searchQuery, id int) bool {
+	for _, group := range q.groups {
+		for _, alt := range group {
+			for _, term := range alt.terms {
+				if si.postings[term][id] > 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// scoreLocked sums the BM25F scores of the alternatives a document matches.
+// A prefix counts once, with its best-scoring expansion.
+func (si *SearchIndex) scoreLocked(q searchQuery, id int, idf map[string]float64) float64 {
+	var score float64
+	for _, group := range q.groups {
+		for _, alt := range group {
+			var best, sum float64
+			for _, term := range alt.terms {
+				s := si.termScoreLocked(term, id, idf)
+				sum += s
+				best = max(best, s)
+			}
+			if alt.prefix {
+				score += best
+			} else {
+				score += sum
+			}
+		}
+	}
+	return score
+}
+
+// termScoreLocked is term's BM25F score for one document. idf caches
+// inverse document frequencies for the duration of a query.
+func (si *SearchIndex) termScoreLocked(term string, id int, idf map[string]float64) float64 {
+	doc := si.docs[id]
+	bodyTF := float64(si.postings[term][id])
+	titleTF := float64(doc.page.terms[term])
+	if bodyTF == 0 && titleTF == 0 {
+		return 0
+	}
+
+	n := float64(len(si.docs))
+	tf := bodyTF/lengthNorm(doc.length, si.bodyLen, n) +
+		titleBoost*titleTF/lengthNorm(doc.page.length, si.titleLen, n)
+
+	w, ok := idf[term]
 	if !ok {
-		return
+		df := float64(len(si.termDocsLocked(term)))
+		w = math.Log(1 + (n-df+0.5)/(df+0.5))
+		idf[term] = w
 	}
+	return w * tf / (bm25K1 + tf)
+}
 
-	for term := range terms {
-		refs := si.index[term]
-		filtered := refs[:0]
-		for _, ref := range refs {
-			if strings.ToLower(ref.pageName) != lowerName {
-				filtered = append(filtered, ref)
+// lengthNorm is BM25's length normalization for a field of length l whose
+// lengths sum to total over n documents.
+func lengthNorm(l, total int, n float64) float64 {
+	if total == 0 {
+		return 1
+	}
+	return 1 - bm25B + bm25B*float64(l)/(float64(total)/n)
+}
+
+// containsPhrase reports whether phrase occurs as consecutive tokens.
+func containsPhrase(tokens, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		match := true
+		for j, term := range phrase {
+			if tokens[i+j] != term {
+				match = false
+				break
 			}
 		}
-		if len(filtered) == 0 {
-			delete(si.index, term)
-		} else {
-			si.index[term] = filtered
+		if match {
+			return true
 		}
 	}
+	return false
+}
 
-	delete(si.pageTerms, lowerName)
+// blockTerms returns the search terms of one of the page's blocks, in order
+// and with repeats.
+func (p *cachedPage) blockTerms(b *types.BlockEntity) []string {
+	if d, ok := p.derived[b.UUID]; ok {
+		return d.Terms
+	}
+	return searchTerms(b.Content)
+}
+
+// searchTerms tokenizes block content and adds the words of its parsed
+// links and tags, so [[Multi Word]] targets are found by each word.
+func searchTerms(content string) []string {
+	terms := tokenize(content)
+
+	parsed := parser.Parse(content)
+	for _, link := range parsed.Links {
+		terms = append(terms, tokenize(link)...)
+	}
+	for _, tag := range parsed.Tags {
+		terms = append(terms, tokenize(tag)...)
+	}
+	return terms
 }
 
 // tokenize splits text into lowercase terms for indexing.
@@ -240,7 +487,7 @@ func tokenize(text string) []string {
 		return !isWordChar(r)
 	})
 
-	// Filter very short terms and deduplicate.
+	// Filter very short terms.
 	var terms []string
 	for _, w := range words {
 		if len(w) >= 2 { // skip single chars
@@ -255,23 +502,3 @@ func tokenize(text string) []string {
 func isWordChar(r rune) bool {
 	return (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r > 127
 }
-
-// SortByRelevance sorts search results by term frequency.
-// Not currently used but available for future ranking improvements.
-func SortByRelevance(results []SearchResult, query string) {
-	terms := tokenize(query)
-	sort.Slice(results, func(i, j int) bool {
-		iScore := countTermHits(results[i].Content, terms)
-		jScore := countTermHits(results[j].Content, terms)
-		return iScore > jScore
-	})
-}
-
-func countTermHits(content string, terms []string) int {
-	lower := strings.ToLower(content)
-	count := 0
-	for _, t := range terms {
-		count += strings.Count(lower, t)
-	}
-	return count
-}
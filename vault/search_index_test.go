@@ -1,6 +1,9 @@
 package vault
 
 import (
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/skridlevsky/graphthulhu/types"
@@ -164,6 +167,116 @@ func TestSearchIndex_RemovePage(t *testing.T) {
 	}
 }
 
+// rankingIndex builds an index for ranking and query syntax tests.
+func rankingIndex() *SearchIndex {
+	page := func(name string, contents ...string) *cachedPage {
+		var blocks []types.BlockEntity
+		for i, c := range contents {
+			blocks = append(blocks, types.BlockEntity{UUID: name + "-" + string(rune('a'+i)), Content: c})
+		}
+		return &cachedPage{
+			entity:    types.PageEntity{Name: name, OriginalName: name},
+			lowerName: strings.ToLower(name),
+			blocks:    blocks,
+		}
+	}
+	pages := map[string]*cachedPage{}
+	for _, p := range []*cachedPage{
+		page("Notes",
+			"a long block that mentions kubernetes once among many other unrelated words here",
+			"kubernetes kubernetes cluster",
+			"deploy the release notes today",
+			"release day: deploy notes later",
+			"Old draft about kubernetes"),
+		page("Kubernetes", "cluster setup"),
+		page("Misc", "nothing to see", "kubelet logs", "deployment notes"),
+	} {
+		pages[p.lowerName] = p
+	}
+	si := NewSearchIndex()
+	si.BuildFrom(pages)
+	return si
+}
+
+func uuids(results []SearchResult) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.UUID
+	}
+	return out
+}
+
+func TestSearchIndex_Ranking(t *testing.T) {
+	si := rankingIndex()
+
+	results := si.Search("kubernetes", 20)
+	got := uuids(results)
+	// Repeated in a short block beats a single mention in a long one; the
+	// page title alone does not match blocks that don't mention the term.
+	want := []string{"Notes-b", "Notes-e", "Notes-a"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("ranking = %v, want %v", got, want)
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i].Score > results[i-1].Score || results[i].Score <= 0 {
+			t.Errorf("scores not descending and positive: %v", results)
+		}
+	}
+
+	// Title boost: the Kubernetes page's block matches "kubernetes" only
+	// through its title, yet outranks a block repeating it in the body.
+	got = uuids(si.Search("kubernetes cluster", 20))
+	if len(got) != 2 || got[0] != "Kubernetes-a" {
+		t.Errorf("title boost: got %v", got)
+	}
+
+	// limit keeps the best hits, not the first indexed ones.
+	if got := uuids(si.Search("kubernetes", 1)); len(got) != 1 || got[0] != "Notes-b" {
+		t.Errorf("limit 1 = %v", got)
+	}
+}
+
+func TestSearchIndex_QuerySyntax(t *testing.T) {
+	si := rankingIndex()
+	tests := []struct {
+		query string
+		want  []string // sorted
+	}{
+		{`"release notes"`, []string{"Notes-c"}},
+		{`release notes`, []string{"Notes-c", "Notes-d"}},
+		{`kube*`, []string{"Misc-b", "Notes-a", "Notes-b", "Notes-e"}},
+		{`kubernetes -draft`, []string{"Notes-a", "Notes-b"}},
+		{`kubernetes -"old draft"`, []string{"Notes-a", "Notes-b"}},
+		{`deploy OR deployment notes`, []string{"Misc-c", "Notes-c", "Notes-d"}},
+		{`kubelet OR cluster`, []string{"Kubernetes-a", "Misc-b", "Notes-b"}},
+		{`-kubernetes`, nil},
+		{`"no such phrase"`, nil},
+	}
+	for _, tt := range tests {
+		got := uuids(si.Search(tt.query, 20))
+		sort.Strings(got)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	// One-letter words are dropped; a trailing OR has nothing to join.
+	q := parseSearchQuery(`aa OR bb cc* -dd -"ee ff" x "gg hh" OR foo.bar OR`)
+	want := searchQuery{
+		groups: [][]queryAlt{
+			{{terms: []string{"aa"}}, {terms: []string{"bb"}}},
+			{{terms: []string{"cc"}, prefix: true}},
+			{{terms: []string{"gg", "hh"}}, {terms: []string{"foo", "bar"}}},
+		},
+		exclude: []queryAlt{{terms: []string{"dd"}}, {terms: []string{"ee", "ff"}}},
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("got %+v\nwant %+v", q, want)
+	}
+}
+
 func TestTokenize(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -0,0 +1,99 @@
+package vault
+
+import (
+	"strings"
+	"unicode"
+)
+
+// searchQuery is a parsed search query. Every group must match (AND), a
+// group matches when any of its alternatives does (OR), and blocks matching
+// an excluded alternative are dropped.
+type searchQuery struct {
+	groups  [][]queryAlt
+	exclude []queryAlt
+}
+
+// queryAlt is one word, phrase or prefix of a query. A phrase has several
+// terms that must appear consecutively. A prefix starts out with the prefix
+// as its only term and is expanded to the matching indexed terms before the
+// query runs.
+type queryAlt struct {
+	terms  []string
+	prefix bool
+}
+
+// parseSearchQuery parses the search syntax:
+//
+//	word         blocks containing word, in the block or its page title
+//	"two words"  the exact phrase
+//	pre*         any word starting with pre
+//	-word        drop blocks containing word (also -"a phrase" and -pre*)
+//	a OR b       either a or b; OR binds tighter than the implicit AND
+//
+// Words are tokenized like indexed text, so "foo.bar" is the phrase
+// "foo bar" and one-letter words are ignored.
+func parseSearchQuery(query string) searchQuery {
+	var q searchQuery
+	or := false
+	rs := []rune(query)
+	for i := 0; i < len(rs); {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+
+		negate := false
+		if rs[i] == '-' && i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) {
+			negate = true
+			i++
+		}
+
+		var word string
+		quoted := rs[i] == '"'
+		if quoted {
+			end := i + 1
+			for end < len(rs) && rs[end] != '"' {
+				end++
+			}
+			word = string(rs[i+1 : end])
+			i = min(end+1, len(rs))
+		} else {
+			end := i
+			for end < len(rs) && !unicode.IsSpace(rs[end]) {
+				end++
+			}
+			word = string(rs[i:end])
+			i = end
+		}
+
+		if !quoted && !negate && word == "OR" {
+			or = len(q.groups) > 0
+			continue
+		}
+
+		alt, ok := parseQueryAlt(word, quoted)
+		switch {
+		case !ok:
+		case negate:
+			q.exclude = append(q.exclude, alt)
+		case or:
+			last := len(q.groups) - 1
+			q.groups[last] = append(q.groups[last], alt)
+		default:
+			q.groups = append(q.groups, []queryAlt{alt})
+		}
+		or = false
+	}
+	return q
+}
+
+// parseQueryAlt turns one word or quoted phrase into an alternative.
+// ok is false when nothing indexable is left after tokenizing.
+func parseQueryAlt(word string, quoted bool) (alt queryAlt, ok bool) {
+	prefix := !quoted && strings.HasSuffix(word, "*")
+	terms := tokenize(strings.TrimRight(word, "*"))
+	if len(terms) == 0 {
+		return queryAlt{}, false
+	}
+	return queryAlt{terms: terms, prefix: prefix && len(terms) == 1}, true
+}
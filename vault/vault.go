@@ -3,10 +3,13 @@ package vault
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -14,13 +17,11 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/google/uuid"
 	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/datalog"
 	"github.com/skridlevsky/graphthulhu/parser"
 	"github.com/skridlevsky/graphthulhu/types"
 )
 
-// ErrNotSupported is returned for Logseq-specific operations (DataScript queries).
-var ErrNotSupported = fmt.Errorf("operation not supported by obsidian backend")
-
 // ErrPathEscape is returned when a resolved path escapes the vault boundary.
 var ErrPathEscape = fmt.Errorf("path escapes vault boundary")
 
@@ -47,26 +48,54 @@ func generateRandomUUID() string {
 	return uuid.New().String()
 }
 
+// insertedUUID returns the UUID for a block InsertBlock creates from content:
+// the one pinned in content, else opts["customUUID"] as in Logseq's API, else
+// a new one. The content is returned with any pin stripped.
+func insertedUUID(content string, opts map[string]any) (string, string) {
+	blockUUID, cleanContent := extractUUID(content)
+	if blockUUID == "" {
+		blockUUID, _ = opts["customUUID"].(string)
+	}
+	if blockUUID == "" {
+		blockUUID = generateRandomUUID()
+	}
+	return blockUUID, cleanContent
+}
+
 // cachedPage holds a parsed markdown file in memory.
 type cachedPage struct {
 	entity    types.PageEntity
 	lowerName string
 	filePath  string
 	blocks    []types.BlockEntity
+
+	// Size and modification time (UnixNano) of the file when it was parsed;
+	// the index cache reuses the parse while both still match.
+	size    int64
+	modTime int64
+
+	// derived holds each block's link targets and search terms when the
+	// page was restored from the index cache, so rebuilding backlinks and
+	// the search index skips re-parsing. nil for pages parsed from disk.
+	derived map[string]blockDerived
 }
 
 // Client implements backend.Backend for an Obsidian vault on disk.
 // It reads all .md files on initialization and serves queries from memory.
 type Client struct {
 	vaultPath     string
-	dailyFolder   string                  // e.g. "daily notes"
-	includeHidden bool                    // index directories starting with "."
-	pages         map[string]*cachedPage  // lowercase name → page
-	backlinks     map[string][]backlink   // lowercase target → backlinks
-	blockIndex    map[string]*blockLookup // uuid → block + page
-	searchIndex   *SearchIndex            // inverted index for full-text search
-	mu            sync.RWMutex            // protects all maps above
-	watcher       *fsnotify.Watcher       // file system watcher
+	dailyFolder   string                   // e.g. "daily notes"
+	includeHidden bool                     // index directories starting with "."
+	logseq        bool                     // Logseq file graph layout (see logseq.go)
+	pages         map[string]*cachedPage   // lowercase name → page
+	basenames     map[string][]*cachedPage // lowercase basename → pages, for [[Spec]]-style links
+	files         map[string]*cachedPage   // vault-relative file path → page
+	backlinks     map[string][]backlink    // lowercase target → backlinks
+	blockIndex    map[string]*blockLookup  // uuid → block + page
+	searchIndex   *SearchIndex             // inverted index for full-text search
+	datalogDB     *datalog.DB              // built on first DatascriptQuery, dropped on every change
+	mu            sync.RWMutex             // protects all maps above
+	watcher       *fsnotify.Watcher        // file system watcher
 
 	// Debounce state for fsnotify events. Absorbs the back-to-back
 	// Remove+Create sequences produced by atomic temp+rename saves
@@ -75,6 +104,29 @@ type Client struct {
 	pendingMu     sync.Mutex
 	pendingEvents map[string]*pendingEvent
 	debounceDelay time.Duration // 0 → defaultDebounceDelay (100ms)
+
+	// WatchPages listeners, told about every page change the watcher
+	// resolves.
+	listenMu     sync.Mutex
+	listeners    map[int]func(backend.PageChange)
+	nextListener int
+
+	// Index cache file (see cache.go) and whether the index differs from it.
+	cachePath  string
+	cacheDirty bool
+
+	// Journal date formats of a Logseq graph, from logseq/config.edn.
+	journalTitleFormat string
+	journalFileFormat  string
+
+	// Git commits of writes (see git.go): whether they are made, the files
+	// written since the last one, and extra git arguments for the identity
+	// they are made with. changed is protected by mu; commitMu lets one
+	// commit run git at a time.
+	gitCommits bool
+	changed    map[string]bool
+	gitArgs    []string
+	commitMu   sync.Mutex
 }
 
 // blockLookup stores a block and its page for UUID-based retrieval.
@@ -105,6 +157,8 @@ func New(vaultPath string, opts ...Option) *Client {
 		vaultPath:   vaultPath,
 		dailyFolder: "daily notes",
 		pages:       make(map[string]*cachedPage),
+		basenames:   make(map[string][]*cachedPage),
+		files:       make(map[string]*cachedPage),
 		backlinks:   make(map[string][]backlink),
 		blockIndex:  make(map[string]*blockLookup),
 		searchIndex: NewSearchIndex(),
@@ -116,8 +170,19 @@ func New(vaultPath string, opts ...Option) *Client {
 }
 
 // Load reads all .md files in the vault and builds the in-memory index.
+// With an index cache, unchanged files are restored from the cache instead.
 func (c *Client) Load() error {
-	return filepath.Walk(c.vaultPath, func(path string, info os.FileInfo, err error) error {
+	if c.logseq {
+		c.loadLogseqConfig()
+	}
+	if c.gitCommits {
+		if err := c.prepareGit(context.Background()); err != nil {
+			return err
+		}
+	}
+	cached := c.readIndexCache()
+	dirty := cached == nil
+	err := filepath.Walk(c.vaultPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // skip errors
 		}
@@ -137,15 +202,34 @@ func (c *Client) Load() error {
 			return nil
 		}
 
+		relPath, _ := filepath.Rel(c.vaultPath, path)
+		if c.logseq && !logseqIndexable(relPath) {
+			return nil // logseq/bak, version-files, etc.
+		}
+
+		if f, ok := cached[relPath]; ok {
+			delete(cached, relPath)
+			if f.matches(info) {
+				c.restoreFile(relPath, f)
+				return nil
+			}
+		}
+		dirty = true
+
 		content, err := os.ReadFile(path)
 		if err != nil {
 			return nil // skip unreadable files
 		}
 
-		relPath, _ := filepath.Rel(c.vaultPath, path)
 		c.indexFile(relPath, string(content), info)
 		return nil
 	})
+
+	// Files left in the cache were deleted since it was written.
+	c.mu.Lock()
+	c.cacheDirty = dirty || len(cached) > 0
+	c.mu.Unlock()
+	return err
 }
 
 // indexFile parses a single markdown file and adds it to the index.
@@ -166,52 +250,119 @@ func (c *Client) indexFileCore(relPath, content string, info os.FileInfo) {
 
 // parseFile creates a cachedPage from file content (no locking needed).
 func (c *Client) parseFile(relPath, content string, info os.FileInfo) *cachedPage {
-	name := strings.TrimSuffix(filepath.ToSlash(relPath), ".md")
-	lowerName := strings.ToLower(name)
-
+	name, day := c.pageNameForFile(relPath)
 	props, body := parseFrontmatter(content)
+	blocks := parseMarkdownBlocks(relPath, body)
 
-	isJournal := false
-	if c.dailyFolder != "" {
-		prefix := strings.ToLower(c.dailyFolder) + "/"
-		isJournal = strings.HasPrefix(lowerName, prefix)
+	isJournal := day != 0
+	if c.logseq {
+		props = applyLogseqProperties(props, blocks)
+		if title, ok := props["title"].(string); ok && strings.TrimSpace(title) != "" {
+			name = strings.TrimSpace(title)
+		}
+	} else {
+		isJournal = c.inDailyFolder(name)
 	}
+	lowerName := strings.ToLower(name)
 
 	entity := types.PageEntity{
 		Name:         name,
 		OriginalName: name,
 		Properties:   props,
 		Journal:      isJournal,
+		JournalDay:   day,
 		CreatedAt:    info.ModTime().UnixMilli(),
 		UpdatedAt:    info.ModTime().UnixMilli(),
 	}
 
-	blocks := parseMarkdownBlocks(relPath, body)
-
 	return &cachedPage{
 		entity:    entity,
 		lowerName: lowerName,
 		filePath:  relPath,
 		blocks:    blocks,
+		size:      info.Size(),
+		modTime:   info.ModTime().UnixNano(),
+	}
+}
+
+// pageNameForFile returns the page name for a vault-relative file path and,
+// for journal pages with a parseable date, the day as yyyymmdd. In a vault,
+// only pages of the daily notes folder are journal pages.
+func (c *Client) pageNameForFile(relPath string) (string, int) {
+	if c.logseq {
+		return c.logseqPageName(relPath)
+	}
+	name := strings.TrimSuffix(filepath.ToSlash(relPath), ".md")
+	if !c.inDailyFolder(name) {
+		return name, 0
+	}
+	if t, err := time.Parse("2006-01-02", path.Base(name)); err == nil {
+		return name, journalDay(t)
+	}
+	return name, 0
+}
+
+// inDailyFolder reports whether the vault page name is in the daily notes
+// folder.
+func (c *Client) inDailyFolder(name string) bool {
+	return c.dailyFolder != "" && strings.HasPrefix(strings.ToLower(name), strings.ToLower(c.dailyFolder)+"/")
+}
+
+// pageFile returns the vault-relative file a new page called name is stored in.
+func (c *Client) pageFile(name string) string {
+	if c.logseq {
+		return c.logseqPageFile(name)
+	}
+	return name + ".md"
+}
+
+// JournalPageName returns the name of the journal page for day t: the date in
+// the graph's journal title format in a Logseq graph, or YYYY-MM-DD in the
+// daily notes folder of a vault.
+func (c *Client) JournalPageName(t time.Time) string {
+	if c.logseq {
+		return formatLogseqDate(t, c.journalTitleFormat)
 	}
+	return path.Join(c.dailyFolder, t.Format("2006-01-02"))
+}
+
+// pageByFileLocked returns the page indexed from relPath. In a Logseq graph
+// a title:: property can name a page differently from its file, so the page
+// cannot be found from the path alone. Caller must hold c.mu.
+func (c *Client) pageByFileLocked(relPath string) *cachedPage {
+	return c.files[relPath]
 }
 
 // applyPageIndex stores a parsed page into all indices. Caller must hold c.mu for write.
 func (c *Client) applyPageIndex(page *cachedPage) {
-	// Handle aliases.
-	if page.entity.Properties != nil {
-		if aliases, ok := page.entity.Properties["aliases"]; ok {
-			if aliasList, ok := aliases.([]any); ok {
-				for _, a := range aliasList {
-					if s, ok := a.(string); ok {
-						c.pages[strings.ToLower(s)] = page
-					}
-				}
+	// Drop alias keys and block UUIDs of the previous version so removed
+	// aliases stop resolving and removed blocks stop being found. The key may
+	// belong to another page that only claims it as an alias; that page is
+	// not a previous version and keeps its index entries.
+	if old, ok := c.pages[page.lowerName]; ok && old.lowerName == page.lowerName {
+		c.unindexPageBlocksLocked(old.blocks, old.entity.Name)
+		if c.files[old.filePath] == old {
+			delete(c.files, old.filePath)
+		}
+		for _, a := range parser.PageAliases(old.entity.Properties) {
+			if key := strings.ToLower(a); c.pages[key] == old && key != old.lowerName {
+				delete(c.pages, key)
 			}
 		}
 	}
 
+	// Aliases are alternate keys for the page; they never shadow a real page.
+	for _, a := range parser.PageAliases(page.entity.Properties) {
+		key := strings.ToLower(a)
+		if existing, ok := c.pages[key]; ok && existing.lowerName == key {
+			continue
+		}
+		c.pages[key] = page
+	}
+
 	c.pages[page.lowerName] = page
+	c.files[page.filePath] = page
+	c.indexBasenameLocked(page)
 	c.indexBlocksLocked(page.blocks, page.entity.Name)
 }
 
@@ -228,19 +379,45 @@ func (c *Client) indexBlocksLocked(blocks []types.BlockEntity, pageName string)
 	}
 }
 
+// unindexPageBlocksLocked removes blocks from the UUID index while they still
+// belong to pageName, leaving UUIDs since claimed by another page (a block
+// moved across pages) alone. Caller must hold c.mu.
+func (c *Client) unindexPageBlocksLocked(blocks []types.BlockEntity, pageName string) {
+	for _, b := range blocks {
+		if lookup, ok := c.blockIndex[b.UUID]; ok && lookup.page == pageName {
+			delete(c.blockIndex, b.UUID)
+		}
+		if len(b.Children) > 0 {
+			c.unindexPageBlocksLocked(b.Children, pageName)
+		}
+	}
+}
+
 // BuildBacklinks must be called after Load() to build the reverse link index.
+// With an index cache, it also saves the cache if Load found changes.
 func (c *Client) BuildBacklinks() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	save := c.cachePath != "" && c.cacheDirty
+	if save {
+		c.deriveBlocksLocked()
+	}
 	c.rebuildLinksLocked()
+	if save {
+		if err := c.writeIndexCacheLocked(); err != nil {
+			log.Printf("graphthulhu: failed to write index cache: %v", err)
+		}
+		c.cacheDirty = false
+	}
 }
 
 // rebuildLinksLocked rebuilds backlinks and search index. Caller must hold c.mu.
 func (c *Client) rebuildLinksLocked() {
-	c.backlinks = buildBacklinks(c.pages)
+	c.backlinks = buildBacklinks(c.pages, c.linkKeyLocked)
 	if c.searchIndex != nil {
 		c.searchIndex.BuildFrom(c.pages)
 	}
+	c.datalogDB = nil
 }
 
 // removePageFromIndexLocked removes a page without locking. Caller must hold c.mu.
@@ -250,12 +427,16 @@ func (c *Client) removePageFromIndexLocked(lowerName string) {
 		return
 	}
 	c.removeBlocksFromIndexLocked(cached.blocks)
+	if c.files[cached.filePath] == cached {
+		delete(c.files, cached.filePath)
+	}
 	for key, page := range c.pages {
 		if key != lowerName && page.lowerName == lowerName {
 			delete(c.pages, key)
 		}
 	}
 	delete(c.pages, lowerName)
+	c.unindexBasenameLocked(lowerName)
 }
 
 // Watch starts watching the vault directory for file changes and automatically
@@ -341,6 +522,9 @@ func (c *Client) handleEvent(event fsnotify.Event) {
 		log.Printf("graphthulhu: failed to get relative path for %s: %v\n", event.Name, err)
 		return
 	}
+	if c.logseq && !logseqIndexable(relPath) {
+		return
+	}
 
 	// Coalesce every Create/Write/Remove/Rename per path. The final
 	// resolve (after debounceDelay) reads real disk state: present →
@@ -373,6 +557,8 @@ func (c *Client) Reload() error {
 	// Clear existing indices.
 	c.mu.Lock()
 	c.pages = make(map[string]*cachedPage)
+	c.basenames = make(map[string][]*cachedPage)
+	c.files = make(map[string]*cachedPage)
 	c.blockIndex = make(map[string]*blockLookup)
 	c.mu.Unlock()
 
@@ -417,10 +603,9 @@ func (c *Client) GetPage(_ context.Context, nameOrID any) (*types.PageEntity, er
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	name := fmt.Sprint(nameOrID)
-	page, ok := c.pages[strings.ToLower(name)]
-	if !ok {
-		return nil, nil
+	page, err := c.resolvePageLocked(fmt.Sprint(nameOrID))
+	if err != nil || page == nil {
+		return nil, err
 	}
 	return &page.entity, nil
 }
@@ -429,10 +614,9 @@ func (c *Client) GetPageBlocksTree(_ context.Context, nameOrID any) ([]types.Blo
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	name := fmt.Sprint(nameOrID)
-	page, ok := c.pages[strings.ToLower(name)]
-	if !ok {
-		return nil, nil
+	page, err := c.resolvePageLocked(fmt.Sprint(nameOrID))
+	if err != nil || page == nil {
+		return nil, err
 	}
 	return page.blocks, nil
 }
@@ -458,6 +642,13 @@ func (c *Client) GetPageLinkedReferences(_ context.Context, nameOrID any) (json.
 
 	name := fmt.Sprint(nameOrID)
 	key := strings.ToLower(name)
+	page, err := c.resolvePageLocked(name)
+	if err != nil {
+		return nil, err
+	}
+	if page != nil {
+		key = page.lowerName
+	}
 
 	links, ok := c.backlinks[key]
 	if !ok || len(links) == 0 {
@@ -489,10 +680,6 @@ func (c *Client) GetPageLinkedReferences(_ context.Context, nameOrID any) (json.
 	return json.Marshal(result)
 }
 
-func (c *Client) DatascriptQuery(_ context.Context, query string, inputs ...any) (json.RawMessage, error) {
-	return nil, ErrNotSupported
-}
-
 // --- Write operations ---
 
 func (c *Client) CreatePage(_ context.Context, name string, properties map[string]any, opts map[string]any) (*types.PageEntity, error) {
@@ -508,11 +695,14 @@ func (c *Client) CreatePage(_ context.Context, name string, properties map[strin
 		return nil, fmt.Errorf("page already exists: %s", name)
 	}
 
-	relPath := name + ".md"
+	relPath := c.pageFile(name)
 	absPath, err := c.safePath(relPath)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := os.Stat(absPath); err == nil {
+		return nil, fmt.Errorf("page already exists: %s", name)
+	}
 
 	dir := filepath.Dir(absPath)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
@@ -520,11 +710,13 @@ func (c *Client) CreatePage(_ context.Context, name string, properties map[strin
 	}
 
 	var content string
-	if len(properties) > 0 {
+	if c.logseq {
+		content = renderLogseqProperties(properties)
+	} else if len(properties) > 0 {
 		content = renderFrontmatter(properties)
 	}
 
-	if err := atomicWrite(absPath, content); err != nil {
+	if err := c.writeFile(absPath, content); err != nil {
 		return nil, fmt.Errorf("write page: %w", err)
 	}
 
@@ -535,7 +727,7 @@ func (c *Client) CreatePage(_ context.Context, name string, properties map[strin
 	c.indexFileCore(relPath, content, info)
 	c.rebuildLinksLocked()
 
-	page := c.pages[lowerName]
+	page := c.pageByFileLocked(relPath)
 	if page == nil {
 		return nil, fmt.Errorf("index failed for %s", name)
 	}
@@ -547,13 +739,19 @@ func (c *Client) AppendBlockInPage(_ context.Context, page string, content strin
 	defer c.mu.Unlock()
 
 	lowerName := strings.ToLower(page)
-	cached, exists := c.pages[lowerName]
+	cached, err := c.resolvePageLocked(page)
+	if err != nil {
+		return nil, err
+	}
+	exists := cached != nil
+	if exists {
+		lowerName = cached.lowerName
+	}
 
 	var absPath string
 	var relPath string
 	if !exists {
-		relPath = page + ".md"
-		var err error
+		relPath = c.pageFile(page)
 		absPath, err = c.safePath(relPath)
 		if err != nil {
 			return nil, err
@@ -562,41 +760,41 @@ func (c *Client) AppendBlockInPage(_ context.Context, page string, content strin
 		if err := os.MkdirAll(dir, 0o755); err != nil {
 			return nil, fmt.Errorf("create directory: %w", err)
 		}
-		if err := atomicWrite(absPath, ""); err != nil {
+		if err := c.writeFile(absPath, ""); err != nil {
 			return nil, fmt.Errorf("create page: %w", err)
 		}
 		info, _ := os.Stat(absPath)
 		c.indexFileCore(relPath, "", info)
-		cached = c.pages[lowerName]
+		cached = c.pageByFileLocked(relPath)
+		lowerName = cached.lowerName
 	} else {
 		relPath = cached.filePath
-		var err error
 		absPath, err = c.safePath(relPath)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	existing, err := os.ReadFile(absPath)
+	o, err := c.readOutline(cached)
 	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
+		return nil, err
 	}
 
 	blockUUID, cleanContent := extractUUID(content)
+	last, ok := o.lastBlock()
+	cleanContent = c.continueOutline(last, ok, cleanContent)
 	if blockUUID == "" {
 		blockUUID = generateRandomUUID()
-		content = embedUUID(cleanContent, blockUUID)
-	} else {
-		content = cleanContent
 	}
+	content = c.embedUUID(cleanContent, blockUUID)
 
-	newContent := string(existing)
+	newContent := o.content
 	if newContent != "" && !strings.HasSuffix(newContent, "\n") {
 		newContent += "\n"
 	}
 	newContent += content + "\n"
 
-	if err := atomicWrite(absPath, newContent); err != nil {
+	if err := c.writeFile(absPath, newContent); err != nil {
 		return nil, fmt.Errorf("write file: %w", err)
 	}
 
@@ -623,22 +821,19 @@ func (c *Client) PrependBlockInPage(_ context.Context, page string, content stri
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	lowerName := strings.ToLower(page)
-	cached, exists := c.pages[lowerName]
+	cached, err := c.resolvePageLocked(page)
+	if err != nil {
+		return nil, err
+	}
 
 	blockUUID, cleanContent := extractUUID(content)
 	if blockUUID == "" {
 		blockUUID = generateRandomUUID()
-		content = embedUUID(cleanContent, blockUUID)
-	} else {
-		content = cleanContent
 	}
 
-	var absPath string
-	if !exists {
-		relPath := page + ".md"
-		var err error
-		absPath, err = c.safePath(relPath)
+	if cached == nil {
+		relPath := c.pageFile(page)
+		absPath, err := c.safePath(relPath)
 		if err != nil {
 			return nil, err
 		}
@@ -646,47 +841,53 @@ func (c *Client) PrependBlockInPage(_ context.Context, page string, content stri
 		if err := os.MkdirAll(dir, 0o755); err != nil {
 			return nil, fmt.Errorf("create directory: %w", err)
 		}
-		if err := atomicWrite(absPath, content+"\n"); err != nil {
+		content = c.embedUUID(c.continueOutline(blockSpan{}, false, cleanContent), blockUUID)
+		if err := c.writeFile(absPath, content+"\n"); err != nil {
 			return nil, fmt.Errorf("create page: %w", err)
 		}
 		info, _ := os.Stat(absPath)
 		c.indexFileCore(relPath, content+"\n", info)
 		c.rebuildLinksLocked()
-		cached = c.pages[lowerName]
+		cached = c.pageByFileLocked(relPath)
 		if cached != nil && len(cached.blocks) > 0 {
 			return &cached.blocks[0], nil
 		}
 		return &types.BlockEntity{UUID: blockUUID, Content: cleanContent}, nil
 	}
 
-	var err error
-	absPath, err = c.safePath(cached.filePath)
+	o, err := c.readOutline(cached)
 	if err != nil {
 		return nil, err
 	}
 
-	existing, err := os.ReadFile(absPath)
-	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
-	}
-
-	props, body := parseFrontmatter(string(existing))
-	var newContent string
-	if props != nil {
-		newContent = renderFrontmatter(props) + content + "\n" + body
-	} else {
-		newContent = content + "\n" + string(existing)
+	// A Logseq page's property pre-block stays first; the new block goes
+	// after it.
+	at := 0
+	first, ok := o.firstBlock()
+	if c.logseq && ok && isPropertyBlock(strings.Join(o.lines[first.start:first.contentEnd], "\n")) {
+		at = first.contentEnd
+		for at < len(o.lines) && strings.TrimSpace(o.lines[at]) == "" {
+			at++
+		}
+		first, ok = blockSpan{}, false
+		for _, s := range o.spans {
+			if s.start >= at && (!ok || s.start < first.start) {
+				first, ok = s, true
+			}
+		}
 	}
 
-	if err := atomicWrite(absPath, newContent); err != nil {
-		return nil, fmt.Errorf("write file: %w", err)
+	content = c.embedUUID(c.continueOutline(first, ok, cleanContent), blockUUID)
+	o.splice(at, at, strings.Split(content, "\n"))
+	if err := c.writeOutline(cached, o); err != nil {
+		return nil, err
 	}
-
-	info, _ := os.Stat(absPath)
-	c.indexFileCore(cached.filePath, newContent, info)
 	c.rebuildLinksLocked()
 
-	cached = c.pages[lowerName]
+	if lookup, ok := c.blockIndex[blockUUID]; ok {
+		return lookup.block, nil
+	}
+	cached = c.pages[cached.lowerName]
 	if cached != nil && len(cached.blocks) > 0 {
 		return &cached.blocks[0], nil
 	}
@@ -710,17 +911,20 @@ func (c *Client) InsertBlock(_ context.Context, srcBlock any, content string, op
 		return nil, fmt.Errorf("page not found for block: %s", pageName)
 	}
 
-	absPath, err := c.safePath(cached.filePath)
+	o, err := c.readOutline(cached)
 	if err != nil {
 		return nil, err
 	}
-	existing, err := os.ReadFile(absPath)
-	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
+
+	// List items get a nested (or sibling) bullet placed after the parent's
+	// subtree, matching how Logseq inserts into an outline.
+	if span, ok := o.spans[parentUUID]; ok && span.isListItem() {
+		return c.insertListItemLocked(cached, o, span, content, opts)
 	}
 
+	absPath := o.absPath
 	parentContent := lookup.block.Content
-	fileStr := string(existing)
+	fileStr := o.content
 	idx := strings.Index(fileStr, parentContent)
 	if idx < 0 {
 		return nil, fmt.Errorf("could not locate parent block content in file")
@@ -735,17 +939,12 @@ func (c *Client) InsertBlock(_ context.Context, srcBlock any, content string, op
 		}
 	}
 
-	blockUUID, cleanContent := extractUUID(childContent)
-	if blockUUID == "" {
-		blockUUID = generateRandomUUID()
-		childContent = embedUUID(cleanContent, blockUUID)
-	} else {
-		childContent = cleanContent
-	}
+	blockUUID, cleanContent := insertedUUID(childContent, opts)
+	childContent = embedUUID(cleanContent, blockUUID)
 
 	newContent := fileStr[:insertPos] + "\n" + childContent + fileStr[insertPos:]
 
-	if err := atomicWrite(absPath, newContent); err != nil {
+	if err := c.writeFile(absPath, newContent); err != nil {
 		return nil, fmt.Errorf("write file: %w", err)
 	}
 
@@ -764,9 +963,43 @@ func (c *Client) InsertBlock(_ context.Context, srcBlock any, content string, op
 	return &types.BlockEntity{UUID: blockUUID, Content: cleanContent}, nil
 }
 
-func (c *Client) UpdateBlock(_ context.Context, uuid string, content string, opts ...map[string]any) error {
+// insertListItemLocked inserts content as a list item relative to the list
+// item at parent: as its last child by default, or as a sibling after it
+// (before it with opts["before"]) when opts["sibling"] is true.
+// Caller must hold c.mu.
+func (c *Client) insertListItemLocked(page *cachedPage, o *outlineFile, parent blockSpan, content string, opts map[string]any) (*types.BlockEntity, error) {
+	sibling, _ := opts["sibling"].(bool)
+	before, _ := opts["before"].(bool)
+
+	indent, bullet := o.childItem(parent)
+	at := parent.end
+	if sibling {
+		indent, bullet = parent.indent, parent.bullet
+		if before {
+			at = parent.start
+		}
+	}
+
+	blockUUID, cleanContent := insertedUUID(content, opts)
+	item := c.embedUUID(renderListItem(indent, bullet, cleanContent), blockUUID)
+	o.splice(at, at, strings.Split(item, "\n"))
+	if err := c.writeOutline(page, o); err != nil {
+		return nil, err
+	}
+	c.rebuildLinksLocked()
+
+	if lookup, ok := c.blockIndex[blockUUID]; ok {
+		return lookup.block, nil
+	}
+	return &types.BlockEntity{UUID: blockUUID, Content: cleanContent}, nil
+}
+
+func (c *Client) UpdateBlock(ctx context.Context, uuid string, content string, opts ...map[string]any) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if err := c.checkIfMatchLocked(ctx, uuid); err != nil {
+		return err
+	}
 
 	lookup, ok := c.blockIndex[uuid]
 	if !ok {
@@ -780,17 +1013,30 @@ func (c *Client) UpdateBlock(_ context.Context, uuid string, content string, opt
 		return fmt.Errorf("page not found: %s", pageName)
 	}
 
-	absPath, err := c.safePath(cached.filePath)
+	o, err := c.readOutline(cached)
 	if err != nil {
 		return err
 	}
-	existing, err := os.ReadFile(absPath)
-	if err != nil {
-		return fmt.Errorf("read file: %w", err)
+
+	// List items are rewritten in place, keeping their bullet, indentation
+	// and nested children.
+	if span, ok := o.spans[uuid]; ok && span.isListItem() {
+		blockUUID, cleanContent := extractUUID(content)
+		if blockUUID == "" {
+			blockUUID = uuid
+		}
+		item := c.embedUUID(renderListItem(span.indent, span.bullet, cleanContent), blockUUID)
+		o.splice(span.start, span.contentEnd, strings.Split(item, "\n"))
+		if err := c.writeOutline(cached, o); err != nil {
+			return err
+		}
+		c.rebuildLinksLocked()
+		return nil
 	}
 
+	absPath := o.absPath
 	oldContent := lookup.block.Content
-	fileStr := string(existing)
+	fileStr := o.content
 
 	// The file might have the UUID embedded, so we need to search for it
 	// We'll look for the old content with or without UUID comment
@@ -823,7 +1069,7 @@ func (c *Client) UpdateBlock(_ context.Context, uuid string, content string, opt
 	}
 
 	newContent := strings.Replace(fileStr, oldInFile, newInFile, 1)
-	if err := atomicWrite(absPath, newContent); err != nil {
+	if err := c.writeFile(absPath, newContent); err != nil {
 		return fmt.Errorf("write file: %w", err)
 	}
 
@@ -834,9 +1080,12 @@ func (c *Client) UpdateBlock(_ context.Context, uuid string, content string, opt
 	return nil
 }
 
-func (c *Client) RemoveBlock(_ context.Context, uuid string) error {
+func (c *Client) RemoveBlock(ctx context.Context, uuid string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if err := c.checkIfMatchLocked(ctx, uuid); err != nil {
+		return err
+	}
 
 	lookup, ok := c.blockIndex[uuid]
 	if !ok {
@@ -850,17 +1099,24 @@ func (c *Client) RemoveBlock(_ context.Context, uuid string) error {
 		return fmt.Errorf("page not found: %s", pageName)
 	}
 
-	absPath, err := c.safePath(cached.filePath)
+	o, err := c.readOutline(cached)
 	if err != nil {
 		return err
 	}
-	existing, err := os.ReadFile(absPath)
-	if err != nil {
-		return fmt.Errorf("read file: %w", err)
+
+	// A list item takes its nested children with it, as in Logseq.
+	if span, ok := o.spans[uuid]; ok && span.isListItem() {
+		o.splice(span.start, span.end, nil)
+		if err := c.writeOutline(cached, o); err != nil {
+			return err
+		}
+		c.rebuildLinksLocked()
+		return nil
 	}
 
+	absPath := o.absPath
 	oldContent := lookup.block.Content
-	fileStr := string(existing)
+	fileStr := o.content
 
 	oldContentWithUUID := embedUUID(oldContent, uuid)
 	var newContent string
@@ -877,7 +1133,7 @@ func (c *Client) RemoveBlock(_ context.Context, uuid string) error {
 		return fmt.Errorf("block content not found in file")
 	}
 
-	if err := atomicWrite(absPath, newContent); err != nil {
+	if err := c.writeFile(absPath, newContent); err != nil {
 		return fmt.Errorf("write file: %w", err)
 	}
 
@@ -892,11 +1148,14 @@ func (c *Client) DeletePage(_ context.Context, name string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	lowerName := strings.ToLower(name)
-	cached, ok := c.pages[lowerName]
-	if !ok {
+	cached, err := c.resolvePageLocked(name)
+	if err != nil {
+		return err
+	}
+	if cached == nil {
 		return fmt.Errorf("page not found: %s", name)
 	}
+	lowerName := cached.lowerName
 
 	absPath, err := c.safePath(cached.filePath)
 	if err != nil {
@@ -905,6 +1164,7 @@ func (c *Client) DeletePage(_ context.Context, name string) error {
 	if err := os.Remove(absPath); err != nil {
 		return fmt.Errorf("delete file: %w", err)
 	}
+	c.noteChange(absPath)
 
 	c.removePageFromIndexLocked(lowerName)
 	c.rebuildLinksLocked()
@@ -925,30 +1185,121 @@ func (c *Client) DeletePage(_ context.Context, name string) error {
 	return nil
 }
 
-func (c *Client) RenamePage(_ context.Context, oldName, newName string) error {
-	if len(newName) > 255 || strings.ContainsAny(newName, "\x00") {
-		return fmt.Errorf("invalid page name: too long or contains null bytes")
+// PageFile implements backend.PageFileStore.
+func (c *Client) PageFile(_ context.Context, name string) (string, string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cached, err := c.resolvePageLocked(name)
+	if err != nil {
+		return "", "", err
+	}
+	if cached == nil {
+		return "", "", fmt.Errorf("page not found: %s", name)
+	}
+	absPath, err := c.safePath(cached.filePath)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", "", fmt.Errorf("read file: %w", err)
+	}
+	return cached.filePath, string(data), nil
+}
+
+// CurrentBlockVersion implements backend.BlockVersioner. The block's page
+// file is parsed fresh, so an edit the watcher has not indexed yet counts.
+// A block the edit removed, or whose UUID it shifted, is a conflict.
+func (c *Client) CurrentBlockVersion(_ context.Context, uuid string) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.currentBlockVersionLocked(uuid)
+}
+
+// checkIfMatchLocked checks the ifMatch precondition ctx carries on block
+// uuid, if any, against the block as stored. Writes call it holding c.mu,
+// so that the block cannot change between the check and the write.
+func (c *Client) checkIfMatchLocked(ctx context.Context, uuid string) error {
+	if !backend.HasIfMatch(ctx, uuid) {
+		return nil
+	}
+	current, err := c.currentBlockVersionLocked(uuid)
+	if err != nil {
+		return err
+	}
+	return backend.CheckIfMatch(ctx, uuid, current)
+}
+
+// currentBlockVersionLocked is CurrentBlockVersion with c.mu held.
+func (c *Client) currentBlockVersionLocked(uuid string) (string, error) {
+	lookup, ok := c.blockIndex[uuid]
+	if !ok {
+		return "", fmt.Errorf("block not found: %s", uuid)
+	}
+	cached, ok := c.pages[strings.ToLower(lookup.page)]
+	if !ok {
+		return "", fmt.Errorf("page not found: %s", lookup.page)
+	}
+	absPath, err := c.safePath(cached.filePath)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", fmt.Errorf("stat file: %w", err)
+	}
+
+	fresh := findBlockByUUID(c.parseFile(cached.filePath, string(data), info).blocks, uuid)
+	if fresh == nil {
+		return "", fmt.Errorf("%w: block %s is no longer in %s", backend.ErrConflict, uuid, cached.filePath)
 	}
+	return backend.BlockVersion(fresh.Content), nil
+}
 
+// WritePageFile implements backend.PageFileStore.
+func (c *Client) WritePageFile(_ context.Context, relPath, content string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	lowerOld := strings.ToLower(oldName)
-	cached, ok := c.pages[lowerOld]
-	if !ok {
-		return fmt.Errorf("page not found: %s", oldName)
+	absPath, err := c.safePath(relPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	if err := c.writeFile(absPath, content); err != nil {
+		return fmt.Errorf("write page: %w", err)
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return fmt.Errorf("stat page file: %w", err)
 	}
+	c.indexFileCore(relPath, content, info)
+	c.rebuildLinksLocked()
+	return nil
+}
+
+func (c *Client) RenamePage(_ context.Context, oldName, newName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	lowerNew := strings.ToLower(newName)
-	if _, exists := c.pages[lowerNew]; exists {
-		return fmt.Errorf("target page already exists: %s", newName)
+	cached, newRelPath, err := c.renameTargetLocked(oldName, newName)
+	if err != nil {
+		return err
 	}
+	lowerOld := cached.lowerName
 
 	oldPath, err := c.safePath(cached.filePath)
 	if err != nil {
 		return err
 	}
-	newRelPath := newName + ".md"
 	newAbsPath, err := c.safePath(newRelPath)
 	if err != nil {
 		return err
@@ -961,9 +1312,11 @@ func (c *Client) RenamePage(_ context.Context, oldName, newName string) error {
 	if err := os.Rename(oldPath, newAbsPath); err != nil {
 		return fmt.Errorf("rename file: %w", err)
 	}
+	c.noteChange(oldPath)
+	c.noteChange(newAbsPath)
 
-	// Update all [[links]] across the vault — log every error.
-	if errs := c.updateLinksAcrossVaultLocked(oldName, newName); len(errs) > 0 {
+	// Update all links across the vault — log every error.
+	if errs := c.updateLinksAcrossVaultLocked(cached, newName); len(errs) > 0 {
 		for _, e := range errs {
 			log.Printf("graphthulhu: link update error during rename: %v", e)
 		}
@@ -972,6 +1325,13 @@ func (c *Client) RenamePage(_ context.Context, oldName, newName string) error {
 	c.removePageFromIndexLocked(lowerOld)
 	content, err := os.ReadFile(newAbsPath)
 	if err == nil {
+		// A Logseq title:: property would keep the old name; follow the rename.
+		if retitled := c.retitle(string(content), newName); retitled != string(content) {
+			content = []byte(retitled)
+			if err := c.writeFile(newAbsPath, string(content)); err != nil {
+				log.Printf("graphthulhu: failed to update title of %s: %v", newRelPath, err)
+			}
+		}
 		info, _ := os.Stat(newAbsPath)
 		c.indexFileCore(newRelPath, string(content), info)
 	}
@@ -1011,13 +1371,47 @@ func (c *Client) removeBlocksFromIndexLocked(blocks []types.BlockEntity) {
 	}
 }
 
-// updateLinksAcrossVaultLocked replaces [[oldName]] with [[newName]] in all files.
-// Caller must hold c.mu. Returns errors from failed writes.
-func (c *Client) updateLinksAcrossVaultLocked(oldName, newName string) []error {
-	oldLink := "[[" + oldName + "]]"
-	newLink := "[[" + newName + "]]"
-	lowerOld := strings.ToLower(oldName)
+// renameTargetLocked checks that page oldName can be renamed to newName and
+// returns it with the file it would move to. Caller must hold c.mu.
+func (c *Client) renameTargetLocked(oldName, newName string) (*cachedPage, string, error) {
+	if len(newName) > 255 || strings.ContainsAny(newName, "\x00") {
+		return nil, "", fmt.Errorf("invalid page name: too long or contains null bytes")
+	}
+	cached, err := c.resolvePageLocked(oldName)
+	if err != nil {
+		return nil, "", err
+	}
+	if cached == nil {
+		return nil, "", fmt.Errorf("page not found: %s", oldName)
+	}
+	if _, exists := c.pages[strings.ToLower(newName)]; exists {
+		return nil, "", fmt.Errorf("target page already exists: %s", newName)
+	}
+	return cached, c.pageFile(newName), nil
+}
+
+// retitle points a Logseq title:: property in content at newName.
+func (c *Client) retitle(content, newName string) string {
+	if !c.logseq {
+		return content
+	}
+	return titlePropertyPattern.ReplaceAllLiteralString(content, "title:: "+newName)
+}
+
+// fileRewrite is a page file and what a rename rewrites it to.
+type fileRewrite struct {
+	page          *cachedPage
+	before, after string
+}
+
+// linkRewritesLocked returns every other page file that links to target,
+// with its links pointing at newName. Links are matched by resolution, so
+// [[Spec]], [[alpha/Spec|label]] and [spec](../alpha/Spec.md) are all
+// caught. Caller must hold c.mu. Returns errors from files it could not read.
+func (c *Client) linkRewritesLocked(target *cachedPage, newName string) ([]fileRewrite, []error) {
+	shortName := c.shortestLinkNameLocked(target, newName)
 
+	var rewrites []fileRewrite
 	var errs []error
 	seen := make(map[string]bool)
 	for _, page := range c.pages {
@@ -1026,8 +1420,8 @@ func (c *Client) updateLinksAcrossVaultLocked(oldName, newName string) []error {
 		}
 		seen[page.lowerName] = true
 
-		// Skip the page being renamed — its file was already moved.
-		if page.lowerName == lowerOld {
+		// Skip the page being renamed — its file moves instead.
+		if page == target {
 			continue
 		}
 
@@ -1043,26 +1437,105 @@ func (c *Client) updateLinksAcrossVaultLocked(oldName, newName string) []error {
 		}
 
 		fileStr := string(content)
-		if !strings.Contains(fileStr, oldLink) {
-			continue
+		updated := c.rewriteLinksLocked(fileStr, page.entity.Name, target, newName, shortName)
+		if updated != fileStr {
+			rewrites = append(rewrites, fileRewrite{page: page, before: fileStr, after: updated})
 		}
+	}
+	slices.SortFunc(rewrites, func(a, b fileRewrite) int { return strings.Compare(a.page.filePath, b.page.filePath) })
+	return rewrites, errs
+}
 
-		updated := strings.ReplaceAll(fileStr, oldLink, newLink)
-		if err := atomicWrite(absPath, updated); err != nil {
-			errs = append(errs, fmt.Errorf("write %s: %w", page.filePath, err))
+// updateLinksAcrossVaultLocked rewrites links to target so they point at
+// newName in all other files. Caller must hold c.mu. Returns errors from
+// failed reads and writes.
+func (c *Client) updateLinksAcrossVaultLocked(target *cachedPage, newName string) []error {
+	rewrites, errs := c.linkRewritesLocked(target, newName)
+	for _, rw := range rewrites {
+		absPath, err := c.safePath(rw.page.filePath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("skip %s: %w", rw.page.filePath, err))
+			continue
+		}
+		if err := c.writeFile(absPath, rw.after); err != nil {
+			errs = append(errs, fmt.Errorf("write %s: %w", rw.page.filePath, err))
 			continue
 		}
 
 		info, _ := os.Stat(absPath)
-		c.indexFileCore(page.filePath, updated, info)
+		c.indexFileCore(rw.page.filePath, rw.after, info)
 	}
 	return errs
 }
 
-func (c *Client) MoveBlock(_ context.Context, uuid string, targetUUID string, opts map[string]any) error {
+// PlanRenamePage implements backend.RenamePlanner. It runs the same checks
+// and link rewriting as RenamePage, without writing.
+func (c *Client) PlanRenamePage(_ context.Context, oldName, newName string) (*backend.RenamePlan, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cached, newRelPath, err := c.renameTargetLocked(oldName, newName)
+	if err != nil {
+		return nil, err
+	}
+	plan := &backend.RenamePlan{
+		From:     cached.entity.OriginalName,
+		To:       newName,
+		FromFile: cached.filePath,
+		ToFile:   newRelPath,
+		Links:    []backend.LineChange{},
+	}
+
+	rewrites, errs := c.linkRewritesLocked(cached, newName)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, rw := range rewrites {
+		plan.Links = append(plan.Links, lineChanges(rw.page.entity.OriginalName, rw.page.filePath, rw.before, rw.after)...)
+	}
+
+	absPath, err := c.safePath(cached.filePath)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := os.ReadFile(absPath); err == nil {
+		if retitled := c.retitle(string(data), newName); retitled != string(data) {
+			plan.Links = append(plan.Links, lineChanges(newName, newRelPath, string(data), retitled)...)
+		}
+	}
+	return plan, nil
+}
+
+// lineChanges lists the lines that differ between two versions of a page
+// file. Link rewrites never add or remove lines, so lines are compared by
+// position.
+func lineChanges(page, file, before, after string) []backend.LineChange {
+	old, updated := strings.Split(before, "\n"), strings.Split(after, "\n")
+	if len(old) != len(updated) {
+		return []backend.LineChange{{Page: page, File: file, Before: before, After: after}}
+	}
+	var changes []backend.LineChange
+	for i := range old {
+		if old[i] != updated[i] {
+			changes = append(changes, backend.LineChange{Page: page, File: file, Line: i + 1, Before: old[i], After: updated[i]})
+		}
+	}
+	return changes
+}
+
+// MoveBlock moves a block before, after or (opts["children"]) under the
+// target block. List items move with their nested children and are
+// re-indented to their new depth; other blocks move their own content.
+func (c *Client) MoveBlock(ctx context.Context, uuid string, targetUUID string, opts map[string]any) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if err := c.checkIfMatchLocked(ctx, uuid); err != nil {
+		return err
+	}
 
+	if uuid == targetUUID {
+		return fmt.Errorf("cannot move block relative to itself: %s", uuid)
+	}
 	srcLookup, ok := c.blockIndex[uuid]
 	if !ok {
 		return fmt.Errorf("source block not found: %s", uuid)
@@ -1072,105 +1545,93 @@ func (c *Client) MoveBlock(_ context.Context, uuid string, targetUUID string, op
 		return fmt.Errorf("target block not found: %s", targetUUID)
 	}
 
-	srcPage := srcLookup.page
-	tgtPage := tgtLookup.page
-	srcContent := srcLookup.block.Content
-	tgtContent := tgtLookup.block.Content
-	samePage := strings.ToLower(srcPage) == strings.ToLower(tgtPage)
-
-	before := false
+	before, children := false, false
 	if opts != nil {
-		if b, ok := opts["before"]; ok {
-			before, _ = b.(bool)
-		}
+		before, _ = opts["before"].(bool)
+		children, _ = opts["children"].(bool)
 	}
 
-	if samePage {
-		lowerName := strings.ToLower(srcPage)
-		cached, ok := c.pages[lowerName]
-		if !ok {
-			return fmt.Errorf("page not found: %s", srcPage)
-		}
-
-		absPath, err := c.safePath(cached.filePath)
-		if err != nil {
-			return err
-		}
-		existing, err := os.ReadFile(absPath)
-		if err != nil {
-			return fmt.Errorf("read file: %w", err)
-		}
-
-		fileStr := string(existing)
-		fileStr = strings.Replace(fileStr, srcContent+"\n", "", 1)
-
-		if before {
-			fileStr = strings.Replace(fileStr, tgtContent, srcContent+"\n"+tgtContent, 1)
-		} else {
-			fileStr = strings.Replace(fileStr, tgtContent, tgtContent+"\n"+srcContent, 1)
-		}
-
-		if err := atomicWrite(absPath, fileStr); err != nil {
-			return fmt.Errorf("write file: %w", err)
-		}
-
-		info, _ := os.Stat(absPath)
-		c.indexFileCore(cached.filePath, fileStr, info)
-		c.rebuildLinksLocked()
-		return nil
-	}
-
-	// Cross-page move.
-	srcLower := strings.ToLower(srcPage)
-	tgtLower := strings.ToLower(tgtPage)
-	srcCached, ok := c.pages[srcLower]
+	srcCached, ok := c.pages[strings.ToLower(srcLookup.page)]
 	if !ok {
-		return fmt.Errorf("source page not found: %s", srcPage)
+		return fmt.Errorf("source page not found: %s", srcLookup.page)
 	}
-	tgtCached, ok := c.pages[tgtLower]
+	tgtCached, ok := c.pages[strings.ToLower(tgtLookup.page)]
 	if !ok {
-		return fmt.Errorf("target page not found: %s", tgtPage)
+		return fmt.Errorf("target page not found: %s", tgtLookup.page)
 	}
 
-	srcAbsPath, err := c.safePath(srcCached.filePath)
+	src, err := c.readOutline(srcCached)
 	if err != nil {
 		return err
 	}
-	tgtAbsPath, err := c.safePath(tgtCached.filePath)
+	tgt := src
+	if tgtCached != srcCached {
+		if tgt, err = c.readOutline(tgtCached); err != nil {
+			return err
+		}
+	}
+	srcSpan, err := src.span(uuid)
 	if err != nil {
 		return err
 	}
-
-	srcFile, err := os.ReadFile(srcAbsPath)
+	tgtSpan, err := tgt.span(targetUUID)
 	if err != nil {
-		return fmt.Errorf("read source file: %w", err)
+		return err
 	}
-	srcStr := strings.Replace(string(srcFile), srcContent+"\n", "", 1)
-	if srcStr == string(srcFile) {
-		srcStr = strings.Replace(string(srcFile), srcContent, "", 1)
+
+	// Lines that travel with the block, with its UUID pinned so it keeps
+	// its identity at the new position.
+	end := srcSpan.contentEnd
+	if srcSpan.isListItem() {
+		end = srcSpan.end
 	}
-	if err := atomicWrite(srcAbsPath, srcStr); err != nil {
-		return fmt.Errorf("write source file: %w", err)
+	moved := slices.Clone(src.lines[srcSpan.start:end])
+	if id, _ := extractUUID(strings.Join(src.lines[srcSpan.start:srcSpan.contentEnd], "\n")); id != uuid {
+		moved = strings.Split(c.embedUUID(strings.Join(moved, "\n"), uuid), "\n")
 	}
 
-	tgtFile, err := os.ReadFile(tgtAbsPath)
-	if err != nil {
-		return fmt.Errorf("read target file: %w", err)
+	// Destination line and, for list items, the indentation to move to.
+	at, indent := tgtSpan.contentEnd, ""
+	switch {
+	case before:
+		at, indent = tgtSpan.start, tgtSpan.indent
+	case children && tgtSpan.isListItem():
+		at = tgtSpan.end
+		indent, _ = tgt.childItem(tgtSpan)
+	case tgtSpan.isListItem():
+		at, indent = tgtSpan.end, tgtSpan.indent
 	}
-	tgtStr := string(tgtFile)
-	if before {
-		tgtStr = strings.Replace(tgtStr, tgtContent, srcContent+"\n"+tgtContent, 1)
-	} else {
-		tgtStr = strings.Replace(tgtStr, tgtContent, tgtContent+"\n"+srcContent, 1)
+	if srcSpan.isListItem() {
+		moved = reindent(moved, indentWidth(srcSpan.indent), indent)
 	}
-	if err := atomicWrite(tgtAbsPath, tgtStr); err != nil {
-		return fmt.Errorf("write target file: %w", err)
+
+	if tgt == src {
+		if at > srcSpan.start && at < end || tgtSpan.start >= srcSpan.start && tgtSpan.start < end {
+			return fmt.Errorf("cannot move block into its own children: %s", uuid)
+		}
+		if at >= end {
+			at -= end - srcSpan.start
+		}
 	}
+	src.splice(srcSpan.start, end, nil)
+	tgt.splice(at, at, moved)
 
-	srcInfo, _ := os.Stat(srcAbsPath)
-	c.indexFileCore(srcCached.filePath, srcStr, srcInfo)
-	tgtInfo, _ := os.Stat(tgtAbsPath)
-	c.indexFileCore(tgtCached.filePath, tgtStr, tgtInfo)
+	// Re-index the source first so the moved UUID ends up on the target page.
+	if err := c.writeOutline(srcCached, src); err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	if tgt != src {
+		if err := c.writeOutline(tgtCached, tgt); err != nil {
+			// Put the source back so a failed move never loses the block.
+			if rerr := c.writeFile(src.absPath, src.content); rerr != nil {
+				return fmt.Errorf("target: %w (restoring source: %v)", err, rerr)
+			}
+			info, _ := os.Stat(src.absPath)
+			c.indexFileCore(srcCached.filePath, src.content, info)
+			c.rebuildLinksLocked()
+			return fmt.Errorf("target: %w", err)
+		}
+	}
 	c.rebuildLinksLocked()
 
 	return nil
@@ -1218,15 +1679,30 @@ func findBlockByContent(blocks []types.BlockEntity, content string) *types.Block
 	return nil
 }
 
+// findBlockByUUID searches for the block with the given UUID.
+func findBlockByUUID(blocks []types.BlockEntity, uuid string) *types.BlockEntity {
+	for i := range blocks {
+		if blocks[i].UUID == uuid {
+			return &blocks[i]
+		}
+		if found := findBlockByUUID(blocks[i].Children, uuid); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
 // --- Optional search interfaces ---
 
-// FindBlocksByTag scans all pages for blocks containing the given #tag.
+// FindBlocksByTag scans all pages for blocks containing the given #tag and
+// for pages carrying it in their frontmatter tags. With includeChildren,
+// nested tags such as "project/alpha" also match "project".
 // Implements backend.TagSearcher.
 func (c *Client) FindBlocksByTag(_ context.Context, tag string, includeChildren bool) ([]backend.TagResult, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	tagLower := strings.ToLower(tag)
+	tagLower := strings.ToLower(strings.TrimPrefix(tag, "#"))
 	var results []backend.TagResult
 
 	seen := make(map[string]bool)
@@ -1236,12 +1712,21 @@ func (c *Client) FindBlocksByTag(_ context.Context, tag string, includeChildren
 		}
 		seen[page.lowerName] = true
 
+		pageTagged := false
+		for _, t := range parser.PageTags(page.entity.Properties) {
+			if tagMatches(t, tagLower, includeChildren) {
+				pageTagged = true
+				break
+			}
+		}
+
 		var matches []types.BlockEntity
-		findTagInBlocks(page.blocks, tagLower, &matches)
-		if len(matches) > 0 {
+		findTagInBlocks(page.blocks, tagLower, includeChildren, &matches)
+		if pageTagged || len(matches) > 0 {
 			results = append(results, backend.TagResult{
-				Page:   page.entity.Name,
-				Blocks: matches,
+				Page:       page.entity.Name,
+				PageTagged: pageTagged,
+				Blocks:     matches,
 			})
 		}
 	}
@@ -1249,105 +1734,111 @@ func (c *Client) FindBlocksByTag(_ context.Context, tag string, includeChildren
 	return results, nil
 }
 
+// tagMatches reports whether tag equals tagLower or, with includeChildren,
+// is nested under it ("project/alpha" under "project").
+func tagMatches(tag, tagLower string, includeChildren bool) bool {
+	t := strings.ToLower(tag)
+	return t == tagLower || (includeChildren && strings.HasPrefix(t, tagLower+"/"))
+}
+
 // findTagInBlocks recursively searches blocks for a tag.
-func findTagInBlocks(blocks []types.BlockEntity, tagLower string, matches *[]types.BlockEntity) {
+func findTagInBlocks(blocks []types.BlockEntity, tagLower string, includeChildren bool, matches *[]types.BlockEntity) {
 	for _, b := range blocks {
 		parsed := parser.Parse(b.Content)
 		for _, t := range parsed.Tags {
-			if strings.ToLower(t) == tagLower {
+			if tagMatches(t, tagLower, includeChildren) {
 				*matches = append(*matches, b)
 				break
 			}
 		}
 		if len(b.Children) > 0 {
-			findTagInBlocks(b.Children, tagLower, matches)
+			findTagInBlocks(b.Children, tagLower, includeChildren, matches)
 		}
 	}
 }
 
-// FindByProperty scans all pages for matching frontmatter properties.
+// FindByProperty scans all pages for matching properties: frontmatter and
+// Logseq page properties, and the key:: value properties of every block.
+// A page's pre-block holds its page properties and is not reported again.
 // Implements backend.PropertySearcher.
-func (c *Client) FindByProperty(_ context.Context, key, value, operator string) ([]backend.PropertyResult, error) {
+func (c *Client) FindByProperty(_ context.Context, key, operator string, values []string) ([]backend.PropertyResult, error) {
+	match, err := parser.NewPropertyMatcher(operator, values)
+	if err != nil {
+		return nil, err
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	var results []backend.PropertyResult
-
-	seen := make(map[string]bool)
-	for _, page := range c.pages {
-		if seen[page.lowerName] {
-			continue
+	for lowerName, page := range c.pages {
+		if lowerName != page.lowerName {
+			continue // alias entry
 		}
-		seen[page.lowerName] = true
-
-		if page.entity.Properties == nil {
-			continue
-		}
-
-		propVal, ok := page.entity.Properties[key]
-		if !ok {
-			continue
-		}
-
-		if value == "" {
-			// Just checking if property exists.
+		if v, ok := page.entity.Properties[key]; ok && match.Match(v) {
 			results = append(results, backend.PropertyResult{
 				Type:       "page",
 				Name:       page.entity.Name,
 				Properties: page.entity.Properties,
 			})
-			continue
 		}
+		results = findPropertyInBlocks(page.blocks, page.entity.Name, key, match, results)
+	}
 
-		propStr := fmt.Sprint(propVal)
-		match := false
-		switch operator {
-		case "eq", "":
-			match = strings.EqualFold(propStr, value)
-		case "contains":
-			match = strings.Contains(strings.ToLower(propStr), strings.ToLower(value))
-		case "gt":
-			match = propStr > value
-		case "lt":
-			match = propStr < value
-		}
+	// Pages by name, each followed by its blocks in document order.
+	slices.SortStableFunc(results, func(a, b backend.PropertyResult) int {
+		return strings.Compare(strings.ToLower(a.Name+a.Page), strings.ToLower(b.Name+b.Page))
+	})
+	return results, nil
+}
 
-		if match {
-			results = append(results, backend.PropertyResult{
-				Type:       "page",
-				Name:       page.entity.Name,
-				Properties: page.entity.Properties,
-			})
+// findPropertyInBlocks appends the blocks of a page tree whose property
+// key matches.
+func findPropertyInBlocks(blocks []types.BlockEntity, pageName, key string, match *parser.PropertyMatcher, results []backend.PropertyResult) []backend.PropertyResult {
+	for i := range blocks {
+		b := &blocks[i]
+		if !b.PreBlock {
+			props := blockProperties(b, parser.Parse(b.Content))
+			if v, ok := props[key]; ok && match.Match(v) {
+				results = append(results, backend.PropertyResult{
+					Type:       "block",
+					UUID:       b.UUID,
+					Content:    b.Content,
+					Page:       pageName,
+					Properties: props,
+				})
+			}
 		}
+		results = findPropertyInBlocks(b.Children, pageName, key, match, results)
 	}
-
-	return results, nil
+	return results
 }
 
-// SearchJournals scans daily notes for matching content.
+// SearchJournals finds journal blocks matching query, ranked with the
+// search index: pages come in the order of their best hit, each page's
+// blocks best first. An empty query returns every block in the date range.
 // Implements backend.JournalSearcher.
 func (c *Client) SearchJournals(_ context.Context, query, from, to string) ([]backend.JournalResult, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	queryLower := strings.ToLower(query)
-	var results []backend.JournalResult
-
-	seen := make(map[string]bool)
-	for _, page := range c.pages {
-		if seen[page.lowerName] {
+	// Journal pages in the date range, with their dates.
+	dates := make(map[string]string)
+	for key, page := range c.pages {
+		if key != page.lowerName || !page.entity.Journal {
 			continue
 		}
-		seen[page.lowerName] = true
 
-		if !page.entity.Journal {
-			continue
+		// Prefer the parsed journal day; otherwise use the last path
+		// segment of the page name.
+		var date string
+		if day := page.entity.JournalDay; day != 0 {
+			date = fmt.Sprintf("%04d-%02d-%02d", day/10000, day/100%100, day%100)
+		} else {
+			parts := strings.Split(page.entity.Name, "/")
+			date = parts[len(parts)-1]
 		}
 
-		// Extract date from page name (last path segment).
-		parts := strings.Split(page.entity.Name, "/")
-		date := parts[len(parts)-1]
-
 		// Filter by date range.
 		if from != "" && date < from {
 			continue
@@ -1355,19 +1846,50 @@ func (c *Client) SearchJournals(_ context.Context, query, from, to string) ([]ba
 		if to != "" && date > to {
 			continue
 		}
+		dates[page.lowerName] = date
+	}
 
-		// Search blocks for query.
-		var matches []types.BlockEntity
-		searchBlocksForText(page.blocks, queryLower, &matches)
-		if len(matches) > 0 {
+	var results []backend.JournalResult
+	if strings.TrimSpace(query) == "" || c.searchIndex == nil {
+		queryLower := strings.ToLower(query)
+		for lowerName, date := range dates {
+			page := c.pages[lowerName]
+			var matches []types.BlockEntity
+			searchBlocksForText(page.blocks, queryLower, &matches)
+			if len(matches) > 0 {
+				results = append(results, backend.JournalResult{
+					Date:   date,
+					Page:   page.entity.Name,
+					Blocks: matches,
+				})
+			}
+		}
+		return results, nil
+	}
+
+	hits := c.searchIndex.SearchPages(query, 0, func(lowerName string) bool {
+		_, ok := dates[lowerName]
+		return ok
+	})
+	byPage := make(map[string]int)
+	for _, hit := range hits {
+		lookup, ok := c.blockIndex[hit.UUID]
+		if !ok {
+			continue
+		}
+		lowerName := strings.ToLower(hit.PageName)
+		i, ok := byPage[lowerName]
+		if !ok {
+			i = len(results)
+			byPage[lowerName] = i
 			results = append(results, backend.JournalResult{
-				Date:   date,
-				Page:   page.entity.Name,
-				Blocks: matches,
+				Date: dates[lowerName],
+				Page: hit.PageName,
 			})
 		}
+		results[i].Blocks = append(results[i].Blocks, *lookup.block)
+		results[i].Scores = append(results[i].Scores, hit.Score)
 	}
-
 	return results, nil
 }
 
@@ -1386,11 +1908,25 @@ func (c *Client) FullTextSearch(_ context.Context, query string, limit int) ([]b
 			PageName: r.PageName,
 			UUID:     r.UUID,
 			Content:  r.Content,
+			Score:    r.Score,
 		}
 	}
 	return hits, nil
 }
 
+// ResolvePageName maps a link target to its canonical page name using
+// Obsidian's shortest-unique-path rules. Implements backend.PageResolver.
+func (c *Client) ResolvePageName(_ context.Context, name string) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	page, err := c.resolvePageLocked(name)
+	if err != nil || page == nil {
+		return "", err
+	}
+	return page.entity.Name, nil
+}
+
 // searchBlocksForText recursively searches blocks for text content.
 func searchBlocksForText(blocks []types.BlockEntity, queryLower string, matches *[]types.BlockEntity) {
 	for _, b := range blocks {
@@ -3,16 +3,19 @@ package vault
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/skridlevsky/graphthulhu/backend"
+	"github.com/skridlevsky/graphthulhu/graph"
 	"github.com/skridlevsky/graphthulhu/types"
 )
 
@@ -188,6 +191,115 @@ func TestBacklinks(t *testing.T) {
 	}
 }
 
+func TestBacklinksObsidianLinkForms(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	c.AppendBlockInPage(ctx, "aliased", "Read [[people/Hanna|Hanna]] and [[people/Hanna#Hanna]]")
+	c.AppendBlockInPage(ctx, "embedder", "![[people/Hanna]]")
+
+	raw, err := c.GetPageLinkedReferences(ctx, "people/Hanna")
+	if err != nil {
+		t.Fatalf("GetPageLinkedReferences: %v", err)
+	}
+	var refs [][]json.RawMessage
+	if err := json.Unmarshal(raw, &refs); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	sources := make(map[string]bool)
+	for _, ref := range refs {
+		var page struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(ref[0], &page)
+		sources[page.Name] = true
+	}
+	if !sources["aliased"] || !sources["embedder"] {
+		t.Errorf("backlink sources = %v, want aliased and embedder", sources)
+	}
+
+	// No phantom targets built from the decorated link text.
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for key := range c.backlinks {
+		if strings.ContainsAny(key, "|#") {
+			t.Errorf("phantom backlink target %q", key)
+		}
+	}
+}
+
+func TestBacklinksMarkdownLinks(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	if _, err := c.AppendBlockInPage(ctx, "projects/roadmap", "Owner: [Hanna](../people/Hanna.md)"); err != nil {
+		t.Fatalf("AppendBlockInPage: %v", err)
+	}
+
+	raw, _ := c.GetPageLinkedReferences(ctx, "people/Hanna")
+	if !strings.Contains(string(raw), "projects/roadmap") {
+		t.Errorf("expected projects/roadmap in backlinks, got %s", raw)
+	}
+}
+
+func TestResolveShortestPath(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	// A unique basename resolves to the full path, as in Obsidian.
+	page, err := c.GetPage(ctx, "Hanna")
+	if err != nil || page == nil || page.Name != "people/Hanna" {
+		t.Fatalf("GetPage(Hanna) = %v, %v; want people/Hanna", page, err)
+	}
+
+	// A second Hanna makes the basename ambiguous; longer suffixes still work.
+	if _, err := c.CreatePage(ctx, "archive/Hanna", nil, nil); err != nil {
+		t.Fatalf("CreatePage: %v", err)
+	}
+	if _, err := c.GetPage(ctx, "Hanna"); !errors.Is(err, ErrAmbiguousPage) {
+		t.Errorf("GetPage(Hanna) error = %v, want ErrAmbiguousPage", err)
+	}
+	if _, err := c.GetPageBlocksTree(ctx, "hanna"); !errors.Is(err, ErrAmbiguousPage) {
+		t.Errorf("GetPageBlocksTree(hanna) error = %v, want ErrAmbiguousPage", err)
+	}
+	if page, _ := c.GetPage(ctx, "archive/hanna"); page == nil || page.Name != "archive/Hanna" {
+		t.Errorf("GetPage(archive/hanna) = %v, want archive/Hanna", page)
+	}
+
+	name, err := c.ResolvePageName(ctx, "graphthulhu-mcp")
+	if err != nil || name != "projects/graphthulhu" {
+		t.Errorf("ResolvePageName(alias) = %q, %v", name, err)
+	}
+	if name, err := c.ResolvePageName(ctx, "nonexistent"); err != nil || name != "" {
+		t.Errorf("ResolvePageName(nonexistent) = %q, %v; want empty", name, err)
+	}
+}
+
+func TestBacklinksBasenameLinks(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	// projects/openchaos links [[graphthulhu]] and [[Hanna]] by basename.
+	raw, _ := c.GetPageLinkedReferences(ctx, "projects/graphthulhu")
+	if !strings.Contains(string(raw), "projects/openchaos") {
+		t.Errorf("expected projects/openchaos in backlinks, got %s", raw)
+	}
+	raw, _ = c.GetPageLinkedReferences(ctx, "Hanna")
+	if !strings.Contains(string(raw), "projects/openchaos") {
+		t.Errorf("expected backlinks via basename lookup, got %s", raw)
+	}
+
+	// Once ambiguous, [[Hanna]] is credited to neither page.
+	c.CreatePage(ctx, "archive/Hanna", nil, nil)
+	raw, _ = c.GetPageLinkedReferences(ctx, "people/Hanna")
+	if strings.Contains(string(raw), "projects/openchaos") {
+		t.Errorf("ambiguous [[Hanna]] should not resolve to people/Hanna, got %s", raw)
+	}
+	if _, err := c.GetPageLinkedReferences(ctx, "Hanna"); !errors.Is(err, ErrAmbiguousPage) {
+		t.Errorf("GetPageLinkedReferences(Hanna) error = %v, want ErrAmbiguousPage", err)
+	}
+}
+
 func TestJournalPages(t *testing.T) {
 	c := testVault(t)
 	ctx := context.Background()
@@ -204,6 +316,28 @@ func TestJournalPages(t *testing.T) {
 	}
 }
 
+func TestJournalDayOnlyInDailyFolder(t *testing.T) {
+	dir := t.TempDir()
+	for _, rel := range []string{"daily notes/2026-10-16.md", "archive/2026-10-15.md"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(rel)), 0o755)
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte("- note\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := New(dir)
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if page, _ := c.GetPage(ctx, "daily notes/2026-10-16"); page == nil || !page.Journal || page.JournalDay != 20261016 {
+		t.Errorf("daily note = %+v", page)
+	}
+	if page, _ := c.GetPage(ctx, "archive/2026-10-15"); page == nil || page.Journal || page.JournalDay != 0 {
+		t.Errorf("dated page outside the daily notes folder = %+v", page)
+	}
+}
+
 func TestPing(t *testing.T) {
 	c := testVault(t)
 	if err := c.Ping(context.Background()); err != nil {
@@ -404,6 +538,37 @@ func TestRemoveBlock(t *testing.T) {
 	}
 }
 
+func TestWriteChecksIfMatch(t *testing.T) {
+	c := testOutlineVault(t, "- First\n- Second\n")
+	blocks := outlineBlocks(c)
+	first, second := blocks["First"], blocks["Second"]
+	ctx := context.Background()
+
+	// The precondition is checked by the write itself, against the file.
+	stale := backend.WithIfMatch(ctx, first.UUID, backend.BlockVersion("something else"))
+	if err := c.UpdateBlock(stale, first.UUID, "overwritten"); !errors.Is(err, backend.ErrConflict) {
+		t.Errorf("UpdateBlock with a stale version = %v", err)
+	}
+	if err := c.RemoveBlock(stale, first.UUID); !errors.Is(err, backend.ErrConflict) {
+		t.Errorf("RemoveBlock with a stale version = %v", err)
+	}
+	if err := c.MoveBlock(stale, first.UUID, second.UUID, nil); !errors.Is(err, backend.ErrConflict) {
+		t.Errorf("MoveBlock with a stale version = %v", err)
+	}
+	if b, _ := c.GetBlock(ctx, first.UUID); b == nil || b.Content != first.Content {
+		t.Fatalf("block after stale writes = %+v", b)
+	}
+
+	// It only applies to its own block.
+	if err := c.UpdateBlock(stale, second.UUID, "second, updated"); err != nil {
+		t.Errorf("UpdateBlock of another block: %v", err)
+	}
+	current := backend.WithIfMatch(ctx, first.UUID, backend.BlockVersion(first.Content))
+	if err := c.UpdateBlock(current, first.UUID, "first, updated"); err != nil {
+		t.Errorf("UpdateBlock with the current version: %v", err)
+	}
+}
+
 func TestDeletePage(t *testing.T) {
 	c := testWritableVault(t)
 	ctx := context.Background()
@@ -490,6 +655,139 @@ func TestRenamePageUpdatesLinks(t *testing.T) {
 	}
 }
 
+func TestRenamePageTrailingHashWithAlias(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	c.CreatePage(ctx, "C#", nil, nil)
+	c.AppendBlockInPage(ctx, "linker", "Learning [[C#|x]] and [[C#]]")
+	if err := c.RenamePage(ctx, "C#", "CSharp"); err != nil {
+		t.Fatalf("RenamePage: %v", err)
+	}
+	blocks, _ := c.GetPageBlocksTree(ctx, "linker")
+	if len(blocks) == 0 || blocks[0].Content != "Learning [[CSharp|x]] and [[CSharp]]" {
+		t.Errorf("linker = %+v", blocks)
+	}
+}
+
+func TestRenamePageRewritesResolvedLinks(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	c.AppendBlockInPage(ctx, "notes/linker",
+		"[[projects/graphthulhu|the tool]], [[projects/graphthulhu#Overview]], [[graphthulhu-mcp]] and [md](../projects/graphthulhu.md)")
+
+	if err := c.RenamePage(ctx, "graphthulhu", "tools/gt"); err != nil {
+		t.Fatalf("RenamePage by basename: %v", err)
+	}
+
+	read := func(rel string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(c.vaultPath, rel))
+		if err != nil {
+			t.Fatalf("read %s: %v", rel, err)
+		}
+		return string(data)
+	}
+
+	linker := read("notes/linker.md")
+	for _, want := range []string{"[[tools/gt|the tool]]", "[[tools/gt#Overview]]", "[[graphthulhu-mcp]]", "[md](../tools/gt.md)"} {
+		if !strings.Contains(linker, want) {
+			t.Errorf("linker missing %q:\n%s", want, linker)
+		}
+	}
+
+	// The basename link keeps its short form.
+	if chaos := read("projects/openchaos.md"); !strings.Contains(chaos, "[[gt]]") {
+		t.Errorf("expected [[gt]] in openchaos, got:\n%s", chaos)
+	}
+
+	raw, _ := c.GetPageLinkedReferences(ctx, "tools/gt")
+	if !strings.Contains(string(raw), "notes/linker") || !strings.Contains(string(raw), "projects/openchaos") {
+		t.Errorf("backlinks after rename = %s", raw)
+	}
+}
+
+func TestPlanRenamePage(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+	c.AppendBlockInPage(ctx, "notes/linker", "[[projects/graphthulhu|the tool]] and [md](../projects/graphthulhu.md)")
+
+	snapshot := func() map[string]string {
+		files := map[string]string{}
+		filepath.WalkDir(c.vaultPath, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				data, _ := os.ReadFile(path)
+				rel, _ := filepath.Rel(c.vaultPath, path)
+				files[filepath.ToSlash(rel)] = string(data)
+			}
+			return nil
+		})
+		return files
+	}
+	before := snapshot()
+
+	plan, err := c.PlanRenamePage(ctx, "graphthulhu", "tools/gt")
+	if err != nil {
+		t.Fatalf("PlanRenamePage: %v", err)
+	}
+	if !reflect.DeepEqual(snapshot(), before) {
+		t.Fatal("planning a rename changed files")
+	}
+	if plan.FromFile != "projects/graphthulhu.md" || plan.ToFile != "tools/gt.md" {
+		t.Errorf("plan moves %s to %s", plan.FromFile, plan.ToFile)
+	}
+
+	// Every planned line is exactly what the rename writes, and every file
+	// the rename rewrites is in the plan.
+	if err := c.RenamePage(ctx, "graphthulhu", "tools/gt"); err != nil {
+		t.Fatalf("RenamePage: %v", err)
+	}
+	after := snapshot()
+	planned := map[string]bool{}
+	for _, l := range plan.Links {
+		planned[l.File] = true
+		lines := strings.Split(after[l.File], "\n")
+		if l.Line < 1 || l.Line > len(lines) || lines[l.Line-1] != l.After {
+			t.Errorf("%s:%d planned %q, rename wrote %q", l.File, l.Line, l.After, lines[l.Line-1])
+		}
+		if old := strings.Split(before[l.File], "\n"); old[l.Line-1] != l.Before {
+			t.Errorf("%s:%d planned before %q, was %q", l.File, l.Line, l.Before, old[l.Line-1])
+		}
+	}
+	for file, content := range after {
+		if file != plan.ToFile && before[file] != content && !planned[file] {
+			t.Errorf("rename rewrote %s, which the plan left out", file)
+		}
+	}
+	if !planned["notes/linker.md"] || !planned["projects/openchaos.md"] {
+		t.Errorf("plan misses link rewrites: %+v", plan.Links)
+	}
+
+	if _, err := c.PlanRenamePage(ctx, "tools/gt", "index"); err == nil {
+		t.Error("expected error when planning a rename onto an existing page")
+	}
+}
+
+func TestRenamePageAmbiguous(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	c.CreatePage(ctx, "archive/Hanna", nil, nil)
+	if err := c.RenamePage(ctx, "Hanna", "Hanna Arendt"); !errors.Is(err, ErrAmbiguousPage) {
+		t.Errorf("RenamePage(Hanna) error = %v, want ErrAmbiguousPage", err)
+	}
+
+	// Renaming to a basename another page already has falls back to full paths.
+	if err := c.RenamePage(ctx, "projects/graphthulhu", "tools/Hanna"); err != nil {
+		t.Fatalf("RenamePage: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(c.vaultPath, "projects/openchaos.md"))
+	if !strings.Contains(string(data), "[[tools/Hanna]]") {
+		t.Errorf("expected full-path link when basename is taken, got:\n%s", data)
+	}
+}
+
 func TestPrependBlockInPage(t *testing.T) {
 	c := testWritableVault(t)
 	ctx := context.Background()
@@ -512,14 +810,6 @@ func TestPrependBlockInPage(t *testing.T) {
 	}
 }
 
-func TestDatascriptQueryNotSupported(t *testing.T) {
-	c := testVault(t)
-	_, err := c.DatascriptQuery(context.Background(), "[:find ...]")
-	if err != ErrNotSupported {
-		t.Errorf("DatascriptQuery: %v, want ErrNotSupported", err)
-	}
-}
-
 func TestFindBlocksByTag(t *testing.T) {
 	c := testVault(t)
 	ctx := context.Background()
@@ -541,11 +831,104 @@ func TestFindBlocksByTag(t *testing.T) {
 	}
 }
 
+func TestFindBlocksByTagFrontmatter(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	results, err := c.FindBlocksByTag(ctx, "#MCP", false)
+	if err != nil {
+		t.Fatalf("FindBlocksByTag: %v", err)
+	}
+	if len(results) != 1 || results[0].Page != "projects/graphthulhu" || !results[0].PageTagged {
+		t.Fatalf("results = %+v, want projects/graphthulhu tagged at page level", results)
+	}
+
+	// Nested tags match their parent only with includeChildren.
+	c.CreatePage(ctx, "alpha", map[string]any{"tags": "project/alpha"}, nil)
+	c.AppendBlockInPage(ctx, "beta", "Kickoff #project/beta")
+	results, _ = c.FindBlocksByTag(ctx, "project", false)
+	if len(results) != 0 {
+		t.Errorf("exact match should not include nested tags, got %+v", results)
+	}
+	results, _ = c.FindBlocksByTag(ctx, "project", true)
+	pages := make(map[string]bool)
+	for _, r := range results {
+		pages[r.Page] = true
+	}
+	if !pages["alpha"] || !pages["beta"] {
+		t.Errorf("includeChildren results = %v, want alpha and beta", pages)
+	}
+}
+
+func TestAliasForms(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	c.CreatePage(ctx, "tools/thulhu", map[string]any{"alias": "gt, thulhu-cli"}, nil)
+	c.AppendBlockInPage(ctx, "notes", "Ran [[thulhu-cli]] and [[gt|the tool]]")
+
+	for _, name := range []string{"gt", "THULHU-CLI"} {
+		if page, _ := c.GetPage(ctx, name); page == nil || page.Name != "tools/thulhu" {
+			t.Errorf("GetPage(%q) = %v, want tools/thulhu", name, page)
+		}
+	}
+	raw, _ := c.GetPageLinkedReferences(ctx, "tools/thulhu")
+	if !strings.Contains(string(raw), "notes") {
+		t.Errorf("alias links should count as backlinks, got %s", raw)
+	}
+
+	// An alias never shadows a real page.
+	c.CreatePage(ctx, "index-alias", map[string]any{"aliases": []any{"index"}}, nil)
+	if page, _ := c.GetPage(ctx, "index"); page == nil || page.Name != "index" {
+		t.Errorf("GetPage(index) = %v, want the real index page", page)
+	}
+
+	// Dropping an alias from frontmatter stops it resolving.
+	path := filepath.Join(c.vaultPath, "tools", "thulhu.md")
+	os.WriteFile(path, []byte("---\nalias: gt\n---\nbody\n"), 0o644)
+	info, _ := os.Stat(path)
+	c.mu.Lock()
+	c.indexFileCore(filepath.Join("tools", "thulhu.md"), "---\nalias: gt\n---\nbody\n", info)
+	c.rebuildLinksLocked()
+	c.mu.Unlock()
+	if page, _ := c.GetPage(ctx, "thulhu-cli"); page != nil {
+		t.Errorf("removed alias still resolves to %s", page.Name)
+	}
+	if page, _ := c.GetPage(ctx, "gt"); page == nil {
+		t.Error("kept alias no longer resolves")
+	}
+}
+
+func TestRealPageTakesAliasKey(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	c.CreatePage(ctx, "Bee", map[string]any{"aliases": []any{"Ay"}}, nil)
+	block, err := c.AppendBlockInPage(ctx, "Bee", "kept")
+	if err != nil || block == nil {
+		t.Fatalf("AppendBlockInPage: %v", err)
+	}
+
+	// Ay.md claims the key Bee held as an alias; Bee's blocks stay indexed.
+	if err := c.WritePageFile(ctx, "Ay.md", "- x\n"); err != nil {
+		t.Fatalf("WritePageFile: %v", err)
+	}
+	if got, _ := c.GetBlock(ctx, block.UUID); got == nil {
+		t.Error("Bee's block no longer resolves after Ay.md was created")
+	}
+	if page, _ := c.GetPage(ctx, "Ay"); page == nil || page.Name != "Ay" {
+		t.Errorf("GetPage(Ay) = %v, want the real page", page)
+	}
+	if page, _ := c.GetPage(ctx, "Bee"); page == nil {
+		t.Error("Bee no longer resolves")
+	}
+}
+
 func TestFindByProperty(t *testing.T) {
 	c := testVault(t)
 	ctx := context.Background()
 
-	results, err := c.FindByProperty(ctx, "type", "project", "eq")
+	results, err := c.FindByProperty(ctx, "type", "eq", []string{"project"})
 	if err != nil {
 		t.Fatalf("FindByProperty: %v", err)
 	}
@@ -558,6 +941,67 @@ func TestFindByProperty(t *testing.T) {
 	}
 }
 
+func TestFindByPropertyTyped(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"A.md": "---\npriority: 9\ndue: 2026-03-01\ntags: [go, mcp]\ndone: false\n---\n- effort:: 3\n- plain\n",
+		"B.md": "---\npriority: 10\ndue: 2026-02-15\ntags: [rust]\ndone: true\n---\n- effort:: 12\n",
+		"C.md": "---\npriority: high\n---\n- owner:: [[Hanna]]\n",
+	} {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+	}
+	c := New(dir)
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	find := func(key, operator string, values ...string) string {
+		t.Helper()
+		results, err := c.FindByProperty(ctx, key, operator, values)
+		if err != nil {
+			t.Fatalf("%s %s %v: %v", key, operator, values, err)
+		}
+		var got []string
+		for _, r := range results {
+			if r.Type == "page" {
+				got = append(got, r.Name)
+			} else {
+				got = append(got, r.Page+":"+r.Content)
+			}
+		}
+		return strings.Join(got, ",")
+	}
+	for _, tt := range []struct {
+		key, operator string
+		values        []string
+		want          string
+	}{
+		{"priority", "gt", []string{"9"}, "B"}, // numeric: 10 > 9, "high" is not a number
+		{"priority", "lte", []string{"9"}, "A"},
+		{"priority", "eq", []string{"HIGH"}, "C"},
+		{"due", "lt", []string{"2026-02-20"}, "B"},
+		{"due", "between", []string{"2026-02-01", "2026-03-01"}, "A,B"},
+		{"tags", "eq", []string{"mcp"}, "A"},
+		{"tags", "in", []string{"rust", "zig"}, "B"},
+		{"done", "eq", []string{"true"}, "B"},
+		{"priority", "exists", nil, "A,B,C"},
+		{"priority", "regex", []string{`^\d+$`}, "A,B"},
+		{"effort", "gte", []string{"5"}, "B:effort:: 12"}, // block properties
+		{"owner", "eq", []string{"Hanna"}, "C:owner:: [[Hanna]]"},
+	} {
+		if got := find(tt.key, tt.operator, tt.values...); got != tt.want {
+			t.Errorf("%s %s %v = %q, want %q", tt.key, tt.operator, tt.values, got, tt.want)
+		}
+	}
+
+	for _, bad := range [][]string{{"between", "1"}, {"regex", "("}, {"near", "1"}} {
+		if _, err := c.FindByProperty(ctx, "priority", bad[0], bad[1:]); err == nil {
+			t.Errorf("accepted %v", bad)
+		}
+	}
+}
+
 func TestSearchJournals(t *testing.T) {
 	c := testVault(t)
 	ctx := context.Background()
@@ -601,6 +1045,22 @@ func TestGraphBuildFromVault(t *testing.T) {
 	}
 }
 
+func TestGraphBuildResolvesBasenameLinks(t *testing.T) {
+	c := testVault(t)
+
+	g, err := graph.Build(context.Background(), c)
+	if err != nil {
+		t.Fatalf("graph.Build: %v", err)
+	}
+	// [[graphthulhu]] in projects/openchaos is an edge to projects/graphthulhu.
+	if !g.Backward["projects/graphthulhu"]["projects/openchaos"] {
+		t.Errorf("missing openchaos → graphthulhu edge, backward = %v", g.Backward["projects/graphthulhu"])
+	}
+	if _, ok := g.Backward["graphthulhu"]; ok {
+		t.Error("basename link should not create a separate graphthulhu node")
+	}
+}
+
 func TestReload(t *testing.T) {
 	c := testWritableVault(t)
 	ctx := context.Background()
@@ -711,6 +1171,28 @@ func TestWatchFileModify(t *testing.T) {
 	}
 }
 
+func TestPageByFile(t *testing.T) {
+	c := testWritableVault(t)
+	ctx := context.Background()
+
+	c.mu.RLock()
+	page := c.pageByFileLocked("index.md")
+	c.mu.RUnlock()
+	if page == nil || page.lowerName != "index" {
+		t.Fatalf("pageByFileLocked(index.md) = %+v", page)
+	}
+
+	if err := c.DeletePage(ctx, "index"); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.RLock()
+	page = c.pageByFileLocked("index.md")
+	c.mu.RUnlock()
+	if page != nil {
+		t.Errorf("deleted page still found by file: %+v", page)
+	}
+}
+
 func TestWatchFileDelete(t *testing.T) {
 	c := testWritableVault(t)
 	ctx := context.Background()
@@ -1127,6 +1609,191 @@ func TestInsertBlockEmbedsUUID(t *testing.T) {
 	}
 }
 
+// testOutlineVault returns a writable vault with an outline page "outline".
+func testOutlineVault(t *testing.T, body string) *Client {
+	t.Helper()
+	c := testWritableVault(t)
+	if err := os.WriteFile(filepath.Join(c.vaultPath, "outline.md"), []byte(body), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := c.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	return c
+}
+
+// outlineBlocks returns the outline page's blocks keyed by content. Blocks
+// without an embedded UUID are keyed by line, so re-fetch after each write.
+func outlineBlocks(c *Client) map[string]types.BlockEntity {
+	blocks, _ := c.GetPageBlocksTree(context.Background(), "outline")
+	byContent := make(map[string]types.BlockEntity)
+	var walk func([]types.BlockEntity)
+	walk = func(bs []types.BlockEntity) {
+		for _, b := range bs {
+			byContent[b.Content] = b
+			walk(b.Children)
+		}
+	}
+	walk(blocks)
+	return byContent
+}
+
+// readOutlineBody returns the outline page's file with UUID comments removed.
+func readOutlineBody(t *testing.T, c *Client) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(c.vaultPath, "outline.md"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	return uuidCommentPattern.ReplaceAllString(string(data), "")
+}
+
+func TestOutlineUpdateBlock(t *testing.T) {
+	c := testOutlineVault(t, "- Parent\n  - Child\n    - Grandchild\n- Other\n")
+	blocks := outlineBlocks(c)
+	ctx := context.Background()
+
+	child := blocks["Child"]
+	if err := c.UpdateBlock(ctx, child.UUID, "Renamed\nsecond line"); err != nil {
+		t.Fatalf("UpdateBlock: %v", err)
+	}
+
+	want := "- Parent\n  - Renamed\n    second line\n    - Grandchild\n- Other\n"
+	if got := readOutlineBody(t, c); got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+	got, _ := c.GetBlock(ctx, child.UUID)
+	if got == nil || got.Content != "Renamed\nsecond line" || len(got.Children) != 1 {
+		t.Errorf("updated block = %+v", got)
+	}
+}
+
+func TestOutlineInsertBlock(t *testing.T) {
+	c := testOutlineVault(t, "- Parent\n\t- Child\n- Other\n")
+	blocks := outlineBlocks(c)
+	ctx := context.Background()
+
+	created, err := c.InsertBlock(ctx, blocks["Parent"].UUID, "New child", nil)
+	if err != nil {
+		t.Fatalf("InsertBlock: %v", err)
+	}
+	blocks = outlineBlocks(c)
+	if _, err := c.InsertBlock(ctx, blocks["Other"].UUID, "Last", map[string]any{"sibling": true}); err != nil {
+		t.Fatalf("InsertBlock sibling: %v", err)
+	}
+
+	want := "- Parent\n\t- Child\n\t- New child\n- Other\n- Last\n"
+	if got := readOutlineBody(t, c); got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+	tree, _ := c.GetPageBlocksTree(ctx, "outline")
+	if len(tree[0].Children) != 2 || tree[0].Children[1].UUID != created.UUID {
+		t.Errorf("new child not nested under parent: %+v", tree[0].Children)
+	}
+}
+
+func TestOutlineRemoveBlock(t *testing.T) {
+	c := testOutlineVault(t, "- A\n  - A1\n    - A2\n- B\n")
+	blocks := outlineBlocks(c)
+	ctx := context.Background()
+
+	if err := c.RemoveBlock(ctx, blocks["A"].UUID); err != nil {
+		t.Fatalf("RemoveBlock: %v", err)
+	}
+	if got := readOutlineBody(t, c); got != "- B\n" {
+		t.Errorf("file = %q", got)
+	}
+	if b, _ := c.GetBlock(ctx, blocks["A2"].UUID); b != nil {
+		t.Error("removed grandchild still indexed")
+	}
+}
+
+func TestOutlineMoveBlock(t *testing.T) {
+	c := testOutlineVault(t, "- A\n  - A1\n    - A2\n- B\n- C\n")
+	blocks := outlineBlocks(c)
+	ctx := context.Background()
+
+	// A1 (with A2) becomes a child of C.
+	if err := c.MoveBlock(ctx, blocks["A1"].UUID, blocks["C"].UUID, map[string]any{"children": true}); err != nil {
+		t.Fatalf("MoveBlock children: %v", err)
+	}
+	want := "- A\n- B\n- C\n  - A1\n    - A2\n"
+	if got := readOutlineBody(t, c); got != want {
+		t.Errorf("after child move file = %q, want %q", got, want)
+	}
+
+	// C (with its subtree) goes before A.
+	blocks = outlineBlocks(c)
+	if err := c.MoveBlock(ctx, blocks["C"].UUID, blocks["A"].UUID, map[string]any{"before": true}); err != nil {
+		t.Fatalf("MoveBlock before: %v", err)
+	}
+	want = "- C\n  - A1\n    - A2\n- A\n- B\n"
+	if got := readOutlineBody(t, c); got != want {
+		t.Errorf("after before move file = %q, want %q", got, want)
+	}
+
+	// UUIDs survive the moves.
+	tree, _ := c.GetPageBlocksTree(ctx, "outline")
+	if tree[0].UUID != blocks["C"].UUID || tree[0].Children[0].UUID != blocks["A1"].UUID {
+		t.Errorf("UUIDs not preserved: %+v", tree)
+	}
+
+	// A block cannot move into its own subtree.
+	blocks = outlineBlocks(c)
+	if err := c.MoveBlock(ctx, blocks["C"].UUID, blocks["A1"].UUID, map[string]any{"children": true}); err == nil {
+		t.Error("expected error moving block into its own children")
+	}
+}
+
+func TestOutlineMoveBlockAcrossPages(t *testing.T) {
+	c := testOutlineVault(t, "- A\n- B\n")
+	ctx := context.Background()
+	other, err := c.AppendBlockInPage(ctx, "other", "Target")
+	if err != nil {
+		t.Fatalf("AppendBlockInPage: %v", err)
+	}
+	blocks := outlineBlocks(c)
+
+	// A failed target write leaves the source page as it was.
+	blocker := filepath.Join(c.vaultPath, "other.md.tmp")
+	if err := os.Mkdir(blocker, 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := c.MoveBlock(ctx, blocks["A"].UUID, other.UUID, nil); err == nil {
+		t.Fatal("expected the target write to fail")
+	}
+	if got := readOutlineBody(t, c); got != "- A\n- B\n" {
+		t.Errorf("source after failed move = %q, want it unchanged", got)
+	}
+	os.Remove(blocker)
+
+	if err := c.MoveBlock(ctx, blocks["A"].UUID, other.UUID, nil); err != nil {
+		t.Fatalf("MoveBlock: %v", err)
+	}
+	if got := readOutlineBody(t, c); got != "- B\n" {
+		t.Errorf("source after move = %q", got)
+	}
+	if b, _ := c.GetBlock(ctx, blocks["A"].UUID); b == nil || b.Page == nil || b.Page.Name != "other" {
+		t.Errorf("moved block = %+v, want it on other", b)
+	}
+}
+
+func TestOutlineAppendBlock(t *testing.T) {
+	c := testOutlineVault(t, "- A\n  - A1\n")
+	ctx := context.Background()
+
+	if _, err := c.AppendBlockInPage(ctx, "outline", "Appended"); err != nil {
+		t.Fatalf("AppendBlockInPage: %v", err)
+	}
+	if _, err := c.PrependBlockInPage(ctx, "outline", "Prepended"); err != nil {
+		t.Fatalf("PrependBlockInPage: %v", err)
+	}
+	want := "- Prepended\n- A\n  - A1\n- Appended\n"
+	if got := readOutlineBody(t, c); got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+}
+
 func TestSafePath_Traversal(t *testing.T) {
 	dir := t.TempDir()
 	vc := New(dir)
@@ -0,0 +1,35 @@
+// Package vaulttest builds small vaults for the tests of the packages that
+// serve them.
+package vaulttest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skridlevsky/graphthulhu/vault"
+)
+
+// New writes files, keyed by vault-relative path, to a temporary directory
+// and returns a client indexing it, with backlinks built, and the directory.
+// The client is closed when the test ends.
+func New(t testing.TB, files map[string]string, opts ...vault.Option) (*vault.Client, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := vault.New(dir, opts...)
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	c.BuildBacklinks()
+	t.Cleanup(func() { c.Close() })
+	return c, dir
+}
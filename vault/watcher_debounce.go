@@ -1,11 +1,13 @@
 package vault
 
 import (
+	"context"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
+
+	"github.com/skridlevsky/graphthulhu/backend"
 )
 
 // defaultDebounceDelay is how long we wait before resolving an fsnotify
@@ -67,10 +69,9 @@ func (c *Client) resolveFileState(absPath, relPath string) {
 	info, err := os.Stat(absPath)
 	if err != nil {
 		// File absent (real delete or unresolved rename). Remove for good.
-		name := strings.TrimSuffix(filepath.ToSlash(relPath), ".md")
-		lowerName := strings.ToLower(name)
-		c.removePageWithLinks(lowerName)
+		changes := c.removeFileWithLinks(filepath.ToSlash(relPath))
 		log.Printf("graphthulhu: removed %s from index\n", relPath)
+		c.notifyPageChanges(changes)
 		return
 	}
 	content, err := os.ReadFile(absPath)
@@ -78,30 +79,82 @@ func (c *Client) resolveFileState(absPath, relPath string) {
 		log.Printf("graphthulhu: failed to read %s: %v\n", absPath, err)
 		return
 	}
-	c.indexFileWithLinks(filepath.ToSlash(relPath), string(content), info)
+	changes := c.indexFileWithLinks(filepath.ToSlash(relPath), string(content), info)
 	log.Printf("graphthulhu: reindexed %s\n", relPath)
+	c.notifyPageChanges(changes)
 }
 
 // indexFileWithLinks parses, indexes a file, AND rebuilds backlinks under
 // a single lock. A reader cannot observe a state where the page exists in
 // c.pages but backlinks don't yet reflect its new content. Replaces the
 // previous indexFile + BuildBacklinks sequence (two separate lock
-// acquisitions with an observable intermediate window).
-func (c *Client) indexFileWithLinks(relPath, content string, info os.FileInfo) {
+// acquisitions with an observable intermediate window). Returns the pages
+// that changed.
+func (c *Client) indexFileWithLinks(relPath, content string, info os.FileInfo) []backend.PageChange {
 	page := c.parseFile(relPath, content, info)
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	changes := []backend.PageChange{{Page: page.entity.OriginalName}}
+	// A title:: edit renames the page without renaming the file.
+	if old := c.pageByFileLocked(relPath); old != nil && old.lowerName != page.lowerName {
+		c.removePageFromIndexLocked(old.lowerName)
+		changes = append(changes, backend.PageChange{Page: old.entity.OriginalName, Deleted: true})
+	}
 	c.applyPageIndex(page)
 	c.rebuildLinksLocked()
+	return changes
 }
 
-// removePageWithLinks removes a page AND rebuilds backlinks under a
-// single lock, so the removal is atomic to readers.
-func (c *Client) removePageWithLinks(lowerName string) {
+// removeFileWithLinks removes the page stored in relPath AND rebuilds
+// backlinks under a single lock, so the removal is atomic to readers.
+// Returns the page removed, if relPath held one.
+func (c *Client) removeFileWithLinks(relPath string) []backend.PageChange {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.removePageFromIndexLocked(lowerName)
+	var changes []backend.PageChange
+	if page := c.pageByFileLocked(relPath); page != nil {
+		c.removePageFromIndexLocked(page.lowerName)
+		changes = append(changes, backend.PageChange{Page: page.entity.OriginalName, Deleted: true})
+	}
 	c.rebuildLinksLocked()
+	return changes
+}
+
+// WatchPages implements backend.PageWatcher. Changes are reported once the
+// file watcher (see Watch) has settled them, for edits made in other
+// programs and through the client alike.
+func (c *Client) WatchPages(ctx context.Context, fn func(backend.PageChange)) {
+	c.listenMu.Lock()
+	if c.listeners == nil {
+		c.listeners = make(map[int]func(backend.PageChange))
+	}
+	id := c.nextListener
+	c.nextListener++
+	c.listeners[id] = fn
+	c.listenMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.listenMu.Lock()
+		delete(c.listeners, id)
+		c.listenMu.Unlock()
+	}()
+}
+
+// notifyPageChanges passes changes to the WatchPages listeners. They are
+// called without listenMu held, so a listener may start or stop watching.
+func (c *Client) notifyPageChanges(changes []backend.PageChange) {
+	c.listenMu.Lock()
+	listeners := make([]func(backend.PageChange), 0, len(c.listeners))
+	for _, fn := range c.listeners {
+		listeners = append(listeners, fn)
+	}
+	c.listenMu.Unlock()
+	for _, change := range changes {
+		for _, fn := range listeners {
+			fn(change)
+		}
+	}
 }
 
 // flushPendingEventsForTest synchronously resolves all pending events.
@@ -8,6 +8,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/skridlevsky/graphthulhu/backend"
 )
 
 // TestDebounce_AtomicRenameNoMissingPage simulates an atomic temp+rename:
@@ -174,8 +176,8 @@ func TestIndexFileWithLinks_Atomic(t *testing.T) {
 	}
 }
 
-// TestRemovePageWithLinks_Atomic: analogue for removal.
-func TestRemovePageWithLinks_Atomic(t *testing.T) {
+// TestRemoveFileWithLinks_Atomic: analogue for removal.
+func TestRemoveFileWithLinks_Atomic(t *testing.T) {
 	c := testWritableVault(t)
 	ctx := context.Background()
 
@@ -199,7 +201,9 @@ func TestRemovePageWithLinks_Atomic(t *testing.T) {
 	}
 
 	// Remove atomically.
-	c.removePageWithLinks("removable")
+	if changes := c.removeFileWithLinks("removable.md"); len(changes) != 1 || !changes[0].Deleted {
+		t.Errorf("changes = %+v, want one deletion", changes)
+	}
 
 	// Page absent; backlinks for 'removable' should be empty or missing.
 	page, _ = c.GetPage(ctx, "removable")
@@ -254,3 +258,36 @@ func TestScheduleEventResolution_Coalesce(t *testing.T) {
 		t.Errorf("page should be indexed after resolve, page=%v err=%v", page, err)
 	}
 }
+
+// TestNotifyPageChanges_ListenerMayWatch: a listener that starts watching
+// from inside its callback must not deadlock on the listener lock.
+func TestNotifyPageChanges_ListenerMayWatch(t *testing.T) {
+	c := testWritableVault(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var got []string
+	c.WatchPages(ctx, func(change backend.PageChange) {
+		c.WatchPages(ctx, func(backend.PageChange) {})
+		mu.Lock()
+		got = append(got, change.Page)
+		mu.Unlock()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		c.notifyPageChanges([]backend.PageChange{{Page: "a"}})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("notifyPageChanges deadlocked")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 || got[0] != "a" {
+		t.Errorf("listener saw %v, want [a]", got)
+	}
+}